@@ -3,6 +3,10 @@ module github.com/operator-framework/operator-sdk
 go 1.16
 
 require (
+	github.com/Masterminds/semver/v3 v3.1.0
+	github.com/aws/aws-sdk-go-v2 v1.7.0
+	github.com/aws/aws-sdk-go-v2/credentials v1.3.0
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.11.0
 	github.com/blang/semver/v4 v4.0.0
 	github.com/fatih/structtag v1.1.0
 	github.com/go-logr/logr v0.3.0
@@ -29,6 +33,7 @@ require (
 	golang.org/x/lint v0.0.0-20210508222113-6edffad5e616 // indirect
 	golang.org/x/mod v0.4.2
 	golang.org/x/sys v0.0.0-20210521090106-6ca3eb03dfc2 // indirect
+	golang.org/x/time v0.0.0-20200630173020-3af7569d3a1e
 	golang.org/x/tools v0.1.1
 	gomodules.xyz/jsonpatch/v3 v3.0.1
 	helm.sh/helm/v3 v3.4.1
@@ -42,6 +47,7 @@ require (
 	sigs.k8s.io/controller-runtime v0.8.3
 	sigs.k8s.io/controller-tools v0.5.0
 	sigs.k8s.io/kubebuilder/v3 v3.0.0-beta.1
+	sigs.k8s.io/kustomize/api v0.8.1
 	sigs.k8s.io/yaml v1.2.0
 )
 
@@ -26,14 +26,19 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	crhandler "sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	ctrlpredicate "sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/operator-framework/operator-sdk/internal/ansible/events"
 	"github.com/operator-framework/operator-sdk/internal/ansible/handler"
 	"github.com/operator-framework/operator-sdk/internal/ansible/predicate"
+	"github.com/operator-framework/operator-sdk/internal/ansible/proxy/kubeconfig"
 	"github.com/operator-framework/operator-sdk/internal/ansible/runner"
 )
 
@@ -48,10 +53,19 @@ type Options struct {
 	ReconcilePeriod             time.Duration
 	ManageStatus                bool
 	AnsibleDebugLogs            bool
+	DryRun                      bool
+	ApplySchema                 bool
+	DriftDetection              string
+	RecordAppliedGeneration     bool
 	WatchDependentResources     bool
 	WatchClusterScopedResources bool
 	MaxConcurrentReconciles     int
+	RateLimiter                 ratelimiter.RateLimiter
+	SerializeByNamespace        bool
 	Selector                    metav1.LabelSelector
+	ProxyTokens                 *kubeconfig.Tokens
+	FinalizerTimeout            time.Duration
+	FinalizerMaxAttempts        int
 }
 
 // Add - Creates a new ansible operator controller and adds it to the manager
@@ -62,6 +76,7 @@ func Add(mgr manager.Manager, options Options) *controller.Controller {
 		options.EventHandlers = []events.EventHandler{}
 	}
 	eventHandlers := append(options.EventHandlers, events.NewLoggingEventHandler(options.LoggingLevel))
+	controllerName := fmt.Sprintf("%v-controller", strings.ToLower(options.GVK.Kind))
 
 	aor := &AnsibleOperatorReconciler{
 		Client:           mgr.GetClient(),
@@ -71,7 +86,19 @@ func Add(mgr manager.Manager, options Options) *controller.Controller {
 		ReconcilePeriod:  options.ReconcilePeriod,
 		ManageStatus:     options.ManageStatus,
 		AnsibleDebugLogs: options.AnsibleDebugLogs,
+		DryRun:           options.DryRun,
+		DriftDetection:   options.DriftDetection,
 		APIReader:        mgr.GetAPIReader(),
+		ProxyTokens:      options.ProxyTokens,
+		EventRecorder:    mgr.GetEventRecorderFor(controllerName),
+
+		FinalizerTimeout:     options.FinalizerTimeout,
+		FinalizerMaxAttempts: options.FinalizerMaxAttempts,
+
+		RecordAppliedGeneration: options.RecordAppliedGeneration,
+	}
+	if options.ApplySchema {
+		aor.SpecSchema = NewSpecSchema(options.GVK, mgr.GetRESTMapper(), mgr.GetAPIReader())
 	}
 
 	scheme := mgr.GetScheme()
@@ -88,11 +115,19 @@ func Add(mgr manager.Manager, options Options) *controller.Controller {
 		os.Exit(1)
 	}
 
+	var reconciler reconcile.Reconciler = aor
+	var serialized *namespaceSerializedReconciler
+	if options.SerializeByNamespace {
+		serialized = newNamespaceSerializedReconciler(aor)
+		reconciler = serialized
+	}
+
 	//Create new controller runtime controller and set the controller to watch GVK.
-	c, err := controller.New(fmt.Sprintf("%v-controller", strings.ToLower(options.GVK.Kind)), mgr,
+	c, err := controller.New(controllerName, mgr,
 		controller.Options{
-			Reconciler:              aor,
+			Reconciler:              reconciler,
 			MaxConcurrentReconciles: options.MaxConcurrentReconciles,
+			RateLimiter:             options.RateLimiter,
 		})
 	if err != nil {
 		log.Error(err, "")
@@ -101,7 +136,8 @@ func Add(mgr manager.Manager, options Options) *controller.Controller {
 
 	// Set up predicates.
 	predicates := []ctrlpredicate.Predicate{
-		ctrlpredicate.Or(ctrlpredicate.GenerationChangedPredicate{}, libpredicate.NoGenerationPredicate{}),
+		ctrlpredicate.Or(ctrlpredicate.GenerationChangedPredicate{}, libpredicate.NoGenerationPredicate{},
+			annotationSetPredicate(isForceRemoveFinalizer), annotationSetPredicate(isApprovedDriftCorrection)),
 	}
 	filterPredicate, err := predicate.NewResourceFilterPredicate(options.Selector)
 	if err != nil {
@@ -118,5 +154,24 @@ func Add(mgr manager.Manager, options Options) *controller.Controller {
 		os.Exit(1)
 	}
 
+	if serialized != nil {
+		// Requests that waited for their namespace are enqueued once it is released.
+		err = c.Watch(&source.Channel{Source: serialized.Released}, &crhandler.EnqueueRequestForObject{})
+		if err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
 	return &c
 }
+
+// annotationSetPredicate lets through updates that set an annotation, e.g. the force remove
+// finalizer annotation, so that it takes effect without waiting for the next reconcile.
+func annotationSetPredicate(isSet func(metav1.Object) bool) ctrlpredicate.Predicate {
+	return ctrlpredicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isSet(e.ObjectNew) && !isSet(e.ObjectOld)
+		},
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	ansiblestatus "github.com/operator-framework/operator-sdk/internal/ansible/controller/status"
+	"github.com/operator-framework/operator-sdk/internal/ansible/metrics"
+	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
+)
+
+const (
+	// DriftDetectionAnnotation - annotation used by a user to set how drift is handled for a CR.
+	// To use create a CR with an annotation "ansible.sdk.operatorframework.io/drift-detection: manual",
+	// "auto" or "disabled". This will override the watches file driftDetection setting for that
+	// particular CR.
+	DriftDetectionAnnotation = "ansible.sdk.operatorframework.io/drift-detection"
+
+	// ApproveDriftCorrectionAnnotation - annotation used by a user to correct the drift reported in
+	// the Drifted condition. To use, annotate the CR with
+	// "ansible.sdk.operatorframework.io/approve-drift-correction: true". The playbook or role is then
+	// run to apply the spec, and the annotation is removed.
+	ApproveDriftCorrectionAnnotation = "ansible.sdk.operatorframework.io/approve-drift-correction"
+
+	// appliedGenerationAnnotation records the generation of a CR that was last applied by a run.
+	appliedGenerationAnnotation = "ansible.sdk.operatorframework.io/applied-generation"
+
+	// correctingDriftAnnotation marks a CR whose drift is corrected automatically by the next run.
+	correctingDriftAnnotation = "ansible.sdk.operatorframework.io/correcting-drift"
+)
+
+// driftDetection returns how drift is handled for u, which is set by the drift detection
+// annotation, or else by the watch.
+func (r *AnsibleOperatorReconciler) driftDetection(u *unstructured.Unstructured) string {
+	if v, ok := u.GetAnnotations()[DriftDetectionAnnotation]; ok {
+		switch v {
+		case watches.DriftDetectionDisabled, watches.DriftDetectionAuto, watches.DriftDetectionManual:
+			return v
+		}
+		logf.Log.WithName("reconciler").Info("Invalid drift detection annotation", "value", v)
+	}
+	return r.DriftDetection
+}
+
+func (r *AnsibleOperatorReconciler) isDriftDetectionEnabled(u *unstructured.Unstructured) bool {
+	policy := r.driftDetection(u)
+	return policy == watches.DriftDetectionAuto || policy == watches.DriftDetectionManual
+}
+
+// isDriftCheck returns true if the run for u should only check for drift, which is the case
+// once its current generation was applied, until a correction is needed or approved.
+func (r *AnsibleOperatorReconciler) isDriftCheck(u *unstructured.Unstructured) bool {
+	if !r.isDriftDetectionEnabled(u) || isDriftCorrection(u) {
+		return false
+	}
+	applied, ok := u.GetAnnotations()[appliedGenerationAnnotation]
+	return ok && applied == strconv.FormatInt(u.GetGeneration(), 10)
+}
+
+func isApprovedDriftCorrection(obj metav1.Object) bool {
+	approved, err := strconv.ParseBool(obj.GetAnnotations()[ApproveDriftCorrectionAnnotation])
+	return err == nil && approved
+}
+
+// isDriftCorrection returns true if the run for u corrects drift, either approved by a user or
+// requested by an automatic correction.
+func isDriftCorrection(u *unstructured.Unstructured) bool {
+	_, correcting := u.GetAnnotations()[correctingDriftAnnotation]
+	return correcting || isApprovedDriftCorrection(u)
+}
+
+// markApplied records that the current generation of u was applied, which consumes the
+// approval or the request of a drift correction.
+func (r *AnsibleOperatorReconciler) markApplied(ctx context.Context, u *unstructured.Unstructured) error {
+	annotations := u.GetAnnotations()
+	generation := strconv.FormatInt(u.GetGeneration(), 10)
+	_, approved := annotations[ApproveDriftCorrectionAnnotation]
+	_, correcting := annotations[correctingDriftAnnotation]
+	if annotations[appliedGenerationAnnotation] == generation && !approved && !correcting {
+		return nil
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[appliedGenerationAnnotation] = generation
+	delete(annotations, ApproveDriftCorrectionAnnotation)
+	delete(annotations, correctingDriftAnnotation)
+	u.SetAnnotations(annotations)
+	return r.Client.Update(ctx, u)
+}
+
+// reconcileEvent returns the event type of the reconcile of u, which is a create until a run
+// applied it, and an update whenever its spec changed since or its drift is corrected.
+func reconcileEvent(u *unstructured.Unstructured) string {
+	if u.GetDeletionTimestamp() != nil {
+		return watches.EventDelete
+	}
+	applied, ok := u.GetAnnotations()[appliedGenerationAnnotation]
+	switch {
+	case !ok:
+		return watches.EventCreate
+	case applied != strconv.FormatInt(u.GetGeneration(), 10), isDriftCorrection(u):
+		return watches.EventUpdate
+	}
+	return watches.EventResync
+}
+
+// recordDriftCheck records the changes predicted by a successful drift check of u, and returns
+// true if the drift is corrected automatically, in which case u should be requeued.
+func (r *AnsibleOperatorReconciler) recordDriftCheck(ctx context.Context, u *unstructured.Unstructured,
+	predictedChanges []string) (bool, error) {
+
+	logger := logf.Log.WithName("reconciler").WithValues("name", u.GetName(), "namespace", u.GetNamespace())
+	if len(predictedChanges) == 0 {
+		metrics.NoDriftDetected(r.GVK.String())
+		logger.V(1).Info("No drift detected")
+		return false, nil
+	}
+	metrics.DriftDetected(r.GVK.String())
+	logger.Info("Drift detected", "predictedChanges", predictedChanges)
+	if r.driftDetection(u) != watches.DriftDetectionAuto {
+		return false, nil
+	}
+	// The next run applies the spec as an update, and consumes the marker once it succeeds.
+	annotations := u.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[correctingDriftAnnotation] = "true"
+	u.SetAnnotations(annotations)
+	return true, r.Client.Update(ctx, u)
+}
+
+// driftedCondition returns the Drifted condition recording the changes a run would make to
+// correct the drift.
+func driftedCondition(predictedChanges []string, correcting bool) *ansiblestatus.Condition {
+	c := ansiblestatus.NewCondition(
+		ansiblestatus.DriftedConditionType,
+		v1.ConditionFalse,
+		nil,
+		ansiblestatus.NoDriftReason,
+		ansiblestatus.NoDriftMessage,
+	)
+	if len(predictedChanges) > 0 {
+		c.Status = v1.ConditionTrue
+		c.Reason = ansiblestatus.DriftDetectedReason
+		if correcting {
+			c.Message = "Correcting the drift, a run is applying these changes:\n"
+		} else {
+			c.Message = fmt.Sprintf("Set the %s annotation to \"true\" to correct the drift "+
+				"by applying these changes:\n", ApproveDriftCorrectionAnnotation)
+		}
+		c.Message += predictedChangesMessage(predictedChanges)
+	}
+	return c
+}
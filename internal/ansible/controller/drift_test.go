@@ -0,0 +1,104 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
+)
+
+func TestReconcileEvent(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		deleted     bool
+		expected    string
+	}{
+		{
+			name:     "not applied",
+			expected: watches.EventCreate,
+		},
+		{
+			name:        "older generation applied",
+			annotations: map[string]string{appliedGenerationAnnotation: "1"},
+			expected:    watches.EventUpdate,
+		},
+		{
+			name:        "current generation applied",
+			annotations: map[string]string{appliedGenerationAnnotation: "2"},
+			expected:    watches.EventResync,
+		},
+		{
+			name: "drift corrected automatically",
+			annotations: map[string]string{appliedGenerationAnnotation: "2",
+				correctingDriftAnnotation: "true"},
+			expected: watches.EventUpdate,
+		},
+		{
+			name: "drift correction approved",
+			annotations: map[string]string{appliedGenerationAnnotation: "2",
+				ApproveDriftCorrectionAnnotation: "true"},
+			expected: watches.EventUpdate,
+		},
+		{
+			name:        "deleted",
+			annotations: map[string]string{appliedGenerationAnnotation: "2"},
+			deleted:     true,
+			expected:    watches.EventDelete,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := newMemcached(map[string]interface{}{})
+			u.SetGeneration(2)
+			u.SetAnnotations(tc.annotations)
+			if tc.deleted {
+				now := metav1.Now()
+				u.SetDeletionTimestamp(&now)
+			}
+			if event := reconcileEvent(u); event != tc.expected {
+				t.Fatalf("Unexpected event %v expected %v", event, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDriftedCondition(t *testing.T) {
+	if c := driftedCondition(nil, false); c.Status != v1.ConditionFalse {
+		t.Fatalf("Unexpected status %v without predicted changes", c.Status)
+	}
+
+	changes := []string{}
+	for i := 0; i < maxPredictedChanges+5; i++ {
+		changes = append(changes, fmt.Sprintf("change %d", i))
+	}
+	c := driftedCondition(changes, false)
+	if c.Status != v1.ConditionTrue {
+		t.Fatalf("Unexpected status %v with predicted changes", c.Status)
+	}
+	if !strings.Contains(c.Message, ApproveDriftCorrectionAnnotation) {
+		t.Fatalf("Message does not tell how to correct the drift:\n%s", c.Message)
+	}
+	if strings.Contains(c.Message, fmt.Sprintf("change %d", maxPredictedChanges)) ||
+		!strings.HasSuffix(c.Message, "... and 5 more") {
+		t.Fatalf("Predicted changes are not capped:\n%s", c.Message)
+	}
+}
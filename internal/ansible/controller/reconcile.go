@@ -31,6 +31,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -49,6 +50,24 @@ const (
 	// To use create a CR with an annotation "ansible.sdk.operatorframework.io/reconcile-period: 30s" or some other valid
 	// Duration. This will override the operators/or controllers reconcile period for that particular CR.
 	ReconcilePeriodAnnotation = "ansible.sdk.operatorframework.io/reconcile-period"
+
+	// DryRunAnnotation - annotation used by a user to preview the changes a reconcile would make.
+	// To use create a CR with an annotation "ansible.sdk.operatorframework.io/dry-run: true". The playbook
+	// or role is then run in check mode and the predicted changes are recorded in the DryRun condition
+	// instead of being applied. This will override the watches file dryRun setting for that particular CR.
+	DryRunAnnotation = "ansible.sdk.operatorframework.io/dry-run"
+
+	// ForceRemoveFinalizerAnnotation - annotation used by a user to unblock the deletion of a CR whose
+	// finalizer keeps failing. To use, annotate the CR with
+	// "ansible.sdk.operatorframework.io/force-remove-finalizer: true". The finalizer is then removed
+	// without being run again.
+	ForceRemoveFinalizerAnnotation = "ansible.sdk.operatorframework.io/force-remove-finalizer"
+
+	// finalizerAttemptsAnnotation counts the failed runs of the finalizer of a deleted CR.
+	finalizerAttemptsAnnotation = "ansible.sdk.operatorframework.io/finalizer-attempts"
+
+	// defaultProxyURL is where the proxy serves runs.
+	defaultProxyURL = "http://localhost:8888"
 )
 
 // AnsibleOperatorReconciler - object to reconcile runner requests
@@ -61,6 +80,23 @@ type AnsibleOperatorReconciler struct {
 	ReconcilePeriod  time.Duration
 	ManageStatus     bool
 	AnsibleDebugLogs bool
+	DryRun           bool
+	// ProxyTokens, when set, issues the token each run authenticates to the proxy with.
+	ProxyTokens *kubeconfig.Tokens
+	// ProxyURL is where runs reach the proxy, http://localhost:8888 by default.
+	ProxyURL      string
+	EventRecorder record.EventRecorder
+	// FinalizerTimeout and FinalizerMaxAttempts, when greater than 0, bound how long a failing
+	// finalizer may block the deletion of a CR before it is removed anyway.
+	FinalizerTimeout     time.Duration
+	FinalizerMaxAttempts int
+	// RecordAppliedGeneration records the generation each run applied, which distinguishes the
+	// create, update and resync events runs are routed by. It is also recorded for drift detection.
+	RecordAppliedGeneration bool
+	// DriftDetection is how drift is handled for CRs without the drift detection annotation.
+	DriftDetection string
+	// SpecSchema, when set, prunes, defaults and validates CRs before they are passed to runs.
+	SpecSchema *SpecSchema
 }
 
 // Reconcile - handle the event.
@@ -117,6 +153,12 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 		}
 	}
 
+	if deleted {
+		if reason, abandon := r.shouldAbandonFinalizer(u, finalizerAttempts(u)); abandon {
+			return reconcile.Result{}, r.abandonFinalizer(ctx, u, finalizer, reason)
+		}
+	}
+
 	spec := u.Object["spec"]
 	_, ok := spec.(map[string]interface{})
 	// Need to handle cases where there is no spec.
@@ -128,7 +170,10 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 		u.Object["spec"] = map[string]interface{}{}
 	}
 
-	if r.ManageStatus {
+	// Finalizers always run for real, otherwise the CR could never be deleted.
+	dryRun := !deleted && r.isDryRun(u)
+	// A dry run applies nothing, so its status only records its results once it completes.
+	if r.ManageStatus && !dryRun {
 		errmark := r.markRunning(ctx, request.NamespacedName, u)
 		if errmark != nil {
 			logger.Error(errmark, "Unable to update the status to mark cr as running")
@@ -136,6 +181,32 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 		}
 	}
 
+	// The run gets the CR with the schema of the CRD applied, while u stays as it is stored.
+	runObj := u
+	if r.SpecSchema != nil {
+		defaulted, invalid, err := r.SpecSchema.Apply(ctx, u)
+		if err != nil {
+			errmark := r.markError(ctx, request.NamespacedName, u, "Unable to apply the schema of the CRD")
+			if errmark != nil {
+				logger.Error(errmark, "Unable to mark error to run reconciliation")
+			}
+			logger.Error(err, "Unable to apply the schema of the CRD")
+			return reconcileResult, err
+		}
+		// Finalizers run regardless, otherwise the CR could never be deleted.
+		if len(invalid) > 0 && !deleted {
+			logger.Info("Spec does not match the schema of the CRD, skipping reconciliation",
+				"errors", invalid.ToAggregate().Error())
+			errmark := r.markFailure(ctx, request.NamespacedName, u, ansiblestatus.InvalidSpecReason,
+				fmt.Sprintf("Invalid spec: %v", invalid.ToAggregate()))
+			if errmark != nil {
+				logger.Error(errmark, "Unable to mark invalid spec")
+			}
+			return reconcile.Result{}, errmark
+		}
+		runObj = defaulted
+	}
+
 	ownerRef := metav1.OwnerReference{
 		APIVersion: u.GetAPIVersion(),
 		Kind:       u.GetKind(),
@@ -143,7 +214,7 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 		UID:        u.GetUID(),
 	}
 
-	kc, err := kubeconfig.Create(ownerRef, "http://localhost:8888", u.GetNamespace())
+	kc, revoke, err := r.createKubeconfig(ownerRef, u.GetNamespace())
 	if err != nil {
 		errmark := r.markError(ctx, request.NamespacedName, u, "Unable to run reconciliation")
 		if errmark != nil {
@@ -152,13 +223,31 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 		logger.Error(err, "Unable to generate kubeconfig")
 		return reconcileResult, err
 	}
-	defer func() {
+	cleanupKubeconfig := func() {
+		revoke()
 		if err := os.Remove(kc.Name()); err != nil {
 			logger.Error(err, "Failed to remove generated kubeconfig file")
 		}
-	}()
-	result, err := r.Runner.Run(ident, u, kc.Name())
+	}
+	// Once the spec was applied, runs only check for drift until a correction is needed or approved.
+	driftCheck := !deleted && !dryRun && r.isDriftCheck(u)
+	runOpts := []runner.RunOption{runner.WithEvent(reconcileEvent(u))}
+	if dryRun {
+		logger.Info("Dry run requested, changes will not be applied")
+		runOpts = append(runOpts, runner.WithCheckMode())
+	} else if driftCheck {
+		logger.V(1).Info("Spec was already applied, checking for drift")
+		runOpts = append(runOpts, runner.WithCheckMode())
+	}
+	// A finalizer run is stopped once the timeout to abandon the finalizer expires.
+	if deleted && r.FinalizerTimeout > 0 {
+		runCtx, cancel := context.WithDeadline(ctx, r.finalizerDeadline(u))
+		defer cancel()
+		runOpts = append(runOpts, runner.WithContext(runCtx))
+	}
+	result, err := r.Runner.Run(ident, runObj, kc.Name(), runOpts...)
 	if err != nil {
+		cleanupKubeconfig()
 		errmark := r.markError(ctx, request.NamespacedName, u, "Unable to run reconciliation")
 		if errmark != nil {
 			logger.Error(errmark, "Unable to mark error to run reconciliation")
@@ -167,9 +256,20 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 		return reconcileResult, err
 	}
 
+	// The token of a run is only revoked once the run ends, which may be after the
+	// reconcile returns, e.g. once the run requested a requeue.
+	defer func() {
+		go func() {
+			for range result.Events() {
+			}
+			cleanupKubeconfig()
+		}()
+	}()
+
 	// iterate events from ansible, looking for the final one
 	statusEvent := eventapi.StatusJobEvent{}
 	failureMessages := eventapi.FailureMessages{}
+	predictedChanges := []string{}
 	for event := range result.Events() {
 		for _, eHandler := range r.EventHandlers {
 			go eHandler.Handle(ident, u, event)
@@ -207,6 +307,15 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 		if event.Event == eventapi.EventRunnerOnFailed && !event.IgnoreError() && !event.Rescued() {
 			failureMessages = append(failureMessages, event.GetFailedPlaybookMessage())
 		}
+		if (dryRun || driftCheck) && event.Event == eventapi.EventRunnerOnOk && event.Changed() {
+			predictedChanges = append(predictedChanges, event.GetChangedTaskMessage())
+		}
+	}
+
+	if len(failureMessages) > 0 && result.Artifacts() != "" {
+		// The Failure condition tells users where to find the artifacts of the failed run.
+		failureMessages = append(failureMessages,
+			fmt.Sprintf("The artifacts of the run were exported to %s", result.Artifacts()))
 	}
 
 	// To print the stats of the task
@@ -216,6 +325,12 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 	r.printAnsibleResult(result, u)
 
 	if statusEvent.Event == "" {
+		// The finalizer run was stopped at the deadline.
+		if deleted {
+			if reason, abandon := r.shouldAbandonFinalizer(u, finalizerAttempts(u)); abandon {
+				return reconcile.Result{}, r.abandonFinalizer(ctx, u, finalizer, reason)
+			}
+		}
 		eventErr := errors.New("did not receive playbook_on_stats event")
 		stdout, err := result.Stdout()
 		if err != nil {
@@ -254,10 +369,65 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 			return reconcileResult, err
 		}
 	}
+	// The finalizer has failed, give up on it or record the attempt
+	var finalizerRetry time.Duration
+	if deleted && controllerutil.ContainsFinalizer(u, finalizer) && !runSuccessful {
+		attempts := finalizerAttempts(u) + 1
+		if reason, abandon := r.shouldAbandonFinalizer(u, attempts); abandon {
+			return reconcile.Result{}, r.abandonFinalizer(ctx, u, finalizer, reason)
+		}
+		if err := r.markFinalizerAttempt(ctx, u, attempts, failureMessages); err != nil {
+			logger.Error(err, "Failed to record failed finalizer attempt")
+			return reconcileResult, err
+		}
+		// The backoff of a failed reconcile could outlast the deadline, so the finalizer is
+		// retried no later than when it is abandoned.
+		if r.FinalizerTimeout > 0 {
+			finalizerRetry = time.Until(r.finalizerDeadline(u))
+			if reconcileResult.RequeueAfter > 0 && reconcileResult.RequeueAfter < finalizerRetry {
+				finalizerRetry = reconcileResult.RequeueAfter
+			}
+		}
+	}
+	correctingDrift := false
+	if driftCheck && runSuccessful {
+		if correctingDrift, err = r.recordDriftCheck(ctx, u, predictedChanges); err != nil {
+			logger.Error(err, "Failed to request the correction of drift")
+			return reconcileResult, err
+		}
+		if correctingDrift {
+			reconcileResult = reconcile.Result{Requeue: true}
+		}
+	}
+	if !deleted && !dryRun && !driftCheck && runSuccessful && (r.RecordAppliedGeneration || r.isDriftDetectionEnabled(u)) {
+		if err := r.markApplied(ctx, u); err != nil {
+			logger.Error(err, "Failed to record the applied generation")
+			return reconcileResult, err
+		}
+	}
+	if dryRun {
+		logger.Info("Dry run completed", "predictedChanges", predictedChanges)
+	}
 	if r.ManageStatus {
-		errmark := r.markDone(ctx, request.NamespacedName, u, statusEvent, failureMessages)
-		if errmark != nil {
-			logger.Error(errmark, "Failed to mark status done")
+		var errmark error
+		if dryRun {
+			// A dry run applied nothing, so only its results are recorded.
+			errmark = r.markDryRun(ctx, request.NamespacedName, u, statusEvent, failureMessages, predictedChanges)
+			if errmark != nil {
+				logger.Error(errmark, "Failed to mark dry run results")
+			}
+		} else {
+			var drifted *ansiblestatus.Condition
+			if driftCheck && runSuccessful {
+				drifted = driftedCondition(predictedChanges, correctingDrift)
+			}
+			errmark = r.markDone(ctx, request.NamespacedName, u, statusEvent, failureMessages, drifted)
+			if errmark != nil {
+				logger.Error(errmark, "Failed to mark status done")
+			}
+		}
+		if finalizerRetry > 0 {
+			return reconcile.Result{RequeueAfter: finalizerRetry}, errmark
 		}
 		// re-trigger reconcile because of failures
 		if !runSuccessful {
@@ -266,6 +436,9 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 		return reconcileResult, errmark
 	}
 
+	if finalizerRetry > 0 {
+		return reconcile.Result{RequeueAfter: finalizerRetry}, nil
+	}
 	// re-trigger reconcile because of failures
 	if !runSuccessful {
 		return reconcileResult, errors.New("received failed task event")
@@ -323,6 +496,12 @@ func (r *AnsibleOperatorReconciler) markRunning(ctx context.Context, nn types.Na
 // i.e Annotations that could be incorrect
 func (r *AnsibleOperatorReconciler) markError(ctx context.Context, nn types.NamespacedName, u *unstructured.Unstructured,
 	failureMessage string) error {
+	return r.markFailure(ctx, nn, u, ansiblestatus.FailedReason, failureMessage)
+}
+
+// markFailure - sets the Failure condition with reason, e.g. when the reconcile could not run.
+func (r *AnsibleOperatorReconciler) markFailure(ctx context.Context, nn types.NamespacedName, u *unstructured.Unstructured,
+	reason, failureMessage string) error {
 
 	logger := logf.Log.WithName("markError")
 	// Immediately update metrics with failed reconciliation, since Get()
@@ -348,7 +527,7 @@ func (r *AnsibleOperatorReconciler) markError(ctx context.Context, nn types.Name
 		ansiblestatus.FailureConditionType,
 		v1.ConditionTrue,
 		nil,
-		ansiblestatus.FailedReason,
+		reason,
 		failureMessage,
 	)
 	ansiblestatus.SetCondition(&crStatus, *c)
@@ -358,8 +537,11 @@ func (r *AnsibleOperatorReconciler) markError(ctx context.Context, nn types.Name
 	return r.Client.Status().Update(ctx, u)
 }
 
+// markDone - records the results of a run. drifted, when set, is the Drifted condition
+// recorded by a drift check, which replaces the results of a previous one.
 func (r *AnsibleOperatorReconciler) markDone(ctx context.Context, nn types.NamespacedName, u *unstructured.Unstructured,
-	statusEvent eventapi.StatusJobEvent, failureMessages eventapi.FailureMessages) error {
+	statusEvent eventapi.StatusJobEvent, failureMessages eventapi.FailureMessages,
+	drifted *ansiblestatus.Condition) error {
 
 	logger := logf.Log.WithName("markDone")
 	// Get the latest resource to prevent updating a stale status.
@@ -401,7 +583,13 @@ func (r *AnsibleOperatorReconciler) markDone(ctx context.Context, nn types.Names
 		)
 		// Remove the failure condition if set, because this completed successfully.
 		ansiblestatus.RemoveCondition(&crStatus, ansiblestatus.FailureConditionType)
+		// Remove the results of a previous dry run or drift check, they no longer describe what is deployed.
+		ansiblestatus.RemoveCondition(&crStatus, ansiblestatus.DryRunConditionType)
+		ansiblestatus.RemoveCondition(&crStatus, ansiblestatus.DriftedConditionType)
 		ansiblestatus.SetCondition(&crStatus, *c)
+		if drifted != nil {
+			ansiblestatus.SetCondition(&crStatus, *drifted)
+		}
 	}
 	// This needs the status subresource to be enabled by default.
 	u.Object["status"] = crStatus.GetJSONMap()
@@ -409,6 +597,212 @@ func (r *AnsibleOperatorReconciler) markDone(ctx context.Context, nn types.Names
 	return r.Client.Status().Update(ctx, u)
 }
 
+// markDryRun - records the changes ansible predicted while running in check mode, and the
+// failures of the run. The Running condition is left as is, since nothing was applied.
+func (r *AnsibleOperatorReconciler) markDryRun(ctx context.Context, nn types.NamespacedName, u *unstructured.Unstructured,
+	statusEvent eventapi.StatusJobEvent, failureMessages eventapi.FailureMessages, predictedChanges []string) error {
+
+	logger := logf.Log.WithName("markDryRun")
+	// Get the latest resource to prevent updating a stale status.
+	if err := r.APIReader.Get(ctx, nn, u); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("Resource not found, assuming it was deleted")
+			return nil
+		}
+		return err
+	}
+	crStatus := getStatus(u)
+
+	if len(failureMessages) > 0 {
+		metrics.ReconcileFailed(r.GVK.String())
+		f := ansiblestatus.NewCondition(
+			ansiblestatus.FailureConditionType,
+			v1.ConditionTrue,
+			ansiblestatus.NewAnsibleResultFromStatusJobEvent(statusEvent),
+			ansiblestatus.FailedReason,
+			strings.Join(failureMessages, "\n"),
+		)
+		ansiblestatus.SetCondition(&crStatus, *f)
+	} else {
+		metrics.ReconcileSucceeded(r.GVK.String())
+		ansiblestatus.RemoveCondition(&crStatus, ansiblestatus.FailureConditionType)
+	}
+	c := ansiblestatus.NewCondition(
+		ansiblestatus.DryRunConditionType,
+		v1.ConditionFalse,
+		nil,
+		ansiblestatus.NoChangesPredictedReason,
+		ansiblestatus.NoChangesPredictedMessage,
+	)
+	if len(predictedChanges) > 0 {
+		c.Status = v1.ConditionTrue
+		c.Reason = ansiblestatus.ChangesPredictedReason
+		c.Message = predictedChangesMessage(predictedChanges)
+	}
+	// Always replace the condition, the predicted changes may differ between runs.
+	ansiblestatus.RemoveCondition(&crStatus, ansiblestatus.DryRunConditionType)
+	ansiblestatus.SetCondition(&crStatus, *c)
+	// This needs the status subresource to be enabled by default.
+	u.Object["status"] = crStatus.GetJSONMap()
+
+	return r.Client.Status().Update(ctx, u)
+}
+
+// maxPredictedChanges is the number of predicted changes listed in the message of the DryRun condition.
+const maxPredictedChanges = 20
+
+// predictedChangesMessage lists the first maxPredictedChanges of changes, one per line,
+// followed by the number of changes that were left out.
+func predictedChangesMessage(changes []string) string {
+	if len(changes) <= maxPredictedChanges {
+		return strings.Join(changes, "\n")
+	}
+	return fmt.Sprintf("%s\n... and %d more", strings.Join(changes[:maxPredictedChanges], "\n"),
+		len(changes)-maxPredictedChanges)
+}
+
+// createKubeconfig writes the kubeconfig a run uses to reach the API server through
+// the proxy. With ProxyTokens set, it holds a token for the owner, which is revoked
+// by calling the returned function once the run is over.
+func (r *AnsibleOperatorReconciler) createKubeconfig(ownerRef metav1.OwnerReference,
+	namespace string) (*os.File, func(), error) {
+	proxyURL := r.ProxyURL
+	if proxyURL == "" {
+		proxyURL = defaultProxyURL
+	}
+	if r.ProxyTokens == nil {
+		kc, err := kubeconfig.Create(ownerRef, proxyURL, namespace)
+		return kc, func() {}, err
+	}
+	token, err := r.ProxyTokens.Issue(kubeconfig.NamespacedOwnerReference{OwnerReference: ownerRef, Namespace: namespace})
+	if err != nil {
+		return nil, nil, err
+	}
+	revoke := func() { r.ProxyTokens.Revoke(token) }
+	kc, err := kubeconfig.CreateWithToken(token, proxyURL, namespace)
+	if err != nil {
+		revoke()
+		return nil, nil, err
+	}
+	return kc, revoke, nil
+}
+
+// shouldAbandonFinalizer returns true, along with the reason, if the finalizer of the
+// deleted CR u should be removed even though it has not run successfully. attempts is
+// the number of times the finalizer has failed.
+func (r *AnsibleOperatorReconciler) shouldAbandonFinalizer(u *unstructured.Unstructured, attempts int) (string, bool) {
+	if isForceRemoveFinalizer(u) {
+		return fmt.Sprintf("the %s annotation is set", ForceRemoveFinalizerAnnotation), true
+	}
+	if r.FinalizerMaxAttempts > 0 && attempts >= r.FinalizerMaxAttempts {
+		return fmt.Sprintf("the finalizer failed %d times", attempts), true
+	}
+	if r.FinalizerTimeout > 0 && !time.Now().Before(r.finalizerDeadline(u)) {
+		return fmt.Sprintf("the finalizer did not succeed within %s of deletion", r.FinalizerTimeout), true
+	}
+	return "", false
+}
+
+// finalizerDeadline returns when the finalizer of the deleted CR u is abandoned if
+// it has not succeeded by then.
+func (r *AnsibleOperatorReconciler) finalizerDeadline(u *unstructured.Unstructured) time.Time {
+	return u.GetDeletionTimestamp().Add(r.FinalizerTimeout)
+}
+
+// abandonFinalizer removes finalizer from u without it having run successfully, so that
+// the deletion of u is no longer blocked, and records why with an Event.
+func (r *AnsibleOperatorReconciler) abandonFinalizer(ctx context.Context, u *unstructured.Unstructured,
+	finalizer, reason string) error {
+	controllerutil.RemoveFinalizer(u, finalizer)
+	if err := r.Client.Update(ctx, u); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	logf.Log.WithName("reconciler").Info("Removed finalizer without cleanup", "name", u.GetName(),
+		"namespace", u.GetNamespace(), "finalizer", finalizer, "reason", reason)
+	if r.EventRecorder != nil {
+		r.EventRecorder.Eventf(u, v1.EventTypeWarning, "FinalizerAbandoned",
+			"Removed finalizer %s without cleanup: %s", finalizer, reason)
+	}
+	return nil
+}
+
+// markFinalizerAttempt records a failed finalizer run on the deleted CR u, and reports
+// why its deletion is blocked in the DeletionBlocked condition.
+func (r *AnsibleOperatorReconciler) markFinalizerAttempt(ctx context.Context, u *unstructured.Unstructured,
+	attempts int, failureMessages eventapi.FailureMessages) error {
+	annotations := u.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[finalizerAttemptsAnnotation] = strconv.Itoa(attempts)
+	u.SetAnnotations(annotations)
+	if err := r.Client.Update(ctx, u); err != nil {
+		return err
+	}
+	if !r.ManageStatus {
+		return nil
+	}
+
+	message := fmt.Sprintf("Failed finalizer attempts: %d", attempts)
+	if r.FinalizerMaxAttempts > 0 {
+		message += fmt.Sprintf(" of %d", r.FinalizerMaxAttempts)
+	}
+	if r.FinalizerTimeout > 0 {
+		deadline := r.finalizerDeadline(u)
+		message += fmt.Sprintf(". The finalizer is removed if it still fails after %s",
+			deadline.UTC().Format(time.RFC3339))
+	}
+	message += fmt.Sprintf(". Set the %s annotation to remove it now.", ForceRemoveFinalizerAnnotation)
+	if len(failureMessages) > 0 {
+		message += "\n" + strings.Join(failureMessages, "\n")
+	}
+
+	crStatus := getStatus(u)
+	c := ansiblestatus.NewCondition(
+		ansiblestatus.DeletionBlockedConditionType,
+		v1.ConditionTrue,
+		nil,
+		ansiblestatus.FinalizerFailedReason,
+		message,
+	)
+	// Always replace the condition, the attempts and failures differ between runs.
+	ansiblestatus.RemoveCondition(&crStatus, ansiblestatus.DeletionBlockedConditionType)
+	ansiblestatus.SetCondition(&crStatus, *c)
+	u.Object["status"] = crStatus.GetJSONMap()
+	return r.Client.Status().Update(ctx, u)
+}
+
+// finalizerAttempts returns the number of failed finalizer runs recorded on u.
+func finalizerAttempts(u *unstructured.Unstructured) int {
+	attempts, err := strconv.Atoi(u.GetAnnotations()[finalizerAttemptsAnnotation])
+	if err != nil {
+		return 0
+	}
+	return attempts
+}
+
+// isForceRemoveFinalizer returns true if obj is annotated to have its finalizer removed.
+func isForceRemoveFinalizer(obj metav1.Object) bool {
+	force, err := strconv.ParseBool(obj.GetAnnotations()[ForceRemoveFinalizerAnnotation])
+	return err == nil && force
+}
+
+// isDryRun returns true if u should only be reconciled in check mode, either because
+// the watch enables it or because the CR sets the dry run annotation.
+func (r *AnsibleOperatorReconciler) isDryRun(u *unstructured.Unstructured) bool {
+	if v, ok := u.GetAnnotations()[DryRunAnnotation]; ok {
+		dryRun, err := strconv.ParseBool(v)
+		if err == nil {
+			return dryRun
+		}
+		logf.Log.WithName("reconciler").Info("Invalid dry run annotation", "err", err, "value", v)
+	}
+	return r.DryRun
+}
+
 // getStatus returns u's "status" block as a status.Status.
 func getStatus(u *unstructured.Unstructured) ansiblestatus.Status {
 	statusInterface := u.Object["status"]
@@ -16,6 +16,7 @@ package controller_test
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -33,6 +34,7 @@ import (
 	"github.com/operator-framework/operator-sdk/internal/ansible/runner"
 	"github.com/operator-framework/operator-sdk/internal/ansible/runner/eventapi"
 	"github.com/operator-framework/operator-sdk/internal/ansible/runner/fake"
+	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
 )
 
 func TestReconcile(t *testing.T) {
@@ -54,6 +56,11 @@ func TestReconcile(t *testing.T) {
 		Request         reconcile.Request
 		ShouldError     bool
 		ManageStatus    bool
+		DryRun          bool
+		DriftDetection  string
+
+		FinalizerTimeout     time.Duration
+		FinalizerMaxAttempts int
 	}{
 		{
 			Name:            "cr not found",
@@ -206,6 +213,82 @@ func TestReconcile(t *testing.T) {
 			},
 			ShouldError: true,
 		},
+		{
+			Name:         "Failure event runner on failed with exported artifacts",
+			GVK:          gvk,
+			ManageStatus: true,
+			Runner: &fake.Runner{
+				JobEvents: []eventapi.JobEvent{
+					eventapi.JobEvent{
+						Event:   eventapi.EventRunnerOnFailed,
+						Created: eventapi.EventTime{Time: eventTime},
+						EventData: map[string]interface{}{
+							"res": map[string]interface{}{
+								"msg": "new failure message",
+							},
+						},
+					},
+					eventapi.JobEvent{
+						Event:   eventapi.EventPlaybookOnStats,
+						Created: eventapi.EventTime{Time: eventTime},
+					},
+				},
+				Artifacts: "s3://bucket/uid/20210301T120000Z-1.tar.gz",
+			},
+			Client: fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+				},
+			}).Build(),
+			Request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "reconcile",
+					Namespace: "default",
+				},
+			},
+			ExpectedObject: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+					"status": map[string]interface{}{
+						"conditions": []interface{}{
+							map[string]interface{}{
+								"status":  "False",
+								"type":    "Running",
+								"message": "Running reconciliation",
+								"reason":  "Running",
+							},
+							map[string]interface{}{
+								"status": "True",
+								"type":   "Failure",
+								"ansibleResult": map[string]interface{}{
+									"changed":    int64(0),
+									"failures":   int64(0),
+									"ok":         int64(0),
+									"skipped":    int64(0),
+									"completion": eventTime.Format("2006-01-02T15:04:05.99999999"),
+								},
+								"message": "new failure message\nThe artifacts of the run were exported to " +
+									"s3://bucket/uid/20210301T120000Z-1.tar.gz",
+								"reason": "Failed",
+							},
+						},
+					},
+				},
+			},
+			ShouldError: true,
+		},
 		{
 			Name:         "Failure event runner on failed",
 			GVK:          gvk,
@@ -439,30 +522,79 @@ func TestReconcile(t *testing.T) {
 			},
 		},
 		{
-			Name:            "No status event",
-			GVK:             gvk,
-			ReconcilePeriod: 5 * time.Second,
+			Name:                 "Finalizer failure records the attempt",
+			GVK:                  gvk,
+			ReconcilePeriod:      5 * time.Second,
+			ManageStatus:         true,
+			FinalizerMaxAttempts: 3,
 			Runner: &fake.Runner{
 				JobEvents: []eventapi.JobEvent{
 					eventapi.JobEvent{
+						Event:   eventapi.EventRunnerOnFailed,
+						Created: eventapi.EventTime{Time: eventTime},
+						EventData: map[string]interface{}{
+							"res": map[string]interface{}{
+								"msg": "new failure message",
+							},
+						},
+					},
+					eventapi.JobEvent{
+						Event:   eventapi.EventPlaybookOnStats,
 						Created: eventapi.EventTime{Time: eventTime},
 					},
 				},
+				Finalizer: "testing.io/finalizer",
 			},
 			Client: fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
 				Object: map[string]interface{}{
 					"metadata": map[string]interface{}{
 						"name":      "reconcile",
 						"namespace": "default",
+						"finalizers": []interface{}{
+							"testing.io/finalizer",
+						},
+						"deletionTimestamp": eventTime.Format(time.RFC3339),
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+				},
+			}).Build(),
+			Result: reconcile.Result{
+				RequeueAfter: 5 * time.Second,
+			},
+			Request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "reconcile",
+					Namespace: "default",
+				},
+			},
+			ExpectedObject: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+						"annotations": map[string]interface{}{
+							"ansible.sdk.operatorframework.io/finalizer-attempts": "1",
+						},
+						"finalizers": []interface{}{
+							"testing.io/finalizer",
+						},
 					},
 					"apiVersion": "operator-sdk/v1beta1",
 					"kind":       "Testing",
 					"spec":       map[string]interface{}{},
 					"status": map[string]interface{}{
 						"conditions": []interface{}{
+							map[string]interface{}{
+								"status":  "False",
+								"type":    "Running",
+								"message": "Running reconciliation",
+								"reason":  "Running",
+							},
 							map[string]interface{}{
 								"status": "True",
-								"type":   "Running",
+								"type":   "Failure",
 								"ansibleResult": map[string]interface{}{
 									"changed":    int64(0),
 									"failures":   int64(0),
@@ -470,44 +602,140 @@ func TestReconcile(t *testing.T) {
 									"skipped":    int64(0),
 									"completion": eventTime.Format("2006-01-02T15:04:05.99999999"),
 								},
-								"message": "Failed to get ansible-runner stdout",
+								"message": "new failure message",
+								"reason":  "Failed",
+							},
+							map[string]interface{}{
+								"status": "True",
+								"type":   "DeletionBlocked",
+								"message": "Failed finalizer attempts: 1 of 3. Set the " +
+									controller.ForceRemoveFinalizerAnnotation +
+									" annotation to remove it now.\nnew failure message",
+								"reason": "FinalizerFailed",
+							},
+						},
+					},
+				},
+			},
+			ShouldError: true,
+		},
+		{
+			Name:                 "Finalizer removed after max attempts",
+			GVK:                  gvk,
+			ReconcilePeriod:      5 * time.Second,
+			FinalizerMaxAttempts: 3,
+			Runner: &fake.Runner{
+				JobEvents: []eventapi.JobEvent{
+					eventapi.JobEvent{
+						Event:   eventapi.EventRunnerOnFailed,
+						Created: eventapi.EventTime{Time: eventTime},
+						EventData: map[string]interface{}{
+							"res": map[string]interface{}{
+								"msg": "new failure message",
 							},
 						},
 					},
+					eventapi.JobEvent{
+						Event:   eventapi.EventPlaybookOnStats,
+						Created: eventapi.EventTime{Time: eventTime},
+					},
+				},
+				Finalizer: "testing.io/finalizer",
+			},
+			Client: fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+						"annotations": map[string]interface{}{
+							"ansible.sdk.operatorframework.io/finalizer-attempts": "2",
+						},
+						"finalizers": []interface{}{
+							"testing.io/finalizer",
+						},
+						"deletionTimestamp": eventTime.Format(time.RFC3339),
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
 				},
 			}).Build(),
-			Result: reconcile.Result{
-				RequeueAfter: 5 * time.Second,
+			Result: reconcile.Result{},
+			Request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "reconcile",
+					Namespace: "default",
+				},
+			},
+		},
+		{
+			Name:             "Finalizer removed without running after the timeout",
+			GVK:              gvk,
+			ReconcilePeriod:  5 * time.Second,
+			FinalizerTimeout: 10 * time.Minute,
+			Runner: &fake.Runner{
+				Error:     errors.New("finalizer should not run"),
+				Finalizer: "testing.io/finalizer",
 			},
+			Client: fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+						"finalizers": []interface{}{
+							"testing.io/finalizer",
+						},
+						"deletionTimestamp": eventTime.Add(-time.Hour).Format(time.RFC3339),
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+				},
+			}).Build(),
+			Result: reconcile.Result{},
 			Request: reconcile.Request{
 				NamespacedName: types.NamespacedName{
 					Name:      "reconcile",
 					Namespace: "default",
 				},
 			},
-			ShouldError: true,
 		},
 		{
-			Name:            "no manage status",
-			GVK:             gvk,
-			ReconcilePeriod: 5 * time.Second,
-			ManageStatus:    false,
+			Name:             "Finalizer failure is retried before the timeout",
+			GVK:              gvk,
+			ReconcilePeriod:  5 * time.Second,
+			FinalizerTimeout: 10 * time.Minute,
 			Runner: &fake.Runner{
 				JobEvents: []eventapi.JobEvent{
+					eventapi.JobEvent{
+						Event:   eventapi.EventRunnerOnFailed,
+						Created: eventapi.EventTime{Time: eventTime},
+						EventData: map[string]interface{}{
+							"res": map[string]interface{}{
+								"msg": "new failure message",
+							},
+						},
+					},
 					eventapi.JobEvent{
 						Event:   eventapi.EventPlaybookOnStats,
 						Created: eventapi.EventTime{Time: eventTime},
 					},
 				},
+				Finalizer: "testing.io/finalizer",
 			},
 			Client: fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
 				Object: map[string]interface{}{
 					"metadata": map[string]interface{}{
 						"name":      "reconcile",
 						"namespace": "default",
+						"finalizers": []interface{}{
+							"testing.io/finalizer",
+						},
+						"deletionTimestamp": eventTime.Format(time.RFC3339),
 					},
 					"apiVersion": "operator-sdk/v1beta1",
 					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
 				},
 			}).Build(),
 			Result: reconcile.Result{
@@ -524,26 +752,681 @@ func TestReconcile(t *testing.T) {
 					"metadata": map[string]interface{}{
 						"name":      "reconcile",
 						"namespace": "default",
+						"annotations": map[string]interface{}{
+							"ansible.sdk.operatorframework.io/finalizer-attempts": "1",
+						},
+						"finalizers": []interface{}{
+							"testing.io/finalizer",
+						},
 					},
 					"apiVersion": "operator-sdk/v1beta1",
 					"kind":       "Testing",
 					"spec":       map[string]interface{}{},
-					"status":     map[string]interface{}{},
 				},
 			},
 		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.Name, func(t *testing.T) {
-			var aor reconcile.Reconciler = &controller.AnsibleOperatorReconciler{
-				GVK:             tc.GVK,
-				Runner:          tc.Runner,
-				Client:          tc.Client,
-				APIReader:       tc.Client,
-				EventHandlers:   tc.EventHandlers,
-				ReconcilePeriod: tc.ReconcilePeriod,
-				ManageStatus:    tc.ManageStatus,
+		{
+			Name:            "Finalizer force removed by annotation",
+			GVK:             gvk,
+			ReconcilePeriod: 5 * time.Second,
+			Runner: &fake.Runner{
+				Error:     errors.New("finalizer should not run"),
+				Finalizer: "testing.io/finalizer",
+			},
+			Client: fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+						"annotations": map[string]interface{}{
+							controller.ForceRemoveFinalizerAnnotation: "true",
+						},
+						"finalizers": []interface{}{
+							"testing.io/finalizer",
+						},
+						"deletionTimestamp": eventTime.Format(time.RFC3339),
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+				},
+			}).Build(),
+			Result: reconcile.Result{},
+			Request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "reconcile",
+					Namespace: "default",
+				},
+			},
+			ExpectedObject: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+						"annotations": map[string]interface{}{
+							controller.ForceRemoveFinalizerAnnotation: "true",
+						},
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+				},
+			},
+		},
+		{
+			Name:            "No status event",
+			GVK:             gvk,
+			ReconcilePeriod: 5 * time.Second,
+			Runner: &fake.Runner{
+				JobEvents: []eventapi.JobEvent{
+					eventapi.JobEvent{
+						Created: eventapi.EventTime{Time: eventTime},
+					},
+				},
+			},
+			Client: fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+					"status": map[string]interface{}{
+						"conditions": []interface{}{
+							map[string]interface{}{
+								"status": "True",
+								"type":   "Running",
+								"ansibleResult": map[string]interface{}{
+									"changed":    int64(0),
+									"failures":   int64(0),
+									"ok":         int64(0),
+									"skipped":    int64(0),
+									"completion": eventTime.Format("2006-01-02T15:04:05.99999999"),
+								},
+								"message": "Failed to get ansible-runner stdout",
+							},
+						},
+					},
+				},
+			}).Build(),
+			Result: reconcile.Result{
+				RequeueAfter: 5 * time.Second,
+			},
+			Request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "reconcile",
+					Namespace: "default",
+				},
+			},
+			ShouldError: true,
+		},
+		{
+			Name:            "dry run records predicted changes",
+			GVK:             gvk,
+			ReconcilePeriod: 5 * time.Second,
+			ManageStatus:    true,
+			DryRun:          true,
+			Runner: &fake.Runner{
+				JobEvents: []eventapi.JobEvent{
+					eventapi.JobEvent{
+						Event:   eventapi.EventRunnerOnOk,
+						Created: eventapi.EventTime{Time: eventTime},
+						EventData: map[string]interface{}{
+							"task": "create deployment",
+							"res": map[string]interface{}{
+								"changed": true,
+								"result": map[string]interface{}{
+									"kind": "Deployment",
+									"metadata": map[string]interface{}{
+										"name":      "example",
+										"namespace": "default",
+									},
+								},
+							},
+						},
+					},
+					eventapi.JobEvent{
+						Event:   eventapi.EventRunnerOnOk,
+						Created: eventapi.EventTime{Time: eventTime},
+						EventData: map[string]interface{}{
+							"task": "unchanged task",
+							"res": map[string]interface{}{
+								"changed": false,
+							},
+						},
+					},
+					eventapi.JobEvent{
+						Event:   eventapi.EventPlaybookOnStats,
+						Created: eventapi.EventTime{Time: eventTime},
+					},
+				},
+			},
+			Client: fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+				},
+			}).Build(),
+			Result: reconcile.Result{
+				RequeueAfter: 5 * time.Second,
+			},
+			Request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "reconcile",
+					Namespace: "default",
+				},
+			},
+			ExpectedObject: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+					"status": map[string]interface{}{
+						"conditions": []interface{}{
+							map[string]interface{}{
+								"status":  "True",
+								"type":    "DryRun",
+								"message": "create deployment: Deployment default/example",
+								"reason":  "ChangesPredicted",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:            "dry run annotation overrides the watch",
+			GVK:             gvk,
+			ReconcilePeriod: 5 * time.Second,
+			ManageStatus:    true,
+			Runner: &fake.Runner{
+				JobEvents: []eventapi.JobEvent{
+					eventapi.JobEvent{
+						Event:   eventapi.EventPlaybookOnStats,
+						Created: eventapi.EventTime{Time: eventTime},
+					},
+				},
+			},
+			Client: fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+						"annotations": map[string]interface{}{
+							controller.DryRunAnnotation: "true",
+						},
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+				},
+			}).Build(),
+			Result: reconcile.Result{
+				RequeueAfter: 5 * time.Second,
+			},
+			Request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "reconcile",
+					Namespace: "default",
+				},
+			},
+			ExpectedObject: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+						"annotations": map[string]interface{}{
+							controller.DryRunAnnotation: "true",
+						},
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+					"status": map[string]interface{}{
+						"conditions": []interface{}{
+							map[string]interface{}{
+								"status":  "False",
+								"type":    "DryRun",
+								"message": "Dry run completed, no changes would be made",
+								"reason":  "NoChangesPredicted",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:            "drift detection records the applied generation",
+			GVK:             gvk,
+			ReconcilePeriod: 5 * time.Second,
+			ManageStatus:    true,
+			DriftDetection:  watches.DriftDetectionManual,
+			Runner: &fake.Runner{
+				JobEvents: []eventapi.JobEvent{
+					eventapi.JobEvent{
+						Event:   eventapi.EventRunnerOnOk,
+						Created: eventapi.EventTime{Time: eventTime},
+						EventData: map[string]interface{}{
+							"task": "create deployment",
+							"res": map[string]interface{}{
+								"changed": true,
+								"result": map[string]interface{}{
+									"kind": "Deployment",
+									"metadata": map[string]interface{}{
+										"name":      "example",
+										"namespace": "default",
+									},
+								},
+							},
+						},
+					},
+					eventapi.JobEvent{
+						Event:   eventapi.EventPlaybookOnStats,
+						Created: eventapi.EventTime{Time: eventTime},
+					},
+				},
+			},
+			Client: fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+				},
+			}).Build(),
+			Result: reconcile.Result{
+				RequeueAfter: 5 * time.Second,
+			},
+			Request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "reconcile",
+					Namespace: "default",
+				},
+			},
+			ExpectedObject: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+						"annotations": map[string]interface{}{
+							"ansible.sdk.operatorframework.io/applied-generation": "0",
+						},
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+					"status": map[string]interface{}{
+						"conditions": []interface{}{
+							map[string]interface{}{
+								"status": "True",
+								"type":   "Running",
+								"ansibleResult": map[string]interface{}{
+									"changed":    int64(0),
+									"failures":   int64(0),
+									"ok":         int64(0),
+									"skipped":    int64(0),
+									"completion": eventTime.Format("2006-01-02T15:04:05.99999999"),
+								},
+								"message": "Awaiting next reconciliation",
+								"reason":  "Successful",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:            "drift check records the drift to correct manually",
+			GVK:             gvk,
+			ReconcilePeriod: 5 * time.Second,
+			ManageStatus:    true,
+			DriftDetection:  watches.DriftDetectionManual,
+			Runner: &fake.Runner{
+				JobEvents: []eventapi.JobEvent{
+					eventapi.JobEvent{
+						Event:   eventapi.EventRunnerOnOk,
+						Created: eventapi.EventTime{Time: eventTime},
+						EventData: map[string]interface{}{
+							"task": "create deployment",
+							"res": map[string]interface{}{
+								"changed": true,
+								"result": map[string]interface{}{
+									"kind": "Deployment",
+									"metadata": map[string]interface{}{
+										"name":      "example",
+										"namespace": "default",
+									},
+								},
+							},
+						},
+					},
+					eventapi.JobEvent{
+						Event:   eventapi.EventPlaybookOnStats,
+						Created: eventapi.EventTime{Time: eventTime},
+					},
+				},
+			},
+			Client: fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+						"annotations": map[string]interface{}{
+							"ansible.sdk.operatorframework.io/applied-generation": "0",
+						},
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+				},
+			}).Build(),
+			Result: reconcile.Result{
+				RequeueAfter: 5 * time.Second,
+			},
+			Request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "reconcile",
+					Namespace: "default",
+				},
+			},
+			ExpectedObject: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+						"annotations": map[string]interface{}{
+							"ansible.sdk.operatorframework.io/applied-generation": "0",
+						},
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+					"status": map[string]interface{}{
+						"conditions": []interface{}{
+							map[string]interface{}{
+								"status": "True",
+								"type":   "Running",
+								"ansibleResult": map[string]interface{}{
+									"changed":    int64(0),
+									"failures":   int64(0),
+									"ok":         int64(0),
+									"skipped":    int64(0),
+									"completion": eventTime.Format("2006-01-02T15:04:05.99999999"),
+								},
+								"message": "Awaiting next reconciliation",
+								"reason":  "Successful",
+							},
+							map[string]interface{}{
+								"status": "True",
+								"type":   "Drifted",
+								"message": "Set the ansible.sdk.operatorframework.io/approve-drift-correction annotation to \"true\" " +
+									"to correct the drift by applying these changes:\ncreate deployment: Deployment default/example",
+								"reason": "DriftDetected",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:            "drift check requests the correction of drift automatically",
+			GVK:             gvk,
+			ReconcilePeriod: 5 * time.Second,
+			ManageStatus:    true,
+			DriftDetection:  watches.DriftDetectionManual,
+			Runner: &fake.Runner{
+				JobEvents: []eventapi.JobEvent{
+					eventapi.JobEvent{
+						Event:   eventapi.EventRunnerOnOk,
+						Created: eventapi.EventTime{Time: eventTime},
+						EventData: map[string]interface{}{
+							"task": "create deployment",
+							"res": map[string]interface{}{
+								"changed": true,
+								"result": map[string]interface{}{
+									"kind": "Deployment",
+									"metadata": map[string]interface{}{
+										"name":      "example",
+										"namespace": "default",
+									},
+								},
+							},
+						},
+					},
+					eventapi.JobEvent{
+						Event:   eventapi.EventPlaybookOnStats,
+						Created: eventapi.EventTime{Time: eventTime},
+					},
+				},
+			},
+			Client: fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+						"annotations": map[string]interface{}{
+							controller.DriftDetectionAnnotation:                   "auto",
+							"ansible.sdk.operatorframework.io/applied-generation": "0",
+						},
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+				},
+			}).Build(),
+			Result: reconcile.Result{
+				Requeue: true,
+			},
+			Request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "reconcile",
+					Namespace: "default",
+				},
+			},
+			ExpectedObject: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+						"annotations": map[string]interface{}{
+							controller.DriftDetectionAnnotation:                   "auto",
+							"ansible.sdk.operatorframework.io/applied-generation": "0",
+							"ansible.sdk.operatorframework.io/correcting-drift":   "true",
+						},
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+					"status": map[string]interface{}{
+						"conditions": []interface{}{
+							map[string]interface{}{
+								"status": "True",
+								"type":   "Running",
+								"ansibleResult": map[string]interface{}{
+									"changed":    int64(0),
+									"failures":   int64(0),
+									"ok":         int64(0),
+									"skipped":    int64(0),
+									"completion": eventTime.Format("2006-01-02T15:04:05.99999999"),
+								},
+								"message": "Awaiting next reconciliation",
+								"reason":  "Successful",
+							},
+							map[string]interface{}{
+								"status": "True",
+								"type":   "Drifted",
+								"message": "Correcting the drift, a run is applying these changes:\n" +
+									"create deployment: Deployment default/example",
+								"reason": "DriftDetected",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:            "approved drift correction applies the spec",
+			GVK:             gvk,
+			ReconcilePeriod: 5 * time.Second,
+			ManageStatus:    true,
+			DriftDetection:  watches.DriftDetectionManual,
+			Runner: &fake.Runner{
+				JobEvents: []eventapi.JobEvent{
+					eventapi.JobEvent{
+						Event:   eventapi.EventRunnerOnOk,
+						Created: eventapi.EventTime{Time: eventTime},
+						EventData: map[string]interface{}{
+							"task": "create deployment",
+							"res": map[string]interface{}{
+								"changed": true,
+								"result": map[string]interface{}{
+									"kind": "Deployment",
+									"metadata": map[string]interface{}{
+										"name":      "example",
+										"namespace": "default",
+									},
+								},
+							},
+						},
+					},
+					eventapi.JobEvent{
+						Event:   eventapi.EventPlaybookOnStats,
+						Created: eventapi.EventTime{Time: eventTime},
+					},
+				},
+			},
+			Client: fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+						"annotations": map[string]interface{}{
+							controller.ApproveDriftCorrectionAnnotation:           "true",
+							"ansible.sdk.operatorframework.io/applied-generation": "0",
+						},
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+				},
+			}).Build(),
+			Result: reconcile.Result{
+				RequeueAfter: 5 * time.Second,
+			},
+			Request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "reconcile",
+					Namespace: "default",
+				},
+			},
+			ExpectedObject: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+						"annotations": map[string]interface{}{
+							"ansible.sdk.operatorframework.io/applied-generation": "0",
+						},
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+					"status": map[string]interface{}{
+						"conditions": []interface{}{
+							map[string]interface{}{
+								"status": "True",
+								"type":   "Running",
+								"ansibleResult": map[string]interface{}{
+									"changed":    int64(0),
+									"failures":   int64(0),
+									"ok":         int64(0),
+									"skipped":    int64(0),
+									"completion": eventTime.Format("2006-01-02T15:04:05.99999999"),
+								},
+								"message": "Awaiting next reconciliation",
+								"reason":  "Successful",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:            "no manage status",
+			GVK:             gvk,
+			ReconcilePeriod: 5 * time.Second,
+			ManageStatus:    false,
+			Runner: &fake.Runner{
+				JobEvents: []eventapi.JobEvent{
+					eventapi.JobEvent{
+						Event:   eventapi.EventPlaybookOnStats,
+						Created: eventapi.EventTime{Time: eventTime},
+					},
+				},
+			},
+			Client: fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+				},
+			}).Build(),
+			Result: reconcile.Result{
+				RequeueAfter: 5 * time.Second,
+			},
+			Request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "reconcile",
+					Namespace: "default",
+				},
+			},
+			ExpectedObject: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "reconcile",
+						"namespace": "default",
+					},
+					"apiVersion": "operator-sdk/v1beta1",
+					"kind":       "Testing",
+					"spec":       map[string]interface{}{},
+					"status":     map[string]interface{}{},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var aor reconcile.Reconciler = &controller.AnsibleOperatorReconciler{
+				GVK:             tc.GVK,
+				Runner:          tc.Runner,
+				Client:          tc.Client,
+				APIReader:       tc.Client,
+				EventHandlers:   tc.EventHandlers,
+				ReconcilePeriod: tc.ReconcilePeriod,
+				ManageStatus:    tc.ManageStatus,
+				DryRun:          tc.DryRun,
+				DriftDetection:  tc.DriftDetection,
+
+				FinalizerTimeout:     tc.FinalizerTimeout,
+				FinalizerMaxAttempts: tc.FinalizerMaxAttempts,
 			}
 			result, err := aor.Reconcile(context.TODO(), tc.Request)
 			if err != nil && !tc.ShouldError {
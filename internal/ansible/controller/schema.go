@@ -0,0 +1,161 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/defaulting"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/pruning"
+	apiservervalidation "k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// specSchemaRecheckInterval is how long a loaded schema is used before the CRD is read
+// again to check whether it changed.
+const specSchemaRecheckInterval = time.Minute
+
+// SpecSchema - applies the OpenAPI schema of a CRD version to its CRs, the way the
+// API server does when they are written: unknown fields are pruned, defaults are set
+// and the result is validated.
+type SpecSchema struct {
+	gvk        schema.GroupVersionKind
+	restMapper meta.RESTMapper
+	reader     client.Reader
+	now        func() time.Time
+
+	mu              sync.RWMutex
+	checked         time.Time // when the CRD was last read
+	resourceVersion string    // of the CRD the schema was loaded from
+	structural      *structuralschema.Structural
+	validate        func(interface{}) field.ErrorList
+}
+
+// NewSpecSchema - returns a SpecSchema for the CRs of gvk. The CRD is read with reader
+// when the schema is first applied, and again at most once per minute to pick up changes.
+func NewSpecSchema(gvk schema.GroupVersionKind, restMapper meta.RESTMapper, reader client.Reader) *SpecSchema {
+	return &SpecSchema{gvk: gvk, restMapper: restMapper, reader: reader, now: time.Now}
+}
+
+// Apply - returns a copy of u that is pruned and defaulted, along with the ways it
+// doesn't match the schema. u itself is not modified.
+func (s *SpecSchema) Apply(ctx context.Context, u *unstructured.Unstructured) (*unstructured.Unstructured,
+	field.ErrorList, error) {
+	structural, validate, err := s.get(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj := u.DeepCopy()
+	if structural == nil {
+		return obj, nil, nil
+	}
+	pruning.Prune(obj.Object, structural, true)
+	defaulting.PruneNonNullableNullsWithoutDefaults(obj.Object, structural)
+	defaulting.Default(obj.Object, structural)
+	return obj, validate(obj.Object), nil
+}
+
+// get returns the loaded schema, reading the CRD again if it was last read more than
+// specSchemaRecheckInterval ago. A nil schema means the CRD version has none.
+func (s *SpecSchema) get(ctx context.Context) (*structuralschema.Structural, func(interface{}) field.ErrorList, error) {
+	s.mu.RLock()
+	if s.fresh() {
+		defer s.mu.RUnlock()
+		return s.structural, s.validate, nil
+	}
+	s.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Another reconcile may have read the CRD while waiting for the lock.
+	if !s.fresh() {
+		if err := s.load(ctx); err != nil {
+			return nil, nil, err
+		}
+		s.checked = s.now()
+	}
+	return s.structural, s.validate, nil
+}
+
+// fresh returns true if the CRD was read recently enough for its schema to be used. s.mu
+// must be held.
+func (s *SpecSchema) fresh() bool {
+	return !s.checked.IsZero() && s.now().Sub(s.checked) < specSchemaRecheckInterval
+}
+
+// load reads the schema of the CRD, unless it was already loaded from the same revision.
+func (s *SpecSchema) load(ctx context.Context) error {
+	mapping, err := s.restMapper.RESTMapping(s.gvk.GroupKind(), s.gvk.Version)
+	if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(apiextv1.SchemeGroupVersion.WithKind("CustomResourceDefinition"))
+	key := client.ObjectKey{Name: mapping.Resource.GroupResource().String()}
+	if err := s.reader.Get(ctx, key, u); err != nil {
+		return fmt.Errorf("unable to get the CRD %s: %w", key.Name, err)
+	}
+	if u.GetResourceVersion() != "" && u.GetResourceVersion() == s.resourceVersion {
+		return nil
+	}
+	crd := &apiextv1.CustomResourceDefinition{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, crd); err != nil {
+		return err
+	}
+
+	var props *apiextv1.JSONSchemaProps
+	for _, v := range crd.Spec.Versions {
+		if v.Name == s.gvk.Version && v.Schema != nil {
+			props = v.Schema.OpenAPIV3Schema
+		}
+	}
+	s.resourceVersion, s.structural, s.validate = "", nil, nil
+	if props == nil {
+		log.Info("The CRD has no schema to apply", "CRD", key.Name, "Version", s.gvk.Version)
+		s.resourceVersion = u.GetResourceVersion()
+		return nil
+	}
+
+	internal := &apiextensions.JSONSchemaProps{}
+	if err := apiextv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(props, internal, nil); err != nil {
+		return err
+	}
+	structural, err := structuralschema.NewStructural(internal)
+	if err != nil {
+		return fmt.Errorf("the schema of the CRD %s is not structural: %w", key.Name, err)
+	}
+	validator, _, err := apiservervalidation.NewSchemaValidator(
+		&apiextensions.CustomResourceValidation{OpenAPIV3Schema: internal})
+	if err != nil {
+		return err
+	}
+	s.resourceVersion = u.GetResourceVersion()
+	s.structural = structural
+	s.validate = func(obj interface{}) field.ErrorList {
+		return apiservervalidation.ValidateCustomResource(nil, obj, validator)
+	}
+	return nil
+}
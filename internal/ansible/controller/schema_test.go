@@ -0,0 +1,252 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ansiblestatus "github.com/operator-framework/operator-sdk/internal/ansible/controller/status"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/fake"
+)
+
+var memcachedGVK = schema.GroupVersionKind{Group: "cache.example.com", Version: "v1alpha1", Kind: "Memcached"}
+
+// memcachedCRD returns a CRD for memcachedGVK whose spec has a defaulted size and
+// an image that must be set, and preserves unknown fields under config.
+func memcachedCRD(versionSchema map[string]interface{}) *unstructured.Unstructured {
+	version := map[string]interface{}{"name": "v1alpha1", "served": true, "storage": true}
+	if versionSchema != nil {
+		version["schema"] = map[string]interface{}{"openAPIV3Schema": versionSchema}
+	}
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"group":    "cache.example.com",
+			"names":    map[string]interface{}{"kind": "Memcached", "plural": "memcacheds"},
+			"scope":    "Namespaced",
+			"versions": []interface{}{version},
+		},
+	}}
+	crd.SetAPIVersion("apiextensions.k8s.io/v1")
+	crd.SetKind("CustomResourceDefinition")
+	crd.SetName("memcacheds.cache.example.com")
+	crd.SetResourceVersion("1")
+	return crd
+}
+
+var memcachedSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"spec": map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"image"},
+			"properties": map[string]interface{}{
+				"size":  map[string]interface{}{"type": "integer", "minimum": int64(1), "default": int64(3)},
+				"image": map[string]interface{}{"type": "string"},
+				"config": map[string]interface{}{
+					"type":                                 "object",
+					"x-kubernetes-preserve-unknown-fields": true,
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"type":                                 "object",
+			"x-kubernetes-preserve-unknown-fields": true,
+		},
+	},
+}
+
+func newMemcached(spec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	u.SetGroupVersionKind(memcachedGVK)
+	u.SetNamespace("default")
+	u.SetName("example")
+	return u
+}
+
+func newMemcachedSpecSchema(c client.Reader) *SpecSchema {
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	restMapper.Add(memcachedGVK, meta.RESTScopeNamespace)
+	return NewSpecSchema(memcachedGVK, restMapper, c)
+}
+
+func TestSpecSchemaApply(t *testing.T) {
+	testCases := []struct {
+		name          string
+		schema        map[string]interface{}
+		spec          map[string]interface{}
+		expectedSpec  map[string]interface{}
+		expectedValid bool
+	}{
+		{
+			name:          "defaults unset fields",
+			schema:        memcachedSchema,
+			spec:          map[string]interface{}{"image": "memcached:1.6"},
+			expectedSpec:  map[string]interface{}{"image": "memcached:1.6", "size": int64(3)},
+			expectedValid: true,
+		},
+		{
+			name:   "prunes unknown fields",
+			schema: memcachedSchema,
+			spec: map[string]interface{}{
+				"image":   "memcached:1.6",
+				"size":    int64(1),
+				"unknown": "value",
+				"config":  map[string]interface{}{"maxConnections": int64(1024)},
+			},
+			expectedSpec: map[string]interface{}{
+				"image":  "memcached:1.6",
+				"size":   int64(1),
+				"config": map[string]interface{}{"maxConnections": int64(1024)},
+			},
+			expectedValid: true,
+		},
+		{
+			name:         "invalid spec",
+			schema:       memcachedSchema,
+			spec:         map[string]interface{}{"size": int64(0)},
+			expectedSpec: map[string]interface{}{"size": int64(0)},
+		},
+		{
+			name:          "version without a schema",
+			spec:          map[string]interface{}{"unknown": "value"},
+			expectedSpec:  map[string]interface{}{"unknown": "value"},
+			expectedValid: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fakeclient.NewClientBuilder().WithObjects(memcachedCRD(tc.schema)).Build()
+			u := newMemcached(tc.spec)
+			original := u.DeepCopy()
+			obj, invalid, err := newMemcachedSpecSchema(c).Apply(context.TODO(), u)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tc.expectedValid != (len(invalid) == 0) {
+				t.Fatalf("Unexpected validation errors: %v", invalid)
+			}
+			if !reflect.DeepEqual(obj.Object["spec"], tc.expectedSpec) {
+				t.Fatalf("Unexpected spec %v expected %v", obj.Object["spec"], tc.expectedSpec)
+			}
+			if !reflect.DeepEqual(u, original) {
+				t.Fatalf("The CR was modified: %v", u.Object)
+			}
+		})
+	}
+}
+
+func TestSpecSchemaApplyMissingCRD(t *testing.T) {
+	s := newMemcachedSpecSchema(fakeclient.NewClientBuilder().Build())
+	if _, _, err := s.Apply(context.TODO(), newMemcached(map[string]interface{}{})); err == nil {
+		t.Fatalf("Expected an error when the CRD does not exist")
+	}
+}
+
+// countingReader counts the objects read with it.
+type countingReader struct {
+	client.Reader
+	gets int
+}
+
+func (r *countingReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	r.gets++
+	return r.Reader.Get(ctx, key, obj)
+}
+
+func TestSpecSchemaRecheck(t *testing.T) {
+	crd := memcachedCRD(memcachedSchema)
+	c := fakeclient.NewClientBuilder().WithObjects(crd).Build()
+	reader := &countingReader{Reader: c}
+	s := newMemcachedSpecSchema(reader)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	apply := func(expectedSize int64) {
+		t.Helper()
+		obj, _, err := s.Apply(context.TODO(), newMemcached(map[string]interface{}{"image": "memcached:1.6"}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if size := obj.Object["spec"].(map[string]interface{})["size"]; size != expectedSize {
+			t.Fatalf("Unexpected size %v expected %d", size, expectedSize)
+		}
+	}
+	apply(3)
+	apply(3)
+	if reader.gets != 1 {
+		t.Fatalf("Expected the CRD to be read once, read %d times", reader.gets)
+	}
+
+	// A changed schema is only used once the CRD is read again.
+	updated := runtime.DeepCopyJSON(memcachedSchema)
+	if err := unstructured.SetNestedField(updated, int64(5), "properties", "spec", "properties", "size",
+		"default"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := c.Update(context.TODO(), memcachedCRD(updated)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	apply(3)
+	now = now.Add(specSchemaRecheckInterval)
+	apply(5)
+	if reader.gets != 2 {
+		t.Fatalf("Expected the CRD to be read twice, read %d times", reader.gets)
+	}
+}
+
+func TestReconcileInvalidSpec(t *testing.T) {
+	cr := newMemcached(map[string]interface{}{"size": int64(0)})
+	c := fakeclient.NewClientBuilder().WithObjects(memcachedCRD(memcachedSchema), cr).Build()
+	r := &AnsibleOperatorReconciler{
+		GVK: memcachedGVK,
+		// The run fails if the playbook is run at all.
+		Runner:       &fake.Runner{Error: errors.New("unexpected run")},
+		Client:       c,
+		APIReader:    c,
+		ManageStatus: true,
+		SpecSchema:   newMemcachedSpecSchema(c),
+	}
+	if _, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(cr)}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(memcachedGVK)
+	if err := c.Get(context.TODO(), client.ObjectKeyFromObject(cr), u); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	failure := ansiblestatus.GetCondition(getStatus(u), ansiblestatus.FailureConditionType)
+	if failure == nil || failure.Status != v1.ConditionTrue || failure.Reason != ansiblestatus.InvalidSpecReason {
+		t.Fatalf("Unexpected Failure condition: %v", failure)
+	}
+	if running := ansiblestatus.GetCondition(getStatus(u), ansiblestatus.RunningConditionType); running == nil ||
+		running.Status != v1.ConditionFalse {
+		t.Fatalf("Unexpected Running condition: %v", running)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// namespaceSerializedReconciler - wraps a reconciler so that at most one request
+// per namespace is reconciled at a time. Requests for a namespace that is already
+// being reconciled wait without blocking a worker, and are sent to Released, to be
+// enqueued again, once the namespace is released.
+type namespaceSerializedReconciler struct {
+	reconcile.Reconciler
+	// Released receives the requests that waited for their namespace.
+	Released chan event.GenericEvent
+
+	mu         sync.Mutex
+	inProgress map[string]struct{}
+	waiting    map[string]map[types.NamespacedName]struct{}
+}
+
+func newNamespaceSerializedReconciler(r reconcile.Reconciler) *namespaceSerializedReconciler {
+	return &namespaceSerializedReconciler{
+		Reconciler: r,
+		Released:   make(chan event.GenericEvent, 64),
+		inProgress: map[string]struct{}{},
+		waiting:    map[string]map[types.NamespacedName]struct{}{},
+	}
+}
+
+// Reconcile - handle the event if no other request for its namespace is in progress.
+func (r *namespaceSerializedReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	if !r.acquire(request.NamespacedName) {
+		log.V(1).Info("Namespace is being reconciled, waiting", "namespace", request.Namespace,
+			"name", request.Name)
+		return reconcile.Result{}, nil
+	}
+	defer r.release(request.Namespace)
+	return r.Reconciler.Reconcile(ctx, request)
+}
+
+// acquire marks the namespace of nn as in progress, or records that nn waits for it.
+func (r *namespaceSerializedReconciler) acquire(nn types.NamespacedName) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.inProgress[nn.Namespace]; ok {
+		if r.waiting[nn.Namespace] == nil {
+			r.waiting[nn.Namespace] = map[types.NamespacedName]struct{}{}
+		}
+		r.waiting[nn.Namespace][nn] = struct{}{}
+		return false
+	}
+	r.inProgress[nn.Namespace] = struct{}{}
+	return true
+}
+
+// release marks namespace as no longer in progress, and sends the requests that waited
+// for it to Released.
+func (r *namespaceSerializedReconciler) release(namespace string) {
+	r.mu.Lock()
+	delete(r.inProgress, namespace)
+	waiting := r.waiting[namespace]
+	delete(r.waiting, namespace)
+	r.mu.Unlock()
+
+	for nn := range waiting {
+		r.Released <- event.GenericEvent{Object: &metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{Namespace: nn.Namespace, Name: nn.Name},
+		}}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// blockingReconciler blocks each reconcile until it is released.
+type blockingReconciler struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingReconciler) Reconcile(context.Context, reconcile.Request) (reconcile.Result, error) {
+	r.started <- struct{}{}
+	<-r.release
+	return reconcile.Result{}, nil
+}
+
+func TestNamespaceSerializedReconciler(t *testing.T) {
+	br := &blockingReconciler{started: make(chan struct{}), release: make(chan struct{})}
+	r := newNamespaceSerializedReconciler(br)
+
+	request := func(namespace, name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}
+	}
+
+	done := make(chan reconcile.Result)
+	go func() {
+		result, _ := r.Reconcile(context.TODO(), request("ns1", "first"))
+		done <- result
+	}()
+	<-br.started
+
+	// A second request in the same namespace waits without reaching the reconciler.
+	result, err := r.Reconcile(context.TODO(), request("ns1", "second"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Requeue || result.RequeueAfter != 0 {
+		t.Fatalf("Expected request in a busy namespace to wait instead of being requeued")
+	}
+	if len(r.Released) != 0 {
+		t.Fatalf("Expected no released requests while the namespace is busy")
+	}
+
+	// A request in another namespace is reconciled concurrently.
+	go func() {
+		result, _ := r.Reconcile(context.TODO(), request("ns2", "other"))
+		done <- result
+	}()
+	<-br.started
+	br.release <- struct{}{}
+	br.release <- struct{}{}
+	for i := 0; i < 2; i++ {
+		if result := <-done; result.Requeue {
+			t.Fatalf("Unexpected requeue for a reconciled request")
+		}
+	}
+
+	// Once the namespace is released, the waiting request is sent to be enqueued again.
+	if len(r.Released) != 1 {
+		t.Fatalf("Expected 1 released request, got %d", len(r.Released))
+	}
+	if released := <-r.Released; released.Object.GetNamespace() != "ns1" || released.Object.GetName() != "second" {
+		t.Fatalf("Unexpected released request %s/%s", released.Object.GetNamespace(), released.Object.GetName())
+	}
+
+	// Its requests are then reconciled again.
+	go func() {
+		result, _ := r.Reconcile(context.TODO(), request("ns1", "second"))
+		done <- result
+	}()
+	<-br.started
+	br.release <- struct{}{}
+	if result := <-done; result.Requeue {
+		t.Fatalf("Unexpected requeue after the namespace was released")
+	}
+}
@@ -93,6 +93,15 @@ const (
 	RunningConditionType ConditionType = "Running"
 	// FailureConditionType - condition type of failure.
 	FailureConditionType ConditionType = "Failure"
+	// DryRunConditionType - condition type of a dry run, reporting the
+	// changes ansible predicted in check mode.
+	DryRunConditionType ConditionType = "DryRun"
+	// DeletionBlockedConditionType - condition type reporting why a failing
+	// finalizer is blocking the deletion of the CR.
+	DeletionBlockedConditionType ConditionType = "DeletionBlocked"
+	// DriftedConditionType - condition type reporting the changes a run would make to
+	// correct the drift of the resources managed for the CR.
+	DriftedConditionType ConditionType = "Drifted"
 )
 
 // Condition - the condition for the ansible operator.
@@ -28,6 +28,18 @@ const (
 	FailedReason = "Failed"
 	// UnknownFailedReason - Condition is unknown
 	UnknownFailedReason = "Unknown"
+	// ChangesPredictedReason - Condition is a dry run that predicted changes
+	ChangesPredictedReason = "ChangesPredicted"
+	// NoChangesPredictedReason - Condition is a dry run that predicted no changes
+	NoChangesPredictedReason = "NoChangesPredicted"
+	// FinalizerFailedReason - Condition is blocked due to the finalizer failing
+	FinalizerFailedReason = "FinalizerFailed"
+	// InvalidSpecReason - Condition is failed due to the spec not matching the schema of the CRD
+	InvalidSpecReason = "InvalidSpec"
+	// DriftDetectedReason - Condition is drifted due to a check run predicting changes
+	DriftDetectedReason = "DriftDetected"
+	// NoDriftReason - Condition is not drifted due to a check run predicting no changes
+	NoDriftReason = "NoDrift"
 )
 
 const (
@@ -35,6 +47,10 @@ const (
 	RunningMessage = "Running reconciliation"
 	// SuccessfulMessage - message for successful reason.
 	SuccessfulMessage = "Awaiting next reconciliation"
+	// NoChangesPredictedMessage - message for no changes predicted reason.
+	NoChangesPredictedMessage = "Dry run completed, no changes would be made"
+	// NoDriftMessage - message for no drift reason.
+	NoDriftMessage = "Drift check completed, no changes would be made"
 )
 
 // NewCondition -  condition
@@ -25,20 +25,32 @@ import (
 
 // Flags - Options to be used by an ansible operator
 type Flags struct {
-	ReconcilePeriod         time.Duration
-	WatchesFile             string
-	InjectOwnerRef          bool
-	LeaderElection          bool
-	MaxConcurrentReconciles int
-	AnsibleVerbosity        int
-	AnsibleRolesPath        string
-	AnsibleCollectionsPath  string
-	MetricsBindAddress      string
-	ProbeAddr               string
-	LeaderElectionID        string
-	LeaderElectionNamespace string
-	GracefulShutdownTimeout time.Duration
-	AnsibleArgs             string
+	ReconcilePeriod          time.Duration
+	WatchesFile              string
+	InjectOwnerRef           bool
+	ProxyAuditLog            bool
+	ProxyTokenAuth           bool
+	ProxyTokenTTL            time.Duration
+	ProxyUnixSocket          string
+	ProxyMaxLazyCaches       int
+	LeaderElection           bool
+	MaxConcurrentReconciles  int
+	AnsibleVerbosity         int
+	AnsibleRolesPath         string
+	AnsibleCollectionsPath   string
+	AnsibleRequirements      string
+	AnsibleRequirementsCache string
+	MetricsBindAddress       string
+	ProbeAddr                string
+	LeaderElectionID         string
+	LeaderElectionNamespace  string
+	GracefulShutdownTimeout  time.Duration
+	AnsibleArgs              string
+	ArtifactSink             string
+	ArtifactSinkEndpoint     string
+	ArtifactSinkRegion       string
+	ArtifactSinkMaxRuns      int
+	ArtifactSinkMaxAge       time.Duration
 
 	// Path to a controller-runtime componentconfig file.
 	// If this is empty, use default values.
@@ -69,6 +81,36 @@ func (f *Flags) AddTo(flagSet *pflag.FlagSet) {
 		true,
 		"The ansible operator will inject owner references unless this flag is false",
 	)
+	flagSet.BoolVar(&f.ProxyAuditLog,
+		"proxy-audit-log",
+		false,
+		"Log every mutating request made by Ansible through the proxy, along with the CR it was made for",
+	)
+	flagSet.BoolVar(&f.ProxyTokenAuth,
+		"proxy-token-auth",
+		false,
+		"Require a bearer token, issued to each run, for every request to the proxy. "+
+			"Otherwise, any process in the pod can use the proxy with the operator's credentials.",
+	)
+	flagSet.DurationVar(&f.ProxyTokenTTL,
+		"proxy-token-ttl",
+		0,
+		"How long a token issued with --proxy-token-auth is valid if its run does not end first. "+
+			"Tokens are revoked when their run ends, and do not expire otherwise if 0",
+	)
+	flagSet.StringVar(&f.ProxyUnixSocket,
+		"proxy-unix-socket",
+		"",
+		"Path of a Unix socket the proxy also listens on, accessible only to the operator's user. "+
+			"Implies --proxy-token-auth",
+	)
+	flagSet.IntVar(&f.ProxyMaxLazyCaches,
+		"proxy-max-lazy-caches",
+		10,
+		"Maximum number of caches the proxy starts for kinds that no watch watches, one for each kind "+
+			"and namespace that runs read, stopping the least recently read one to start another. "+
+			"Those kinds are read from the API server if 0",
+	)
 	flagSet.IntVar(&f.AnsibleVerbosity,
 		"ansible-verbosity",
 		2,
@@ -85,11 +127,50 @@ func (f *Flags) AddTo(flagSet *pflag.FlagSet) {
 		"Path to installed Ansible Collections. If set, collections should be located in {{value}}/ansible_collections/. "+
 			"If unset, collections are assumed to be in ~/.ansible/collections or /usr/share/ansible/collections.",
 	)
+	flagSet.StringVar(&f.AnsibleRequirements,
+		"ansible-requirements",
+		"",
+		"Path to a requirements.yml whose collections and roles are verified to be installed at start-up. "+
+			"If unset, requirements are not verified.",
+	)
+	flagSet.StringVar(&f.AnsibleRequirementsCache,
+		"ansible-requirements-cache",
+		"",
+		"Directory of collection and role archives or directories that the requirements missing at start-up "+
+			"are installed from, e.g. for development. Requires --ansible-requirements.",
+	)
 	flagSet.StringVar(&f.AnsibleArgs,
 		"ansible-args",
 		"",
 		"Ansible args. Allows user to specify arbitrary arguments for ansible-based operators.",
 	)
+	flagSet.StringVar(&f.ArtifactSink,
+		"artifact-sink",
+		"",
+		"Where to export the artifacts of each ansible-runner run to, keyed by CR UID: a local path, "+
+			"such as a mounted volume, or s3://<bucket>/<prefix>. If unset, artifacts are not exported.",
+	)
+	flagSet.StringVar(&f.ArtifactSinkEndpoint,
+		"artifact-sink-endpoint",
+		"",
+		"URL of the S3-compatible API of an s3:// artifact sink. If unset, AWS S3 is used. "+
+			"Credentials are read from the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables.",
+	)
+	flagSet.StringVar(&f.ArtifactSinkRegion,
+		"artifact-sink-region",
+		"us-east-1",
+		"Region of an s3:// artifact sink.",
+	)
+	flagSet.IntVar(&f.ArtifactSinkMaxRuns,
+		"artifact-sink-max-runs",
+		20,
+		"Number of exported runs to keep per CR, besides the last failed one. Zero keeps all of them.",
+	)
+	flagSet.DurationVar(&f.ArtifactSinkMaxAge,
+		"artifact-sink-max-age",
+		0,
+		"How long exported runs are kept. Zero keeps them until they are beyond --artifact-sink-max-runs.",
+	)
 
 	// Controller flags.
 	flagSet.DurationVar(&f.ReconcilePeriod,
@@ -100,7 +181,7 @@ func (f *Flags) AddTo(flagSet *pflag.FlagSet) {
 	flagSet.IntVar(&f.MaxConcurrentReconciles,
 		"max-concurrent-reconciles",
 		runtime.NumCPU(),
-		"Maximum number of concurrent reconciles for controllers. Overridden by environment variable.",
+		"Maximum number of concurrent reconciles for controllers. Overridden by the watches file and environment variable.",
 	)
 
 	// Controller manager flags.
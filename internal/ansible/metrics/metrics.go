@@ -60,11 +60,36 @@ var (
 		[]string{
 			"GVK",
 		})
+
+	driftChecks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "drift_checks_total",
+			Help:      "Counter of drift checks by whether a run would change managed resources (drifted) or not (in_sync).",
+		},
+		[]string{
+			"GVK",
+			"result",
+		})
+
+	proxyCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "proxy_cache_requests_total",
+			Help: "Counter of read requests to the proxy by whether they were served from the cache (hit), " +
+				"looked up in the cache but not found (miss), or sent to the API server without a lookup (skip).",
+		},
+		[]string{
+			"GVK",
+			"result",
+		})
 )
 
 func init() {
 	metrics.Registry.MustRegister(reconcileResults)
 	metrics.Registry.MustRegister(reconciles)
+	metrics.Registry.MustRegister(proxyCacheRequests)
+	metrics.Registry.MustRegister(driftChecks)
 }
 
 // We will never want to panic our app because of metric saving.
@@ -99,3 +124,28 @@ func ReconcileTimer(gvk string) *prometheus.Timer {
 		reconciles.WithLabelValues(gvk).Observe(duration)
 	}))
 }
+
+func ProxyCacheHit(gvk string) {
+	defer recoverMetricPanic()
+	proxyCacheRequests.WithLabelValues(gvk, "hit").Inc()
+}
+
+func ProxyCacheMiss(gvk string) {
+	defer recoverMetricPanic()
+	proxyCacheRequests.WithLabelValues(gvk, "miss").Inc()
+}
+
+func ProxyCacheSkip(gvk string) {
+	defer recoverMetricPanic()
+	proxyCacheRequests.WithLabelValues(gvk, "skip").Inc()
+}
+
+func DriftDetected(gvk string) {
+	defer recoverMetricPanic()
+	driftChecks.WithLabelValues(gvk, "drifted").Inc()
+}
+
+func NoDriftDetected(gvk string) {
+	defer recoverMetricPanic()
+	driftChecks.WithLabelValues(gvk, "in_sync").Inc()
+}
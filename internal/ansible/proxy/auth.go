@@ -0,0 +1,45 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/proxy/kubeconfig"
+)
+
+type ownerContextKey struct{}
+
+// tokenAuthHandler will reject proxied requests that don't carry a valid bearer
+// token. The owner reference the token was issued for is added to the request
+// context, where getRequestOwnerRef finds it.
+type tokenAuthHandler struct {
+	next   http.Handler
+	tokens *kubeconfig.Tokens
+}
+
+func (t *tokenAuthHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	value := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	owner, ok := t.tokens.Lookup(value)
+	if value == "" || !ok {
+		log.Info("Rejected request without a valid token", "method", req.Method, "uri", req.RequestURI)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	ctx := context.WithValue(req.Context(), ownerContextKey{}, owner)
+	t.next.ServeHTTP(w, req.WithContext(ctx))
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/proxy/kubeconfig"
+)
+
+func TestTokenAuthHandler(t *testing.T) {
+	tokens := kubeconfig.NewTokens(time.Hour)
+	owner := kubeconfig.NamespacedOwnerReference{
+		OwnerReference: metav1.OwnerReference{APIVersion: "cache.example.com/v1alpha1", Kind: "Memcached",
+			Name: "example", UID: "uid"},
+		Namespace: "default",
+	}
+	token, err := tokens.Issue(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := tokens.Issue(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens.Revoke(revoked)
+
+	testCases := []struct {
+		name          string
+		authorization func(req *http.Request)
+		expectedCode  int
+	}{
+		{
+			name:          "valid token",
+			authorization: func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) },
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:          "revoked token",
+			authorization: func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+revoked) },
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "no token",
+			authorization: func(req *http.Request) {},
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "owner in username",
+			authorization: func(req *http.Request) { req.SetBasicAuth("owner", "unused") },
+			expectedCode:  http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got *kubeconfig.NamespacedOwnerReference
+			h := &tokenAuthHandler{
+				next: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					var err error
+					if got, err = getRequestOwnerRef(req); err != nil {
+						t.Fatal(err)
+					}
+				}),
+				tokens: tokens,
+			}
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces/default/pods", nil)
+			tc.authorization(req)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tc.expectedCode {
+				t.Fatalf("Unexpected code %d expected code %d", w.Code, tc.expectedCode)
+			}
+			if tc.expectedCode == http.StatusOK && (got == nil || *got != owner) {
+				t.Fatalf("Unexpected owner %v", got)
+			}
+		})
+	}
+}
@@ -35,6 +35,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/operator-framework/operator-sdk/internal/ansible/metrics"
 	"github.com/operator-framework/operator-sdk/internal/ansible/proxy/controllermap"
 	k8sRequest "github.com/operator-framework/operator-sdk/internal/ansible/proxy/requestfactory"
 )
@@ -52,6 +53,8 @@ type cacheResponseHandler struct {
 	injectOwnerRef    bool
 	apiResources      *apiResources
 	skipPathRegexp    []*regexp.Regexp
+	// lazyCaches, when set, caches the kinds that no watch watches.
+	lazyCaches *lazyCaches
 }
 
 func (c *cacheResponseHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -87,6 +90,13 @@ func (c *cacheResponseHandler) ServeHTTP(w http.ResponseWriter, req *http.Reques
 
 		if c.skipCacheLookup(r, k, req) {
 			log.V(2).Info("Skipping cache lookup", "resource", r)
+			metrics.ProxyCacheSkip(k.String())
+			break
+		}
+		informerCache, ok := c.cacheFor(r, k, req)
+		if !ok {
+			log.V(2).Info("Skipping cache lookup, because gvk is not watched", "resource", r)
+			metrics.ProxyCacheSkip(k.String())
 			break
 		}
 
@@ -100,6 +110,13 @@ func (c *cacheResponseHandler) ServeHTTP(w http.ResponseWriter, req *http.Reques
 
 		if isVR {
 			log.V(2).Info("Virtual resource, must ask the cluster API", "gvk", k)
+			metrics.ProxyCacheSkip(k.String())
+			break
+		}
+
+		if !informerSynced(informerCache, k) {
+			log.V(2).Info("Informer has not synced yet, must ask the cluster API", "gvk", k)
+			metrics.ProxyCacheMiss(k.String())
 			break
 		}
 
@@ -107,15 +124,13 @@ func (c *cacheResponseHandler) ServeHTTP(w http.ResponseWriter, req *http.Reques
 
 		log.V(2).Info("Get resource in our cache", "r", r)
 		if r.Verb == "list" {
-			m, err = c.getListFromCache(r, req, k)
-			if err != nil {
-				break
-			}
+			m, err = c.getListFromCache(informerCache, r, req, k)
 		} else {
-			m, err = c.getObjectFromCache(r, req, k)
-			if err != nil {
-				break
-			}
+			m, err = c.getObjectFromCache(informerCache, r, req, k)
+		}
+		if err != nil {
+			metrics.ProxyCacheMiss(k.String())
+			break
 		}
 
 		i := bytes.Buffer{}
@@ -143,6 +158,7 @@ func (c *cacheResponseHandler) ServeHTTP(w http.ResponseWriter, req *http.Reques
 
 		// Return so that request isn't passed along to APIserver
 		log.Info("Read object from cache", "resource", r)
+		metrics.ProxyCacheHit(k.String())
 		return
 	}
 	c.next.ServeHTTP(w, req)
@@ -180,8 +196,8 @@ func (c *cacheResponseHandler) skipCacheLookup(r *k8sRequest.RequestInfo, gvk sc
 			log.Info("Could not find controller for gvk.", "ownerGVK:", ownerGVK)
 			return false
 		}
-		if relatedController.Blacklist[gvk] {
-			log.Info("Skipping, because gvk is blacklisted", "GVK", gvk)
+		if relatedController.IsExcluded(gvk) {
+			log.Info("Skipping, because gvk is not a watched dependent resource", "GVK", gvk)
 			return true
 		}
 	}
@@ -200,9 +216,79 @@ func (c *cacheResponseHandler) skipCacheLookup(r *k8sRequest.RequestInfo, gvk sc
 		return true
 	}
 
+	// Field selectors are evaluated in memory, which only works for some fields.
+	if fs := req.URL.Query().Get("fieldSelector"); fs != "" {
+		sel, err := fields.ParseSelector(fs)
+		if err != nil || !canSelectInMemory(gvk.GroupKind(), sel) {
+			return true
+		}
+	}
+
 	return false
 }
 
+// cacheFor returns the cache to read the objects of gvk from. The kinds of watches, and the
+// dependent kinds that the controller of the owner of req already watches, are read from
+// informerCache, since the manager runs their informers anyway. Other kinds are read from
+// a lazily started cache of the namespace of the request, so that no informer caches every
+// object of a kind in the cluster just because a run reads it. Requests across namespaces
+// for those kinds are not read from a cache.
+func (c *cacheResponseHandler) cacheFor(r *k8sRequest.RequestInfo, gvk schema.GroupVersionKind,
+	req *http.Request) (cache.Cache, bool) {
+
+	var contents *controllermap.Contents
+	if owner, err := getRequestOwnerRef(req); err == nil && owner != nil {
+		if ownerGV, err := schema.ParseGroupVersion(owner.APIVersion); err == nil {
+			contents, _ = c.cMap.Get(ownerGV.WithKind(owner.Kind))
+		}
+	}
+	if c.watched(gvk, contents) {
+		return c.informerCache, true
+	}
+	if c.lazyCaches == nil || r.Namespace == "" {
+		return nil, false
+	}
+	lazyCache, err := c.lazyCaches.get(gvk, r.Namespace)
+	if err != nil {
+		log.Error(err, "Failed to get lazy cache", "GVK", gvk, "Namespace", r.Namespace)
+		return nil, false
+	}
+	return lazyCache, true
+}
+
+// watched returns true if gvk is the kind of a watch, or a dependent kind that
+// contents, the controller of the request's owner, already watches.
+func (c *cacheResponseHandler) watched(gvk schema.GroupVersionKind, contents *controllermap.Contents) bool {
+	if _, ok := c.cMap.Get(gvk); ok {
+		return true
+	}
+	if contents == nil {
+		return false
+	}
+	for _, m := range []*controllermap.WatchMap{contents.OwnerWatchMap, contents.AnnotationWatchMap} {
+		if m == nil {
+			continue
+		}
+		if _, ok := m.Get(gvk); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// informerSynced returns true if the informer for gvk in informerCache has synced. The
+// informer is started if it doesn't exist yet, but not waited for, so that the first
+// requests for a GVK are sent to the API server instead of waiting for its informer to sync.
+func informerSynced(informerCache cache.Cache, gvk schema.GroupVersionKind) bool {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	// GetInformer checks whether the informer has synced once before waiting on the context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := informerCache.GetInformer(ctx, u)
+	return err == nil
+}
+
 func (c *cacheResponseHandler) recoverDependentWatches(req *http.Request, un *unstructured.Unstructured) {
 	ownerRef, err := getRequestOwnerRef(req)
 	if err != nil {
@@ -240,8 +326,8 @@ func (c *cacheResponseHandler) recoverDependentWatches(req *http.Request, un *un
 	}
 }
 
-func (c *cacheResponseHandler) getListFromCache(r *k8sRequest.RequestInfo, req *http.Request,
-	k schema.GroupVersionKind) (marshaler, error) {
+func (c *cacheResponseHandler) getListFromCache(informerCache cache.Cache, r *k8sRequest.RequestInfo,
+	req *http.Request, k schema.GroupVersionKind) (marshaler, error) {
 	k8sListOpts := &metav1.ListOptions{}
 	if err := metainternalscheme.ParameterCodec.DecodeParameters(req.URL.Query(), metav1.SchemeGroupVersion,
 		k8sListOpts); err != nil {
@@ -252,44 +338,47 @@ func (c *cacheResponseHandler) getListFromCache(r *k8sRequest.RequestInfo, req *
 		client.InNamespace(r.Namespace),
 	}
 	if k8sListOpts.LabelSelector != "" {
-		sel, err := labels.ConvertSelectorToLabelsMap(k8sListOpts.LabelSelector)
+		sel, err := labels.Parse(k8sListOpts.LabelSelector)
 		if err != nil {
-			log.Error(err, "Unable to convert label selectors for the client")
+			log.Error(err, "Unable to parse label selectors for the client")
 			return nil, err
 		}
-		clientListOpts = append(clientListOpts, client.MatchingLabels(sel))
+		clientListOpts = append(clientListOpts, client.MatchingLabelsSelector{Selector: sel})
 	}
+	// The cache only supports field selectors on indexed fields, so they are evaluated here.
+	fieldSel := fields.Everything()
 	if k8sListOpts.FieldSelector != "" {
 		sel, err := fields.ParseSelector(k8sListOpts.FieldSelector)
 		if err != nil {
 			log.Error(err, "Unable to parse field selectors for the client")
 			return nil, err
 		}
-		clientListOpts = append(clientListOpts, client.MatchingFieldsSelector{Selector: sel})
+		fieldSel = sel
 	}
 	k.Kind = k.Kind + "List"
 	un := unstructured.UnstructuredList{}
 	un.SetGroupVersionKind(k)
 	ctx, cancel := context.WithTimeout(context.Background(), cacheEstablishmentTimeout)
 	defer cancel()
-	err := c.informerCache.List(ctx, &un, clientListOpts...)
+	err := informerCache.List(ctx, &un, clientListOpts...)
 	if err != nil {
 		// break here in case resource doesn't exist in cache but exists on APIserver
 		// This is very unlikely but provides user with expected 404
 		log.Info(fmt.Sprintf("cache miss: %v err-%v", k, err))
 		return nil, err
 	}
+	filterByFields(&un, fieldSel)
 	return &un, nil
 }
 
-func (c *cacheResponseHandler) getObjectFromCache(r *k8sRequest.RequestInfo, req *http.Request,
-	k schema.GroupVersionKind) (marshaler, error) {
+func (c *cacheResponseHandler) getObjectFromCache(informerCache cache.Cache, r *k8sRequest.RequestInfo,
+	req *http.Request, k schema.GroupVersionKind) (marshaler, error) {
 	un := &unstructured.Unstructured{}
 	un.SetGroupVersionKind(k)
 	obj := client.ObjectKey{Namespace: r.Namespace, Name: r.Name}
 	ctx, cancel := context.WithTimeout(context.Background(), cacheEstablishmentTimeout)
 	defer cancel()
-	err := c.informerCache.Get(ctx, obj, un)
+	err := informerCache.Get(ctx, obj, un)
 	if err != nil {
 		// break here in case resource doesn't exist in cache but exists on APIserver
 		// This is very unlikely but provides user with expected 404
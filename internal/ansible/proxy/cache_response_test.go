@@ -0,0 +1,306 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/proxy/controllermap"
+	"github.com/operator-framework/operator-sdk/internal/ansible/proxy/kubeconfig"
+)
+
+// fakeCache serves the pods of the "default" namespace.
+type fakeCache struct {
+	cache.Cache
+	pods     []unstructured.Unstructured
+	unsynced bool
+	started  chan context.Context
+}
+
+func (f *fakeCache) Start(ctx context.Context) error {
+	if f.started != nil {
+		f.started <- ctx
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (f *fakeCache) WaitForCacheSync(context.Context) bool {
+	return true
+}
+
+func (f *fakeCache) GetInformer(context.Context, client.Object) (cache.Informer, error) {
+	if f.unsynced {
+		return nil, errors.New("informer has not synced")
+	}
+	return nil, nil
+}
+
+func (f *fakeCache) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if listOpts.FieldSelector != nil {
+		return errors.New("field selectors require an index")
+	}
+	u := list.(*unstructured.UnstructuredList)
+	for _, pod := range f.pods {
+		if listOpts.LabelSelector == nil || listOpts.LabelSelector.Matches(labels.Set(pod.GetLabels())) {
+			u.Items = append(u.Items, pod)
+		}
+	}
+	return nil
+}
+
+func TestCacheResponseHandler(t *testing.T) {
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	restMapper.Add(podGVK, meta.RESTScopeNamespace)
+
+	pod := func(name, phase string, podLabels map[string]string) unstructured.Unstructured {
+		u := unstructured.Unstructured{Object: map[string]interface{}{
+			"status": map[string]interface{}{"phase": phase},
+		}}
+		u.SetGroupVersionKind(podGVK)
+		u.SetNamespace("default")
+		u.SetName(name)
+		u.SetLabels(podLabels)
+		return u
+	}
+	pods := []unstructured.Unstructured{
+		pod("web", "Running", map[string]string{"app": "web", "tier": "frontend"}),
+		pod("db", "Pending", map[string]string{"app": "db", "tier": "backend"}),
+		pod("job", "Succeeded", nil),
+	}
+
+	ownerGVK := schema.GroupVersionKind{Group: "cache.example.com", Version: "v1alpha1", Kind: "Memcached"}
+	restMapper.Add(ownerGVK, meta.RESTScopeNamespace)
+	otherGVK := schema.GroupVersionKind{Group: "cache.example.com", Version: "v1alpha1", Kind: "Other"}
+	restMapper.Add(otherGVK, meta.RESTScopeNamespace)
+	cMap := controllermap.NewControllerMap()
+	ownerWatches := controllermap.NewWatchMap()
+	ownerWatches.Store(podGVK)
+	cMap.Store(ownerGVK, &controllermap.Contents{
+		OwnerWatchMap:      ownerWatches,
+		AnnotationWatchMap: controllermap.NewWatchMap(),
+	}, nil)
+	cMap.Store(otherGVK, &controllermap.Contents{
+		OwnerWatchMap:      controllermap.NewWatchMap(),
+		AnnotationWatchMap: controllermap.NewWatchMap(),
+	}, nil)
+	owner := func(gvk schema.GroupVersionKind) string {
+		data, err := json.Marshal(kubeconfig.NamespacedOwnerReference{
+			OwnerReference: metav1.OwnerReference{
+				APIVersion: gvk.GroupVersion().String(),
+				Kind:       gvk.Kind,
+				Name:       "example",
+			},
+			Namespace: "default",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(data)
+	}
+
+	testCases := []struct {
+		name          string
+		path          string
+		owner         *schema.GroupVersionKind
+		noOwner       bool
+		unsynced      bool
+		lazy          bool
+		expectedCache bool
+		expectedNames []string
+	}{
+		{
+			name:  "kind the owner doesn't watch",
+			path:  "/api/v1/namespaces/default/pods",
+			owner: &otherGVK,
+		},
+		{
+			name:          "kind the owner doesn't watch from a lazy cache",
+			path:          "/api/v1/namespaces/default/pods",
+			owner:         &otherGVK,
+			lazy:          true,
+			expectedCache: true,
+			expectedNames: []string{"web", "db", "job"},
+		},
+		{
+			name:    "dependent kind without an owner",
+			path:    "/api/v1/namespaces/default/pods",
+			noOwner: true,
+		},
+		{
+			name:  "kind the owner doesn't watch across namespaces",
+			path:  "/api/v1/pods",
+			owner: &otherGVK,
+			lazy:  true,
+		},
+		{
+			name:          "list from the cache",
+			path:          "/api/v1/namespaces/default/pods",
+			expectedCache: true,
+			expectedNames: []string{"web", "db", "job"},
+		},
+		{
+			name:          "set-based label selector",
+			path:          "/api/v1/namespaces/default/pods?labelSelector=tier+in+(frontend,backend),app!=db",
+			expectedCache: true,
+			expectedNames: []string{"web"},
+		},
+		{
+			name:          "field selector on a common field",
+			path:          "/api/v1/namespaces/default/pods?fieldSelector=metadata.name=db",
+			expectedCache: true,
+			expectedNames: []string{"db"},
+		},
+		{
+			name:          "field selector on a kind specific field",
+			path:          "/api/v1/namespaces/default/pods?fieldSelector=status.phase!=Running",
+			expectedCache: true,
+			expectedNames: []string{"db", "job"},
+		},
+		{
+			name: "field selector on a field that can't be evaluated in memory",
+			path: "/api/v1/namespaces/default/pods?fieldSelector=spec.hostNetwork=true",
+		},
+		{
+			name: "namespace that isn't watched",
+			path: "/api/v1/namespaces/other/pods",
+		},
+		{
+			name:     "informer that hasn't synced yet",
+			path:     "/api/v1/namespaces/default/pods",
+			unsynced: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			forwarded := false
+			h := &cacheResponseHandler{
+				next: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					forwarded = true
+				}),
+				informerCache:     &fakeCache{pods: pods, unsynced: tc.unsynced},
+				restMapper:        restMapper,
+				watchedNamespaces: map[string]interface{}{"default": nil},
+				cMap:              cMap,
+				apiResources: &apiResources{
+					mu: &sync.RWMutex{},
+					gvkToAPIResource: map[string]metav1.APIResource{
+						podGVK.String(): {Name: "pods", Kind: "Pod", Verbs: []string{"get", "list", "watch"}},
+					},
+				},
+			}
+			if tc.lazy {
+				h.lazyCaches = &lazyCaches{
+					max: 1,
+					newCache: func(string) (cache.Cache, error) {
+						return &fakeCache{pods: pods}, nil
+					},
+					caches: map[lazyCacheKey]*lazyCache{},
+				}
+				h.informerCache = nil
+			}
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			switch {
+			case tc.owner != nil:
+				req.SetBasicAuth(owner(*tc.owner), "unused")
+			case !tc.noOwner:
+				req.SetBasicAuth(owner(ownerGVK), "unused")
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if forwarded == tc.expectedCache {
+				t.Fatalf("Unexpected cache use, forwarded to the API server: %v", forwarded)
+			}
+			if !tc.expectedCache {
+				return
+			}
+			list := unstructured.UnstructuredList{}
+			if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+				t.Fatalf("Invalid list in response: %v", err)
+			}
+			names := []string{}
+			for _, item := range list.Items {
+				names = append(names, item.GetName())
+			}
+			if len(names) != len(tc.expectedNames) {
+				t.Fatalf("Unexpected items %v expected %v", names, tc.expectedNames)
+			}
+			for i := range names {
+				if names[i] != tc.expectedNames[i] {
+					t.Fatalf("Unexpected items %v expected %v", names, tc.expectedNames)
+				}
+			}
+		})
+	}
+}
+
+func TestLazyCaches(t *testing.T) {
+	started := make(chan context.Context, 3)
+	l := &lazyCaches{
+		max: 2,
+		newCache: func(string) (cache.Cache, error) {
+			return &fakeCache{started: started}, nil
+		},
+		caches: map[lazyCacheKey]*lazyCache{},
+	}
+	pods := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	get := func(namespace string) cache.Cache {
+		c, err := l.get(pods, namespace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	a := get("a")
+	ctxA := <-started
+	get("b")
+	ctxB := <-started
+	if get("a") != a {
+		t.Fatalf("Cache of a namespace was started again")
+	}
+	// The cache of "b" is the least recently read one.
+	get("c")
+	<-started
+	if ctxB.Err() == nil {
+		t.Fatalf("Least recently read cache was not stopped")
+	}
+	if ctxA.Err() != nil {
+		t.Fatalf("Recently read cache was stopped")
+	}
+	if len(l.caches) != 2 {
+		t.Fatalf("Unexpected number of caches %d", len(l.caches))
+	}
+}
@@ -19,6 +19,8 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
 )
 
 // ControllerMap - map of GVK to ControllerMapContents
@@ -41,6 +43,24 @@ type Contents struct {
 	OwnerWatchMap               *WatchMap
 	AnnotationWatchMap          *WatchMap
 	Blacklist                   map[schema.GroupVersionKind]bool
+	// Whitelist, when not empty, limits the dependent resources that are watched and
+	// cached to the GVKs it contains.
+	Whitelist map[schema.GroupVersionKind]bool
+	// AnnotationEnqueue holds the dependent GVKs that are always mapped back to their
+	// owner with annotations, even when an ownerReference could be set.
+	AnnotationEnqueue map[schema.GroupVersionKind]bool
+	// APIPolicy, when not empty, limits the requests the proxy allows while
+	// reconciling a CR to those matching one of its rules.
+	APIPolicy []watches.APIRule
+}
+
+// IsExcluded - Returns true if a dependent resource of the given GVK must not be
+// watched or cached for this controller
+func (c *Contents) IsExcluded(gvk schema.GroupVersionKind) bool {
+	if c.Blacklist[gvk] {
+		return true
+	}
+	return len(c.Whitelist) > 0 && !c.Whitelist[gvk]
 }
 
 // NewControllerMap returns a new object that contains a mapping between GVK
@@ -68,6 +88,18 @@ func (cm *ControllerMap) Get(key schema.GroupVersionKind) (value *Contents, ok b
 	return value, ok
 }
 
+// HasAPIPolicy - Returns true if any controller limits its requests with an API policy
+func (cm *ControllerMap) HasAPIPolicy() bool {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+	for _, c := range cm.internal {
+		if len(c.APIPolicy) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // Delete - Deletes associated GVK to controller mapping from the ControllerMap
 func (cm *ControllerMap) Delete(key schema.GroupVersionKind) {
 	cm.mutex.Lock()
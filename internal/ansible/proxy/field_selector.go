@@ -0,0 +1,80 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// commonSelectableFields can be used in the field selectors of any kind.
+var commonSelectableFields = sets.NewString("metadata.name", "metadata.namespace")
+
+// selectableFields are the fields, other than the common ones, that the API server
+// supports in field selectors and that are evaluated the same way in memory.
+var selectableFields = map[schema.GroupKind]sets.String{
+	{Kind: "Pod"}: sets.NewString("spec.nodeName", "spec.restartPolicy", "spec.schedulerName",
+		"spec.serviceAccountName", "status.phase", "status.podIP", "status.nominatedNodeName"),
+	{Kind: "Event"}: sets.NewString("involvedObject.kind", "involvedObject.namespace", "involvedObject.name",
+		"involvedObject.uid", "involvedObject.apiVersion", "involvedObject.resourceVersion",
+		"involvedObject.fieldPath", "reason", "type"),
+	{Kind: "Secret"}:    sets.NewString("type"),
+	{Kind: "Namespace"}: sets.NewString("status.phase"),
+}
+
+// canSelectInMemory returns true if every field of sel can be evaluated against
+// objects of kind gk read from the cache.
+func canSelectInMemory(gk schema.GroupKind, sel fields.Selector) bool {
+	for _, req := range sel.Requirements() {
+		if !commonSelectableFields.Has(req.Field) && !selectableFields[gk].Has(req.Field) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterByFields removes the items of list that don't match sel.
+func filterByFields(list *unstructured.UnstructuredList, sel fields.Selector) {
+	if sel.Empty() {
+		return
+	}
+	items := list.Items[:0]
+	for _, item := range list.Items {
+		if sel.Matches(fieldSet(item.Object, sel)) {
+			items = append(items, item)
+		}
+	}
+	list.Items = items
+}
+
+// fieldSet returns the values of the fields of sel in obj. Unset fields are empty,
+// as they are for the API server.
+func fieldSet(obj map[string]interface{}, sel fields.Selector) fields.Set {
+	set := fields.Set{}
+	for _, req := range sel.Requirements() {
+		value, found, err := unstructured.NestedFieldNoCopy(obj, strings.Split(req.Field, ".")...)
+		if err != nil || !found || value == nil {
+			set[req.Field] = ""
+			continue
+		}
+		set[req.Field] = fmt.Sprint(value)
+	}
+	return set
+}
@@ -143,6 +143,9 @@ func (i *injectOwnerReferenceHandler) ServeHTTP(w http.ResponseWriter, req *http
 				http.Error(w, m, http.StatusBadRequest)
 				return
 			}
+			if contents, ok := i.cMap.Get(ownerGVK); ok && contents.AnnotationEnqueue[k] {
+				addOwnerRef = false
+			}
 			if addOwnerRef {
 				data.SetOwnerReferences(append(data.GetOwnerReferences(), owner.OwnerReference))
 			} else {
@@ -52,13 +52,18 @@ preferences: {}
 users:
 - name: admin/proxy-server
   user:
+{{- if .Token }}
+    token: {{.Token}}
+{{- else }}
     username: {{.Username}}
     password: unused
+{{- end }}
 `
 
 // values holds the data used to render the template
 type values struct {
 	Username  string
+	Token     string
 	ProxyURL  string
 	Namespace string
 }
@@ -81,12 +86,27 @@ func Create(ownerRef metav1.OwnerReference, proxyURL string, namespace string) (
 	}
 	username := base64.URLEncoding.EncodeToString(ownerRefJSON)
 	parsedURL.User = url.User(username)
-	v := values{
+	return write(values{
 		Username:  username,
 		ProxyURL:  parsedURL.String(),
 		Namespace: namespace,
+	})
+}
+
+// CreateWithToken renders a kubeconfig template that authenticates to the proxy
+// with a bearer token issued by Tokens, and writes it to disk
+func CreateWithToken(token, proxyURL, namespace string) (*os.File, error) {
+	if _, err := url.Parse(proxyURL); err != nil {
+		return nil, err
 	}
+	return write(values{
+		Token:     token,
+		ProxyURL:  proxyURL,
+		Namespace: namespace,
+	})
+}
 
+func write(v values) (*os.File, error) {
 	var parsed bytes.Buffer
 
 	t := template.Must(template.New("kubeconfig").Parse(kubeConfigTemplate))
@@ -0,0 +1,97 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeconfig
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// Tokens - issues bearer tokens that identify the owner reference of a run to
+// the proxy. Tokens are valid until revoked, when their run ends, or until their
+// time to live passes, if it is greater than 0.
+type Tokens struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	tokens map[string]token
+
+	// now is overridden in tests.
+	now func() time.Time
+}
+
+type token struct {
+	owner NamespacedOwnerReference
+	// expires is zero for tokens that are only revoked.
+	expires time.Time
+}
+
+func (tok token) expired(now time.Time) bool {
+	return !tok.expires.IsZero() && now.After(tok.expires)
+}
+
+// NewTokens - returns Tokens that expire after ttl, or only when revoked if ttl is 0.
+func NewTokens(ttl time.Duration) *Tokens {
+	return &Tokens{
+		ttl:    ttl,
+		tokens: map[string]token{},
+		now:    time.Now,
+	}
+}
+
+// Issue - returns a new token for owner.
+func (t *Tokens) Issue(owner NamespacedOwnerReference) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	value := base64.RawURLEncoding.EncodeToString(b)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	// Drop expired tokens, so that tokens of runs that never revoked them don't pile up.
+	for v, tok := range t.tokens {
+		if tok.expired(now) {
+			delete(t.tokens, v)
+		}
+	}
+	tok := token{owner: owner}
+	if t.ttl > 0 {
+		tok.expires = now.Add(t.ttl)
+	}
+	t.tokens[value] = tok
+	return value, nil
+}
+
+// Revoke - invalidates a token.
+func (t *Tokens) Revoke(value string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.tokens, value)
+}
+
+// Lookup - returns the owner a token was issued for, if the token is valid.
+func (t *Tokens) Lookup(value string) (*NamespacedOwnerReference, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tok, ok := t.tokens[value]
+	if !ok || tok.expired(t.now()) {
+		return nil, false
+	}
+	owner := tok.owner
+	return &owner, true
+}
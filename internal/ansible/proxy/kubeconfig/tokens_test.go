@@ -0,0 +1,113 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTokens(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	tokens := NewTokens(time.Hour)
+	tokens.now = func() time.Time { return now }
+	owner := NamespacedOwnerReference{
+		OwnerReference: metav1.OwnerReference{APIVersion: "cache.example.com/v1alpha1", Kind: "Memcached",
+			Name: "example", UID: "uid"},
+		Namespace: "default",
+	}
+
+	first, err := tokens.Issue(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := tokens.Issue(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatalf("Issued the same token twice")
+	}
+
+	if got, ok := tokens.Lookup(first); !ok || *got != owner {
+		t.Fatalf("Unexpected owner %v for a valid token", got)
+	}
+	if _, ok := tokens.Lookup("unknown"); ok {
+		t.Fatalf("Unknown token is valid")
+	}
+
+	tokens.Revoke(first)
+	if _, ok := tokens.Lookup(first); ok {
+		t.Fatalf("Revoked token is valid")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := tokens.Lookup(second); ok {
+		t.Fatalf("Expired token is valid")
+	}
+	if _, err := tokens.Issue(owner); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tokens.tokens[second]; ok {
+		t.Fatalf("Expired token was not dropped")
+	}
+}
+
+func TestTokensWithoutTTL(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	tokens := NewTokens(0)
+	tokens.now = func() time.Time { return now }
+	owner := NamespacedOwnerReference{
+		OwnerReference: metav1.OwnerReference{Kind: "Memcached", Name: "example"},
+		Namespace:      "default",
+	}
+
+	value, err := tokens.Issue(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(24 * time.Hour)
+	if _, ok := tokens.Lookup(value); !ok {
+		t.Fatalf("Token of a run that did not end is not valid")
+	}
+	tokens.Revoke(value)
+	if _, ok := tokens.Lookup(value); ok {
+		t.Fatalf("Revoked token is valid")
+	}
+}
+
+func TestCreateWithToken(t *testing.T) {
+	kc, err := CreateWithToken("secret-token", "http://localhost:8888", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(kc.Name())
+	data, err := ioutil.ReadFile(kc.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	if !strings.Contains(content, "token: secret-token") {
+		t.Fatalf("Kubeconfig does not hold the token:\n%s", content)
+	}
+	if strings.Contains(content, "username:") || strings.Contains(content, "@localhost") {
+		t.Fatalf("Kubeconfig holds a username:\n%s", content)
+	}
+}
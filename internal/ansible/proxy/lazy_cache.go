@@ -0,0 +1,116 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// lazyCaches caches the objects of kinds that no watch watches, in the namespaces that
+// runs read them from. The cache of a kind in a namespace is started on its first read,
+// and at most max caches run at a time: the least recently read one is stopped to start
+// another.
+type lazyCaches struct {
+	mu       sync.Mutex
+	max      int
+	newCache func(namespace string) (cache.Cache, error)
+	caches   map[lazyCacheKey]*lazyCache
+	// reads orders the caches by their last read.
+	reads uint64
+}
+
+type lazyCacheKey struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+}
+
+type lazyCache struct {
+	cache.Cache
+	stop     context.CancelFunc
+	lastRead uint64
+}
+
+func newLazyCaches(cfg *rest.Config, mapper meta.RESTMapper, max int) *lazyCaches {
+	return &lazyCaches{
+		max: max,
+		newCache: func(namespace string) (cache.Cache, error) {
+			return cache.New(cfg, cache.Options{Mapper: mapper, Namespace: namespace})
+		},
+		caches: map[lazyCacheKey]*lazyCache{},
+	}
+}
+
+// get returns the cache of gvk in namespace, which is started if it is not running yet.
+// Its informer is only started by the first read of gvk from it.
+func (l *lazyCaches) get(gvk schema.GroupVersionKind, namespace string) (cache.Cache, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.reads++
+	key := lazyCacheKey{gvk: gvk, namespace: namespace}
+	if c, ok := l.caches[key]; ok {
+		c.lastRead = l.reads
+		return c, nil
+	}
+
+	if len(l.caches) >= l.max {
+		l.evict()
+	}
+	c, err := l.newCache(namespace)
+	if err != nil {
+		return nil, err
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	go func() {
+		if err := c.Start(ctx); err != nil {
+			log.Error(err, "Failed to start lazy cache", "GVK", gvk, "Namespace", namespace)
+		}
+	}()
+	// Informers of a cache that has not started yet are returned before they synced, so
+	// the cache is only used once it started. It has no informers to sync yet.
+	startCtx, cancel := context.WithTimeout(ctx, cacheEstablishmentTimeout)
+	defer cancel()
+	if !c.WaitForCacheSync(startCtx) {
+		stop()
+		return nil, fmt.Errorf("failed to start the cache of %s in namespace %q", gvk, namespace)
+	}
+	log.V(1).Info("Started lazy cache", "GVK", gvk, "Namespace", namespace)
+	l.caches[key] = &lazyCache{Cache: c, stop: stop, lastRead: l.reads}
+	return l.caches[key], nil
+}
+
+// evict stops the least recently read cache.
+func (l *lazyCaches) evict() {
+	var lru *lazyCacheKey
+	for key, c := range l.caches {
+		if lru == nil || c.lastRead < l.caches[*lru].lastRead {
+			key := key
+			lru = &key
+		}
+	}
+	if lru == nil {
+		return
+	}
+	log.V(1).Info("Stopping least recently read lazy cache", "GVK", lru.gvk, "Namespace", lru.namespace)
+	l.caches[*lru].stop()
+	delete(l.caches, *lru)
+}
@@ -0,0 +1,208 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/proxy/controllermap"
+	"github.com/operator-framework/operator-sdk/internal/ansible/proxy/kubeconfig"
+	k8sRequest "github.com/operator-framework/operator-sdk/internal/ansible/proxy/requestfactory"
+)
+
+var auditLog = log.WithName("audit")
+
+// mutatingVerbs are the verbs of requests that are audited.
+var mutatingVerbs = sets.NewString("create", "update", "patch", "delete", "deletecollection")
+
+// policyHandler will deny proxied requests that the API policy of the watch of
+// the requesting CR does not allow, and requests without an owner once any watch
+// has an API policy. When audit is set, every mutating request is
+// logged along with the CR it was made for and the response code.
+type policyHandler struct {
+	next       http.Handler
+	cMap       *controllermap.ControllerMap
+	restMapper meta.RESTMapper
+	audit      bool
+}
+
+func (p *policyHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rf := k8sRequest.RequestInfoFactory{APIPrefixes: sets.NewString("api", "apis"),
+		GrouplessAPIPrefixes: sets.NewString("api")}
+	r, err := rf.NewRequestInfo(req)
+	if err != nil {
+		m := "Could not convert request"
+		log.Error(err, m)
+		http.Error(w, m, http.StatusBadRequest)
+		return
+	}
+	// Discovery and other non-resource requests are always allowed.
+	if !r.IsResourceRequest {
+		p.next.ServeHTTP(w, req)
+		return
+	}
+
+	owner, err := getRequestOwnerRef(req)
+	if err != nil {
+		m := "Could not get owner reference"
+		log.Error(err, m)
+		http.Error(w, m, http.StatusInternalServerError)
+		return
+	}
+
+	name, err := requestObjectName(r, req)
+	if err != nil {
+		m := "Could not read request body"
+		log.Error(err, m)
+		http.Error(w, m, http.StatusBadRequest)
+		return
+	}
+
+	mutating := mutatingVerbs.Has(r.Verb)
+	entry := []interface{}{
+		"verb", r.Verb,
+		"apiVersion", schema.GroupVersion{Group: r.APIGroup, Version: r.APIVersion}.String(),
+		"resource", r.Resource,
+		"subresource", r.Subresource,
+		"namespace", r.Namespace,
+		"name", name,
+	}
+	if owner != nil {
+		entry = append(entry,
+			"ownerAPIVersion", owner.APIVersion,
+			"ownerKind", owner.Kind,
+			"ownerNamespace", owner.Namespace,
+			"ownerName", owner.Name,
+			"ownerUID", owner.UID,
+		)
+	}
+	if reason, allowed := p.allowed(r, owner); !allowed {
+		auditLog.Info("Denied API request", append(entry, "reason", reason)...)
+		writeForbidden(w, r, reason)
+		return
+	}
+
+	if !p.audit || !mutating {
+		p.next.ServeHTTP(w, req)
+		return
+	}
+	rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+	p.next.ServeHTTP(rec, req)
+	auditLog.Info("API request", append(entry, "code", rec.code)...)
+}
+
+// allowed checks a request against the API policy of the watch of owner. A request
+// without an owner is denied once any watch has an API policy, since it cannot be
+// attributed to a watch. The reason a request is denied is returned with false.
+func (p *policyHandler) allowed(r *k8sRequest.RequestInfo, owner *kubeconfig.NamespacedOwnerReference) (string, bool) {
+	if owner == nil {
+		if p.cMap.HasAPIPolicy() {
+			return "requests without an owner are not allowed when an API policy is set", false
+		}
+		return "", true
+	}
+	ownerGV, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil {
+		return fmt.Sprintf("invalid owner apiVersion %q", owner.APIVersion), false
+	}
+	contents, ok := p.cMap.Get(ownerGV.WithKind(owner.Kind))
+	if !ok || len(contents.APIPolicy) == 0 {
+		return "", true
+	}
+
+	if p.restMapper == nil {
+		return "unable to determine the kind of the resource", false
+	}
+	gvk, err := getGVKFromRequestInfo(r, p.restMapper)
+	if err != nil {
+		return fmt.Sprintf("unable to determine the kind of resource %q: %v", r.Resource, err), false
+	}
+
+	// The CR being reconciled can always be read and updated, e.g. to set its status.
+	if gvk.GroupKind() == ownerGV.WithKind(owner.Kind).GroupKind() &&
+		r.Namespace == owner.Namespace && r.Name == owner.Name {
+		return "", true
+	}
+
+	for _, rule := range contents.APIPolicy {
+		if rule.Allows(r.Verb, gvk.Group, gvk.Kind, r.Namespace, owner.Namespace) {
+			return "", true
+		}
+	}
+	return fmt.Sprintf("the API policy of %s does not allow %s of %s in namespace %q",
+		ownerGV.WithKind(owner.Kind).GroupKind(), r.Verb, gvk.GroupKind(), r.Namespace), false
+}
+
+// requestObjectName returns the name of the object of a request. The name of an
+// object being created is read from the request body, which is then restored.
+func requestObjectName(r *k8sRequest.RequestInfo, req *http.Request) (string, error) {
+	if r.Name != "" || req.Method != http.MethodPost || req.Body == nil {
+		return r.Name, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	obj := metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return "", nil
+	}
+	if obj.Name == "" {
+		return obj.GenerateName, nil
+	}
+	return obj.Name, nil
+}
+
+// writeForbidden responds to a request with a Forbidden status, as the API server would.
+func writeForbidden(w http.ResponseWriter, r *k8sRequest.RequestInfo, reason string) {
+	status := metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusFailure,
+		Message:  reason,
+		Reason:   metav1.StatusReasonForbidden,
+		Details: &metav1.StatusDetails{
+			Name:  r.Name,
+			Group: r.APIGroup,
+			Kind:  r.Resource,
+		},
+		Code: http.StatusForbidden,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Error(err, "Failed to write response")
+	}
+}
+
+// statusRecorder records the status code written to a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.code = code
+	s.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/proxy/controllermap"
+	"github.com/operator-framework/operator-sdk/internal/ansible/proxy/kubeconfig"
+	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
+)
+
+func TestPolicyHandler(t *testing.T) {
+	ownerGVK := schema.GroupVersionKind{Group: "cache.example.com", Version: "v1alpha1", Kind: "Memcached"}
+	noPolicyGVK := schema.GroupVersionKind{Group: "cache.example.com", Version: "v1alpha1", Kind: "Redis"}
+
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	for _, gvk := range []schema.GroupVersionKind{
+		ownerGVK,
+		noPolicyGVK,
+		{Version: "v1", Kind: "ConfigMap"},
+		{Version: "v1", Kind: "Secret"},
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+	} {
+		restMapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+
+	cMap := controllermap.NewControllerMap()
+	cMap.Store(ownerGVK, &controllermap.Contents{
+		APIPolicy: []watches.APIRule{
+			{Groups: []string{""}, Kinds: []string{"ConfigMap"}, Verbs: []string{"get", "create"}},
+			{Groups: []string{"apps"}, Kinds: []string{"*"}, Verbs: []string{"*"},
+				Namespaces: []string{watches.OwnerNamespace}},
+		},
+	}, nil)
+	cMap.Store(noPolicyGVK, &controllermap.Contents{}, nil)
+
+	owner := func(gvk schema.GroupVersionKind) string {
+		data, err := json.Marshal(kubeconfig.NamespacedOwnerReference{
+			OwnerReference: metav1.OwnerReference{
+				APIVersion: gvk.GroupVersion().String(),
+				Kind:       gvk.Kind,
+				Name:       "example",
+				UID:        "uid",
+			},
+			Namespace: "default",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(data)
+	}
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		owner        string
+		expectedCode int
+	}{
+		{
+			name:         "request without an owner is denied",
+			method:       http.MethodDelete,
+			path:         "/api/v1/namespaces/default/secrets/test",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "request for a watch without a policy is allowed",
+			method:       http.MethodDelete,
+			path:         "/api/v1/namespaces/default/secrets/test",
+			owner:        owner(noPolicyGVK),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "non-resource request is allowed",
+			method:       http.MethodGet,
+			path:         "/apis",
+			owner:        owner(ownerGVK),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "request matching a rule is allowed",
+			method:       http.MethodPost,
+			path:         "/api/v1/namespaces/other/configmaps",
+			owner:        owner(ownerGVK),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "request with a verb no rule allows is denied",
+			method:       http.MethodDelete,
+			path:         "/api/v1/namespaces/default/configmaps/test",
+			owner:        owner(ownerGVK),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "request for a kind no rule allows is denied",
+			method:       http.MethodGet,
+			path:         "/api/v1/namespaces/default/secrets/test",
+			owner:        owner(ownerGVK),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "request in the owner namespace is allowed",
+			method:       http.MethodPatch,
+			path:         "/apis/apps/v1/namespaces/default/deployments/test",
+			owner:        owner(ownerGVK),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "request outside the owner namespace is denied",
+			method:       http.MethodPatch,
+			path:         "/apis/apps/v1/namespaces/other/deployments/test",
+			owner:        owner(ownerGVK),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "status update of the owner is allowed",
+			method:       http.MethodPut,
+			path:         "/apis/cache.example.com/v1alpha1/namespaces/default/memcacheds/example/status",
+			owner:        owner(ownerGVK),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "request for another CR of the owner kind is denied",
+			method:       http.MethodGet,
+			path:         "/apis/cache.example.com/v1alpha1/namespaces/default/memcacheds/other",
+			owner:        owner(ownerGVK),
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &policyHandler{
+				next:       http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}),
+				cMap:       cMap,
+				restMapper: restMapper,
+				audit:      true,
+			}
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(`{"metadata":{"name":"test"}}`))
+			if tc.owner != "" {
+				req.SetBasicAuth(tc.owner, "unused")
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tc.expectedCode {
+				t.Fatalf("Unexpected code %d expected code %d: %s", w.Code, tc.expectedCode, w.Body.String())
+			}
+			if tc.expectedCode == http.StatusForbidden {
+				status := metav1.Status{}
+				if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+					t.Fatalf("Invalid status in response: %v", err)
+				}
+				if status.Reason != metav1.StatusReasonForbidden {
+					t.Fatalf("Unexpected reason %s", status.Reason)
+				}
+			}
+		})
+	}
+}
@@ -22,7 +22,9 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -86,6 +88,19 @@ type Options struct {
 	DisableCache      bool
 	OwnerInjection    bool
 	LogRequests       bool
+	// AuditLog logs every mutating request along with the CR it was made for.
+	AuditLog bool
+	// Tokens, when set, authenticate each request with a bearer token issued by it,
+	// which also identifies the owner of the request.
+	Tokens *kubeconfig.Tokens
+	// UnixSocket, when set, is the path of a Unix socket the proxy also listens on.
+	UnixSocket string
+	// Listener, when set, is served on instead of listening on Address and Port.
+	Listener net.Listener
+	// MaxLazyCaches is the number of caches that are started for the kinds that no watch
+	// watches, one for each kind and namespace that runs read. Those kinds are read from
+	// the API server if it is 0.
+	MaxLazyCaches int
 }
 
 // Run will start a proxy server in a go routine that returns on the error
@@ -168,7 +183,7 @@ func Run(done chan error, o Options) error {
 		if err != nil {
 			log.Error(err, "Failed to parse cache skip regular expression")
 		}
-		server.Handler = &cacheResponseHandler{
+		handler := &cacheResponseHandler{
 			next:              server.Handler,
 			informerCache:     o.Cache,
 			restMapper:        o.RESTMapper,
@@ -178,19 +193,60 @@ func Run(done chan error, o Options) error {
 			apiResources:      resources,
 			skipPathRegexp:    autoSkipCacheRegexp,
 		}
+		if o.MaxLazyCaches > 0 {
+			handler.lazyCaches = newLazyCaches(o.KubeConfig, o.RESTMapper, o.MaxLazyCaches)
+		}
+		server.Handler = handler
 	}
 
-	l, err := server.Listen(o.Address, o.Port)
-	if err != nil {
-		return err
+	// Enforce API policies before anything, including the cache, can serve a request.
+	server.Handler = &policyHandler{
+		next:       server.Handler,
+		cMap:       o.ControllerMap,
+		restMapper: o.RESTMapper,
+		audit:      o.AuditLog,
+	}
+	if o.Tokens != nil {
+		server.Handler = &tokenAuthHandler{next: server.Handler, tokens: o.Tokens}
+	}
+
+	// Runs always reach the proxy over TCP, since the Kubernetes Python client used by
+	// the k8s modules cannot connect to a Unix socket.
+	l := o.Listener
+	if l == nil {
+		if l, err = server.Listen(o.Address, o.Port); err != nil {
+			return err
+		}
+	}
+	var ul net.Listener
+	if o.UnixSocket != "" {
+		if ul, err = server.ListenUnix(o.UnixSocket); err != nil {
+			return err
+		}
+	}
+	go serve(done, server, l)
+	if ul != nil {
+		go func() {
+			serve(done, server, ul)
+			if err := os.Remove(o.UnixSocket); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Error(err, "Failed to remove the proxy socket", "Path", o.UnixSocket)
+			}
+		}()
 	}
-	go func() {
-		log.Info("Starting to serve", "Address", l.Addr().String())
-		done <- server.ServeOnListener(l)
-	}()
 	return nil
 }
 
+// serve serves s on l, and sends the error it stopped serving with on done.
+func serve(done chan error, s *server, l net.Listener) {
+	addr := l.Addr().String()
+	log.Info("Starting to serve", "Address", addr)
+	if err := s.ServeOnListener(l); err != nil {
+		done <- fmt.Errorf("proxy on %s: %w", addr, err)
+		return
+	}
+	done <- nil
+}
+
 // Helper function used by cache response and owner injection
 func addWatchToController(owner kubeconfig.NamespacedOwnerReference, cMap *controllermap.ControllerMap,
 	resource *unstructured.Unstructured, restMapper meta.RESTMapper, useOwnerRef bool) error {
@@ -227,7 +283,11 @@ func addWatchToController(owner kubeconfig.NamespacedOwnerReference, cMap *contr
 	u.SetGroupVersionKind(ownerMapping.GroupVersionKind)
 
 	// Add a watch to controller
-	if contents.WatchDependentResources && !contents.Blacklist[resource.GroupVersionKind()] {
+	// Honor an explicit request to map this GVK back to its owner with annotations
+	if contents.AnnotationEnqueue[resource.GroupVersionKind()] {
+		useOwnerRef = false
+	}
+	if contents.WatchDependentResources && !contents.IsExcluded(resource.GroupVersionKind()) {
 		// Store watch in map
 		// Use EnqueueRequestForOwner unless user has configured watching cluster scoped resources and we have to
 		switch {
@@ -289,6 +349,10 @@ func removeAuthorizationHeader(h http.Handler) http.Handler {
 
 // Helper function used by recovering dependent watches and owner ref injection.
 func getRequestOwnerRef(req *http.Request) (*kubeconfig.NamespacedOwnerReference, error) {
+	// With token authentication, the owner comes from the token and the username is not trusted.
+	if owner, ok := req.Context().Value(ownerContextKey{}).(*kubeconfig.NamespacedOwnerReference); ok {
+		return owner, nil
+	}
 	owner := kubeconfig.NamespacedOwnerReference{}
 	user, _, ok := req.BasicAuth()
 	if !ok {
@@ -0,0 +1,243 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requirements
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+const (
+	archiveExt = ".tar.gz"
+	// roleInstallInfoPath is where ansible-galaxy records the version of an installed role.
+	roleInstallInfoPath = "meta/.galaxy_install_info"
+)
+
+// Install - installs the requirements that are not satisfied in paths from cacheDir, into the
+// first collections and roles path. The cache holds collections, named
+// <namespace>-<name>-<version>, and roles, named <name>-<version>, each either a directory or
+// a .tar.gz archive, such as those downloaded by "ansible-galaxy collection download". The
+// newest version in the cache that satisfies a requirement is installed.
+func (reqs *Requirements) Install(paths Paths, cacheDir string) error {
+	entries, err := ioutil.ReadDir(cacheDir)
+	if err != nil {
+		return fmt.Errorf("failed to read the requirements cache %s: %w", cacheDir, err)
+	}
+	cache := []string{}
+	for _, e := range entries {
+		cache = append(cache, e.Name())
+	}
+
+	for _, r := range reqs.Collections {
+		namespace, name, ok := r.collectionNames()
+		if !ok || verifyCollection(r, paths) == nil {
+			continue
+		}
+		if len(paths.Collections) == 0 {
+			return fmt.Errorf("no collections path to install collection %s in", r.Name)
+		}
+		src, version, err := findCached(cache, namespace+"-"+name+"-", r)
+		if err != nil {
+			return fmt.Errorf("unable to install collection %s from %s: %w", r.Name, cacheDir, err)
+		}
+		dst := filepath.Join(paths.Collections[0], "ansible_collections", namespace, name)
+		if err := install(filepath.Join(cacheDir, src), dst, false); err != nil {
+			return fmt.Errorf("failed to install collection %s: %w", r.Name, err)
+		}
+		log.Info("Installed collection", "name", r.Name, "version", version, "path", dst)
+	}
+
+	for _, r := range reqs.Roles {
+		name := r.name()
+		if name == "" || verifyRole(r, paths) == nil {
+			continue
+		}
+		if len(paths.Roles) == 0 {
+			return fmt.Errorf("no roles path to install role %s in", name)
+		}
+		src, version, err := findCached(cache, name+"-", r)
+		if err != nil {
+			return fmt.Errorf("unable to install role %s from %s: %w", name, cacheDir, err)
+		}
+		dst := filepath.Join(paths.Roles[0], name)
+		// Role archives, e.g. those of git repositories, usually have a top-level directory.
+		if err := install(filepath.Join(cacheDir, src), dst, true); err != nil {
+			return fmt.Errorf("failed to install role %s: %w", name, err)
+		}
+		installInfo := fmt.Sprintf("install_date: %s\nversion: %s\n", time.Now().UTC().Format(time.ANSIC), version)
+		if err := writeFile(filepath.Join(dst, roleInstallInfoPath), strings.NewReader(installInfo), 0644); err != nil {
+			return fmt.Errorf("failed to record the version of role %s: %w", name, err)
+		}
+		log.Info("Installed role", "name", name, "version", version, "path", dst)
+	}
+	return nil
+}
+
+// findCached returns the name of the entry of the cache for the newest version of r, whose
+// entries are named prefix followed by the version.
+func findCached(cache []string, prefix string, r Requirement) (string, string, error) {
+	var found, foundVersion string
+	var newest semver.Version
+	for _, entry := range cache {
+		if !strings.HasPrefix(entry, prefix) {
+			continue
+		}
+		version := strings.TrimSuffix(strings.TrimPrefix(entry, prefix), archiveExt)
+		v, err := semver.ParseTolerant(version)
+		if err != nil || !r.satisfiedBy(version) {
+			continue
+		}
+		if found == "" || v.GT(newest) {
+			found, foundVersion, newest = entry, version, v
+		}
+	}
+	if found == "" {
+		if r.Version == "" {
+			return "", "", fmt.Errorf("no version is cached")
+		}
+		return "", "", fmt.Errorf("no cached version satisfies %s", r.Version)
+	}
+	return found, foundVersion, nil
+}
+
+// install replaces dst with the contents of src, a directory or a .tar.gz archive. With
+// stripTopDir, a directory that is the only entry of an archive is stripped.
+func install(src, dst string, stripTopDir bool) error {
+	// The content is staged next to dst, so that it can be moved into place.
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(filepath.Dir(dst), "."+filepath.Base(dst)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	content := tmp
+	if strings.HasSuffix(src, archiveExt) {
+		if err := extract(src, tmp); err != nil {
+			return err
+		}
+		if stripTopDir {
+			entries, err := ioutil.ReadDir(tmp)
+			if err != nil {
+				return err
+			}
+			if len(entries) == 1 && entries[0].IsDir() {
+				content = filepath.Join(tmp, entries[0].Name())
+			}
+		}
+	} else if err := copyDir(src, tmp); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	return os.Rename(content, dst)
+}
+
+// extract writes the directories and regular files of the .tar.gz archive src into dir.
+func extract(src, dir string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.Clean(hdr.Name)
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("archive %s has an entry outside of it: %s", src, hdr.Name)
+		}
+		path := filepath.Join(dir, name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(path, tr, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		default:
+			log.V(1).Info("Skipping an archive entry that is neither a directory nor a file", "archive", src,
+				"entry", hdr.Name)
+		}
+	}
+}
+
+// copyDir copies the directories and regular files under src into dst.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, 0755)
+		case info.Mode().IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return writeFile(target, f, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+func writeFile(path string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,265 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package requirements verifies that the Ansible collections and roles listed in a
+// requirements.yml are installed, and installs them from a local cache.
+package requirements
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	apiutilerrors "k8s.io/apimachinery/pkg/util/errors"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	yaml "sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/flags"
+)
+
+var log = logf.Log.WithName("requirements")
+
+// Requirements - the collections and roles of a requirements.yml.
+type Requirements struct {
+	Collections []Requirement `json:"collections"`
+	Roles       []Requirement `json:"roles"`
+}
+
+// Requirement - a collection or role, and the versions of it that are required.
+type Requirement struct {
+	// Name is the name of a collection, e.g. "community.kubernetes", or of a role.
+	Name string `json:"name"`
+	// Src is where a role is installed from. It names the role when Name is not set.
+	Src string `json:"src"`
+	// Version is a version, e.g. "1.2.1", or a range, e.g. ">=1.0.0,<2.0.0". When it is
+	// empty or "*", any version is accepted.
+	Version string `json:"version"`
+}
+
+// UnmarshalJSON - a requirement can also be just the name of a collection or role.
+func (r *Requirement) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*r = Requirement{Name: name}
+		return nil
+	}
+	type requirement Requirement
+	return json.Unmarshal(data, (*requirement)(r))
+}
+
+// Load - reads the requirements at path. A file that is a list, the older format, lists roles.
+func Load(path string) (*Requirements, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the requirements file %s: %w", path, err)
+	}
+	reqs := &Requirements{}
+	if err := yaml.Unmarshal(b, reqs); err != nil {
+		if listErr := yaml.Unmarshal(b, &reqs.Roles); listErr != nil {
+			return nil, fmt.Errorf("failed to parse the requirements file %s: %w", path, err)
+		}
+	}
+	for _, r := range append(reqs.Collections, reqs.Roles...) {
+		if _, err := r.versionRange(); err != nil {
+			return nil, fmt.Errorf("invalid version %q of requirement %s: %w", r.Version, r.name(), err)
+		}
+	}
+	return reqs, nil
+}
+
+// name returns the name of the requirement, which for a role installed from a URL without
+// a name is derived from the URL the way ansible-galaxy does.
+func (r Requirement) name() string {
+	if r.Name != "" || r.Src == "" {
+		return r.Name
+	}
+	src := strings.TrimSuffix(r.Src, "/")
+	src = src[strings.LastIndexAny(src, "/:")+1:]
+	for _, ext := range []string{".git", ".tar.gz", ".tar"} {
+		src = strings.TrimSuffix(src, ext)
+	}
+	return src
+}
+
+// collectionNames returns the namespace and name of a collection requirement, or false
+// if it isn't a collection on a Galaxy server, e.g. a path or a git URL.
+func (r Requirement) collectionNames() (string, string, bool) {
+	parts := strings.Split(r.Name, ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" || strings.ContainsAny(r.Name, "/:") {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// versionRange returns the range of versions that satisfy the requirement, or nil if any does.
+func (r Requirement) versionRange() (semver.Range, error) {
+	v := strings.TrimSpace(r.Version)
+	if v == "" || v == "*" {
+		return nil, nil
+	}
+	// Ansible separates the constraints of a range with commas and uses == for equality.
+	v = strings.ReplaceAll(strings.ReplaceAll(v, ",", " "), "==", "=")
+	return semver.ParseRange(v)
+}
+
+// satisfiedBy returns true if version, which is empty when it isn't known, satisfies the requirement.
+func (r Requirement) satisfiedBy(version string) bool {
+	rng, err := r.versionRange()
+	if err != nil {
+		return false
+	}
+	if rng == nil {
+		return true
+	}
+	v, err := semver.ParseTolerant(version)
+	return err == nil && rng(v)
+}
+
+// Paths - the directories Ansible looks for collections and roles in. Collections are
+// in the ansible_collections directory of a collections path.
+type Paths struct {
+	Collections []string
+	Roles       []string
+}
+
+// PathsFromEnv - returns the paths set by the ANSIBLE_COLLECTIONS_PATH and ANSIBLE_ROLES_PATH
+// environment variables, or else those Ansible uses by default.
+func PathsFromEnv() Paths {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "~"
+	}
+	p := Paths{
+		Collections: []string{filepath.Join(home, ".ansible/collections"), "/usr/share/ansible/collections"},
+		Roles:       []string{filepath.Join(home, ".ansible/roles"), "/usr/share/ansible/roles", "/etc/ansible/roles"},
+	}
+	if env := os.Getenv(flags.AnsibleCollectionsPathEnvVar); env != "" {
+		p.Collections = filepath.SplitList(env)
+	}
+	if env := os.Getenv(flags.AnsibleRolesPathEnvVar); env != "" {
+		p.Roles = filepath.SplitList(env)
+	}
+	return p
+}
+
+func (p Paths) collectionDirs(namespace, name string) []string {
+	dirs := []string{}
+	for _, path := range p.Collections {
+		dirs = append(dirs, filepath.Join(path, "ansible_collections", namespace, name))
+	}
+	return dirs
+}
+
+func (p Paths) roleDirs(name string) []string {
+	dirs := []string{}
+	for _, path := range p.Roles {
+		dirs = append(dirs, filepath.Join(path, name))
+	}
+	// Roles can also live in the current working directory.
+	return append(dirs, filepath.Join("roles", name))
+}
+
+// Verify - returns an error listing the requirements that are not installed in paths, or
+// whose installed version doesn't satisfy them.
+func (reqs *Requirements) Verify(paths Paths) error {
+	errs := []error{}
+	for _, r := range reqs.Collections {
+		if err := verifyCollection(r, paths); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, r := range reqs.Roles {
+		if err := verifyRole(r, paths); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return apiutilerrors.NewAggregate(errs)
+}
+
+func verifyCollection(r Requirement, paths Paths) error {
+	namespace, name, ok := r.collectionNames()
+	if !ok {
+		log.Info("Unable to verify a collection that is not installed from a Galaxy server", "collection", r.Name)
+		return nil
+	}
+	dirs := paths.collectionDirs(namespace, name)
+	return verifyInstalled("collection", r, dirs, collectionVersion)
+}
+
+func verifyRole(r Requirement, paths Paths) error {
+	if r.name() == "" {
+		return fmt.Errorf("role requirement has neither a name nor a src")
+	}
+	return verifyInstalled("role", r, paths.roleDirs(r.name()), roleVersion)
+}
+
+// verifyInstalled checks that r is installed in the first of dirs that exists, which is the
+// one Ansible uses.
+func verifyInstalled(kind string, r Requirement, dirs []string, installedVersion func(string) string) error {
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		version := installedVersion(dir)
+		if !r.satisfiedBy(version) {
+			if version == "" {
+				return fmt.Errorf("%s %s in %s has no version, %s is required", kind, r.name(), dir, r.Version)
+			}
+			return fmt.Errorf("%s %s in %s is version %s, %s is required", kind, r.name(), dir, version, r.Version)
+		}
+		log.V(1).Info("Found the required "+kind, "name", r.name(), "version", version, "path", dir)
+		return nil
+	}
+	return fmt.Errorf("%s %s is not installed in any of %s", kind, r.name(), strings.Join(dirs, ", "))
+}
+
+// collectionVersion returns the version of the collection installed in dir, which is read
+// from its MANIFEST.json, or from its galaxy.yml if it was not built.
+func collectionVersion(dir string) string {
+	if b, err := ioutil.ReadFile(filepath.Join(dir, "MANIFEST.json")); err == nil {
+		manifest := struct {
+			CollectionInfo struct {
+				Version string `json:"version"`
+			} `json:"collection_info"`
+		}{}
+		if err := json.Unmarshal(b, &manifest); err == nil {
+			return manifest.CollectionInfo.Version
+		}
+	}
+	return readVersion(filepath.Join(dir, "galaxy.yml"))
+}
+
+// roleVersion returns the version of the role installed in dir, which is recorded by
+// ansible-galaxy in meta/.galaxy_install_info.
+func roleVersion(dir string) string {
+	return readVersion(filepath.Join(dir, roleInstallInfoPath))
+}
+
+func readVersion(path string) string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	info := struct {
+		Version string `json:"version"`
+	}{}
+	if err := yaml.Unmarshal(b, &info); err != nil {
+		return ""
+	}
+	return info.Version
+}
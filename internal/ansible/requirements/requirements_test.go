@@ -0,0 +1,289 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requirements
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+// writeTestArchive writes a .tar.gz archive at path with files, keyed by their path in it.
+func writeTestArchive(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func collectionManifest(version string) string {
+	return `{"collection_info": {"namespace": "community", "name": "kubernetes", "version": "` + version + `"}}`
+}
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name        string
+		content     string
+		expected    *Requirements
+		shouldError bool
+	}{
+		{
+			name: "collections and roles",
+			content: `---
+collections:
+  - name: community.kubernetes
+    version: "1.2.1"
+  - operator_sdk.util
+roles:
+  - name: geerlingguy.java
+    version: ">=1.9.0,<2.0.0"
+  - src: https://github.com/example/ansible-role-memcached.git
+`,
+			expected: &Requirements{
+				Collections: []Requirement{
+					{Name: "community.kubernetes", Version: "1.2.1"},
+					{Name: "operator_sdk.util"},
+				},
+				Roles: []Requirement{
+					{Name: "geerlingguy.java", Version: ">=1.9.0,<2.0.0"},
+					{Src: "https://github.com/example/ansible-role-memcached.git"},
+				},
+			},
+		},
+		{
+			name: "list of roles",
+			content: `---
+- src: geerlingguy.java
+  version: 1.9.6
+`,
+			expected: &Requirements{Roles: []Requirement{{Src: "geerlingguy.java", Version: "1.9.6"}}},
+		},
+		{
+			name: "invalid version",
+			content: `---
+collections:
+  - name: community.kubernetes
+    version: "one"
+`,
+			shouldError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "requirements.yml")
+			writeTestFile(t, path, tc.content)
+			reqs, err := Load(path)
+			if tc.shouldError {
+				if err == nil {
+					t.Fatalf("Expected an error, got requirements %#v", reqs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(reqs, tc.expected) {
+				t.Fatalf("Unexpected requirements %#v expected %#v", reqs, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRequirementName(t *testing.T) {
+	testCases := []struct {
+		requirement Requirement
+		expected    string
+	}{
+		{requirement: Requirement{Name: "memcached", Src: "geerlingguy.memcached"}, expected: "memcached"},
+		{requirement: Requirement{Src: "geerlingguy.memcached"}, expected: "geerlingguy.memcached"},
+		{requirement: Requirement{Src: "https://github.com/example/memcached.git"}, expected: "memcached"},
+		{requirement: Requirement{Src: "git@github.com:example/memcached.git"}, expected: "memcached"},
+		{requirement: Requirement{Src: "https://example.com/memcached.tar.gz"}, expected: "memcached"},
+	}
+	for _, tc := range testCases {
+		if name := tc.requirement.name(); name != tc.expected {
+			t.Errorf("Unexpected name %v of %#v expected %v", name, tc.requirement, tc.expected)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	paths := Paths{
+		Collections: []string{filepath.Join(dir, "collections")},
+		Roles:       []string{filepath.Join(dir, "roles")},
+	}
+	writeTestFile(t, filepath.Join(dir, "collections/ansible_collections/community/kubernetes/MANIFEST.json"),
+		collectionManifest("1.2.1"))
+	writeTestFile(t, filepath.Join(dir, "collections/ansible_collections/operator_sdk/util/galaxy.yml"),
+		"namespace: operator_sdk\nname: util\nversion: 0.2.0\n")
+	writeTestFile(t, filepath.Join(dir, "roles/geerlingguy.java", roleInstallInfoPath), "version: 1.9.6\n")
+	writeTestFile(t, filepath.Join(dir, "roles/memcached/tasks/main.yml"), "---\n")
+
+	testCases := []struct {
+		name          string
+		requirements  Requirements
+		expectedError string
+	}{
+		{
+			name: "satisfied",
+			requirements: Requirements{
+				Collections: []Requirement{
+					{Name: "community.kubernetes", Version: "1.2.1"},
+					{Name: "operator_sdk.util", Version: ">=0.1.0,<1.0.0"},
+					{Name: "https://github.com/example/collection.git"},
+				},
+				Roles: []Requirement{
+					{Name: "geerlingguy.java", Version: "==1.9.6"},
+					{Src: "https://github.com/example/memcached.git", Version: "*"},
+				},
+			},
+		},
+		{
+			name: "wrong collection version",
+			requirements: Requirements{
+				Collections: []Requirement{{Name: "community.kubernetes", Version: "1.3.0"}},
+			},
+			expectedError: "collection community.kubernetes in " + paths.collectionDirs("community", "kubernetes")[0] +
+				" is version 1.2.1, 1.3.0 is required",
+		},
+		{
+			name: "missing collection",
+			requirements: Requirements{
+				Collections: []Requirement{{Name: "community.general"}},
+			},
+			expectedError: "collection community.general is not installed",
+		},
+		{
+			name: "role without a version",
+			requirements: Requirements{
+				Roles: []Requirement{{Name: "memcached", Version: "1.0.0"}},
+			},
+			expectedError: "role memcached in " + paths.roleDirs("memcached")[0] + " has no version, 1.0.0 is required",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.requirements.Verify(paths)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Fatalf("Unexpected error %v expected %q", err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestInstall(t *testing.T) {
+	dir := t.TempDir()
+	paths := Paths{
+		Collections: []string{filepath.Join(dir, "collections")},
+		Roles:       []string{filepath.Join(dir, "roles")},
+	}
+	cache := filepath.Join(dir, "cache")
+	if err := os.MkdirAll(cache, 0755); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// An older version of the collection is installed.
+	writeTestFile(t, filepath.Join(dir, "collections/ansible_collections/community/kubernetes/MANIFEST.json"),
+		collectionManifest("1.1.0"))
+	for _, version := range []string{"1.2.0", "1.2.1", "2.0.0"} {
+		writeTestArchive(t, filepath.Join(cache, "community-kubernetes-"+version+".tar.gz"), map[string]string{
+			"MANIFEST.json":          collectionManifest(version),
+			"plugins/modules/k8s.py": "# " + version,
+		})
+	}
+	writeTestArchive(t, filepath.Join(cache, "geerlingguy.java-1.9.6.tar.gz"), map[string]string{
+		"ansible-role-java-1.9.6/tasks/main.yml": "---\n",
+	})
+	writeTestFile(t, filepath.Join(cache, "memcached-0.1.0/tasks/main.yml"), "---\n")
+
+	reqs := &Requirements{
+		Collections: []Requirement{{Name: "community.kubernetes", Version: ">=1.2.0,<2.0.0"}},
+		Roles: []Requirement{
+			{Name: "geerlingguy.java", Version: "1.9.6"},
+			{Src: "https://github.com/example/memcached.git"},
+		},
+	}
+	if err := reqs.Install(paths, cache); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := reqs.Verify(paths); err != nil {
+		t.Fatalf("Unexpected error after installing: %v", err)
+	}
+
+	module, err := ioutil.ReadFile(filepath.Join(dir, "collections/ansible_collections/community/kubernetes/plugins/modules/k8s.py"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(module) != "# 1.2.1" {
+		t.Fatalf("Unexpected collection version installed: %s", module)
+	}
+	for _, path := range []string{"roles/geerlingguy.java/tasks/main.yml", "roles/memcached/tasks/main.yml"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Fatalf("Role was not installed: %v", err)
+		}
+	}
+
+	missing := &Requirements{Collections: []Requirement{{Name: "community.kubernetes", Version: "1.3.0"}}}
+	if err := missing.Install(paths, cache); err == nil {
+		t.Fatalf("Expected an error for a version that is not cached")
+	}
+}
+
+func TestExtractOutside(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "evil.tar.gz")
+	writeTestArchive(t, archive, map[string]string{"../evil": "evil"})
+	if err := extract(archive, filepath.Join(dir, "out")); err == nil {
+		t.Fatalf("Expected an error for an entry outside of the archive")
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifacts
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("artifacts")
+
+const (
+	// LastFailedKey - name of the object, under the prefix of a CR, holding the key
+	// of the archive of the last failed run for that CR.
+	LastFailedKey = "last-failed"
+
+	archiveExt = ".tar.gz"
+	timeFormat = "20060102T150405Z"
+)
+
+// ErrNotFound is returned by a Store when an object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Store - a place to keep run archives. Keys are slash separated paths.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// List returns the keys of all objects directly or indirectly under prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, key string) error
+	// Location returns where users find the object at key, e.g. its s3:// URL.
+	Location(key string) string
+}
+
+// NewStore - creates the Store described by sink, which is either a local path,
+// a file:// URL or an s3://<bucket>/<prefix> URL. The S3 endpoint, region and
+// credentials are taken from opts.
+func NewStore(sink string, opts S3Options) (Store, error) {
+	u, err := url.Parse(sink)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifact sink %q: %v", sink, err)
+	}
+	switch u.Scheme {
+	case "", "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("invalid artifact sink %q: path is required", sink)
+		}
+		return &LocalStore{Root: u.Path}, nil
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid artifact sink %q: bucket is required", sink)
+		}
+		opts.Bucket = u.Host
+		opts.Prefix = strings.Trim(u.Path, "/")
+		return NewS3Store(opts)
+	default:
+		return nil, fmt.Errorf("invalid artifact sink %q: unsupported scheme %q", sink, u.Scheme)
+	}
+}
+
+// Exporter - archives the artifact directories of ansible-runner runs to a Store,
+// keyed by the UID of the CR that was reconciled, and prunes old archives.
+type Exporter struct {
+	Store Store
+	// MaxRuns is the number of archives kept per CR. Zero keeps all of them.
+	MaxRuns int
+	// MaxAge is how long archives are kept. Zero keeps them forever.
+	MaxAge time.Duration
+
+	// now is overridden in tests.
+	now func() time.Time
+}
+
+// Export - archives the artifact directory dir of the run ident for the CR with
+// the given UID, then prunes the archives of that CR. The key of the archive is
+// returned. If the run did not succeed, the archive is also recorded as the last
+// failed run of the CR.
+func (e *Exporter) Export(ctx context.Context, uid, ident, dir string) (string, error) {
+	now := time.Now
+	if e.now != nil {
+		now = e.now
+	}
+	ts := now().UTC()
+
+	data, err := archive(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to archive %s: %v", dir, err)
+	}
+	key := path.Join(uid, fmt.Sprintf("%s-%s%s", ts.Format(timeFormat), ident, archiveExt))
+	if err := e.Store.Put(ctx, key, data); err != nil {
+		return "", fmt.Errorf("failed to upload %s: %v", key, err)
+	}
+
+	// ansible-runner writes "successful", "failed", "timeout" or "canceled".
+	status, err := ioutil.ReadFile(filepath.Join(dir, "status"))
+	if err != nil || strings.TrimSpace(string(status)) != "successful" {
+		if err := e.Store.Put(ctx, path.Join(uid, LastFailedKey), []byte(key)); err != nil {
+			return key, fmt.Errorf("failed to record last failed run: %v", err)
+		}
+	}
+
+	return key, e.prune(ctx, uid, ts)
+}
+
+// LastFailedRun - returns the key of the archive of the last failed run for the
+// CR with the given UID, or ErrNotFound if there is none.
+func (e *Exporter) LastFailedRun(ctx context.Context, uid string) (string, error) {
+	data, err := e.Store.Get(ctx, path.Join(uid, LastFailedKey))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// prune deletes the archives of a CR that are beyond MaxRuns or older than MaxAge.
+// The last failed run is kept while it is pointed to, so pruning never leaves a
+// dangling pointer unless that archive is itself too old.
+func (e *Exporter) prune(ctx context.Context, uid string, now time.Time) error {
+	if e.MaxRuns <= 0 && e.MaxAge <= 0 {
+		return nil
+	}
+	keys, err := e.Store.List(ctx, uid+"/")
+	if err != nil {
+		return err
+	}
+	archives := []string{}
+	for _, k := range keys {
+		if strings.HasSuffix(k, archiveExt) {
+			archives = append(archives, k)
+		}
+	}
+	// Keys start with the run's timestamp, so they sort oldest first.
+	sort.Strings(archives)
+
+	lastFailed, err := e.LastFailedRun(ctx, uid)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	for i, k := range archives {
+		tooMany := e.MaxRuns > 0 && i < len(archives)-e.MaxRuns
+		tooOld := e.MaxAge > 0 && now.Sub(archiveTime(k)) > e.MaxAge
+		if !tooOld && (!tooMany || k == lastFailed) {
+			continue
+		}
+		if err := e.Store.Delete(ctx, k); err != nil {
+			return err
+		}
+		if k == lastFailed {
+			if err := e.Store.Delete(ctx, path.Join(uid, LastFailedKey)); err != nil {
+				return err
+			}
+		}
+		log.V(1).Info("Pruned run archive", "key", k)
+	}
+	return nil
+}
+
+// archiveTime returns the time of the run archived at key, or the zero time if
+// the key is not of an archive.
+func archiveTime(key string) time.Time {
+	name := path.Base(key)
+	i := strings.Index(name, "-")
+	if i < 0 {
+		return time.Time{}
+	}
+	t, err := time.Parse(timeFormat, name[:i])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// archive returns a gzipped tarball of the files in dir.
+func archive(dir string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,215 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifacts
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestNewStore(t *testing.T) {
+	s3Opts := S3Options{AccessKeyID: "id", SecretAccessKey: "secret"}
+	testCases := []struct {
+		name        string
+		sink        string
+		opts        S3Options
+		expected    Store
+		shouldError bool
+	}{
+		{
+			name:     "local path",
+			sink:     "/var/lib/artifacts",
+			expected: &LocalStore{Root: "/var/lib/artifacts"},
+		},
+		{
+			name:     "file URL",
+			sink:     "file:///var/lib/artifacts",
+			expected: &LocalStore{Root: "/var/lib/artifacts"},
+		},
+		{
+			name: "s3 URL",
+			sink: "s3://bucket/some/prefix/",
+			opts: s3Opts,
+		},
+		{
+			name:        "s3 URL without credentials",
+			sink:        "s3://bucket/prefix",
+			shouldError: true,
+		},
+		{
+			name:        "s3 URL without bucket",
+			sink:        "s3:///prefix",
+			opts:        s3Opts,
+			shouldError: true,
+		},
+		{
+			name:        "unsupported scheme",
+			sink:        "gs://bucket",
+			shouldError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store, err := NewStore(tc.sink, tc.opts)
+			if err != nil {
+				if !tc.shouldError {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if tc.shouldError {
+				t.Fatalf("Expected an error")
+			}
+			if s3, ok := store.(*S3Store); ok {
+				if s3.opts.Bucket != "bucket" || s3.opts.Prefix != "some/prefix" {
+					t.Fatalf("Unexpected bucket %q and prefix %q", s3.opts.Bucket, s3.opts.Prefix)
+				}
+				return
+			}
+			if !reflect.DeepEqual(store, tc.expected) {
+				t.Fatalf("Unexpected store %#v expected %#v", store, tc.expected)
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	root, err := ioutil.TempDir("", "artifacts-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// writeRun creates an ansible-runner artifact directory with the given status.
+	writeRun := func(ident, status string) string {
+		dir := filepath.Join(root, "runner", ident)
+		if err := os.MkdirAll(filepath.Join(dir, "job_events"), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		files := map[string]string{
+			"stdout":              "PLAY RECAP",
+			"rc":                  "0",
+			"status":              status,
+			"job_events/1-a.json": "{}",
+		}
+		for name, content := range files {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	e := &Exporter{
+		Store:   &LocalStore{Root: filepath.Join(root, "sink")},
+		MaxRuns: 2,
+		MaxAge:  time.Hour,
+		now:     func() time.Time { return now },
+	}
+	ctx := context.TODO()
+
+	if _, err := e.LastFailedRun(ctx, "uid"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+
+	failed, err := e.Export(ctx, "uid", "1", writeRun("1", "failed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != "uid/20210301T120000Z-1.tar.gz" {
+		t.Fatalf("Unexpected key %s", failed)
+	}
+	if last, err := e.LastFailedRun(ctx, "uid"); err != nil || last != failed {
+		t.Fatalf("Unexpected last failed run %q: %v", last, err)
+	}
+
+	// Successful runs beyond MaxRuns prune the oldest successful archive, but not the last failed one.
+	keys := []string{failed}
+	for _, ident := range []string{"2", "3", "4"} {
+		now = now.Add(time.Minute)
+		key, err := e.Export(ctx, "uid", ident, writeRun(ident, "successful"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+	assertKeys(t, e.Store, append([]string{"uid/last-failed"}, keys[0], keys[2], keys[3]))
+	if last, err := e.LastFailedRun(ctx, "uid"); err != nil || last != failed {
+		t.Fatalf("Unexpected last failed run %q: %v", last, err)
+	}
+
+	// Archives older than MaxAge are pruned, including the last failed one.
+	now = now.Add(2 * time.Hour)
+	key, err := e.Export(ctx, "uid", "5", writeRun("5", "successful"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertKeys(t, e.Store, []string{key})
+	if _, err := e.LastFailedRun(ctx, "uid"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+
+	// The archive holds the artifact directory.
+	data, err := e.Store.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertArchive(t, data, []string{"job_events/", "job_events/1-a.json", "rc", "status", "stdout"})
+}
+
+func assertKeys(t *testing.T, s Store, expected []string) {
+	t.Helper()
+	keys, err := s.List(context.TODO(), "uid/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	sort.Strings(expected)
+	if !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("Unexpected keys %v expected %v", keys, expected)
+	}
+}
+
+func assertArchive(t *testing.T, data []byte, expected []string) {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	names := []string{}
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, hdr.Name)
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("Unexpected archive contents %v expected %v", names, expected)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// LocalStore - a Store keeping objects as files under Root, e.g. on a persistent volume.
+type LocalStore struct {
+	Root string
+}
+
+// Put - writes the object at key, replacing it if it exists.
+func (s *LocalStore) Put(_ context.Context, key string, data []byte) error {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		return err
+	}
+	// Write to a temporary file first so readers never see a partial object.
+	tmp := p + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// Get - reads the object at key.
+func (s *LocalStore) Get(_ context.Context, key string) ([]byte, error) {
+	data, err := ioutil.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// List - returns the keys of the objects under prefix.
+func (s *LocalStore) List(_ context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	err := filepath.Walk(s.path(prefix), func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || filepath.Ext(p) == ".tmp" {
+			return nil
+		}
+		rel, err := filepath.Rel(s.Root, p)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, filepath.ToSlash(rel))
+		return nil
+	})
+	return keys, err
+}
+
+// Delete - removes the object at key. Deleting an object that does not exist is not an error.
+func (s *LocalStore) Delete(_ context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Location - returns the path of the file of the object at key.
+func (s *LocalStore) Location(key string) string {
+	return s.path(key)
+}
+
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.Root, filepath.FromSlash(key))
+}
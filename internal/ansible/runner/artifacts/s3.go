@@ -0,0 +1,164 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// AccessKeyIDEnvVar and SecretAccessKeyEnvVar are the environment variables the
+	// S3 credentials are read from, as with the AWS CLI.
+	AccessKeyIDEnvVar     = "AWS_ACCESS_KEY_ID"
+	SecretAccessKeyEnvVar = "AWS_SECRET_ACCESS_KEY"
+
+	defaultS3Region = "us-east-1"
+)
+
+// S3Options - configure an S3Store.
+type S3Options struct {
+	// Endpoint is the URL of an S3-compatible API. Defaults to AWS S3.
+	Endpoint string
+	// Region is used to sign requests. Defaults to us-east-1.
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Client          *http.Client
+}
+
+// S3Store - a Store keeping objects in a bucket of an S3-compatible API. Requests
+// to a custom Endpoint are path-style.
+type S3Store struct {
+	opts   S3Options
+	client *s3.Client
+}
+
+// NewS3Store - creates an S3Store, defaulting unset options.
+func NewS3Store(opts S3Options) (*S3Store, error) {
+	if opts.Region == "" {
+		opts.Region = defaultS3Region
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: time.Minute}
+	}
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	if opts.AccessKeyID == "" || opts.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 credentials are required, set %s and %s", AccessKeyIDEnvVar, SecretAccessKeyEnvVar)
+	}
+	s3Opts := s3.Options{
+		Region:      opts.Region,
+		Credentials: credentials.NewStaticCredentialsProvider(opts.AccessKeyID, opts.SecretAccessKey, ""),
+		HTTPClient:  opts.Client,
+	}
+	if opts.Endpoint != "" {
+		s3Opts.EndpointResolver = s3.EndpointResolverFromURL(opts.Endpoint)
+		s3Opts.UsePathStyle = true
+	}
+	return &S3Store{opts: opts, client: s3.New(s3Opts)}, nil
+}
+
+// Put - uploads the object at key.
+func (s *S3Store) Put(ctx context.Context, key string, data []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.opts.Bucket),
+		Key:    aws.String(s.fullKey(key)),
+		Body:   bytes.NewReader(data),
+	})
+	return s.wrap("put", key, err)
+}
+
+// Get - downloads the object at key.
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.opts.Bucket),
+		Key:    aws.String(s.fullKey(key)),
+	})
+	if err != nil {
+		return nil, s.wrap("get", key, err)
+	}
+	defer out.Body.Close()
+	return ioutil.ReadAll(out.Body)
+}
+
+// Delete - removes the object at key. Deleting a missing object is not an error.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.opts.Bucket),
+		Key:    aws.String(s.fullKey(key)),
+	})
+	if err = s.wrap("delete", key, err); errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// List - returns the keys of the objects under prefix, using ListObjectsV2.
+func (s *S3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.opts.Bucket),
+		Prefix: aws.String(s.fullKey(prefix)),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, s.wrap("list", prefix, err)
+		}
+		for _, c := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(strings.TrimPrefix(aws.ToString(c.Key), s.opts.Prefix), "/"))
+		}
+	}
+	return keys, nil
+}
+
+// Location - returns the s3://<bucket>/<key> URL of the object at key.
+func (s *S3Store) Location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", s.opts.Bucket, s.fullKey(key))
+}
+
+func (s *S3Store) fullKey(key string) string {
+	if s.opts.Prefix == "" {
+		return key
+	}
+	return s.opts.Prefix + "/" + key
+}
+
+// wrap returns ErrNotFound for a 404 response and adds the operation to any other error.
+func (s *S3Store) wrap(op, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return fmt.Errorf("S3 %s %s: %w", op, key, err)
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifacts
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestS3Errors(t *testing.T) {
+	status := http.StatusForbidden
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+	}))
+	defer server.Close()
+
+	s, err := NewS3Store(S3Options{
+		Endpoint:        server.URL,
+		Bucket:          "bucket",
+		AccessKeyID:     "id",
+		SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.TODO()
+
+	err = s.Put(ctx, "uid/1.tar.gz", []byte("data"))
+	if err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Fatalf("Expected an AccessDenied error, got %v", err)
+	}
+	if _, err := s.List(ctx, "uid/"); err == nil {
+		t.Fatal("Expected an error listing objects")
+	}
+
+	status = http.StatusNotFound
+	if _, err := s.Get(ctx, "uid/1.tar.gz"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if err := s.Delete(ctx, "uid/1.tar.gz"); err != nil {
+		t.Fatalf("Expected deleting a missing object to succeed, got %v", err)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	// A minimal in-memory S3 serving the bucket "bucket".
+	mu := sync.Mutex{}
+	objects := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=id/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path == "/bucket" {
+			result := struct {
+				XMLName  xml.Name `xml:"ListBucketResult"`
+				Contents []struct{ Key string }
+			}{}
+			for k := range objects {
+				if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+					result.Contents = append(result.Contents, struct{ Key string }{k})
+				}
+			}
+			_ = xml.NewEncoder(w).Encode(result)
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := ioutil.ReadAll(r.Body)
+			objects[key] = data
+		case http.MethodGet:
+			data, ok := objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		case http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewS3Store(S3Options{
+		Endpoint:        server.URL,
+		Bucket:          "bucket",
+		Prefix:          "operator",
+		AccessKeyID:     "id",
+		SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.TODO()
+
+	for _, k := range []string{"uid/1.tar.gz", "uid/last-failed", "other/1.tar.gz"} {
+		if err := s.Put(ctx, k, []byte(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := objects["operator/uid/1.tar.gz"]; !ok {
+		t.Fatalf("Object was not put under the prefix: %v", objects)
+	}
+	if location := s.Location("uid/1.tar.gz"); location != "s3://bucket/operator/uid/1.tar.gz" {
+		t.Fatalf("Unexpected location %q", location)
+	}
+
+	keys, err := s.List(ctx, "uid/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if expected := []string{"uid/1.tar.gz", "uid/last-failed"}; !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("Unexpected keys %v expected %v", keys, expected)
+	}
+
+	if data, err := s.Get(ctx, "uid/last-failed"); err != nil || string(data) != "uid/last-failed" {
+		t.Fatalf("Unexpected object %q: %v", data, err)
+	}
+	if err := s.Delete(ctx, "uid/last-failed"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "uid/last-failed"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+}
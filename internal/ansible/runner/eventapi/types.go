@@ -95,6 +95,8 @@ type StatsEventData struct {
 	Ok           map[string]int `json:"ok"`
 	Failures     map[string]int `json:"failures"`
 	Skipped      map[string]int `json:"skipped"`
+	// ArtifactData holds the data set by the set_stats module.
+	ArtifactData map[string]interface{} `json:"artifact_data"`
 }
 
 // FailureMessages - failure messages from the event api
@@ -136,3 +138,41 @@ func (je JobEvent) Rescued() bool {
 	}
 	return false
 }
+
+// Changed - Does the job event report that the task changed (or, in check
+// mode, would change) something
+func (je JobEvent) Changed() bool {
+	result, ok := je.EventData["res"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	changed, ok := result["changed"].(bool)
+	return ok && changed
+}
+
+// GetChangedTaskMessage - get a message describing the change made by a task,
+// including the object it acted on when the task result contains one
+func (je JobEvent) GetChangedTaskMessage() string {
+	message, ok := je.EventData["task"].(string)
+	if !ok || message == "" {
+		message = "unnamed task"
+	}
+	result, ok := je.EventData["res"].(map[string]interface{})
+	if !ok {
+		return message
+	}
+	obj, ok := result["result"].(map[string]interface{})
+	if !ok {
+		return message
+	}
+	kind, _ := obj["kind"].(string)
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	if kind == "" || name == "" {
+		return message
+	}
+	if namespace, _ := metadata["namespace"].(string); namespace != "" {
+		name = fmt.Sprintf("%s/%s", namespace, name)
+	}
+	return fmt.Sprintf("%s: %s %s", message, kind, name)
+}
@@ -37,11 +37,14 @@ type Runner struct {
 	JobEvents []eventapi.JobEvent
 	//Stdout standard out to reply if failure occurs.
 	Stdout string
+	// Artifacts is the location the artifacts of runs are exported to.
+	Artifacts string
 }
 
 type runResult struct {
-	events <-chan eventapi.JobEvent
-	stdout string
+	events    <-chan eventapi.JobEvent
+	stdout    string
+	artifacts string
 }
 
 func (r *runResult) Events() <-chan eventapi.JobEvent {
@@ -55,8 +58,12 @@ func (r *runResult) Stdout() (string, error) {
 	return r.stdout, fmt.Errorf("unable to find standard out")
 }
 
+func (r *runResult) Artifacts() string {
+	return r.artifacts
+}
+
 // Run - runs the fake runner.
-func (r *Runner) Run(_ string, u *unstructured.Unstructured, _ string) (runner.RunResult, error) {
+func (r *Runner) Run(_ string, u *unstructured.Unstructured, _ string, _ ...runner.RunOption) (runner.RunResult, error) {
 	if r.Error != nil {
 		return nil, r.Error
 	}
@@ -67,7 +74,7 @@ func (r *Runner) Run(_ string, u *unstructured.Unstructured, _ string) (runner.R
 		}
 		close(c)
 	}()
-	return &runResult{events: c, stdout: r.Stdout}, nil
+	return &runResult{events: c, stdout: r.Stdout, artifacts: r.Artifacts}, nil
 }
 
 // GetReconcilePeriod - new reconcile period.
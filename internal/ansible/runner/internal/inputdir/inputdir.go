@@ -29,14 +29,41 @@ import (
 
 var log = logf.Log.WithName("inputdir")
 
+// secretParametersFile is the path, relative to the input directory, that
+// SecretParameters are written to for the duration of a run.
+const secretParametersFile = "env/secretvars"
+
 // InputDir represents an input directory for ansible-runner.
 type InputDir struct {
 	Path         string
 	PlaybookPath string
 	Parameters   map[string]interface{}
-	EnvVars      map[string]string
-	Settings     map[string]string
-	CmdLine      string
+	// SecretParameters are passed as extra vars like Parameters, but are written to
+	// their own file, which should be removed with RemoveSecretParameters once the
+	// run completes.
+	SecretParameters map[string]interface{}
+	EnvVars          map[string]string
+	Settings         map[string]string
+	CmdLine          string
+}
+
+// SecretParametersOption returns the ansible option that loads the secret parameters
+// written to the input directory at path, or "" if none were written.
+func SecretParametersOption(path string) string {
+	fullPath := filepath.Join(path, secretParametersFile)
+	if _, err := os.Stat(fullPath); err != nil {
+		return ""
+	}
+	return "-e @" + fullPath
+}
+
+// RemoveSecretParameters removes the secret parameters from the filesystem.
+func (i *InputDir) RemoveSecretParameters() error {
+	err := os.Remove(filepath.Join(i.Path, secretParametersFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
 }
 
 // makeDirs creates the required directory structure.
@@ -137,12 +164,32 @@ func (i *InputDir) Write() error {
 		i.CmdLine = i.CmdLine[1 : len(i.CmdLine)-1]
 	}
 
+	// Secret parameters are readable only by the operator, and are never left in
+	// the input directory of a previous run.
+	if len(i.SecretParameters) > 0 {
+		secretParamBytes, err := json.Marshal(i.SecretParameters)
+		if err != nil {
+			return err
+		}
+		fullPath := filepath.Join(i.Path, secretParametersFile)
+		if err = ioutil.WriteFile(fullPath, secretParamBytes, 0600); err != nil {
+			log.Error(err, "Unable to write file", "Path", fullPath)
+			return err
+		}
+		i.CmdLine = strings.TrimSpace(i.CmdLine + " " + SecretParametersOption(i.Path))
+	} else if err = i.RemoveSecretParameters(); err != nil {
+		return err
+	}
+
 	cmdLineBytes := []byte(i.CmdLine)
 	if len(cmdLineBytes) > 0 {
 		err = i.addFile("env/cmdline", cmdLineBytes)
 		if err != nil {
 			return err
 		}
+	} else if err = os.Remove(filepath.Join(i.Path, "env/cmdline")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		// A command line left by a previous run may refer to its secret parameters.
+		return err
 	}
 
 	// ANSIBLE_INVENTORY takes precedence over our generated hosts file
@@ -15,6 +15,8 @@
 package runner
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -24,12 +26,16 @@ import (
 	"strconv"
 	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/operator-framework/operator-sdk/internal/ansible/metrics"
 	"github.com/operator-framework/operator-sdk/internal/ansible/paramconv"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/artifacts"
 	"github.com/operator-framework/operator-sdk/internal/ansible/runner/eventapi"
 	"github.com/operator-framework/operator-sdk/internal/ansible/runner/internal/inputdir"
 	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
@@ -50,15 +56,76 @@ const (
 	AnsibleVerbosityAnnotation = "ansible.sdk.operatorframework.io/verbosity"
 
 	ansibleRunnerBin = "ansible-runner"
+
+	// reconcileRunDir and webhookRunDir are the parent directories of the ansible-runner
+	// input directories for reconciles and admission webhooks respectively
+	reconcileRunDir = "/tmp/ansible-operator/runner/"
+	webhookRunDir   = "/tmp/ansible-operator/webhook/"
 )
 
 // Runner - a runnable that should take the parameters and name and namespace
 // and run the correct code.
 type Runner interface {
-	Run(string, *unstructured.Unstructured, string) (RunResult, error)
+	Run(string, *unstructured.Unstructured, string, ...RunOption) (RunResult, error)
 	GetFinalizer() (string, bool)
 }
 
+// RunOption - modifies how a single call to Run invokes ansible-runner.
+type RunOption func(*runOptions)
+
+type runOptions struct {
+	checkMode bool
+	extraVars map[string]interface{}
+	event     string
+	ctx       context.Context
+}
+
+// WithCheckMode - runs the playbook or role with ansible's --check --diff
+// options, so that changes are only predicted and never applied.
+func WithCheckMode() RunOption {
+	return func(o *runOptions) {
+		o.checkMode = true
+	}
+}
+
+// WithExtraVars - passes vars to the playbook or role in addition to the
+// parameters built from the CR. They take precedence over parameters of the same name.
+func WithExtraVars(vars map[string]interface{}) RunOption {
+	return func(o *runOptions) {
+		if o.extraVars == nil {
+			o.extraVars = map[string]interface{}{}
+		}
+		for k, v := range vars {
+			o.extraVars[k] = v
+		}
+	}
+}
+
+// WithEvent - sets the event type of the reconcile, e.g. watches.EventUpdate, which
+// selects the route of the Watch whose playbook or role is run.
+func WithEvent(event string) RunOption {
+	return func(o *runOptions) {
+		o.event = event
+	}
+}
+
+// WithContext - stops the run by killing ansible-runner once ctx is done, e.g. when
+// its deadline passes.
+func WithContext(ctx context.Context) RunOption {
+	return func(o *runOptions) {
+		o.ctx = ctx
+	}
+}
+
+// getRunOptions returns the runOptions resulting from applying opts.
+func getRunOptions(opts ...RunOption) runOptions {
+	o := runOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
 // ansibleVerbosityString will return the string with the -v* levels
 func ansibleVerbosityString(verbosity int) string {
 	if verbosity > 0 {
@@ -119,8 +186,54 @@ func roleCmdFunc(path string) cmdFuncType {
 	}
 }
 
-// New - creates a Runner from a Watch struct
-func New(watch watches.Watch, runnerArgs string) (Runner, error) {
+// checkModeCmdFunc wraps cmdFunc so that ansible is run with --check --diff.
+// ansible-runner's --cmdline takes precedence over env/cmdline, so the
+// user provided ansible args are passed along with the check mode options.
+func checkModeCmdFunc(cmdFunc cmdFuncType, ansibleArgs string) cmdFuncType {
+	cmdLine := strings.TrimSpace(strings.Trim(ansibleArgs, "'") + " --check --diff")
+	return func(ident, inputDirPath string, maxArtifacts, verbosity int) *exec.Cmd {
+		dc := cmdFunc(ident, inputDirPath, maxArtifacts, verbosity)
+		dc.Args = append(dc.Args, "--cmdline",
+			strings.TrimSpace(cmdLine+" "+inputdir.SecretParametersOption(inputDirPath)))
+		return dc
+	}
+}
+
+// New - creates a Runner from a Watch struct. If exporter is not nil, the artifacts
+// of each run are exported with it once the run completes. reader is used to read
+// the Secrets and ConfigMaps that the Watch passes as vars.
+func New(watch watches.Watch, runnerArgs string, exporter *artifacts.Exporter, reader client.Reader) (Runner, error) {
+	r, err := newRunner(watch, runnerArgs)
+	if err != nil {
+		return nil, err
+	}
+	r.exporter = exporter
+	r.reader = reader
+	return r, nil
+}
+
+// NewWebhook - creates a Runner for an admission webhook of a Watch. The webhook's
+// playbook or role and vars are run in place of the Watch's, and no finalizer is set.
+// name distinguishes the input directories of the webhooks of a GVK, e.g. "validate".
+func NewWebhook(watch watches.Watch, webhook watches.Webhook, name, runnerArgs string) (Runner, error) {
+	watch.Playbook = webhook.Playbook
+	watch.Role = webhook.Role
+	watch.Vars = webhook.Vars
+	watch.Finalizer = nil
+	watch.Routes = nil
+	watch.SecretVars = nil
+	watch.ConfigMapVars = nil
+	r, err := newRunner(watch, runnerArgs)
+	if err != nil {
+		return nil, err
+	}
+	r.runDir = filepath.Join(webhookRunDir, name)
+	// Admission requests for the same object, or without a name, may be handled concurrently.
+	r.runInputDirs = true
+	return r, nil
+}
+
+func newRunner(watch watches.Watch, runnerArgs string) (*runner, error) {
 	var path string
 	var cmdFunc, finalizerCmdFunc cmdFuncType
 
@@ -151,10 +264,28 @@ func New(watch watches.Watch, runnerArgs string) (Runner, error) {
 		finalizerCmdFunc = cmdFunc
 	}
 
+	routes := []route{}
+	for _, rt := range watch.Routes {
+		rc := route{Route: rt}
+		if rt.Playbook != "" {
+			rc.path = rt.Playbook
+			rc.cmdFunc = playbookCmdFunc(rt.Playbook)
+		} else {
+			rc.path = rt.Role
+			rc.cmdFunc = roleCmdFunc(rt.Role)
+		}
+		rc.checkModeCmdFunc = checkModeCmdFunc(rc.cmdFunc, runnerArgs)
+		routes = append(routes, rc)
+	}
+
 	return &runner{
 		Path:                path,
 		cmdFunc:             cmdFunc,
+		routes:              routes,
+		checkModeCmdFunc:    checkModeCmdFunc(cmdFunc, runnerArgs),
 		Vars:                watch.Vars,
+		SecretVars:          watch.SecretVars,
+		ConfigMapVars:       watch.ConfigMapVars,
 		Finalizer:           watch.Finalizer,
 		finalizerCmdFunc:    finalizerCmdFunc,
 		GVK:                 watch.GroupVersionKind,
@@ -163,29 +294,48 @@ func New(watch watches.Watch, runnerArgs string) (Runner, error) {
 		ansibleArgs:         runnerArgs,
 		snakeCaseParameters: watch.SnakeCaseParameters,
 		markUnsafe:          watch.MarkUnsafe,
+		runDir:              reconcileRunDir,
 	}, nil
 }
 
+// route - a watches.Route and the commands that run its playbook or role.
+type route struct {
+	watches.Route
+	path             string
+	cmdFunc          cmdFuncType
+	checkModeCmdFunc cmdFuncType
+}
+
 // runner - implements the Runner interface for a GVK that's being watched.
 type runner struct {
 	Path                string                  // path on disk to a playbook or role depending on what cmdFunc expects
 	GVK                 schema.GroupVersionKind // GVK being watched that corresponds to the Path
 	Finalizer           *watches.Finalizer
 	Vars                map[string]interface{}
+	SecretVars          []watches.VarsSource
+	ConfigMapVars       []watches.VarsSource
 	cmdFunc             cmdFuncType // returns a Cmd that runs ansible-runner
+	checkModeCmdFunc    cmdFuncType // returns a Cmd that runs ansible-runner in check mode
 	finalizerCmdFunc    cmdFuncType
+	routes              []route
 	maxRunnerArtifacts  int
 	ansibleVerbosity    int
 	snakeCaseParameters bool
 	markUnsafe          bool
 	ansibleArgs         string
+	runDir              string // parent directory of the ansible-runner input directories
+	runInputDirs        bool   // each run has its own input directory, removed once it completes
+	exporter            *artifacts.Exporter
+	reader              client.Reader // reads the Secrets and ConfigMaps of SecretVars and ConfigMapVars
 }
 
-func (r *runner) Run(ident string, u *unstructured.Unstructured, kubeconfig string) (RunResult, error) {
+func (r *runner) Run(ident string, u *unstructured.Unstructured, kubeconfig string, opts ...RunOption) (RunResult, error) {
 	if _, err := exec.LookPath(ansibleRunnerBin); err != nil {
 		return nil, err
 	}
 
+	runOpts := getRunOptions(opts...)
+
 	timer := metrics.ReconcileTimer(r.GVK.String())
 	defer timer.ObserveDuration()
 
@@ -198,6 +348,20 @@ func (r *runner) Run(ident string, u *unstructured.Unstructured, kubeconfig stri
 		"namespace", u.GetNamespace(),
 	)
 
+	sourceParams, secretParams, err := r.makeSourceParameters(context.TODO(), u)
+	if err != nil {
+		return nil, err
+	}
+	path, cmdFunc, checkModeCmdFunc := r.Path, r.cmdFunc, r.checkModeCmdFunc
+	rt, err := r.matchRoute(u, runOpts.event)
+	if err != nil {
+		return nil, err
+	}
+	if rt != nil {
+		logger.V(1).Info("Running the playbook or role of a route", "event", runOpts.event, "path", rt.path)
+		path, cmdFunc, checkModeCmdFunc = rt.path, rt.cmdFunc, rt.checkModeCmdFunc
+	}
+
 	// start the event receiver. We'll check errChan for an error after
 	// ansible-runner exits.
 	errChan := make(chan error, 1)
@@ -206,27 +370,40 @@ func (r *runner) Run(ident string, u *unstructured.Unstructured, kubeconfig stri
 		return nil, err
 	}
 	inputDir := inputdir.InputDir{
-		Path: filepath.Join("/tmp/ansible-operator/runner/", r.GVK.Group, r.GVK.Version, r.GVK.Kind,
-			u.GetNamespace(), u.GetName()),
+		Path:       r.inputDirPath(ident, u),
 		Parameters: r.makeParameters(u),
-		EnvVars: map[string]string{
-			"K8S_AUTH_KUBECONFIG": kubeconfig,
-			"KUBECONFIG":          kubeconfig,
-		},
+		EnvVars:    map[string]string{},
 		Settings: map[string]string{
 			"runner_http_url":  receiver.SocketPath,
 			"runner_http_path": receiver.URLPath,
 		},
 		CmdLine: r.ansibleArgs,
 	}
+	// Without a kubeconfig, the k8s modules fall back to the operator's own credentials.
+	if kubeconfig != "" {
+		inputDir.EnvVars["K8S_AUTH_KUBECONFIG"] = kubeconfig
+		inputDir.EnvVars["KUBECONFIG"] = kubeconfig
+	}
+	for k, v := range sourceParams {
+		inputDir.Parameters[k] = v
+	}
+	if len(secretParams) > 0 {
+		inputDir.SecretParameters = secretParams
+		for k := range secretParams {
+			delete(inputDir.Parameters, k)
+		}
+	}
+	for k, v := range runOpts.extraVars {
+		inputDir.Parameters[k] = v
+	}
 	// If Path is a dir, assume it is a role path. Otherwise assume it's a
 	// playbook path
-	fi, err := os.Lstat(r.Path)
+	fi, err := os.Lstat(path)
 	if err != nil {
 		return nil, err
 	}
 	if !fi.IsDir() {
-		inputDir.PlaybookPath = r.Path
+		inputDir.PlaybookPath = path
 	}
 	err = inputDir.Write()
 	if err != nil {
@@ -252,58 +429,146 @@ func (r *runner) Run(ident string, u *unstructured.Unstructured, kubeconfig stri
 		}
 	}
 
+	result := &runResult{
+		events:   receiver.Events,
+		inputDir: &inputDir,
+		ident:    ident,
+	}
 	go func() {
+		if r.runInputDirs {
+			defer func() {
+				if err := os.RemoveAll(inputDir.Path); err != nil {
+					logger.Error(err, "Error removing the input directory of the run")
+				}
+			}()
+		}
 		var dc *exec.Cmd
-		if r.isFinalizerRun(u) {
+		if r.isFinalizerRun(u) && rt == nil {
 			logger.V(1).Info("Resource is marked for deletion, running finalizer",
 				"Finalizer", r.Finalizer.Name)
 			dc = r.finalizerCmdFunc(ident, inputDir.Path, maxArtifacts, verbosity)
+		} else if runOpts.checkMode {
+			logger.V(1).Info("Running in check mode, changes will not be applied")
+			dc = checkModeCmdFunc(ident, inputDir.Path, maxArtifacts, verbosity)
 		} else {
-			dc = r.cmdFunc(ident, inputDir.Path, maxArtifacts, verbosity)
+			dc = cmdFunc(ident, inputDir.Path, maxArtifacts, verbosity)
 		}
 		// Append current environment since setting dc.Env to anything other than nil overwrites current env
 		dc.Env = append(dc.Env, os.Environ()...)
-		dc.Env = append(dc.Env, fmt.Sprintf("K8S_AUTH_KUBECONFIG=%s", kubeconfig),
-			fmt.Sprintf("KUBECONFIG=%s", kubeconfig))
+		if kubeconfig != "" {
+			dc.Env = append(dc.Env, fmt.Sprintf("K8S_AUTH_KUBECONFIG=%s", kubeconfig),
+				fmt.Sprintf("KUBECONFIG=%s", kubeconfig))
+		}
 
-		output, err := dc.CombinedOutput()
+		output, err := combinedOutput(runOpts.ctx, dc)
 		if err != nil {
 			logger.Error(err, string(output))
 		} else {
 			logger.Info("Ansible-runner exited successfully")
 		}
+		if err := inputDir.RemoveSecretParameters(); err != nil {
+			logger.Error(err, "Error removing secret vars")
+		}
 
-		receiver.Close()
-		err = <-errChan
-		// http.Server returns this in the case of being closed cleanly
-		if err != nil && err != http.ErrServerClosed {
-			logger.Error(err, "Error from event API")
+		if r.runInputDirs {
+			// The input directory is removed once the run completes, so its stdout is read
+			// before the events are closed.
+			stdout, err := inputDir.Stdout(ident)
+			result.stdout, result.stdoutErr = &stdout, err
 		}
 
 		// link the current run to the `latest` directory under artifacts
 		currentRun := filepath.Join(inputDir.Path, "artifacts", ident)
 		latestArtifacts := filepath.Join(inputDir.Path, "artifacts", "latest")
-		if _, err = os.Lstat(latestArtifacts); err != nil {
-			if !errors.Is(err, os.ErrNotExist) {
-				logger.Error(err, "Latest artifacts dir has error")
-				return
+		if err = linkLatestArtifacts(currentRun, latestArtifacts); err != nil {
+			logger.Error(err, "Error symlinking latest artifacts")
+		}
+
+		// The artifacts of the run are exported even if the symlink could not be updated,
+		// and before the events are closed, so that their location is known once they are.
+		if r.exporter != nil {
+			key, err := r.exporter.Export(context.TODO(), string(u.GetUID()), ident, currentRun)
+			if err != nil {
+				logger.Error(err, "Error exporting artifacts")
+			} else {
+				logger.V(1).Info("Exported artifacts", "key", key)
+				result.artifacts = r.exporter.Store.Location(key)
 			}
-		} else if err = os.Remove(latestArtifacts); err != nil {
-			logger.Error(err, "Error removing the latest artifacts symlink")
-			return
 		}
 
-		if err = os.Symlink(currentRun, latestArtifacts); err != nil {
-			logger.Error(err, "Error symlinking latest artifacts")
+		receiver.Close()
+		err = <-errChan
+		// http.Server returns this in the case of being closed cleanly
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error(err, "Error from event API")
 		}
+	}()
 
+	return result, nil
+}
+
+// combinedOutput runs dc like its CombinedOutput method, but kills it once ctx, if
+// not nil, is done.
+func combinedOutput(ctx context.Context, dc *exec.Cmd) ([]byte, error) {
+	if ctx == nil {
+		return dc.CombinedOutput()
+	}
+	var output bytes.Buffer
+	dc.Stdout, dc.Stderr = &output, &output
+	if err := dc.Start(); err != nil {
+		return nil, err
+	}
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = dc.Process.Kill()
+		case <-exited:
+		}
 	}()
+	if err := dc.Wait(); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("%v: %w", err, ctx.Err())
+		}
+		return output.Bytes(), err
+	}
+	return output.Bytes(), nil
+}
 
-	return &runResult{
-		events:   receiver.Events,
-		inputDir: &inputDir,
-		ident:    ident,
-	}, nil
+// linkLatestArtifacts points the latest symlink at the artifacts of the current run,
+// replacing the link of a previous run.
+func linkLatestArtifacts(currentRun, latestArtifacts string) error {
+	if _, err := os.Lstat(latestArtifacts); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("latest artifacts dir has error: %w", err)
+		}
+	} else if err := os.Remove(latestArtifacts); err != nil {
+		return fmt.Errorf("error removing the latest artifacts symlink: %w", err)
+	}
+	return os.Symlink(currentRun, latestArtifacts)
+}
+
+// inputDirPath returns the ansible-runner input directory of the run ident of u.
+func (r *runner) inputDirPath(ident string, u *unstructured.Unstructured) string {
+	if r.runInputDirs {
+		return filepath.Join(r.runDir, r.GVK.Group, r.GVK.Version, r.GVK.Kind, ident)
+	}
+	return filepath.Join(r.runDir, r.GVK.Group, r.GVK.Version, r.GVK.Kind, u.GetNamespace(), u.GetName())
+}
+
+// matchRoute returns the first route that matches the reconcile of u for event, or nil if none does.
+func (r *runner) matchRoute(u *unstructured.Unstructured, event string) (*route, error) {
+	for i := range r.routes {
+		ok, err := r.routes[i].Matches(event, u.Object)
+		if err != nil {
+			return nil, fmt.Errorf("unable to evaluate the jsonPath of route %d: %w", i, err)
+		}
+		if ok {
+			return &r.routes[i], nil
+		}
+	}
+	return nil, nil
 }
 
 func (r *runner) isFinalizerRun(u *unstructured.Unstructured) bool {
@@ -379,6 +644,109 @@ func (r *runner) makeParameters(u *unstructured.Unstructured) map[string]interfa
 	return parameters
 }
 
+// makeSourceParameters - reads the Secrets and ConfigMaps that the watch references for
+// u, and returns their data as parameters. The parameters read from Secrets are always
+// marked unsafe, and are returned separately so that they are not left on disk.
+func (r *runner) makeSourceParameters(ctx context.Context, u *unstructured.Unstructured) (
+	map[string]interface{}, map[string]interface{}, error) {
+	if len(r.SecretVars) == 0 && len(r.ConfigMapVars) == 0 {
+		return nil, nil, nil
+	}
+	if r.reader == nil {
+		return nil, nil, errors.New("unable to read secretVars and configMapVars without a client")
+	}
+
+	parameters := map[string]interface{}{}
+	for _, vs := range r.ConfigMapVars {
+		cm := &corev1.ConfigMap{}
+		found, err := r.getVarsSource(ctx, vs, u, cm)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !found {
+			continue
+		}
+		if err := addSourceVars(parameters, vs, cm.Data); err != nil {
+			return nil, nil, fmt.Errorf("config map %s/%s: %w", cm.Namespace, cm.Name, err)
+		}
+	}
+	if r.markUnsafe {
+		for key, val := range parameters {
+			parameters[key] = markUnsafe(val)
+		}
+	}
+
+	secretParameters := map[string]interface{}{}
+	for _, vs := range r.SecretVars {
+		secret := &corev1.Secret{}
+		found, err := r.getVarsSource(ctx, vs, u, secret)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !found {
+			continue
+		}
+		data := map[string]string{}
+		for k, v := range secret.Data {
+			data[k] = string(v)
+		}
+		if err := addSourceVars(secretParameters, vs, data); err != nil {
+			return nil, nil, fmt.Errorf("secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+	}
+	for key, val := range secretParameters {
+		secretParameters[key] = markUnsafe(val)
+	}
+	return parameters, secretParameters, nil
+}
+
+// getVarsSource reads the object that vs references for u into obj. false is
+// returned if an optional object does not exist.
+func (r *runner) getVarsSource(ctx context.Context, vs watches.VarsSource, u *unstructured.Unstructured,
+	obj client.Object) (bool, error) {
+	key, err := vs.ObjectKey(u.GetName(), u.GetNamespace())
+	if err != nil {
+		return false, err
+	}
+	if err := r.reader.Get(ctx, key, obj); err != nil {
+		if apierrors.IsNotFound(err) && vs.Optional {
+			return false, nil
+		}
+		return false, fmt.Errorf("unable to read vars from %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// addSourceVars adds the vars that vs selects from data to parameters.
+func addSourceVars(parameters map[string]interface{}, vs watches.VarsSource, data map[string]string) error {
+	switch {
+	case vs.Key != "":
+		value, ok := data[vs.Key]
+		if !ok {
+			if vs.Optional {
+				return nil
+			}
+			return fmt.Errorf("key %q not found", vs.Key)
+		}
+		name := vs.Var
+		if name == "" {
+			name = vs.Key
+		}
+		parameters[name] = value
+	case vs.Var != "":
+		values := map[string]interface{}{}
+		for k, v := range data {
+			values[k] = v
+		}
+		parameters[vs.Var] = values
+	default:
+		for k, v := range data {
+			parameters[escapeAnsibleKey(k)] = v
+		}
+	}
+	return nil
+}
+
 // markUnsafe recursively checks for string values and marks them unsafe.
 // for eg:
 //		spec:
@@ -430,6 +798,9 @@ type RunResult interface {
 	Stdout() (string, error)
 	// Events returns the events from ansible-runner if it is available, else an error.
 	Events() <-chan eventapi.JobEvent
+	// Artifacts returns where the artifacts of the run were exported to, empty if they were
+	// not. It is known once the events are closed.
+	Artifacts() string
 }
 
 // RunResult facilitates access to information about a run of ansible.
@@ -440,10 +811,18 @@ type runResult struct {
 
 	ident    string
 	inputDir *inputdir.InputDir
+	// stdout and stdoutErr are set when stdout is read before the input directory is removed.
+	stdout    *string
+	stdoutErr error
+	// artifacts is the location of the exported artifacts, set before events is closed.
+	artifacts string
 }
 
 // Stdout returns the stdout from ansible-runner if it is available, else an error.
 func (r *runResult) Stdout() (string, error) {
+	if r.stdout != nil {
+		return *r.stdout, r.stdoutErr
+	}
 	return r.inputDir.Stdout(r.ident)
 }
 
@@ -451,3 +830,8 @@ func (r *runResult) Stdout() (string, error) {
 func (r *runResult) Events() <-chan eventapi.JobEvent {
 	return r.events
 }
+
+// Artifacts returns where the artifacts of the run were exported to, empty if they were not.
+func (r *runResult) Artifacts() string {
+	return r.artifacts
+}
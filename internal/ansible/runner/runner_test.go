@@ -15,14 +15,20 @@
 package runner
 
 import (
+	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
 )
@@ -159,7 +165,7 @@ func TestNew(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			testWatch := watches.New(tc.gvk, tc.role, tc.playbook, tc.vars, tc.finalizer)
 
-			testRunner, err := New(*testWatch, "")
+			testRunner, err := New(*testWatch, "", nil, nil)
 			if err != nil {
 				t.Fatalf("Error occurred unexpectedly: %v", err)
 			}
@@ -225,6 +231,224 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestCheckModeCmdFunc(t *testing.T) {
+	testCases := []struct {
+		name            string
+		ansibleArgs     string
+		expectedCmdLine string
+	}{
+		{
+			name:            "without ansible args",
+			expectedCmdLine: "--check --diff",
+		},
+		{
+			name:            "with ansible args",
+			ansibleArgs:     "--skip-tags=slow",
+			expectedCmdLine: "--skip-tags=slow --check --diff",
+		},
+		{
+			name:            "with quoted ansible args",
+			ansibleArgs:     "'--skip-tags=slow'",
+			expectedCmdLine: "--skip-tags=slow --check --diff",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmdFunc := playbookCmdFunc("/test/playbook.yml")
+			expectedArgs := append(cmdFunc("test", "/test/path", 1, 2).Args, "--cmdline", tc.expectedCmdLine)
+			gotArgs := checkModeCmdFunc(cmdFunc, tc.ansibleArgs)("test", "/test/path", 1, 2).Args
+			if !reflect.DeepEqual(expectedArgs, gotArgs) {
+				t.Fatalf("Unexpected cmd args %v expected cmd args %v", gotArgs, expectedArgs)
+			}
+		})
+	}
+}
+
+func TestNewWebhook(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Unable to get working director: %v", err)
+	}
+	validPlaybook := filepath.Join(cwd, "testdata", "playbook.yml")
+	validRole := filepath.Join(cwd, "testdata", "roles", "role")
+	gvk := schema.GroupVersionKind{
+		Group:   "operator.example.com",
+		Version: "v1alpha1",
+		Kind:    "Example",
+	}
+	watch := watches.New(gvk, validRole, "", map[string]interface{}{"watch": "var"}, &watches.Finalizer{
+		Name: "operator.example.com/finalizer",
+		Role: validRole,
+	})
+	webhook := watches.Webhook{
+		Playbook: validPlaybook,
+		Vars:     map[string]interface{}{"webhook": "var"},
+	}
+
+	testRunnerStruct, err := NewWebhook(*watch, webhook, "validate", "")
+	if err != nil {
+		t.Fatalf("Error occurred unexpectedly: %v", err)
+	}
+	testRunner := testRunnerStruct.(*runner)
+	if testRunner.Path != validPlaybook {
+		t.Fatalf("Unexpected path %v expected path %v", testRunner.Path, validPlaybook)
+	}
+	if !reflect.DeepEqual(testRunner.Vars, webhook.Vars) {
+		t.Fatalf("Unexpected vars %v expected vars %v", testRunner.Vars, webhook.Vars)
+	}
+	if _, ok := testRunner.GetFinalizer(); ok {
+		t.Fatalf("Unexpected finalizer for webhook runner")
+	}
+	if expected := filepath.Join(webhookRunDir, "validate"); testRunner.runDir != expected {
+		t.Fatalf("Unexpected run dir %v expected run dir %v", testRunner.runDir, expected)
+	}
+
+	// Concurrent requests for an object without a name yet must not share an input directory.
+	u := &unstructured.Unstructured{}
+	u.SetNamespace("default")
+	first, second := testRunner.inputDirPath("1", u), testRunner.inputDirPath("2", u)
+	if first == second {
+		t.Fatalf("Expected runs to have their own input directories, got %v", first)
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Unable to get working director: %v", err)
+	}
+	validPlaybook := filepath.Join(cwd, "testdata", "playbook.yml")
+	validRole := filepath.Join(cwd, "testdata", "roles", "role")
+	gvk := schema.GroupVersionKind{
+		Group:   "operator.example.com",
+		Version: "v1alpha1",
+		Kind:    "Example",
+	}
+	watch := watches.New(gvk, validRole, "", nil, nil)
+	watch.Routes = []watches.Route{
+		{Events: []string{watches.EventCreate}, Playbook: validPlaybook},
+		{JSONPath: "{.spec.mode}", Value: "maintenance", Role: validRole},
+	}
+	testRunner, err := newRunner(*watch, "")
+	if err != nil {
+		t.Fatalf("Error occurred unexpectedly: %v", err)
+	}
+
+	testCases := []struct {
+		name         string
+		event        string
+		spec         map[string]interface{}
+		expectedPath string
+	}{
+		{
+			name:         "event route",
+			event:        watches.EventCreate,
+			spec:         map[string]interface{}{"mode": "maintenance"},
+			expectedPath: validPlaybook,
+		},
+		{
+			name:         "jsonPath route",
+			event:        watches.EventUpdate,
+			spec:         map[string]interface{}{"mode": "maintenance"},
+			expectedPath: validRole,
+		},
+		{
+			name:  "no route",
+			event: watches.EventUpdate,
+			spec:  map[string]interface{}{"mode": "active"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": tc.spec}}
+			rt, err := testRunner.matchRoute(u, tc.event)
+			if err != nil {
+				t.Fatalf("Error occurred unexpectedly: %v", err)
+			}
+			if tc.expectedPath == "" {
+				if rt != nil {
+					t.Fatalf("Unexpected route %v", rt.Route)
+				}
+				return
+			}
+			if rt == nil {
+				t.Fatalf("No route matched, expected path %v", tc.expectedPath)
+			}
+			if rt.path != tc.expectedPath {
+				t.Fatalf("Unexpected path %v expected path %v", rt.path, tc.expectedPath)
+			}
+			if rt.Role != "" {
+				checkCmdFunc(t, rt.cmdFunc, "", rt.Role, 0)
+			} else {
+				checkCmdFunc(t, rt.cmdFunc, rt.Playbook, "", 0)
+			}
+		})
+	}
+}
+
+func TestGetRunOptions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []RunOption
+		expected runOptions
+	}{
+		{
+			name: "no options",
+		},
+		{
+			name:     "check mode",
+			opts:     []RunOption{WithCheckMode()},
+			expected: runOptions{checkMode: true},
+		},
+		{
+			name: "extra vars are merged",
+			opts: []RunOption{
+				WithExtraVars(map[string]interface{}{"a": "a", "b": "b"}),
+				WithExtraVars(map[string]interface{}{"b": "c"}),
+			},
+			expected: runOptions{extraVars: map[string]interface{}{"a": "a", "b": "c"}},
+		},
+		{
+			name:     "event",
+			opts:     []RunOption{WithEvent(watches.EventUpdate)},
+			expected: runOptions{event: watches.EventUpdate},
+		},
+		{
+			name:     "context",
+			opts:     []RunOption{WithContext(context.TODO())},
+			expected: runOptions{ctx: context.TODO()},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := getRunOptions(tc.opts...)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("Unexpected run options %#v expected run options %#v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestCombinedOutput(t *testing.T) {
+	output, err := combinedOutput(context.TODO(), exec.Command("echo", "done"))
+	if err != nil || string(output) != "done\n" {
+		t.Fatalf("Unexpected output %q: %v", output, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = combinedOutput(ctx, exec.Command("sleep", "10"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the run to be stopped at the deadline, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("The run was not killed at the deadline")
+	}
+}
+
 func TestAnsibleVerbosityString(t *testing.T) {
 	testCases := []struct {
 		verbosity      int
@@ -371,3 +595,99 @@ func TestMakeParameters(t *testing.T) {
 		}
 	}
 }
+
+func TestMakeSourceParameters(t *testing.T) {
+	reader := fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example-credentials"},
+			Data:       map[string][]byte{"password": []byte("s3cr3t"), "tls.crt": []byte("cert")},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "config", Name: "settings"},
+			Data:       map[string]string{"log-level": "debug"},
+		},
+	).Build()
+	u := &unstructured.Unstructured{}
+	u.SetNamespace("default")
+	u.SetName("example")
+
+	testCases := []struct {
+		name                 string
+		secretVars           []watches.VarsSource
+		configMapVars        []watches.VarsSource
+		markUnsafe           bool
+		expectedParams       map[string]interface{}
+		expectedSecretParams map[string]interface{}
+		shouldError          bool
+	}{
+		{
+			name:           "single key of a templated secret",
+			secretVars:     []watches.VarsSource{{Name: "{{ .Name }}-credentials", Key: "password", Var: "db_password"}},
+			expectedParams: map[string]interface{}{},
+			expectedSecretParams: map[string]interface{}{
+				"db_password": map[string]interface{}{"__ansible_unsafe": "s3cr3t"},
+			},
+		},
+		{
+			name:                 "all keys of a config map as vars",
+			configMapVars:        []watches.VarsSource{{Name: "settings", Namespace: "config"}},
+			expectedParams:       map[string]interface{}{"log_level": "debug"},
+			expectedSecretParams: map[string]interface{}{},
+		},
+		{
+			name:          "all keys of a config map as a dict marked unsafe",
+			configMapVars: []watches.VarsSource{{Name: "settings", Namespace: "config", Var: "settings"}},
+			markUnsafe:    true,
+			expectedParams: map[string]interface{}{
+				"settings": map[string]interface{}{"log-level": map[string]interface{}{"__ansible_unsafe": "debug"}},
+			},
+			expectedSecretParams: map[string]interface{}{},
+		},
+		{
+			name: "missing optional secret and key",
+			secretVars: []watches.VarsSource{
+				{Name: "missing", Optional: true},
+				{Name: "example-credentials", Key: "token", Optional: true},
+			},
+			expectedParams:       map[string]interface{}{},
+			expectedSecretParams: map[string]interface{}{},
+		},
+		{
+			name:        "missing secret",
+			secretVars:  []watches.VarsSource{{Name: "missing"}},
+			shouldError: true,
+		},
+		{
+			name:          "missing config map key",
+			configMapVars: []watches.VarsSource{{Name: "settings", Namespace: "config", Key: "replicas"}},
+			shouldError:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := runner{
+				SecretVars:    tc.secretVars,
+				ConfigMapVars: tc.configMapVars,
+				markUnsafe:    tc.markUnsafe,
+				reader:        reader,
+			}
+			params, secretParams, err := r.makeSourceParameters(context.TODO(), u)
+			if tc.shouldError {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(params, tc.expectedParams) {
+				t.Fatalf("Unexpected parameters %v expected %v", params, tc.expectedParams)
+			}
+			if !reflect.DeepEqual(secretParams, tc.expectedSecretParams) {
+				t.Fatalf("Unexpected secret parameters %v expected %v", secretParams, tc.expectedSecretParams)
+			}
+		})
+	}
+}
@@ -22,13 +22,17 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/jsonpath"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	yaml "sigs.k8s.io/yaml"
 
@@ -42,6 +46,7 @@ var log = logf.Log.WithName("watches")
 type Watch struct {
 	GroupVersionKind            schema.GroupVersionKind   `yaml:",inline"`
 	Blacklist                   []schema.GroupVersionKind `yaml:"blacklist"`
+	DependentWatches            []DependentWatch          `yaml:"dependentWatches"`
 	Playbook                    string                    `yaml:"playbook"`
 	Role                        string                    `yaml:"role"`
 	Vars                        map[string]interface{}    `yaml:"vars"`
@@ -53,11 +58,223 @@ type Watch struct {
 	WatchClusterScopedResources bool                      `yaml:"watchClusterScopedResources"`
 	SnakeCaseParameters         bool                      `yaml:"snakeCaseParameters"`
 	MarkUnsafe                  bool                      `yaml:"markUnsafe"`
+	DryRun                      bool                      `yaml:"dryRun"`
+	ApplySchema                 bool                      `yaml:"applySchema"`
+	DriftDetection              string                    `yaml:"driftDetection"`
+	Routes                      []Route                   `yaml:"routes"`
 	Selector                    metav1.LabelSelector      `yaml:"selector"`
+	MaxConcurrentReconciles     int                       `yaml:"maxConcurrentReconciles"`
+	RateLimiter                 *RateLimiter              `yaml:"rateLimiter"`
+	SerializeByNamespace        bool                      `yaml:"serializeByNamespace"`
+	ValidatingWebhook           *Webhook                  `yaml:"validatingWebhook"`
+	MutatingWebhook             *Webhook                  `yaml:"mutatingWebhook"`
+	APIPolicy                   []APIRule                 `yaml:"apiPolicy"`
+	SecretVars                  []VarsSource              `yaml:"secretVars"`
+	ConfigMapVars               []VarsSource              `yaml:"configMapVars"`
 
 	// Not configurable via watches.yaml
-	MaxConcurrentReconciles int `yaml:"-"`
-	AnsibleVerbosity        int `yaml:"-"`
+	AnsibleVerbosity int `yaml:"-"`
+}
+
+// RateLimiter - Expose the backoff used to requeue CRs that failed to reconcile.
+// When nil, the controller's default rate limiter is used.
+type RateLimiter struct {
+	BaseDelay time.Duration `yaml:"baseDelay"`
+	MaxDelay  time.Duration `yaml:"maxDelay"`
+}
+
+// DependentWatch - Allows a dependent resource GVK to be watched and configures
+// how its events are mapped back to the owning CR. When a Watch lists any
+// DependentWatches, only those GVKs are watched and cached.
+type DependentWatch struct {
+	GroupVersionKind schema.GroupVersionKind `yaml:",inline"`
+	EnqueueBy        string                  `yaml:"enqueueBy"`
+}
+
+// Supported values for DependentWatch.EnqueueBy
+const (
+	// EnqueueByOwnerReference sets an ownerReference on the dependent resource when
+	// the owner supports it, and falls back to annotations otherwise.
+	EnqueueByOwnerReference = "ownerReference"
+	// EnqueueByAnnotation always sets owner annotations on the dependent resource.
+	// The resource is then not garbage collected along with its owner.
+	EnqueueByAnnotation = "annotation"
+)
+
+// Supported values for Watch.DriftDetection
+const (
+	// DriftDetectionDisabled runs the playbook or role on every reconcile, which is the default.
+	DriftDetectionDisabled = "disabled"
+	// DriftDetectionAuto runs the playbook or role in check mode once the spec of a CR was
+	// applied, and runs it again to correct any drift that is detected.
+	DriftDetectionAuto = "auto"
+	// DriftDetectionManual runs the playbook or role in check mode once the spec of a CR was
+	// applied, and only corrects the drift that is detected once the correction is approved.
+	DriftDetectionManual = "manual"
+)
+
+// Webhook - Maps admission requests for the GVK to an ansible playbook or role.
+type Webhook struct {
+	Playbook string                 `yaml:"playbook"`
+	Role     string                 `yaml:"role"`
+	Vars     map[string]interface{} `yaml:"vars"`
+}
+
+// Route - Runs a playbook or role in place of the Watch's for the reconciles it matches,
+// which are those of one of Events, if any are listed, for CRs that match JSONPath, if set.
+// Without Events, a Route matches every event but EventDelete, so that it does not take over
+// the finalizer unless asked to. The first Route that matches a reconcile is used.
+type Route struct {
+	Events []string `yaml:"events"`
+	// JSONPath is a template, e.g. "{.spec.mode}", that matches a CR if its result equals Value.
+	// When Value is empty, it matches if the result is neither empty nor "false".
+	JSONPath string `yaml:"jsonPath"`
+	Value    string `yaml:"value"`
+	Playbook string `yaml:"playbook"`
+	Role     string `yaml:"role"`
+}
+
+// Matches - returns true if the Route matches the reconcile of obj for event.
+func (rt Route) Matches(event string, obj map[string]interface{}) (bool, error) {
+	if len(rt.Events) > 0 {
+		found := false
+		for _, e := range rt.Events {
+			found = found || e == event
+		}
+		if !found {
+			return false, nil
+		}
+	} else if event == EventDelete {
+		return false, nil
+	}
+	if rt.JSONPath == "" {
+		return true, nil
+	}
+	jp, err := ParseRouteJSONPath(rt.JSONPath)
+	if err != nil {
+		return false, err
+	}
+	var b strings.Builder
+	if err := jp.Execute(&b, obj); err != nil {
+		return false, err
+	}
+	if rt.Value == "" {
+		return b.Len() > 0 && b.String() != "false", nil
+	}
+	return b.String() == rt.Value, nil
+}
+
+// Supported values for Route.Events
+const (
+	// EventCreate is the reconcile of a CR that no run has applied yet.
+	EventCreate = "create"
+	// EventUpdate is the reconcile of a CR whose spec changed since a run last applied it.
+	EventUpdate = "update"
+	// EventResync is the reconcile of a CR whose spec was already applied, e.g. once the
+	// reconcile period elapsed or a dependent resource changed.
+	EventResync = "resync"
+	// EventDelete is the finalizer run of a deleted CR.
+	EventDelete = "delete"
+)
+
+// APIRule - Allows the playbook or role of a Watch to make requests through the proxy.
+// When a Watch has any APIRules, requests that none of them match are denied, except
+// for those on the CR being reconciled. "*" matches any group, kind, verb or namespace.
+type APIRule struct {
+	Groups []string `yaml:"groups"`
+	Kinds  []string `yaml:"kinds"`
+	Verbs  []string `yaml:"verbs"`
+	// Namespaces limits the rule to requests in these namespaces. OwnerNamespace matches
+	// the namespace of the CR, and "" matches cluster-scoped and all-namespace requests.
+	// If empty, requests in any namespace are matched.
+	Namespaces []string `yaml:"namespaces"`
+}
+
+// OwnerNamespace - value of APIRule.Namespaces matching the namespace of the CR being reconciled.
+const OwnerNamespace = "@owner"
+
+// apiVerbs are the verbs of Kubernetes resource requests.
+var apiVerbs = map[string]bool{
+	"get": true, "list": true, "watch": true, "create": true, "update": true,
+	"patch": true, "delete": true, "deletecollection": true, "*": true,
+}
+
+// Allows - returns true if the rule matches a request with the given verb, group,
+// kind and namespace, made while reconciling a CR in ownerNamespace.
+func (r APIRule) Allows(verb, group, kind, namespace, ownerNamespace string) bool {
+	if !matchesAny(r.Verbs, verb) || !matchesAny(r.Groups, group) || !matchesAny(r.Kinds, kind) {
+		return false
+	}
+	if len(r.Namespaces) == 0 {
+		return true
+	}
+	for _, ns := range r.Namespaces {
+		if ns == "*" || ns == namespace || (ns == OwnerNamespace && namespace == ownerNamespace) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(values []string, value string) bool {
+	for _, v := range values {
+		if v == "*" || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// VarsSource - References a Secret or ConfigMap whose data is passed to the playbook
+// or role as vars. Name and Namespace are templates that may refer to the {{ .Name }}
+// and {{ .Namespace }} of the CR being reconciled.
+type VarsSource struct {
+	Name string `yaml:"name"`
+	// Namespace defaults to the namespace of the CR.
+	Namespace string `yaml:"namespace"`
+	// Key selects a single value of the data. If empty, all of the data is passed.
+	Key string `yaml:"key"`
+	// Var is the name of the var the value of Key is passed as, which defaults to Key.
+	// Without a Key, the data is passed as a dict named Var, or as one var per key
+	// if Var is empty as well.
+	Var string `yaml:"var"`
+	// Optional allows the Secret or ConfigMap, or its Key, to be missing.
+	Optional bool `yaml:"optional"`
+}
+
+// ansibleVarName matches the names ansible allows for vars.
+var ansibleVarName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ObjectKey - returns the name and namespace of the referenced object for a CR with
+// the given name and namespace.
+func (s VarsSource) ObjectKey(name, namespace string) (types.NamespacedName, error) {
+	data := struct{ Name, Namespace string }{Name: name, Namespace: namespace}
+	key := types.NamespacedName{Namespace: namespace}
+	var err error
+	if key.Name, err = executeTemplate(s.Name, data); err != nil {
+		return key, err
+	}
+	if s.Namespace != "" {
+		if key.Namespace, err = executeTemplate(s.Namespace, data); err != nil {
+			return key, err
+		}
+	}
+	if key.Name == "" || key.Namespace == "" {
+		return key, fmt.Errorf("vars source %q must have a name and namespace, got %q", s.Name, key)
+	}
+	return key, nil
+}
+
+func executeTemplate(text string, data interface{}) (string, error) {
+	t, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	b := &strings.Builder{}
+	if err := t.Execute(b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
 }
 
 // Finalizer - Expose finalizer to be used by a user.
@@ -66,6 +283,11 @@ type Finalizer struct {
 	Playbook string                 `yaml:"playbook"`
 	Role     string                 `yaml:"role"`
 	Vars     map[string]interface{} `yaml:"vars"`
+	// Timeout and MaxAttempts, when greater than 0, bound how long the finalizer may keep
+	// failing after the CR is deleted. Once either is exceeded, the finalizer is removed
+	// without having run successfully.
+	Timeout     time.Duration `yaml:"timeout"`
+	MaxAttempts int           `yaml:"maxAttempts"`
 }
 
 // Default values for optional fields on Watch
@@ -78,7 +300,14 @@ var (
 	watchClusterScopedResourcesDefault = false
 	snakeCaseParametersDefault         = true
 	markUnsafeDefault                  = false
+	dryRunDefault                      = false
+	applySchemaDefault                 = false
 	selectorDefault                    = metav1.LabelSelector{}
+	serializeByNamespaceDefault        = false
+
+	// these match the per-item backoff of the controller's default rate limiter
+	rateLimiterBaseDelayDefault = metav1.Duration{Duration: 5 * time.Millisecond}
+	rateLimiterMaxDelayDefault  = metav1.Duration{Duration: 1000 * time.Second}
 
 	// these are overridden by cmdline flags
 	maxConcurrentReconcilesDefault = runtime.NumCPU()
@@ -115,6 +344,27 @@ type tempRequirement struct {
 	Values   []string                     `json:"values,omitempty"`
 }
 
+type tempDependentWatch struct {
+	Group     string `yaml:"group"`
+	Version   string `yaml:"version"`
+	Kind      string `yaml:"kind"`
+	EnqueueBy string `yaml:"enqueueBy,omitempty"`
+}
+
+type tempFinalizer struct {
+	Name        string                 `yaml:"name"`
+	Playbook    string                 `yaml:"playbook"`
+	Role        string                 `yaml:"role"`
+	Vars        map[string]interface{} `yaml:"vars"`
+	Timeout     *metav1.Duration       `yaml:"timeout,omitempty"`
+	MaxAttempts int                    `yaml:"maxAttempts,omitempty"`
+}
+
+type tempRateLimiter struct {
+	BaseDelay *metav1.Duration `yaml:"baseDelay,omitempty"`
+	MaxDelay  *metav1.Duration `yaml:"maxDelay,omitempty"`
+}
+
 // Use an alias struct to handle complex types
 type alias struct {
 	Group                       string                    `yaml:"group"`
@@ -130,9 +380,22 @@ type alias struct {
 	WatchClusterScopedResources *bool                     `yaml:"watchClusterScopedResources,omitempty"`
 	SnakeCaseParameters         *bool                     `yaml:"snakeCaseParameters"`
 	MarkUnsafe                  *bool                     `yaml:"markUnsafe"`
+	DryRun                      *bool                     `yaml:"dryRun,omitempty"`
+	ApplySchema                 *bool                     `yaml:"applySchema,omitempty"`
+	DriftDetection              string                    `yaml:"driftDetection,omitempty"`
+	Routes                      []Route                   `yaml:"routes,omitempty"`
 	Blacklist                   []schema.GroupVersionKind `yaml:"blacklist,omitempty"`
-	Finalizer                   *Finalizer                `yaml:"finalizer"`
+	DependentWatches            []tempDependentWatch      `yaml:"dependentWatches,omitempty"`
+	Finalizer                   *tempFinalizer            `yaml:"finalizer"`
 	Selector                    tempLabelSelector         `yaml:"selector"`
+	MaxConcurrentReconciles     *int                      `yaml:"maxConcurrentReconciles,omitempty"`
+	RateLimiter                 *tempRateLimiter          `yaml:"rateLimiter,omitempty"`
+	SerializeByNamespace        *bool                     `yaml:"serializeByNamespace,omitempty"`
+	ValidatingWebhook           *Webhook                  `yaml:"validatingWebhook,omitempty"`
+	MutatingWebhook             *Webhook                  `yaml:"mutatingWebhook,omitempty"`
+	APIPolicy                   []APIRule                 `yaml:"apiPolicy,omitempty"`
+	SecretVars                  []VarsSource              `yaml:"secretVars,omitempty"`
+	ConfigMapVars               []VarsSource              `yaml:"configMapVars,omitempty"`
 }
 
 // buildWatch will build Watch based on the values parsed from alias
@@ -169,6 +432,18 @@ func (w *Watch) setValuesFromAlias(tmp alias) error {
 		tmp.MarkUnsafe = &markUnsafeDefault
 	}
 
+	if tmp.DryRun == nil {
+		tmp.DryRun = &dryRunDefault
+	}
+
+	if tmp.ApplySchema == nil {
+		tmp.ApplySchema = &applySchemaDefault
+	}
+
+	if tmp.SerializeByNamespace == nil {
+		tmp.SerializeByNamespace = &serializeByNamespaceDefault
+	}
+
 	gvk := schema.GroupVersionKind{
 		Group:   tmp.Group,
 		Version: tmp.Version,
@@ -179,22 +454,43 @@ func (w *Watch) setValuesFromAlias(tmp alias) error {
 		return fmt.Errorf("invalid GVK: %s: %w", gvk, err)
 	}
 
+	// the value set by the watch is used as is. Otherwise, the environment variables of the
+	// GVK, or else the cmdline flag, set it.
+	if tmp.MaxConcurrentReconciles == nil {
+		maxConcurrentReconciles := getMaxConcurrentReconciles(gvk, maxConcurrentReconcilesDefault)
+		tmp.MaxConcurrentReconciles = &maxConcurrentReconciles
+	} else if *tmp.MaxConcurrentReconciles < 1 {
+		return fmt.Errorf("invalid maxConcurrentReconciles for GVK: %s: must be greater than 0", gvk)
+	}
+
 	// Rewrite values to struct being unmarshalled
 	w.GroupVersionKind = gvk
 	w.Playbook = tmp.Playbook
 	w.Role = tmp.Role
 	w.Vars = tmp.Vars
 	w.MaxRunnerArtifacts = tmp.MaxRunnerArtifacts
-	w.MaxConcurrentReconciles = getMaxConcurrentReconciles(gvk, maxConcurrentReconcilesDefault)
+	w.MaxConcurrentReconciles = *tmp.MaxConcurrentReconciles
+	w.RateLimiter = parseRateLimiter(tmp.RateLimiter)
+	w.SerializeByNamespace = *tmp.SerializeByNamespace
 	w.ReconcilePeriod = tmp.ReconcilePeriod.Duration
 	w.ManageStatus = *tmp.ManageStatus
 	w.WatchDependentResources = *tmp.WatchDependentResources
 	w.SnakeCaseParameters = *tmp.SnakeCaseParameters
 	w.MarkUnsafe = *tmp.MarkUnsafe
+	w.DryRun = *tmp.DryRun
+	w.ApplySchema = *tmp.ApplySchema
+	w.DriftDetection = tmp.DriftDetection
+	w.Routes = tmp.Routes
 	w.WatchClusterScopedResources = *tmp.WatchClusterScopedResources
-	w.Finalizer = tmp.Finalizer
+	w.Finalizer = parseFinalizer(tmp.Finalizer)
+	w.ValidatingWebhook = tmp.ValidatingWebhook
+	w.MutatingWebhook = tmp.MutatingWebhook
+	w.APIPolicy = tmp.APIPolicy
+	w.SecretVars = tmp.SecretVars
+	w.ConfigMapVars = tmp.ConfigMapVars
 	w.AnsibleVerbosity = getAnsibleVerbosity(gvk, ansibleVerbosityDefault)
 	w.Blacklist = tmp.Blacklist
+	w.DependentWatches = parseDependentWatches(tmp.DependentWatches)
 
 	wd, err := os.Getwd()
 	if err != nil {
@@ -206,6 +502,65 @@ func (w *Watch) setValuesFromAlias(tmp alias) error {
 	return nil
 }
 
+// parseDependentWatches returns the DependentWatches for a watch, defaulting
+// each unset enqueueBy to EnqueueByOwnerReference.
+func parseDependentWatches(tdws []tempDependentWatch) []DependentWatch {
+	if len(tdws) == 0 {
+		return nil
+	}
+	dws := make([]DependentWatch, 0, len(tdws))
+	for _, tdw := range tdws {
+		if tdw.EnqueueBy == "" {
+			tdw.EnqueueBy = EnqueueByOwnerReference
+		}
+		dws = append(dws, DependentWatch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Group:   tdw.Group,
+				Version: tdw.Version,
+				Kind:    tdw.Kind,
+			},
+			EnqueueBy: tdw.EnqueueBy,
+		})
+	}
+	return dws
+}
+
+// parseRateLimiter returns the RateLimiter for a watch, filling in any unset delay
+// with the controller's default. nil is returned if the watch sets no rate limiter.
+func parseRateLimiter(trl *tempRateLimiter) *RateLimiter {
+	if trl == nil {
+		return nil
+	}
+	if trl.BaseDelay == nil {
+		trl.BaseDelay = &rateLimiterBaseDelayDefault
+	}
+	if trl.MaxDelay == nil {
+		trl.MaxDelay = &rateLimiterMaxDelayDefault
+	}
+	return &RateLimiter{
+		BaseDelay: trl.BaseDelay.Duration,
+		MaxDelay:  trl.MaxDelay.Duration,
+	}
+}
+
+// parseFinalizer returns the Finalizer for a watch. An unset timeout never expires.
+func parseFinalizer(tf *tempFinalizer) *Finalizer {
+	if tf == nil {
+		return nil
+	}
+	f := &Finalizer{
+		Name:        tf.Name,
+		Playbook:    tf.Playbook,
+		Role:        tf.Role,
+		Vars:        tf.Vars,
+		MaxAttempts: tf.MaxAttempts,
+	}
+	if tf.Timeout != nil {
+		f.Timeout = tf.Timeout.Duration
+	}
+	return f
+}
+
 // addRolePlaybookPaths will add the full path based on the current dir
 func (w *Watch) addRolePlaybookPaths(rootDir string) {
 	if len(w.Playbook) > 0 {
@@ -233,6 +588,45 @@ func (w *Watch) addRolePlaybookPaths(rootDir string) {
 	if w.Finalizer != nil && len(w.Finalizer.Playbook) > 0 {
 		w.Finalizer.Playbook = getFullPath(rootDir, w.Finalizer.Playbook)
 	}
+	for _, wh := range []*Webhook{w.ValidatingWebhook, w.MutatingWebhook} {
+		if wh != nil {
+			wh.addRolePlaybookPaths(rootDir)
+		}
+	}
+	for i := range w.Routes {
+		w.Routes[i].addRolePlaybookPaths(rootDir)
+	}
+}
+
+// addRolePlaybookPaths will add the full path of a webhook's role or playbook based on the current dir
+func (wh *Webhook) addRolePlaybookPaths(rootDir string) {
+	if len(wh.Playbook) > 0 {
+		wh.Playbook = getFullPath(rootDir, wh.Playbook)
+	}
+	if len(wh.Role) > 0 {
+		possibleRolePaths := getPossibleRolePaths(rootDir, wh.Role)
+		for _, possiblePath := range possibleRolePaths {
+			if _, err := os.Stat(possiblePath); err == nil {
+				wh.Role = possiblePath
+				break
+			}
+		}
+	}
+}
+
+func (rt *Route) addRolePlaybookPaths(rootDir string) {
+	if len(rt.Playbook) > 0 {
+		rt.Playbook = getFullPath(rootDir, rt.Playbook)
+	}
+	if len(rt.Role) > 0 {
+		possibleRolePaths := getPossibleRolePaths(rootDir, rt.Role)
+		for _, possiblePath := range possibleRolePaths {
+			if _, err := os.Stat(possiblePath); err == nil {
+				rt.Role = possiblePath
+				break
+			}
+		}
+	}
 }
 
 // getFullPath returns an absolute path for the playbook
@@ -284,6 +678,11 @@ func getPossibleRolePaths(rootDir, path string) []string {
 // A Watch is considered valid if it:
 // - Specifies a valid path to a Role||Playbook
 // - If a Finalizer is non-nil, it must have a name + valid path to a Role||Playbook or Vars
+// - If a RateLimiter is non-nil, it must have a positive base delay no greater than its max delay
+// - Each DependentWatch must have a valid GVK and a supported enqueueBy
+// - If a ValidatingWebhook or MutatingWebhook is non-nil, it must have a valid path to a Role||Playbook
+// - Each APIRule must have groups, kinds and supported verbs
+// - Each VarsSource must have a name, valid templates and a valid var name
 func (w *Watch) Validate() error {
 	err := verifyAnsiblePath(w.Playbook, w.Role)
 	if err != nil {
@@ -291,12 +690,89 @@ func (w *Watch) Validate() error {
 		return err
 	}
 
+	if w.RateLimiter != nil {
+		err = verifyRateLimiter(*w.RateLimiter)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Invalid rate limiter for GVK: %v", w.GroupVersionKind.String()))
+			return err
+		}
+	}
+
+	for _, dw := range w.DependentWatches {
+		err = verifyDependentWatch(dw)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Invalid dependent watch for GVK: %v", w.GroupVersionKind.String()))
+			return err
+		}
+	}
+
+	for _, rule := range w.APIPolicy {
+		err = verifyAPIRule(rule)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Invalid API policy for GVK: %v", w.GroupVersionKind.String()))
+			return err
+		}
+	}
+
+	for _, vs := range w.SecretVars {
+		err = verifyVarsSource(vs)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Invalid secret vars for GVK: %v", w.GroupVersionKind.String()))
+			return err
+		}
+	}
+
+	for _, vs := range w.ConfigMapVars {
+		err = verifyVarsSource(vs)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Invalid config map vars for GVK: %v", w.GroupVersionKind.String()))
+			return err
+		}
+	}
+
+	for _, rt := range w.Routes {
+		err = verifyRoute(rt)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Invalid route for GVK: %v", w.GroupVersionKind.String()))
+			return err
+		}
+	}
+
+	err = verifyDriftDetection(w.DriftDetection)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("Invalid drift detection for GVK: %v", w.GroupVersionKind.String()))
+		return err
+	}
+
+	if w.ValidatingWebhook != nil {
+		err = verifyAnsiblePath(w.ValidatingWebhook.Playbook, w.ValidatingWebhook.Role)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Invalid ansible path on ValidatingWebhook for GVK: %v",
+				w.GroupVersionKind.String()))
+			return err
+		}
+	}
+
+	if w.MutatingWebhook != nil {
+		err = verifyAnsiblePath(w.MutatingWebhook.Playbook, w.MutatingWebhook.Role)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Invalid ansible path on MutatingWebhook for GVK: %v",
+				w.GroupVersionKind.String()))
+			return err
+		}
+	}
+
 	if w.Finalizer != nil {
 		if w.Finalizer.Name == "" {
 			err = fmt.Errorf("finalizer must have name")
 			log.Error(err, fmt.Sprintf("Invalid finalizer for GVK: %v", w.GroupVersionKind.String()))
 			return err
 		}
+		if w.Finalizer.Timeout < 0 || w.Finalizer.MaxAttempts < 0 {
+			err = fmt.Errorf("finalizer timeout and maxAttempts must not be negative")
+			log.Error(err, fmt.Sprintf("Invalid finalizer for GVK: %v", w.GroupVersionKind.String()))
+			return err
+		}
 		// only fail if Vars not set
 		err = verifyAnsiblePath(w.Finalizer.Playbook, w.Finalizer.Role)
 		if err != nil && len(w.Finalizer.Vars) == 0 {
@@ -325,9 +801,12 @@ func New(gvk schema.GroupVersionKind, role, playbook string, vars map[string]int
 		WatchClusterScopedResources: watchClusterScopedResourcesDefault,
 		SnakeCaseParameters:         snakeCaseParametersDefault,
 		MarkUnsafe:                  markUnsafeDefault,
+		DryRun:                      dryRunDefault,
+		ApplySchema:                 applySchemaDefault,
 		Finalizer:                   finalizer,
 		AnsibleVerbosity:            ansibleVerbosityDefault,
 		Selector:                    selectorDefault,
+		SerializeByNamespace:        serializeByNamespaceDefault,
 	}
 }
 
@@ -411,6 +890,103 @@ func verifyAnsiblePath(playbook string, role string) error {
 	return nil
 }
 
+// verify that a rate limiter backs off by a positive, bounded delay
+func verifyRateLimiter(rl RateLimiter) error {
+	if rl.BaseDelay <= 0 {
+		return fmt.Errorf("rate limiter baseDelay must be greater than 0")
+	}
+	if rl.MaxDelay < rl.BaseDelay {
+		return fmt.Errorf("rate limiter maxDelay must not be less than baseDelay")
+	}
+	return nil
+}
+
+// verify that a dependent watch names a GVK and a supported enqueue mode
+func verifyDependentWatch(dw DependentWatch) error {
+	if err := verifyGVK(dw.GroupVersionKind); err != nil {
+		return fmt.Errorf("dependent watch %s: %w", dw.GroupVersionKind, err)
+	}
+	switch dw.EnqueueBy {
+	case EnqueueByOwnerReference, EnqueueByAnnotation:
+	default:
+		return fmt.Errorf("dependent watch %s: enqueueBy must be one of %q or %q, got %q",
+			dw.GroupVersionKind, EnqueueByOwnerReference, EnqueueByAnnotation, dw.EnqueueBy)
+	}
+	return nil
+}
+
+// verify that a route has known events, a valid jsonPath and a playbook or role
+func verifyRoute(rt Route) error {
+	for _, event := range rt.Events {
+		switch event {
+		case EventCreate, EventUpdate, EventResync, EventDelete:
+		default:
+			return fmt.Errorf("route events must be %q, %q, %q or %q, got %q",
+				EventCreate, EventUpdate, EventResync, EventDelete, event)
+		}
+	}
+	if rt.JSONPath != "" {
+		if _, err := ParseRouteJSONPath(rt.JSONPath); err != nil {
+			return fmt.Errorf("invalid route jsonPath %q: %w", rt.JSONPath, err)
+		}
+	} else if rt.Value != "" {
+		return fmt.Errorf("route value %q requires a jsonPath", rt.Value)
+	}
+	return verifyAnsiblePath(rt.Playbook, rt.Role)
+}
+
+// ParseRouteJSONPath - parses the JSONPath template of a Route. Missing keys evaluate to "".
+func ParseRouteJSONPath(template string) (*jsonpath.JSONPath, error) {
+	jp := jsonpath.New("route").AllowMissingKeys(true)
+	if err := jp.Parse(template); err != nil {
+		return nil, err
+	}
+	return jp, nil
+}
+
+// verify that a drift detection policy is known, an empty policy defaults to disabled
+func verifyDriftDetection(policy string) error {
+	switch policy {
+	case "", DriftDetectionDisabled, DriftDetectionAuto, DriftDetectionManual:
+		return nil
+	}
+	return fmt.Errorf("driftDetection must be one of %q, %q or %q, got %q",
+		DriftDetectionDisabled, DriftDetectionAuto, DriftDetectionManual, policy)
+}
+
+// verify that an API rule names the groups, kinds and verbs it allows
+func verifyAPIRule(rule APIRule) error {
+	if len(rule.Groups) == 0 || len(rule.Kinds) == 0 || len(rule.Verbs) == 0 {
+		return fmt.Errorf("API rule must have groups, kinds and verbs, use \"*\" to allow any")
+	}
+	for _, verb := range rule.Verbs {
+		if !apiVerbs[verb] {
+			return fmt.Errorf("API rule has unsupported verb %q", verb)
+		}
+	}
+	return nil
+}
+
+// verify that a vars source names its object with valid templates and
+// is passed as valid var names
+func verifyVarsSource(vs VarsSource) error {
+	if vs.Name == "" {
+		return fmt.Errorf("vars source must have a name")
+	}
+	for _, text := range []string{vs.Name, vs.Namespace} {
+		if _, err := template.New("").Parse(text); err != nil {
+			return fmt.Errorf("vars source %q: %w", vs.Name, err)
+		}
+	}
+	if vs.Var != "" && !ansibleVarName.MatchString(vs.Var) {
+		return fmt.Errorf("vars source %q: invalid var name %q", vs.Name, vs.Var)
+	}
+	if vs.Var == "" && vs.Key != "" && !ansibleVarName.MatchString(vs.Key) {
+		return fmt.Errorf("vars source %q: key %q is not a valid var name, set var", vs.Name, vs.Key)
+	}
+	return nil
+}
+
 // if the WORKER_* environment variable is set, use that value.
 // Otherwise, use defValue. This is definitely
 // counter-intuitive but it allows the operator admin adjust the
@@ -27,6 +27,7 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 func TestNew(t *testing.T) {
@@ -85,6 +86,12 @@ func TestNew(t *testing.T) {
 			if watch.MarkUnsafe != markUnsafeDefault {
 				t.Fatalf("Unexpected markUnsafe %v expected %v", watch.MarkUnsafe, markUnsafeDefault)
 			}
+			if watch.DryRun != dryRunDefault {
+				t.Fatalf("Unexpected dryRun %v expected %v", watch.DryRun, dryRunDefault)
+			}
+			if watch.ApplySchema != applySchemaDefault {
+				t.Fatalf("Unexpected applySchema %v expected %v", watch.ApplySchema, applySchemaDefault)
+			}
 			if watch.WatchClusterScopedResources != watchClusterScopedResourcesDefault {
 				t.Fatalf("Unexpected watchClusterScopedResources %v expected %v",
 					watch.WatchClusterScopedResources, watchClusterScopedResourcesDefault)
@@ -163,6 +170,40 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 			ReconcilePeriod: twoSeconds,
 			MarkUnsafe:      true,
 		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "DryRun",
+			},
+			Playbook:     validTemplate.ValidPlaybook,
+			ManageStatus: true,
+			DryRun:       true,
+		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "ApplySchema",
+			},
+			Playbook:       validTemplate.ValidPlaybook,
+			ManageStatus:   true,
+			ApplySchema:    true,
+			DriftDetection: DriftDetectionManual,
+		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "Routes",
+			},
+			Playbook:     validTemplate.ValidPlaybook,
+			ManageStatus: true,
+			Routes: []Route{
+				{Events: []string{EventCreate}, Playbook: validTemplate.ValidPlaybook},
+				{JSONPath: "{.spec.mode}", Value: "maintenance", Role: validTemplate.ValidRole},
+			},
+		},
 		Watch{
 			GroupVersionKind: schema.GroupVersionKind{
 				Version: "v1alpha1",
@@ -252,8 +293,10 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 			Role:         validTemplate.ValidRole,
 			ManageStatus: true,
 			Finalizer: &Finalizer{
-				Name: "app.example.com/finalizer",
-				Vars: map[string]interface{}{"sentinel": "finalizer_running"},
+				Name:        "app.example.com/finalizer",
+				Vars:        map[string]interface{}{"sentinel": "finalizer_running"},
+				Timeout:     10 * time.Minute,
+				MaxAttempts: 5,
 			},
 		},
 		Watch{
@@ -286,6 +329,128 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 			ManageStatus:            true,
 			MaxConcurrentReconciles: 4,
 		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "MaxConcurrentReconcilesWatch",
+			},
+			Role:                    validTemplate.ValidRole,
+			ManageStatus:            true,
+			MaxConcurrentReconciles: 3,
+		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "MaxConcurrentReconcilesWatchAndEnv",
+			},
+			Role:                    validTemplate.ValidRole,
+			ManageStatus:            true,
+			MaxConcurrentReconciles: 3,
+		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "RateLimiter",
+			},
+			Role:         validTemplate.ValidRole,
+			ManageStatus: true,
+			RateLimiter: &RateLimiter{
+				BaseDelay: time.Second,
+				MaxDelay:  1000 * time.Second,
+			},
+		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "SerializeByNamespace",
+			},
+			Role:                 validTemplate.ValidRole,
+			ManageStatus:         true,
+			SerializeByNamespace: true,
+		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "DependentWatchesTest",
+			},
+			Role:         validTemplate.ValidRole,
+			ManageStatus: true,
+			DependentWatches: []DependentWatch{
+				{
+					GroupVersionKind: schema.GroupVersionKind{
+						Version: "v1",
+						Group:   "apps",
+						Kind:    "Deployment",
+					},
+					EnqueueBy: EnqueueByOwnerReference,
+				},
+				{
+					GroupVersionKind: schema.GroupVersionKind{
+						Version: "v1",
+						Kind:    "ConfigMap",
+					},
+					EnqueueBy: EnqueueByAnnotation,
+				},
+			},
+		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "WebhookTest",
+			},
+			Role:         validTemplate.ValidRole,
+			ManageStatus: true,
+			ValidatingWebhook: &Webhook{
+				Playbook: validTemplate.ValidPlaybook,
+				Vars:     map[string]interface{}{"sentinel": "validating"},
+			},
+			MutatingWebhook: &Webhook{
+				Role: validTemplate.ValidRole,
+			},
+		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "APIPolicyTest",
+			},
+			Role:         validTemplate.ValidRole,
+			ManageStatus: true,
+			APIPolicy: []APIRule{
+				{
+					Groups: []string{""},
+					Kinds:  []string{"ConfigMap", "Secret"},
+					Verbs:  []string{"get", "list", "watch"},
+				},
+				{
+					Groups:     []string{"apps"},
+					Kinds:      []string{"*"},
+					Verbs:      []string{"*"},
+					Namespaces: []string{OwnerNamespace},
+				},
+			},
+		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "VarsSourceTest",
+			},
+			Role:         validTemplate.ValidRole,
+			ManageStatus: true,
+			SecretVars: []VarsSource{
+				{Name: "{{ .Name }}-credentials", Key: "password", Var: "db_password"},
+			},
+			ConfigMapVars: []VarsSource{
+				{Name: "shared-settings", Namespace: "operator-config", Optional: true},
+			},
+		},
 		Watch{
 			GroupVersionKind: schema.GroupVersionKind{
 				Version: "v1alpha1",
@@ -459,6 +624,60 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 			path:        "testdata/invalid_status.yaml",
 			shouldError: true,
 		},
+		{
+			name:                    "error invalid max concurrent reconciles",
+			path:                    "testdata/invalid_max_concurrent_reconciles.yaml",
+			maxConcurrentReconciles: 1,
+			shouldError:             true,
+		},
+		{
+			name:                    "error invalid rate limiter",
+			path:                    "testdata/invalid_rate_limiter.yaml",
+			maxConcurrentReconciles: 1,
+			shouldError:             true,
+		},
+		{
+			name:                    "error invalid webhook playbook path",
+			path:                    "testdata/invalid_webhook_playbook_path.yaml",
+			maxConcurrentReconciles: 1,
+			shouldError:             true,
+		},
+		{
+			name:                    "error invalid dependent watch",
+			path:                    "testdata/invalid_dependent_watch.yaml",
+			maxConcurrentReconciles: 1,
+			shouldError:             true,
+		},
+		{
+			name:                    "error invalid api policy",
+			path:                    "testdata/invalid_api_policy.yaml",
+			maxConcurrentReconciles: 1,
+			shouldError:             true,
+		},
+		{
+			name:                    "error invalid finalizer timeout",
+			path:                    "testdata/invalid_finalizer_timeout.yaml",
+			maxConcurrentReconciles: 1,
+			shouldError:             true,
+		},
+		{
+			name:                    "error invalid drift detection",
+			path:                    "testdata/invalid_drift_detection.yaml",
+			maxConcurrentReconciles: 1,
+			shouldError:             true,
+		},
+		{
+			name:                    "error invalid route",
+			path:                    "testdata/invalid_route.yaml",
+			maxConcurrentReconciles: 1,
+			shouldError:             true,
+		},
+		{
+			name:                    "error invalid secret vars",
+			path:                    "testdata/invalid_secret_vars.yaml",
+			maxConcurrentReconciles: 1,
+			shouldError:             true,
+		},
 		{
 			name:        "if collection env var is not set and collection is not installed to the default locations, fail",
 			path:        "testdata/invalid_collection.yaml",
@@ -485,6 +704,9 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 
 	os.Setenv("WORKER_MAXCONCURRENTRECONCILESENV_APP_EXAMPLE_COM", "4")
 	defer os.Unsetenv("WORKER_MAXCONCURRENTRECONCILESENV_APP_EXAMPLE_COM")
+	// The value set by a watch wins over the environment variables.
+	os.Setenv("MAX_CONCURRENT_RECONCILES_MAXCONCURRENTRECONCILESWATCHANDENV_APP_EXAMPLE_COM", "4")
+	defer os.Unsetenv("MAX_CONCURRENT_RECONCILES_MAXCONCURRENTRECONCILESWATCHANDENV_APP_EXAMPLE_COM")
 	os.Setenv("ANSIBLE_VERBOSITY_ANSIBLEVERBOSITYENV_APP_EXAMPLE_COM", "4")
 	defer os.Unsetenv("ANSIBLE_VERBOSITY_ANSIBLEVERBOSITYENV_APP_EXAMPLE_COM")
 
@@ -541,8 +763,10 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 				if gotWatch.Finalizer != expectedWatch.Finalizer {
 					if gotWatch.Finalizer.Name != expectedWatch.Finalizer.Name || gotWatch.Finalizer.Playbook !=
 						expectedWatch.Finalizer.Playbook || gotWatch.Finalizer.Role !=
-						expectedWatch.Finalizer.Role || reflect.DeepEqual(gotWatch.Finalizer.Vars["sentinel"],
-						expectedWatch.Finalizer.Vars["sentininel"]) {
+						expectedWatch.Finalizer.Role || gotWatch.Finalizer.Timeout != expectedWatch.Finalizer.Timeout ||
+						gotWatch.Finalizer.MaxAttempts != expectedWatch.Finalizer.MaxAttempts ||
+						reflect.DeepEqual(gotWatch.Finalizer.Vars["sentinel"],
+							expectedWatch.Finalizer.Vars["sentininel"]) {
 						t.Fatalf("The GVK: %v\nunexpected finalizer: %#v\nexpected finalizer: %#v", gvk,
 							gotWatch.Finalizer, expectedWatch.Finalizer)
 					}
@@ -555,6 +779,22 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 					t.Fatalf("The GVK: %v unexpected mark unsafe: %v expected mark unsafe: %v", gvk,
 						gotWatch.MarkUnsafe, expectedWatch.MarkUnsafe)
 				}
+				if gotWatch.DryRun != expectedWatch.DryRun {
+					t.Fatalf("The GVK: %v unexpected dry run: %v expected dry run: %v", gvk,
+						gotWatch.DryRun, expectedWatch.DryRun)
+				}
+				if gotWatch.DriftDetection != expectedWatch.DriftDetection {
+					t.Fatalf("The GVK: %v unexpected drift detection: %v expected drift detection: %v", gvk,
+						gotWatch.DriftDetection, expectedWatch.DriftDetection)
+				}
+				if gotWatch.ApplySchema != expectedWatch.ApplySchema {
+					t.Fatalf("The GVK: %v unexpected apply schema: %v expected apply schema: %v", gvk,
+						gotWatch.ApplySchema, expectedWatch.ApplySchema)
+				}
+				if !reflect.DeepEqual(gotWatch.Routes, expectedWatch.Routes) {
+					t.Fatalf("The GVK: %v unexpected routes: %#v expected routes: %#v", gvk,
+						gotWatch.Routes, expectedWatch.Routes)
+				}
 
 				for i, val := range expectedWatch.Blacklist {
 					if val != gotWatch.Blacklist[i] {
@@ -563,6 +803,43 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 					}
 				}
 
+				if !reflect.DeepEqual(gotWatch.ValidatingWebhook, expectedWatch.ValidatingWebhook) {
+					t.Fatalf("The GVK: %v unexpected validating webhook: %#v expected validating webhook: %#v", gvk,
+						gotWatch.ValidatingWebhook, expectedWatch.ValidatingWebhook)
+				}
+				if !reflect.DeepEqual(gotWatch.MutatingWebhook, expectedWatch.MutatingWebhook) {
+					t.Fatalf("The GVK: %v unexpected mutating webhook: %#v expected mutating webhook: %#v", gvk,
+						gotWatch.MutatingWebhook, expectedWatch.MutatingWebhook)
+				}
+
+				if !reflect.DeepEqual(gotWatch.APIPolicy, expectedWatch.APIPolicy) {
+					t.Fatalf("The GVK: %v unexpected API policy: %#v expected API policy: %#v", gvk,
+						gotWatch.APIPolicy, expectedWatch.APIPolicy)
+				}
+
+				if !reflect.DeepEqual(gotWatch.SecretVars, expectedWatch.SecretVars) {
+					t.Fatalf("The GVK: %v unexpected secret vars: %#v expected secret vars: %#v", gvk,
+						gotWatch.SecretVars, expectedWatch.SecretVars)
+				}
+				if !reflect.DeepEqual(gotWatch.ConfigMapVars, expectedWatch.ConfigMapVars) {
+					t.Fatalf("The GVK: %v unexpected config map vars: %#v expected config map vars: %#v", gvk,
+						gotWatch.ConfigMapVars, expectedWatch.ConfigMapVars)
+				}
+
+				if !reflect.DeepEqual(gotWatch.DependentWatches, expectedWatch.DependentWatches) {
+					t.Fatalf("The GVK: %v unexpected dependent watches: %#v expected dependent watches: %#v", gvk,
+						gotWatch.DependentWatches, expectedWatch.DependentWatches)
+				}
+
+				if !reflect.DeepEqual(gotWatch.RateLimiter, expectedWatch.RateLimiter) {
+					t.Fatalf("The GVK: %v unexpected rate limiter: %#v expected rate limiter: %#v", gvk,
+						gotWatch.RateLimiter, expectedWatch.RateLimiter)
+				}
+				if gotWatch.SerializeByNamespace != expectedWatch.SerializeByNamespace {
+					t.Fatalf("The GVK: %v unexpected serialize by namespace: %v expected serialize by namespace: %v",
+						gvk, gotWatch.SerializeByNamespace, expectedWatch.SerializeByNamespace)
+				}
+
 				if !reflect.DeepEqual(gotWatch.Selector, expectedWatch.Selector) {
 					t.Fatalf("Incorrect selector GVK %s:\n\tgot %s\n\texpected %s", gvk,
 						gotWatch.Selector, expectedWatch.Selector)
@@ -892,3 +1169,220 @@ func TestGetPossibleRolePaths(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIRuleAllows(t *testing.T) {
+	rule := APIRule{
+		Groups:     []string{"", "apps"},
+		Kinds:      []string{"ConfigMap", "Deployment"},
+		Verbs:      []string{"get", "create"},
+		Namespaces: []string{OwnerNamespace, "shared"},
+	}
+	testCases := []struct {
+		name      string
+		rule      APIRule
+		verb      string
+		group     string
+		kind      string
+		namespace string
+		expected  bool
+	}{
+		{
+			name:      "matching request in the owner namespace",
+			rule:      rule,
+			verb:      "get",
+			kind:      "ConfigMap",
+			namespace: "owner",
+			expected:  true,
+		},
+		{
+			name:      "matching request in a listed namespace",
+			rule:      rule,
+			verb:      "create",
+			group:     "apps",
+			kind:      "Deployment",
+			namespace: "shared",
+			expected:  true,
+		},
+		{
+			name:      "request in another namespace",
+			rule:      rule,
+			verb:      "get",
+			kind:      "ConfigMap",
+			namespace: "other",
+		},
+		{
+			name:      "request with another verb",
+			rule:      rule,
+			verb:      "delete",
+			kind:      "ConfigMap",
+			namespace: "owner",
+		},
+		{
+			name:      "request for another kind",
+			rule:      rule,
+			verb:      "get",
+			kind:      "Secret",
+			namespace: "owner",
+		},
+		{
+			name:      "request for a kind in another group",
+			rule:      rule,
+			verb:      "get",
+			group:     "batch",
+			kind:      "Deployment",
+			namespace: "owner",
+		},
+		{
+			name:     "wildcards match anything",
+			rule:     APIRule{Groups: []string{"*"}, Kinds: []string{"*"}, Verbs: []string{"*"}},
+			verb:     "deletecollection",
+			group:    "batch",
+			kind:     "Job",
+			expected: true,
+		},
+		{
+			name: "empty namespace matches cluster-scoped requests",
+			rule: APIRule{Groups: []string{""}, Kinds: []string{"Namespace"}, Verbs: []string{"get"},
+				Namespaces: []string{""}},
+			verb:     "get",
+			kind:     "Namespace",
+			expected: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.rule.Allows(tc.verb, tc.group, tc.kind, tc.namespace, "owner"); got != tc.expected {
+				t.Fatalf("Unexpected result %v expected %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestVarsSourceObjectKey(t *testing.T) {
+	testCases := []struct {
+		name        string
+		source      VarsSource
+		expected    types.NamespacedName
+		shouldError bool
+	}{
+		{
+			name:     "defaults to the namespace of the CR",
+			source:   VarsSource{Name: "credentials"},
+			expected: types.NamespacedName{Namespace: "team-a", Name: "credentials"},
+		},
+		{
+			name:     "templated name and namespace",
+			source:   VarsSource{Name: "{{ .Name }}-credentials", Namespace: "{{ .Namespace }}-secrets"},
+			expected: types.NamespacedName{Namespace: "team-a-secrets", Name: "db-credentials"},
+		},
+		{
+			name:        "unknown template field",
+			source:      VarsSource{Name: "{{ .UID }}"},
+			shouldError: true,
+		},
+		{
+			name:        "empty name after templating",
+			source:      VarsSource{Name: "{{ if false }}x{{ end }}"},
+			shouldError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := tc.source.ObjectKey("db", "team-a")
+			if tc.shouldError {
+				if err == nil {
+					t.Fatalf("Expected an error, got key %s", key)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if key != tc.expected {
+				t.Fatalf("Unexpected key %s expected %s", key, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRouteMatches(t *testing.T) {
+	obj := map[string]interface{}{
+		"spec": map[string]interface{}{"mode": "maintenance", "backup": true},
+	}
+	testCases := []struct {
+		name     string
+		route    Route
+		event    string
+		expected bool
+	}{
+		{
+			name:     "no events or jsonPath",
+			event:    EventResync,
+			expected: true,
+		},
+		{
+			name:     "listed event",
+			route:    Route{Events: []string{EventCreate, EventUpdate}},
+			event:    EventUpdate,
+			expected: true,
+		},
+		{
+			name:  "unlisted event",
+			route: Route{Events: []string{EventCreate, EventUpdate}},
+			event: EventDelete,
+		},
+		{
+			name:     "jsonPath equals value",
+			route:    Route{JSONPath: "{.spec.mode}", Value: "maintenance"},
+			event:    EventResync,
+			expected: true,
+		},
+		{
+			name:  "jsonPath differs from value",
+			route: Route{JSONPath: "{.spec.mode}", Value: "active"},
+			event: EventResync,
+		},
+		{
+			name:     "jsonPath is true without a value",
+			route:    Route{JSONPath: "{.spec.backup}"},
+			event:    EventResync,
+			expected: true,
+		},
+		{
+			name:  "missing jsonPath without a value",
+			route: Route{JSONPath: "{.spec.restore}"},
+			event: EventResync,
+		},
+		{
+			name:  "no events does not match delete",
+			event: EventDelete,
+		},
+		{
+			name:  "jsonPath without events does not match delete",
+			route: Route{JSONPath: "{.spec.mode}", Value: "maintenance"},
+			event: EventDelete,
+		},
+		{
+			name:     "listed delete event",
+			route:    Route{Events: []string{EventDelete}, JSONPath: "{.spec.mode}", Value: "maintenance"},
+			event:    EventDelete,
+			expected: true,
+		},
+		{
+			name:  "jsonPath matches an unlisted event",
+			route: Route{Events: []string{EventCreate}, JSONPath: "{.spec.mode}", Value: "maintenance"},
+			event: EventResync,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matches, err := tc.route.Matches(tc.event, obj)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if matches != tc.expected {
+				t.Fatalf("Unexpected match %v expected %v", matches, tc.expected)
+			}
+		})
+	}
+}
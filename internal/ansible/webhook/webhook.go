@@ -0,0 +1,257 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/eventapi"
+	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
+)
+
+var log = logf.Log.WithName("ansible-webhook")
+
+const (
+	// AdmissionRequestVar - extra var holding the admission request, as sent by the
+	// API server, that is passed to the playbook or role of a webhook.
+	AdmissionRequestVar = "ansible_operator_admission"
+
+	// PatchStatsKey - key of the set_stats data in which the playbook or role of a
+	// mutating webhook returns a list of JSONPatch operations to apply to the object.
+	PatchStatsKey = "patch"
+
+	// defaultRequestTimeout - timeout of admission requests that are sent without one,
+	// which is the default timeoutSeconds of admissionregistration.k8s.io/v1 webhooks.
+	defaultRequestTimeout = 10 * time.Second
+	// maxRequestTimeout - the largest timeoutSeconds the API server accepts.
+	maxRequestTimeout = 30 * time.Second
+)
+
+// Add - registers the admission webhooks declared by a Watch with the manager's webhook
+// server. The webhook server is only started if at least one webhook is registered.
+func Add(mgr manager.Manager, w watches.Watch, runnerArgs string) error {
+	if w.ValidatingWebhook != nil {
+		r, err := runner.NewWebhook(w, *w.ValidatingWebhook, "validate", runnerArgs)
+		if err != nil {
+			return err
+		}
+		path := ValidatePath(w.GroupVersionKind)
+		mgr.GetWebhookServer().Register(path, &crwebhook.Admission{
+			Handler:         &admissionHandler{runner: r},
+			WithContextFunc: withRequestTimeout,
+		})
+		log.Info("Serving validating webhook", "GVK", w.GroupVersionKind.String(), "path", path)
+	}
+	if w.MutatingWebhook != nil {
+		r, err := runner.NewWebhook(w, *w.MutatingWebhook, "mutate", runnerArgs)
+		if err != nil {
+			return err
+		}
+		path := MutatePath(w.GroupVersionKind)
+		mgr.GetWebhookServer().Register(path, &crwebhook.Admission{
+			Handler:         &admissionHandler{runner: r, mutating: true},
+			WithContextFunc: withRequestTimeout,
+		})
+		log.Info("Serving mutating webhook", "GVK", w.GroupVersionKind.String(), "path", path)
+	}
+	return nil
+}
+
+// ValidatePath returns the path on which the validating webhook of a GVK is served.
+func ValidatePath(gvk schema.GroupVersionKind) string {
+	return generatePath("validate", gvk)
+}
+
+// MutatePath returns the path on which the mutating webhook of a GVK is served.
+func MutatePath(gvk schema.GroupVersionKind) string {
+	return generatePath("mutate", gvk)
+}
+
+// generatePath follows the path convention of Go operators, so that webhook
+// configurations look the same for both.
+func generatePath(prefix string, gvk schema.GroupVersionKind) string {
+	return fmt.Sprintf("/%s-%s-%s-%s", prefix, strings.ReplaceAll(gvk.Group, ".", "-"), gvk.Version,
+		strings.ToLower(gvk.Kind))
+}
+
+type requestTimeoutKey struct{}
+
+// withRequestTimeout returns ctx with the timeout of the admission request r, which the
+// API server sends as the timeout query parameter of the webhook URL.
+func withRequestTimeout(ctx context.Context, r *http.Request) context.Context {
+	timeout, err := time.ParseDuration(r.URL.Query().Get("timeout"))
+	if err != nil || timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	if timeout > maxRequestTimeout {
+		timeout = maxRequestTimeout
+	}
+	return context.WithValue(ctx, requestTimeoutKey{}, timeout)
+}
+
+// requestTimeout returns the timeout of the admission request handled with ctx.
+func requestTimeout(ctx context.Context) time.Duration {
+	if timeout, ok := ctx.Value(requestTimeoutKey{}).(time.Duration); ok {
+		return timeout
+	}
+	return defaultRequestTimeout
+}
+
+// admissionHandler - runs a playbook or role for each admission request. A failed task
+// denies the request. When mutating, a JSONPatch returned with set_stats is applied.
+type admissionHandler struct {
+	runner   runner.Runner
+	mutating bool
+}
+
+// Handle - handle the admission request. The run is stopped once 90% of the timeout of the
+// request passed, so that the API server still gets a response.
+func (h *admissionHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	ident := strconv.Itoa(rand.Int())
+	logger := log.WithValues(
+		"job", ident,
+		"uid", req.UID,
+		"name", req.Name,
+		"namespace", req.Namespace,
+		"operation", req.Operation,
+	)
+
+	// The object being deleted is only sent as the old object.
+	raw := req.Object.Raw
+	if req.Operation == admissionv1.Delete {
+		raw = req.OldObject.Raw
+	}
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON(raw); err != nil {
+		logger.Error(err, "Unable to decode admission request object")
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	reqVars, err := admissionRequestVars(req)
+	if err != nil {
+		logger.Error(err, "Unable to convert admission request to extra vars")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	// Without a kubeconfig, the run talks to the API server with the operator's own
+	// credentials instead of through the proxy, so nothing it creates gets an owner.
+	timeout := requestTimeout(ctx)
+	runCtx, cancel := context.WithTimeout(ctx, timeout-timeout/10)
+	defer cancel()
+	result, err := h.runner.Run(ident, u, "", runner.WithExtraVars(map[string]interface{}{
+		AdmissionRequestVar: reqVars,
+	}), runner.WithContext(runCtx))
+	if err != nil {
+		logger.Error(err, "Unable to run ansible runner")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	statusEvent := eventapi.StatusJobEvent{}
+	failureMessages := eventapi.FailureMessages{}
+	for event := range result.Events() {
+		if event.Event == eventapi.EventPlaybookOnStats {
+			// convert to StatusJobEvent; would love a better way to do this
+			data, err := json.Marshal(event)
+			if err != nil {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			if err := json.Unmarshal(data, &statusEvent); err != nil {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+		}
+		if event.Event == eventapi.EventRunnerOnFailed && !event.IgnoreError() && !event.Rescued() {
+			failureMessages = append(failureMessages, event.GetFailedPlaybookMessage())
+		}
+	}
+
+	if statusEvent.Event == "" {
+		if runCtx.Err() != nil {
+			err := fmt.Errorf("run did not complete within the %s timeout of the request: %w", timeout, runCtx.Err())
+			logger.Error(err, "Stopped run")
+			return admission.Errored(http.StatusGatewayTimeout, err)
+		}
+		err := errors.New("did not receive playbook_on_stats event")
+		if stdout, serr := result.Stdout(); serr == nil {
+			logger.Error(err, stdout)
+		} else {
+			logger.Error(err, "Failed to get ansible-runner stdout")
+		}
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(failureMessages) > 0 {
+		logger.Info("Denied admission request", "reasons", failureMessages)
+		return admission.Denied(strings.Join(failureMessages, "; "))
+	}
+	if !h.mutating {
+		return admission.Allowed("")
+	}
+	return patchResponse(statusEvent.EventData.ArtifactData[PatchStatsKey])
+}
+
+// admissionRequestVars converts an admission request to the form it is sent in by the
+// API server, so that playbooks can address its fields by their usual names.
+func admissionRequestVars(req admission.Request) (map[string]interface{}, error) {
+	data, err := json.Marshal(req.AdmissionRequest)
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]interface{}{}
+	if err := json.Unmarshal(data, &vars); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// patchResponse returns a response allowing the request and applying patch, which must
+// be nil or a list of JSONPatch operations.
+func patchResponse(patch interface{}) admission.Response {
+	if patch == nil {
+		return admission.Allowed("")
+	}
+	ops, ok := patch.([]interface{})
+	if !ok {
+		err := fmt.Errorf("%s must be a list of JSONPatch operations, got %T", PatchStatsKey, patch)
+		log.Error(err, "Invalid patch returned by mutating webhook")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(ops) == 0 {
+		return admission.Allowed("")
+	}
+	data, err := json.Marshal(ops)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	resp := admission.Allowed("")
+	patchType := admissionv1.PatchTypeJSONPatch
+	resp.Patch = data
+	resp.PatchType = &patchType
+	return resp
+}
@@ -0,0 +1,210 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/eventapi"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/fake"
+)
+
+func TestGeneratePaths(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "cache.example.com", Version: "v1alpha1", Kind: "Memcached"}
+	if got := ValidatePath(gvk); got != "/validate-cache-example-com-v1alpha1-memcached" {
+		t.Fatalf("Unexpected validate path %s", got)
+	}
+	if got := MutatePath(gvk); got != "/mutate-cache-example-com-v1alpha1-memcached" {
+		t.Fatalf("Unexpected mutate path %s", got)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	statsEvent := func(artifactData map[string]interface{}) eventapi.JobEvent {
+		return eventapi.JobEvent{
+			Event: eventapi.EventPlaybookOnStats,
+			EventData: map[string]interface{}{
+				"artifact_data": artifactData,
+			},
+		}
+	}
+	patch := []interface{}{
+		map[string]interface{}{"op": "add", "path": "/spec/size", "value": float64(3)},
+	}
+
+	testCases := []struct {
+		name            string
+		mutating        bool
+		operation       admissionv1.Operation
+		timeout         string
+		runner          *fake.Runner
+		expectedAllowed bool
+		expectedCode    int32
+		expectedReason  string
+		expectedPatch   string
+	}{
+		{
+			name:            "validating webhook allows a successful run",
+			runner:          &fake.Runner{JobEvents: []eventapi.JobEvent{statsEvent(nil)}},
+			expectedAllowed: true,
+			expectedCode:    200,
+		},
+		{
+			name:            "validating webhook allows deletes",
+			operation:       admissionv1.Delete,
+			runner:          &fake.Runner{JobEvents: []eventapi.JobEvent{statsEvent(nil)}},
+			expectedAllowed: true,
+			expectedCode:    200,
+		},
+		{
+			name: "failed task denies the request",
+			runner: &fake.Runner{JobEvents: []eventapi.JobEvent{
+				{
+					Event: eventapi.EventRunnerOnFailed,
+					EventData: map[string]interface{}{
+						"res": map[string]interface{}{"msg": "size must be odd"},
+					},
+				},
+				statsEvent(nil),
+			}},
+			expectedCode:   403,
+			expectedReason: "size must be odd",
+		},
+		{
+			name: "ignored failed task allows the request",
+			runner: &fake.Runner{JobEvents: []eventapi.JobEvent{
+				{
+					Event: eventapi.EventRunnerOnFailed,
+					EventData: map[string]interface{}{
+						"ignore_errors": true,
+					},
+				},
+				statsEvent(nil),
+			}},
+			expectedAllowed: true,
+			expectedCode:    200,
+		},
+		{
+			name:            "validating webhook ignores a returned patch",
+			runner:          &fake.Runner{JobEvents: []eventapi.JobEvent{statsEvent(map[string]interface{}{"patch": patch})}},
+			expectedAllowed: true,
+			expectedCode:    200,
+		},
+		{
+			name:            "mutating webhook applies a returned patch",
+			mutating:        true,
+			runner:          &fake.Runner{JobEvents: []eventapi.JobEvent{statsEvent(map[string]interface{}{"patch": patch})}},
+			expectedAllowed: true,
+			expectedCode:    200,
+			expectedPatch:   `[{"op":"add","path":"/spec/size","value":3}]`,
+		},
+		{
+			name:            "mutating webhook without a patch allows the request",
+			mutating:        true,
+			runner:          &fake.Runner{JobEvents: []eventapi.JobEvent{statsEvent(nil)}},
+			expectedAllowed: true,
+			expectedCode:    200,
+		},
+		{
+			name:         "mutating webhook errors on an invalid patch",
+			mutating:     true,
+			runner:       &fake.Runner{JobEvents: []eventapi.JobEvent{statsEvent(map[string]interface{}{"patch": "invalid"})}},
+			expectedCode: 500,
+		},
+		{
+			name:         "run without stats event errors",
+			runner:       &fake.Runner{Stdout: "ansible-runner failed"},
+			expectedCode: 500,
+		},
+		{
+			name:         "run stopped at the deadline of the request errors",
+			timeout:      "1ns",
+			runner:       &fake.Runner{Stdout: "ansible-runner killed"},
+			expectedCode: 504,
+		},
+		{
+			name:         "runner error errors",
+			runner:       &fake.Runner{Error: errors.New("ansible-runner not found")},
+			expectedCode: 500,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := runtime.RawExtension{
+				Raw: []byte(`{"apiVersion":"cache.example.com/v1alpha1","kind":"Memcached",` +
+					`"metadata":{"name":"test","namespace":"default"},"spec":{"size":1}}`),
+			}
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				UID:       "test-uid",
+				Name:      "test",
+				Namespace: "default",
+				Operation: admissionv1.Create,
+				Object:    obj,
+			}}
+			if tc.operation != "" {
+				req.Operation = tc.operation
+				req.Object = runtime.RawExtension{}
+				req.OldObject = obj
+			}
+
+			h := &admissionHandler{runner: tc.runner, mutating: tc.mutating}
+			ctx := withRequestTimeout(context.TODO(), httptest.NewRequest("POST", "/validate?timeout="+tc.timeout, nil))
+			resp := h.Handle(ctx, req)
+			if resp.Allowed != tc.expectedAllowed {
+				t.Fatalf("Unexpected allowed %v expected allowed %v: %+v", resp.Allowed, tc.expectedAllowed, resp.Result)
+			}
+			if resp.Result.Code != tc.expectedCode {
+				t.Fatalf("Unexpected code %d expected code %d", resp.Result.Code, tc.expectedCode)
+			}
+			if tc.expectedReason != "" && string(resp.Result.Reason) != tc.expectedReason {
+				t.Fatalf("Unexpected reason %q expected reason %q", resp.Result.Reason, tc.expectedReason)
+			}
+			if string(resp.Patch) != tc.expectedPatch {
+				t.Fatalf("Unexpected patch %s expected patch %s", resp.Patch, tc.expectedPatch)
+			}
+			if tc.expectedPatch != "" && (resp.PatchType == nil || *resp.PatchType != admissionv1.PatchTypeJSONPatch) {
+				t.Fatalf("Unexpected patch type %v", resp.PatchType)
+			}
+		})
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected time.Duration
+	}{
+		{query: "timeout=5s", expected: 5 * time.Second},
+		{query: "", expected: defaultRequestTimeout},
+		{query: "timeout=invalid", expected: defaultRequestTimeout},
+		{query: "timeout=1m", expected: maxRequestTimeout},
+	}
+	for _, tc := range testCases {
+		ctx := withRequestTimeout(context.TODO(), httptest.NewRequest("POST", "/validate?"+tc.query, nil))
+		if timeout := requestTimeout(ctx); timeout != tc.expected {
+			t.Fatalf("Unexpected timeout %s for query %q expected %s", timeout, tc.query, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cachebuilder
+
+import (
+	"context"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// MultiNamespacedCacheBuilder returns a builder of caches of the namespaced objects in
+// namespaces, as cache.MultiNamespacedCacheBuilder, that also cache cluster-scoped objects,
+// e.g. the ClusterRoles of a Helm release. The caches of cache.MultiNamespacedCacheBuilder
+// cannot get, list or watch them, since they only have caches of namespaces.
+func MultiNamespacedCacheBuilder(namespaces []string) cache.NewCacheFunc {
+	return func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		if opts.Scheme == nil {
+			opts.Scheme = scheme.Scheme
+		}
+		if opts.Mapper == nil {
+			var err error
+			if opts.Mapper, err = apiutil.NewDynamicRESTMapper(config); err != nil {
+				return nil, err
+			}
+		}
+		namespaced, err := cache.MultiNamespacedCacheBuilder(namespaces)(config, opts)
+		if err != nil {
+			return nil, err
+		}
+		// Informers are only started for the kinds that are used, so the cluster cache is
+		// empty unless cluster-scoped objects are read or watched.
+		clusterOpts := opts
+		clusterOpts.Namespace = metav1.NamespaceAll
+		cluster, err := cache.New(config, clusterOpts)
+		if err != nil {
+			return nil, err
+		}
+		return &multiScopeCache{namespaced: namespaced, cluster: cluster, scheme: opts.Scheme, mapper: opts.Mapper}, nil
+	}
+}
+
+// multiScopeCache caches namespaced objects in a cache of namespaces, and cluster-scoped
+// objects in a cache of the cluster.
+type multiScopeCache struct {
+	namespaced cache.Cache
+	cluster    cache.Cache
+	scheme     *runtime.Scheme
+	mapper     meta.RESTMapper
+}
+
+var _ cache.Cache = &multiScopeCache{}
+
+// cacheFor returns the cache of the objects of gvk.
+func (c *multiScopeCache) cacheFor(gvk schema.GroupVersionKind) (cache.Cache, error) {
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		return c.cluster, nil
+	}
+	return c.namespaced, nil
+}
+
+// cacheForObject returns the cache of obj, or of the items of obj if it is a list.
+func (c *multiScopeCache) cacheForObject(obj runtime.Object) (cache.Cache, error) {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return nil, err
+	}
+	if meta.IsListType(obj) {
+		gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+	}
+	return c.cacheFor(gvk)
+}
+
+func (c *multiScopeCache) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	objCache, err := c.cacheForObject(obj)
+	if err != nil {
+		return err
+	}
+	return objCache.Get(ctx, key, obj)
+}
+
+func (c *multiScopeCache) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listCache, err := c.cacheForObject(list)
+	if err != nil {
+		return err
+	}
+	return listCache.List(ctx, list, opts...)
+}
+
+func (c *multiScopeCache) GetInformer(ctx context.Context, obj client.Object) (cache.Informer, error) {
+	objCache, err := c.cacheForObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	return objCache.GetInformer(ctx, obj)
+}
+
+func (c *multiScopeCache) GetInformerForKind(ctx context.Context, gvk schema.GroupVersionKind) (cache.Informer, error) {
+	kindCache, err := c.cacheFor(gvk)
+	if err != nil {
+		return nil, err
+	}
+	return kindCache.GetInformerForKind(ctx, gvk)
+}
+
+func (c *multiScopeCache) IndexField(ctx context.Context, obj client.Object, field string,
+	extractValue client.IndexerFunc) error {
+
+	objCache, err := c.cacheForObject(obj)
+	if err != nil {
+		return err
+	}
+	return objCache.IndexField(ctx, obj, field, extractValue)
+}
+
+// Start runs the informers of both caches until ctx is done.
+func (c *multiScopeCache) Start(ctx context.Context) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- c.cluster.Start(ctx)
+	}()
+	if err := c.namespaced.Start(ctx); err != nil {
+		return err
+	}
+	return <-errs
+}
+
+func (c *multiScopeCache) WaitForCacheSync(ctx context.Context) bool {
+	return c.namespaced.WaitForCacheSync(ctx) && c.cluster.WaitForCacheSync(ctx)
+}
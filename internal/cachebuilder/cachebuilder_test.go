@@ -0,0 +1,84 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cachebuilder
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fakeCache records the kinds of the objects that it is called with.
+type fakeCache struct {
+	cache.Cache
+	kinds []string
+}
+
+func (c *fakeCache) Get(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+	c.kinds = append(c.kinds, obj.GetObjectKind().GroupVersionKind().Kind)
+	return nil
+}
+
+func (c *fakeCache) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	c.kinds = append(c.kinds, list.GetObjectKind().GroupVersionKind().Kind)
+	return nil
+}
+
+func (c *fakeCache) GetInformerForKind(_ context.Context, gvk schema.GroupVersionKind) (cache.Informer, error) {
+	c.kinds = append(c.kinds, gvk.Kind)
+	return nil, nil
+}
+
+func TestMultiScopeCache(t *testing.T) {
+	configMap := corev1.SchemeGroupVersion.WithKind("ConfigMap")
+	clusterRole := rbacv1.SchemeGroupVersion.WithKind("ClusterRole")
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configMap, meta.RESTScopeNamespace)
+	mapper.Add(clusterRole, meta.RESTScopeRoot)
+
+	namespaced, cluster := &fakeCache{}, &fakeCache{}
+	c := &multiScopeCache{namespaced: namespaced, cluster: cluster, scheme: scheme.Scheme, mapper: mapper}
+	ctx := context.TODO()
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(clusterRole)
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Name: "admin"}, u))
+	ul := &unstructured.UnstructuredList{}
+	ul.SetGroupVersionKind(configMap.GroupVersion().WithKind("ConfigMapList"))
+	require.NoError(t, c.List(ctx, ul))
+	m := &metav1.PartialObjectMetadata{}
+	m.SetGroupVersionKind(configMap)
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "ns", Name: "values"}, m))
+	_, err := c.GetInformerForKind(ctx, clusterRole)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"ConfigMapList", "ConfigMap"}, namespaced.kinds)
+	assert.Equal(t, []string{"ClusterRole", "ClusterRole"}, cluster.kinds)
+
+	unknown := &unstructured.Unstructured{}
+	unknown.SetGroupVersionKind(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Unknown"})
+	assert.Error(t, c.Get(ctx, client.ObjectKey{Name: "unknown"}, unknown))
+}
@@ -23,9 +23,13 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
@@ -35,14 +39,19 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
 
 	"github.com/operator-framework/operator-sdk/internal/ansible/controller"
 	"github.com/operator-framework/operator-sdk/internal/ansible/flags"
 	"github.com/operator-framework/operator-sdk/internal/ansible/metrics"
 	"github.com/operator-framework/operator-sdk/internal/ansible/proxy"
 	"github.com/operator-framework/operator-sdk/internal/ansible/proxy/controllermap"
+	"github.com/operator-framework/operator-sdk/internal/ansible/proxy/kubeconfig"
+	"github.com/operator-framework/operator-sdk/internal/ansible/requirements"
 	"github.com/operator-framework/operator-sdk/internal/ansible/runner"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/artifacts"
 	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
+	"github.com/operator-framework/operator-sdk/internal/ansible/webhook"
 	"github.com/operator-framework/operator-sdk/internal/clientbuilder"
 	"github.com/operator-framework/operator-sdk/internal/util/k8sutil"
 	sdkVersion "github.com/operator-framework/operator-sdk/internal/version"
@@ -96,7 +105,6 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 			os.Exit(1)
 		}
 	}
-	exitIfUnsupported(options)
 
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -177,6 +185,12 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 		os.Exit(1)
 	}
 
+	// Requirements are checked before the watches are loaded, which look up installed roles.
+	if err := checkAnsibleRequirements(f); err != nil {
+		log.Error(err, "Ansible requirements are not satisfied.")
+		os.Exit(1)
+	}
+
 	// Create a new manager to provide shared dependencies and start components
 	mgr, err := manager.New(cfg, options)
 	if err != nil {
@@ -199,32 +213,67 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 		log.Error(err, "Failed to load watches.")
 		os.Exit(1)
 	}
+	// Runs still reach the proxy on localhost:8888 with a socket, which must not be
+	// usable without a token.
+	var proxyTokens *kubeconfig.Tokens
+	if f.ProxyTokenAuth || f.ProxyUnixSocket != "" {
+		proxyTokens = kubeconfig.NewTokens(f.ProxyTokenTTL)
+	}
+	exporter, err := newArtifactExporter(f)
+	if err != nil {
+		log.Error(err, "Failed to configure artifact sink.")
+		os.Exit(1)
+	}
 	for _, w := range watches {
-		runner, err := runner.New(w, f.AnsibleArgs)
+		runner, err := runner.New(w, f.AnsibleArgs, exporter, mgr.GetAPIReader())
 		if err != nil {
 			log.Error(err, "Failed to create runner")
 			os.Exit(1)
 		}
 
+		var finalizerTimeout time.Duration
+		var finalizerMaxAttempts int
+		if w.Finalizer != nil {
+			finalizerTimeout = w.Finalizer.Timeout
+			finalizerMaxAttempts = w.Finalizer.MaxAttempts
+		}
 		ctr := controller.Add(mgr, controller.Options{
 			GVK:                     w.GroupVersionKind,
 			Runner:                  runner,
 			ManageStatus:            w.ManageStatus,
 			AnsibleDebugLogs:        getAnsibleDebugLog(),
+			DryRun:                  w.DryRun,
+			ApplySchema:             w.ApplySchema,
+			DriftDetection:          w.DriftDetection,
+			RecordAppliedGeneration: len(w.Routes) > 0,
 			MaxConcurrentReconciles: w.MaxConcurrentReconciles,
+			RateLimiter:             newRateLimiter(w.RateLimiter),
+			SerializeByNamespace:    w.SerializeByNamespace,
 			ReconcilePeriod:         w.ReconcilePeriod,
 			Selector:                w.Selector,
+			ProxyTokens:             proxyTokens,
+			FinalizerTimeout:        finalizerTimeout,
+			FinalizerMaxAttempts:    finalizerMaxAttempts,
 		})
 		if ctr == nil {
 			log.Error(fmt.Errorf("failed to add controller for GVK %v", w.GroupVersionKind.String()), "")
 			os.Exit(1)
 		}
 
+		if err := webhook.Add(mgr, w, f.AnsibleArgs); err != nil {
+			log.Error(err, "Failed to add webhooks", "GVK", w.GroupVersionKind.String())
+			os.Exit(1)
+		}
+
+		whitelist, annotationEnqueue := dependentWatchMaps(w.DependentWatches)
 		cMap.Store(w.GroupVersionKind, &controllermap.Contents{Controller: *ctr, //nolint:staticcheck
 			WatchDependentResources:     w.WatchDependentResources,
 			WatchClusterScopedResources: w.WatchClusterScopedResources,
 			OwnerWatchMap:               controllermap.NewWatchMap(),
 			AnnotationWatchMap:          controllermap.NewWatchMap(),
+			Whitelist:                   whitelist,
+			AnnotationEnqueue:           annotationEnqueue,
+			APIPolicy:                   w.APIPolicy,
 		}, w.Blacklist)
 	}
 
@@ -234,10 +283,10 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 		log.Error(err, "Failed to add Healthz check.")
 	}
 
-	done := make(chan error)
+	proxyDone := make(chan error, 1)
 
 	// start the proxy
-	err = proxy.Run(done, proxy.Options{
+	err = proxy.Run(proxyDone, proxy.Options{
 		Address:           "localhost",
 		Port:              8888,
 		KubeConfig:        mgr.GetConfig(),
@@ -245,6 +294,10 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 		RESTMapper:        mgr.GetRESTMapper(),
 		ControllerMap:     cMap,
 		OwnerInjection:    f.InjectOwnerRef,
+		AuditLog:          f.ProxyAuditLog,
+		Tokens:            proxyTokens,
+		UnixSocket:        f.ProxyUnixSocket,
+		MaxLazyCaches:     f.ProxyMaxLazyCaches,
 		WatchedNamespaces: strings.Split(namespace, ","),
 	})
 	if err != nil {
@@ -253,40 +306,29 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 	}
 
 	// start the operator
+	mgrDone := make(chan error, 1)
 	go func() {
-		done <- mgr.Start(signals.SetupSignalHandler())
+		mgrDone <- mgr.Start(signals.SetupSignalHandler())
 	}()
 
 	// wait for either to finish
-	err = <-done
-	if err != nil {
-		log.Error(err, "Proxy or operator exited with error.")
+	select {
+	case err = <-proxyDone:
+		// The proxy only stops serving on error, and runs cannot go on without it.
+		if err == nil {
+			err = errors.New("proxy stopped serving")
+		}
+		log.Error(err, "Proxy exited with error.")
 		os.Exit(1)
+	case err = <-mgrDone:
+		if err != nil {
+			log.Error(err, "Operator exited with error.")
+			os.Exit(1)
+		}
 	}
 	log.Info("Exiting.")
 }
 
-// exitIfUnsupported prints an error containing unsupported field names and exits
-// if any of those fields are not their default values.
-func exitIfUnsupported(options manager.Options) {
-	var keys []string
-	// The below options are webhook-specific, which is not supported by ansible.
-	if options.CertDir != "" {
-		keys = append(keys, "certDir")
-	}
-	if options.Host != "" {
-		keys = append(keys, "host")
-	}
-	if options.Port != 0 {
-		keys = append(keys, "port")
-	}
-
-	if len(keys) > 0 {
-		log.Error(fmt.Errorf("%s set in manager options", strings.Join(keys, ", ")), "unsupported fields")
-		os.Exit(1)
-	}
-}
-
 // getAnsibleDebugLog return the value from the ANSIBLE_DEBUG_LOGS it order to
 // print the full Ansible logs
 func getAnsibleDebugLog() bool {
@@ -306,6 +348,57 @@ func getAnsibleDebugLog() bool {
 	return val
 }
 
+// newRateLimiter returns a rate limiter that backs off failed reconciles as configured
+// by the watch. nil is returned when the watch doesn't configure one, so the
+// controller's default rate limiter is used.
+func newRateLimiter(rl *watches.RateLimiter) ratelimiter.RateLimiter {
+	if rl == nil {
+		return nil
+	}
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(rl.BaseDelay, rl.MaxDelay),
+		// 10 qps, 100 bucket size. This is the overall limit of the controller's default rate limiter.
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
+}
+
+// dependentWatchMaps converts a watch's dependent watches into the lookup maps used
+// by the proxy: the GVKs allowed to be watched, and those enqueued by annotation.
+func dependentWatchMaps(dws []watches.DependentWatch) (whitelist, annotationEnqueue map[schema.GroupVersionKind]bool) {
+	whitelist = map[schema.GroupVersionKind]bool{}
+	annotationEnqueue = map[schema.GroupVersionKind]bool{}
+	for _, dw := range dws {
+		whitelist[dw.GroupVersionKind] = true
+		if dw.EnqueueBy == watches.EnqueueByAnnotation {
+			annotationEnqueue[dw.GroupVersionKind] = true
+		}
+	}
+	return whitelist, annotationEnqueue
+}
+
+// newArtifactExporter returns the exporter of runner artifacts configured by the
+// artifact sink flags, or nil if no sink is set.
+func newArtifactExporter(f *flags.Flags) (*artifacts.Exporter, error) {
+	if f.ArtifactSink == "" {
+		return nil, nil
+	}
+	store, err := artifacts.NewStore(f.ArtifactSink, artifacts.S3Options{
+		Endpoint:        f.ArtifactSinkEndpoint,
+		Region:          f.ArtifactSinkRegion,
+		AccessKeyID:     os.Getenv(artifacts.AccessKeyIDEnvVar),
+		SecretAccessKey: os.Getenv(artifacts.SecretAccessKeyEnvVar),
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Exporting runner artifacts", "sink", f.ArtifactSink)
+	return &artifacts.Exporter{
+		Store:   store,
+		MaxRuns: f.ArtifactSinkMaxRuns,
+		MaxAge:  f.ArtifactSinkMaxAge,
+	}, nil
+}
+
 // setAnsibleEnvVars will set environment variables based on CLI flags
 func setAnsibleEnvVars(f *flags.Flags) error {
 	if len(f.AnsibleRolesPath) > 0 {
@@ -325,3 +418,29 @@ func setAnsibleEnvVars(f *flags.Flags) error {
 	}
 	return nil
 }
+
+// checkAnsibleRequirements verifies that the collections and roles of the requirements file
+// are installed, after installing those that are missing from the requirements cache, if set.
+func checkAnsibleRequirements(f *flags.Flags) error {
+	if f.AnsibleRequirements == "" {
+		if f.AnsibleRequirementsCache != "" {
+			return errors.New("--ansible-requirements-cache requires --ansible-requirements")
+		}
+		return nil
+	}
+	reqs, err := requirements.Load(f.AnsibleRequirements)
+	if err != nil {
+		return err
+	}
+	paths := requirements.PathsFromEnv()
+	if f.AnsibleRequirementsCache != "" {
+		if err := reqs.Install(paths, f.AnsibleRequirementsCache); err != nil {
+			return err
+		}
+	}
+	if err := reqs.Verify(paths); err != nil {
+		return err
+	}
+	log.Info("Verified the Ansible requirements", "path", f.AnsibleRequirements)
+	return nil
+}
@@ -15,17 +15,18 @@
 package run
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -34,7 +35,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
+	"github.com/operator-framework/operator-sdk/internal/cachebuilder"
 	"github.com/operator-framework/operator-sdk/internal/clientbuilder"
+	"github.com/operator-framework/operator-sdk/internal/helm/chartsource"
 	"github.com/operator-framework/operator-sdk/internal/helm/controller"
 	"github.com/operator-framework/operator-sdk/internal/helm/flags"
 	"github.com/operator-framework/operator-sdk/internal/helm/metrics"
@@ -46,6 +49,9 @@ import (
 
 var log = logf.Log.WithName("cmd")
 
+// chartPullTimeout is the timeout of each request made to pull a chart.
+const chartPullTimeout = time.Minute
+
 func printVersion() {
 	log.Info("Version",
 		"Go Version", runtime.Version(),
@@ -132,6 +138,8 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 		options.ClientBuilder = clientbuilder.NewUnstructedCached()
 	}
 
+	// The watched namespaces, or nil if all namespaces are watched.
+	var watchedNamespaces []string
 	namespace, found := os.LookupEnv(k8sutil.WatchNamespaceEnvVar)
 	log = log.WithValues("Namespace", namespace)
 	if found {
@@ -140,9 +148,10 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 			log.Info("Watching all namespaces.")
 			options.Namespace = metav1.NamespaceAll
 		} else {
+			watchedNamespaces = strings.Split(namespace, ",")
 			if strings.Contains(namespace, ",") {
 				log.Info("Watching multiple namespaces.")
-				options.NewCache = cache.MultiNamespacedCacheBuilder(strings.Split(namespace, ","))
+				options.NewCache = cachebuilder.MultiNamespacedCacheBuilder(watchedNamespaces)
 			} else {
 				log.Info("Watching single namespace.")
 				options.Namespace = namespace
@@ -152,6 +161,22 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 		log.Info(fmt.Sprintf("Watch namespaces not configured by environment variable %s or file. "+
 			"Watching all namespaces.", k8sutil.WatchNamespaceEnvVar))
 		options.Namespace = metav1.NamespaceAll
+	} else {
+		watchedNamespaces = []string{options.Namespace}
+	}
+
+	ws, err := watches.Load(f.WatchesFile)
+	if err != nil {
+		log.Error(err, "Failed to create new manager factories.")
+		os.Exit(1)
+	}
+	// The namespaces of watches override the watched namespaces, so they are cached too.
+	if watchedNamespaces != nil {
+		if cacheNamespaces := addWatchNamespaces(watchedNamespaces, ws); len(cacheNamespaces) > len(watchedNamespaces) {
+			log.Info("Watching additional namespaces of watches.", "namespaces", cacheNamespaces)
+			options.Namespace = metav1.NamespaceAll
+			options.NewCache = cachebuilder.MultiNamespacedCacheBuilder(cacheNamespaces)
+		}
 	}
 
 	mgr, err := manager.New(cfg, options)
@@ -169,21 +194,94 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 		os.Exit(1)
 	}
 
-	ws, err := watches.Load(f.WatchesFile)
-	if err != nil {
-		log.Error(err, "Failed to create new manager factories.")
-		os.Exit(1)
+	puller := chartsource.Puller{
+		Timeout:  chartPullTimeout,
+		CacheDir: f.ChartCacheDir,
 	}
 	for _, w := range ws {
+		chartPath := w.ChartDir
+		var refresher *chartsource.Refresher
+		if w.IsRemoteChart() {
+			src, err := chartsource.ForWatch(w)
+			if err != nil {
+				log.Error(err, "Failed to pull chart.", "GVK", w.GroupVersionKind)
+				os.Exit(1)
+			}
+			if chartPath, err = puller.Pull(context.TODO(), src); err != nil {
+				log.Error(err, "Failed to pull chart.", "GVK", w.GroupVersionKind)
+				os.Exit(1)
+			}
+			if w.ChartPull != nil && w.ChartPull.Interval != nil {
+				refresher = chartsource.NewRefresher(puller, src, w.ChartPull.Interval.Duration, chartPath)
+				if err := mgr.Add(refresher); err != nil {
+					log.Error(err, "Failed to add chart refresher.", "GVK", w.GroupVersionKind)
+					os.Exit(1)
+				}
+			}
+		}
+		valuesFrom := &release.ValuesFrom{
+			Reader:      mgr.GetAPIReader(),
+			Refs:        w.ValuesFrom,
+			AllowCRRefs: w.AllowCRValuesFrom,
+		}
+		var timeout time.Duration
+		if w.Timeout != nil {
+			timeout = w.Timeout.Duration
+		}
+		factoryOpts := []release.ManagerFactoryOption{release.WithValuesFrom(*valuesFrom)}
+		if refresher != nil {
+			factoryOpts = append(factoryOpts, release.WithChartPath(refresher.Path))
+		}
+		if w.Drift != nil {
+			factoryOpts = append(factoryOpts, release.WithDrift(*w.Drift))
+		}
+		if len(w.IgnoreDifferences) > 0 {
+			factoryOpts = append(factoryOpts, release.WithIgnoreDifferences(w.IgnoreDifferences))
+		}
+		if w.ServerSideApply {
+			factoryOpts = append(factoryOpts, release.WithServerSideApply(true))
+		}
+		if w.ReleaseName != "" {
+			factoryOpts = append(factoryOpts, release.WithReleaseNameTemplate(w.ReleaseName))
+		}
+		if w.PostRender != nil {
+			postRenderer, err := release.NewPostRenderer(*w.PostRender)
+			if err != nil {
+				log.Error(err, "Failed to create post-renderer.", "GVK", w.GroupVersionKind)
+				os.Exit(1)
+			}
+			factoryOpts = append(factoryOpts, release.WithPostRenderer(postRenderer))
+		}
+		namespaces := w.Namespaces
+		if len(namespaces) == 0 {
+			namespaces = watchedNamespaces
+		}
 		// Register the controller with the factory.
 		err := controller.Add(mgr, controller.WatchOptions{
 			Namespace:               namespace,
 			GVK:                     w.GroupVersionKind,
-			ManagerFactory:          release.NewManagerFactory(mgr, w.ChartDir),
+			ManagerFactory:          release.NewManagerFactory(mgr, chartPath, factoryOpts...),
 			ReconcilePeriod:         f.ReconcilePeriod,
 			WatchDependentResources: *w.WatchDependentResources,
 			OverrideValues:          w.OverrideValues,
 			MaxConcurrentReconciles: f.MaxConcurrentReconciles,
+			ValuesFrom:              valuesFrom,
+			InstallOptions: []release.InstallOption{
+				release.AtomicInstall(w.Atomic), release.WaitInstall(w.Wait), release.TimeoutInstall(timeout),
+			},
+			UpgradeOptions: []release.UpgradeOption{
+				release.AtomicUpgrade(w.Atomic), release.WaitUpgrade(w.Wait), release.TimeoutUpgrade(timeout),
+			},
+			RollbackOptions: []release.RollbackOption{
+				release.WaitRollback(w.Wait || w.Atomic), release.TimeoutRollback(timeout),
+			},
+			Remediation: w.Remediation,
+			Readiness:   w.Readiness,
+			Drift:       w.Drift,
+			Selector:    w.Selector,
+			Namespaces:  namespaces,
+
+			UninstallUnselected: w.UninstallUnselected,
 		})
 		if err != nil {
 			log.Error(err, "Failed to add manager factory to controller.")
@@ -218,3 +316,22 @@ func exitIfUnsupported(options manager.Options) {
 		os.Exit(1)
 	}
 }
+
+// addWatchNamespaces returns namespaces, followed by the namespaces of ws that it does not contain.
+func addWatchNamespaces(namespaces []string, ws []watches.Watch) []string {
+	seen := map[string]struct{}{}
+	out := []string{}
+	for _, ns := range namespaces {
+		seen[ns] = struct{}{}
+		out = append(out, ns)
+	}
+	for _, w := range ws {
+		for _, ns := range w.Namespaces {
+			if _, ok := seen[ns]; !ok {
+				seen[ns] = struct{}{}
+				out = append(out, ns)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,656 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package chartsource pulls the charts of watches from chart repositories and OCI
+// registries into a local cache.
+package chartsource
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/repo"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+var log = logf.Log.WithName("chartsource")
+
+const (
+	archiveExt = ".tgz"
+
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+)
+
+// chartLayerMediaTypes are the media types of the layer of a chart in an OCI registry,
+// the first used by Helm before 3.7 and the second since.
+var chartLayerMediaTypes = []string{
+	"application/tar+gzip",
+	"application/vnd.cncf.helm.chart.content.v1.tar+gzip",
+}
+
+// Source is a chart in a chart repository or an OCI registry.
+type Source struct {
+	// Chart is the name of a chart in Repository, or the oci:// reference of a chart.
+	Chart      string
+	Repository string
+	// Version is a version or a semver range. The newest stable version when empty.
+	Version string
+	// Digest is the "sha256:" digest the chart archive must have, if not empty.
+	Digest string
+
+	// Username and Password are the basic auth credentials of the repository or registry.
+	Username string
+	Password string
+	// CertFile and KeyFile are the client certificate and key presented to the repository
+	// or registry, and CAFile the certificate authorities that its certificate is verified with.
+	CertFile              string
+	KeyFile               string
+	CAFile                string
+	InsecureSkipTLSVerify bool
+}
+
+// ForWatch returns the source of the chart of w, which must be a remote chart, with the
+// credentials read from the files of its chartPull options.
+func ForWatch(w watches.Watch) (Source, error) {
+	src := Source{Chart: w.ChartDir, Repository: w.ChartRepository, Version: w.ChartVersion, Digest: w.ChartDigest}
+	if w.ChartPull == nil {
+		return src, nil
+	}
+	src.CertFile = w.ChartPull.CertFile
+	src.KeyFile = w.ChartPull.KeyFile
+	src.CAFile = w.ChartPull.CAFile
+	src.InsecureSkipTLSVerify = w.ChartPull.InsecureSkipTLSVerify
+	for _, f := range []struct {
+		path  string
+		value *string
+	}{{w.ChartPull.UsernameFile, &src.Username}, {w.ChartPull.PasswordFile, &src.Password}} {
+		if f.path == "" {
+			continue
+		}
+		b, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			return Source{}, fmt.Errorf("failed to read the credentials of chart %s: %w", w.ChartDir, err)
+		}
+		*f.value = strings.TrimSpace(string(b))
+	}
+	return src, nil
+}
+
+func (s Source) isOCI() bool {
+	return strings.HasPrefix(s.Chart, watches.OCIScheme)
+}
+
+// name returns the name of the chart, which is the last element of an OCI reference.
+func (s Source) name() string {
+	return path.Base(s.Chart)
+}
+
+// cacheDir returns the directory of cacheDir the archives of the chart are cached in, which
+// is named after the registry and repository of an OCI chart, or after the URL of a chart
+// repository and the name of the chart.
+func (s Source) cacheDir(cacheDir string) string {
+	key := path.Join("oci", strings.TrimPrefix(s.Chart, watches.OCIScheme))
+	if !s.isOCI() {
+		key = path.Join("repository", s.Repository, s.Chart)
+		if u, err := url.Parse(s.Repository); err == nil {
+			key = path.Join("repository", u.Host, u.Path, s.Chart)
+		}
+	}
+	key = path.Clean("/" + strings.ReplaceAll(key, ":", "_"))
+	return filepath.Join(cacheDir, filepath.FromSlash(key))
+}
+
+// Puller pulls charts into a cache directory.
+type Puller struct {
+	// Timeout is the timeout of each request, none when zero.
+	Timeout  time.Duration
+	CacheDir string
+}
+
+// Pull returns the path of the archive of the version of the chart of src, which it
+// downloads into the cache unless it is already there. When the versions of the chart
+// can't be listed, the newest cached version of it that satisfies src is used.
+func (p Puller) Pull(ctx context.Context, src Source) (string, error) {
+	dir := src.cacheDir(p.CacheDir)
+	name := src.name()
+
+	// A pinned version that is already cached doesn't need any request.
+	if _, err := semver.StrictNewVersion(src.Version); err == nil {
+		archive := filepath.Join(dir, archiveName(name, src.Version))
+		if err := verifyDigest(archive, src.Digest); err == nil {
+			log.V(1).Info("Using cached chart", "chart", src.Chart, "version", src.Version, "path", archive)
+			return archive, nil
+		}
+	}
+
+	var cv *repo.ChartVersion
+	var err error
+	if src.isOCI() {
+		cv, err = p.resolveOCI(ctx, src)
+	} else {
+		cv, err = p.resolveRepository(src)
+	}
+	if err != nil {
+		archive, cacheErr := newestCached(dir, name, src)
+		if cacheErr != nil {
+			return "", fmt.Errorf("failed to resolve chart %s: %w", src.Chart, err)
+		}
+		log.Info("Failed to resolve chart, using the newest cached version", "chart", src.Chart,
+			"path", archive, "error", err.Error())
+		return archive, nil
+	}
+
+	archive := filepath.Join(dir, archiveName(name, cv.Version))
+	if verifyDigest(archive, src.Digest) == nil && verifyDigest(archive, cv.Digest) == nil {
+		log.V(1).Info("Using cached chart", "chart", src.Chart, "version", cv.Version, "path", archive)
+		return archive, nil
+	}
+	if err := p.download(ctx, src, cv.URLs[0], archive, src.Digest, cv.Digest); err != nil {
+		return "", fmt.Errorf("failed to pull chart %s version %s: %w", src.Chart, cv.Version, err)
+	}
+	log.Info("Pulled chart", "chart", src.Chart, "version", cv.Version, "path", archive)
+	return archive, nil
+}
+
+// resolveRepository returns the newest version of the chart of src in the index of its repository
+// that satisfies it, with the absolute URL of its archive.
+func (p Puller) resolveRepository(src Source) (*repo.ChartVersion, error) {
+	r, err := repo.NewChartRepository(&repo.Entry{
+		Name:                  src.name(),
+		URL:                   src.Repository,
+		Username:              src.Username,
+		Password:              src.Password,
+		CertFile:              src.CertFile,
+		KeyFile:               src.KeyFile,
+		CAFile:                src.CAFile,
+		InsecureSkipTLSverify: src.InsecureSkipTLSVerify,
+	}, p.getters())
+	if err != nil {
+		return nil, err
+	}
+	// The index is saved next to the archives of the chart.
+	r.CachePath = src.cacheDir(p.CacheDir)
+	indexFile, err := r.DownloadIndexFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the index of repository %s: %w", src.Repository, err)
+	}
+	index, err := repo.LoadIndexFile(indexFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the index of repository %s: %w", src.Repository, err)
+	}
+	cv, err := index.Get(src.Chart, src.Version)
+	if err != nil {
+		return nil, err
+	}
+	if len(cv.URLs) == 0 {
+		return nil, fmt.Errorf("chart %s version %s has no URL", src.Chart, cv.Version)
+	}
+	chartURL, err := repo.ResolveReferenceURL(src.Repository, cv.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+	return &repo.ChartVersion{Metadata: cv.Metadata, URLs: []string{chartURL}, Digest: cv.Digest}, nil
+}
+
+// getters returns the getters of chart repositories, whose requests time out after p.Timeout.
+func (p Puller) getters() getter.Providers {
+	return getter.Providers{{
+		Schemes: []string{"http", "https"},
+		New: func(options ...getter.Option) (getter.Getter, error) {
+			return getter.NewHTTPGetter(append(options, getter.WithTimeout(p.Timeout))...)
+		},
+	}}
+}
+
+// resolveOCI returns the newest version of the chart of src in its registry that satisfies it,
+// with the URL and digest of the layer of its archive. The registry client of Helm 3.4 is
+// experimental and internal to Helm, so the registry API is used directly.
+func (p Puller) resolveOCI(ctx context.Context, src Source) (*repo.ChartVersion, error) {
+	ref := strings.TrimPrefix(src.Chart, watches.OCIScheme)
+	slash := strings.Index(ref, "/")
+	if slash < 0 {
+		return nil, fmt.Errorf("invalid OCI reference %s", src.Chart)
+	}
+	base := "https://" + ref[:slash] + "/v2/" + ref[slash+1:]
+
+	version := src.Version
+	if _, err := semver.StrictNewVersion(version); err != nil {
+		tags, err := p.listTags(ctx, src, base)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the tags of %s: %w", src.Chart, err)
+		}
+		// OCI tags can't have a "+", which Helm replaces with "_" in chart versions.
+		versions := []string{}
+		for _, tag := range tags {
+			versions = append(versions, strings.ReplaceAll(tag, "_", "+"))
+		}
+		cv, err := versionsIndex(src.name(), versions).Get(src.name(), src.Version)
+		if err != nil {
+			return nil, err
+		}
+		version = cv.Version
+	}
+
+	b, _, err := p.get(ctx, src, base+"/manifests/"+strings.ReplaceAll(version, "+", "_"), ociManifestMediaType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the manifest of %s version %s: %w", src.Chart, version, err)
+	}
+	manifest := struct {
+		Layers []struct {
+			MediaType string `json:"mediaType"`
+			Digest    string `json:"digest"`
+		} `json:"layers"`
+	}{}
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse the manifest of %s version %s: %w", src.Chart, version, err)
+	}
+	for _, layer := range manifest.Layers {
+		for _, mediaType := range chartLayerMediaTypes {
+			if layer.MediaType == mediaType {
+				return &repo.ChartVersion{
+					Metadata: &chart.Metadata{Name: src.name(), Version: version},
+					URLs:     []string{base + "/blobs/" + layer.Digest},
+					Digest:   layer.Digest,
+				}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s version %s is not a Helm chart", src.Chart, version)
+}
+
+var linkNextRegexp = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// listTags returns the tags of the OCI repository at base, following the links of the pages
+// that a registry splits them into.
+func (p Puller) listTags(ctx context.Context, src Source, base string) ([]string, error) {
+	tags := []string{}
+	next := base + "/tags/list"
+	for next != "" {
+		b, header, err := p.get(ctx, src, next, "")
+		if err != nil {
+			return nil, err
+		}
+		page := struct {
+			Tags []string `json:"tags"`
+		}{}
+		if err := json.Unmarshal(b, &page); err != nil {
+			return nil, err
+		}
+		tags = append(tags, page.Tags...)
+
+		next = ""
+		if m := linkNextRegexp.FindStringSubmatch(header.Get("Link")); m != nil {
+			u, err := url.Parse(base)
+			if err != nil {
+				return nil, err
+			}
+			if u, err = u.Parse(m[1]); err != nil {
+				return nil, err
+			}
+			next = u.String()
+		}
+	}
+	return tags, nil
+}
+
+// newestCached returns the path of the newest archive of the chart in dir that satisfies src.
+func newestCached(dir, name string, src Source) (string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	prefix := name + "-"
+	versions := []string{}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), prefix) && strings.HasSuffix(e.Name(), archiveExt) {
+			versions = append(versions, strings.TrimSuffix(strings.TrimPrefix(e.Name(), prefix), archiveExt))
+		}
+	}
+	cv, err := versionsIndex(name, versions).Get(name, src.Version)
+	if err != nil {
+		return "", err
+	}
+	archive := filepath.Join(dir, archiveName(name, cv.Version))
+	if err := verifyDigest(archive, src.Digest); err != nil {
+		return "", err
+	}
+	return archive, nil
+}
+
+// versionsIndex returns an index of the versions of a chart, to select one the way Helm does.
+func versionsIndex(name string, versions []string) *repo.IndexFile {
+	index := repo.NewIndexFile()
+	for _, v := range versions {
+		index.Add(&chart.Metadata{Name: name, Version: v}, "", "", "")
+	}
+	index.SortEntries()
+	return index
+}
+
+func archiveName(name, version string) string {
+	return name + "-" + version + archiveExt
+}
+
+// download writes the archive at chartURL to archive, if its content has each of digests.
+func (p Puller) download(ctx context.Context, src Source, chartURL, archive string, digests ...string) error {
+	var b []byte
+	var err error
+	if src.isOCI() {
+		b, _, err = p.get(ctx, src, chartURL, "")
+	} else {
+		b, err = p.getArchive(src, chartURL)
+	}
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(b)
+	for _, d := range digests {
+		if err := compareDigest(sum[:], d); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(archive), 0755); err != nil {
+		return err
+	}
+	// The archive is written next to its path and then moved, so that a partial archive is never cached.
+	tmp, err := ioutil.TempFile(filepath.Dir(archive), "."+filepath.Base(archive)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), archive)
+}
+
+// verifyDigest returns an error if the file at archive does not exist, or if digest is not
+// empty and the file does not have it.
+func verifyDigest(archive, digest string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if digest == "" {
+		return nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	return compareDigest(h.Sum(nil), digest)
+}
+
+// compareDigest compares sum to digest, which is the hexadecimal sha256 of a repository index,
+// or the same with a "sha256:" prefix.
+func compareDigest(sum []byte, digest string) error {
+	if digest == "" {
+		return nil
+	}
+	if !strings.Contains(digest, ":") {
+		digest = "sha256:" + digest
+	}
+	if actual := "sha256:" + hex.EncodeToString(sum); actual != digest {
+		return fmt.Errorf("digest %s does not match the expected digest %s", actual, digest)
+	}
+	return nil
+}
+
+// getArchive returns the archive at chartURL in the repository of src. The credentials of the
+// repository are only sent to the host of the repository.
+func (p Puller) getArchive(src Source, chartURL string) ([]byte, error) {
+	g, err := p.getters().ByScheme(strings.SplitN(chartURL, ":", 2)[0])
+	if err != nil {
+		return nil, err
+	}
+	options := []getter.Option{
+		getter.WithURL(src.Repository),
+		getter.WithTLSClientConfig(src.CertFile, src.KeyFile, src.CAFile),
+		getter.WithInsecureSkipVerifyTLS(src.InsecureSkipTLSVerify),
+	}
+	repoURL, err := url.Parse(src.Repository)
+	if err != nil {
+		return nil, err
+	}
+	archiveURL, err := url.Parse(chartURL)
+	if err != nil {
+		return nil, err
+	}
+	if archiveURL.Host == repoURL.Host {
+		options = append(options, getter.WithBasicAuth(src.Username, src.Password))
+	}
+	buf, err := g.Get(chartURL, options...)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var challengeParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// get returns the body and the header of the response of the registry of src to rawURL. When
+// the registry requires a bearer token, one is requested as described by its challenge, with
+// the credentials of src if it has any.
+func (p Puller) get(ctx context.Context, src Source, rawURL, accept string) ([]byte, http.Header, error) {
+	client, err := p.httpClient(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := p.do(ctx, client, rawURL, accept, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	if resp.StatusCode == http.StatusUnauthorized {
+		var authorization string
+		switch {
+		case strings.HasPrefix(strings.ToLower(challenge), "bearer "):
+			token, err := p.token(ctx, client, src, challenge)
+			if err != nil {
+				resp.Body.Close()
+				return nil, nil, fmt.Errorf("failed to get a token for %s: %w", rawURL, err)
+			}
+			authorization = "Bearer " + token
+		case strings.HasPrefix(strings.ToLower(challenge), "basic ") && src.Username != "":
+			authorization = basicAuth(src)
+		}
+		if authorization != "" {
+			resp.Body.Close()
+			if resp, err = p.do(ctx, client, rawURL, accept, authorization); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	return b, resp.Header, err
+}
+
+func (p Puller) do(ctx context.Context, client *http.Client, rawURL, accept, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return client.Do(req)
+}
+
+// httpClient returns a client of the registry of src, with its TLS options.
+func (p Puller) httpClient(src Source) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if src.CertFile != "" || src.CAFile != "" || src.InsecureSkipTLSVerify {
+		config := &tls.Config{InsecureSkipVerify: src.InsecureSkipTLSVerify} //nolint:gosec
+		if src.CertFile != "" {
+			cert, err := tls.LoadX509KeyPair(src.CertFile, src.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load the client certificate: %w", err)
+			}
+			config.Certificates = []tls.Certificate{cert}
+		}
+		if src.CAFile != "" {
+			b, err := ioutil.ReadFile(src.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the CA file: %w", err)
+			}
+			config.RootCAs = x509.NewCertPool()
+			if !config.RootCAs.AppendCertsFromPEM(b) {
+				return nil, fmt.Errorf("no certificates found in the CA file %s", src.CAFile)
+			}
+		}
+		transport.TLSClientConfig = config
+	}
+	return &http.Client{Transport: transport, Timeout: p.Timeout}, nil
+}
+
+func basicAuth(src Source) string {
+	req := &http.Request{Header: http.Header{}}
+	req.SetBasicAuth(src.Username, src.Password)
+	return req.Header.Get("Authorization")
+}
+
+// token returns a token from the realm of a bearer challenge, which is anonymous unless src
+// has credentials.
+func (p Puller) token(ctx context.Context, client *http.Client, src Source, challenge string) (string, error) {
+	params := map[string]string{}
+	for _, m := range challengeParamRegexp.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid challenge %q", challenge)
+	}
+	q := realm.Query()
+	for _, k := range []string{"service", "scope"} {
+		if params[k] != "" {
+			q.Set(k, params[k])
+		}
+	}
+	realm.RawQuery = q.Encode()
+
+	var authorization string
+	if src.Username != "" {
+		authorization = basicAuth(src)
+	}
+	resp, err := p.do(ctx, client, realm.String(), "", authorization)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", realm, resp.Status)
+	}
+	body := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", errors.New("the token response has no token")
+}
+
+// Refresher pulls the chart of Source again every Interval while it runs, so that the newest
+// version that satisfies it is used. It is a manager.Runnable.
+type Refresher struct {
+	Puller   Puller
+	Source   Source
+	Interval time.Duration
+
+	mu   sync.RWMutex
+	path string
+}
+
+// NewRefresher returns a Refresher of src, whose chart was pulled to path.
+func NewRefresher(puller Puller, src Source, interval time.Duration, path string) *Refresher {
+	return &Refresher{Puller: puller, Source: src, Interval: interval, path: path}
+}
+
+// Path returns the path of the archive of the newest pulled chart.
+func (r *Refresher) Path() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.path
+}
+
+// Start pulls the chart every Interval until ctx is done. Failed pulls are logged, and the
+// previous chart is kept.
+func (r *Refresher) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			r.refresh(ctx)
+		}
+	}
+}
+
+func (r *Refresher) refresh(ctx context.Context) {
+	path, err := r.Puller.Pull(ctx, r.Source)
+	if err != nil {
+		log.Error(err, "Failed to pull chart", "chart", r.Source.Chart)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if path != r.path {
+		log.Info("Chart changed, releases are upgraded when their CRs are reconciled", "chart",
+			r.Source.Chart, "path", path)
+		r.path = path
+	}
+}
@@ -0,0 +1,427 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	testToken    = "t0ken"
+	testUsername = "user"
+	testPassword = "passw0rd"
+	// tagsPageSize is the number of tags of each page of the tags list.
+	tagsPageSize = 2
+)
+
+// testServer serves the archives of versions of test-chart both as a chart repository,
+// at /charts, as the same repository that requires basic auth, at /private, and as an
+// OCI registry that requires a bearer token, at /v2/charts/test-chart.
+type testServer struct {
+	*httptest.Server
+	// caFile is the path of the certificate of the server.
+	caFile   string
+	archives map[string][]byte
+	// tampered versions are served with content that does not match their digest.
+	tampered map[string]bool
+}
+
+func newTestServer(t *testing.T, versions ...string) *testServer {
+	s := &testServer{archives: map[string][]byte{}, tampered: map[string]bool{}}
+	index := repo.NewIndexFile()
+	dir := t.TempDir()
+	for _, v := range versions {
+		c := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "test-chart", Version: v}}
+		archive, err := chartutil.Save(c, dir)
+		require.NoError(t, err)
+		b, err := ioutil.ReadFile(archive)
+		require.NoError(t, err)
+		s.archives[v] = b
+		index.Add(c.Metadata, filepath.Base(archive), "", digest(b))
+	}
+	index.SortEntries()
+	indexYAML, err := yaml.Marshal(index)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/charts/index.yaml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(indexYAML)
+	})
+	mux.HandleFunc("/charts/", func(w http.ResponseWriter, r *http.Request) {
+		v := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/charts/test-chart-"), archiveExt)
+		s.write(w, v)
+	})
+	mux.HandleFunc("/private/", func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != testUsername || password != testPassword {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/private/index.yaml" {
+			_, _ = w.Write(indexYAML)
+			return
+		}
+		v := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/private/test-chart-"), archiveExt)
+		s.write(w, v)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("scope") != "repository:charts/test-chart:pull" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		// Anonymous tokens are allowed, but credentials must be valid.
+		if username, password, ok := r.BasicAuth(); ok && (username != testUsername || password != testPassword) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"token": "` + testToken + `"}`))
+	})
+	mux.HandleFunc("/v2/charts/test-chart/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+s.URL+`/token",service="registry",`+
+				`scope="repository:charts/test-chart:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		p := strings.TrimPrefix(r.URL.Path, "/v2/charts/test-chart/")
+		switch {
+		case p == "tags/list":
+			tags := []string{"latest"}
+			for v := range s.archives {
+				tags = append(tags, strings.ReplaceAll(v, "+", "_"))
+			}
+			sort.Strings(tags)
+			// Tags are listed in pages that link to the next one.
+			start := 0
+			if last := r.URL.Query().Get("last"); last != "" {
+				start = sort.SearchStrings(tags, last) + 1
+			}
+			end := start + tagsPageSize
+			if end < len(tags) {
+				w.Header().Set("Link", fmt.Sprintf(`</v2/charts/test-chart/tags/list?n=%d&last=%s>; rel="next"`,
+					tagsPageSize, url.QueryEscape(tags[end-1])))
+			} else {
+				end = len(tags)
+			}
+			b, _ := json.Marshal(map[string]interface{}{"name": "charts/test-chart", "tags": tags[start:end]})
+			_, _ = w.Write(b)
+		case strings.HasPrefix(p, "manifests/"):
+			b, ok := s.archives[strings.ReplaceAll(strings.TrimPrefix(p, "manifests/"), "_", "+")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			manifest, _ := json.Marshal(map[string]interface{}{
+				"schemaVersion": 2,
+				"config":        map[string]interface{}{"mediaType": "application/vnd.cncf.helm.config.v1+json"},
+				"layers": []interface{}{
+					map[string]interface{}{"mediaType": chartLayerMediaTypes[0], "digest": "sha256:" + digest(b)},
+				},
+			})
+			w.Header().Set("Content-Type", ociManifestMediaType)
+			_, _ = w.Write(manifest)
+		case strings.HasPrefix(p, "blobs/sha256:"):
+			for v, b := range s.archives {
+				if "blobs/sha256:"+digest(b) == p {
+					s.write(w, v)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	s.Server = httptest.NewTLSServer(mux)
+	s.caFile = filepath.Join(dir, "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(s.caFile, ca, 0600))
+	return s
+}
+
+// write writes the archive of version v.
+func (s *testServer) write(w http.ResponseWriter, v string) {
+	b, ok := s.archives[v]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if s.tampered[v] {
+		b = append(append([]byte{}, b...), 0)
+	}
+	_, _ = w.Write(b)
+}
+
+func (s *testServer) ociChart() string {
+	return "oci://" + strings.TrimPrefix(s.URL, "https://") + "/charts/test-chart"
+}
+
+func digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestPull(t *testing.T) {
+	srv := newTestServer(t, "0.1.0", "0.2.0", "0.3.0-rc.1", "1.0.0+build.1")
+	defer srv.Close()
+
+	testCases := []struct {
+		name          string
+		source        func() Source
+		tamper        string
+		expectVersion string
+		expectErr     bool
+	}{
+		{
+			name:          "repository newest stable version",
+			source:        func() Source { return Source{Chart: "test-chart", Repository: srv.URL + "/charts"} },
+			expectVersion: "1.0.0+build.1",
+		},
+		{
+			name: "repository version range",
+			source: func() Source {
+				return Source{Chart: "test-chart", Repository: srv.URL + "/charts/", Version: ">=0.1.0 <1.0.0"}
+			},
+			expectVersion: "0.2.0",
+		},
+		{
+			name: "repository pinned version and digest",
+			source: func() Source {
+				return Source{Chart: "test-chart", Repository: srv.URL + "/charts", Version: "0.1.0",
+					Digest: "sha256:" + digest(srv.archives["0.1.0"])}
+			},
+			expectVersion: "0.1.0",
+		},
+		{
+			name: "repository wrong digest",
+			source: func() Source {
+				return Source{Chart: "test-chart", Repository: srv.URL + "/charts", Version: "0.1.0",
+					Digest: "sha256:" + digest(srv.archives["0.2.0"])}
+			},
+			expectErr: true,
+		},
+		{
+			name:      "repository archive that does not match the index",
+			source:    func() Source { return Source{Chart: "test-chart", Repository: srv.URL + "/charts", Version: "0.2.0"} },
+			tamper:    "0.2.0",
+			expectErr: true,
+		},
+		{
+			name: "repository with credentials",
+			source: func() Source {
+				return Source{Chart: "test-chart", Repository: srv.URL + "/private", Version: "0.1.0",
+					Username: testUsername, Password: testPassword}
+			},
+			expectVersion: "0.1.0",
+		},
+		{
+			name:      "repository without credentials",
+			source:    func() Source { return Source{Chart: "test-chart", Repository: srv.URL + "/private"} },
+			expectErr: true,
+		},
+		{
+			name:      "repository missing chart",
+			source:    func() Source { return Source{Chart: "other-chart", Repository: srv.URL + "/charts"} },
+			expectErr: true,
+		},
+		{
+			name:          "OCI prerelease version range",
+			source:        func() Source { return Source{Chart: srv.ociChart(), Version: "~0.3.0-0"} },
+			expectVersion: "0.3.0-rc.1",
+		},
+		{
+			name:          "OCI newest stable version",
+			source:        func() Source { return Source{Chart: srv.ociChart()} },
+			expectVersion: "1.0.0+build.1",
+		},
+		{
+			name: "OCI pinned version and digest",
+			source: func() Source {
+				return Source{Chart: srv.ociChart(), Version: "0.2.0", Digest: "sha256:" + digest(srv.archives["0.2.0"])}
+			},
+			expectVersion: "0.2.0",
+		},
+		{
+			name:      "OCI layer that does not match the manifest",
+			source:    func() Source { return Source{Chart: srv.ociChart(), Version: "0.1.0"} },
+			tamper:    "0.1.0",
+			expectErr: true,
+		},
+		{
+			name: "OCI with credentials",
+			source: func() Source {
+				return Source{Chart: srv.ociChart(), Version: "<0.3.0", Username: testUsername, Password: testPassword}
+			},
+			expectVersion: "0.2.0",
+		},
+		{
+			name: "OCI with wrong credentials",
+			source: func() Source {
+				return Source{Chart: srv.ociChart(), Version: "<0.3.0", Username: testUsername, Password: "wrong"}
+			},
+			expectErr: true,
+		},
+		{
+			name:      "OCI missing version",
+			source:    func() Source { return Source{Chart: srv.ociChart(), Version: "2.0.0"} },
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.tamper != "" {
+				srv.tampered[tc.tamper] = true
+				defer delete(srv.tampered, tc.tamper)
+			}
+			src := tc.source()
+			src.CAFile = srv.caFile
+			p := Puller{CacheDir: t.TempDir()}
+			archive, err := p.Pull(context.TODO(), src)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			c, err := loader.Load(archive)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectVersion, c.Metadata.Version)
+		})
+	}
+}
+
+func TestPullCached(t *testing.T) {
+	srv := newTestServer(t, "0.1.0", "0.2.0", "1.0.0")
+	cacheDir := t.TempDir()
+	p := Puller{CacheDir: cacheDir}
+	for _, src := range []Source{
+		{Chart: "test-chart", Repository: srv.URL + "/charts", Version: "0.1.0"},
+		{Chart: "test-chart", Repository: srv.URL + "/charts", Version: "0.2.0"},
+		{Chart: srv.ociChart(), Version: "1.0.0"},
+	} {
+		src.CAFile = srv.caFile
+		_, err := p.Pull(context.TODO(), src)
+		require.NoError(t, err)
+	}
+	// The cache is used once the server is gone.
+	srv.Close()
+
+	testCases := []struct {
+		name          string
+		source        Source
+		expectVersion string
+		expectErr     bool
+	}{
+		{
+			name:          "pinned version",
+			source:        Source{Chart: "test-chart", Repository: srv.URL + "/charts", Version: "0.1.0"},
+			expectVersion: "0.1.0",
+		},
+		{
+			name:          "newest cached version in range",
+			source:        Source{Chart: "test-chart", Repository: srv.URL + "/charts", Version: "<1.0.0"},
+			expectVersion: "0.2.0",
+		},
+		{
+			name:          "OCI pinned version and digest",
+			source:        Source{Chart: srv.ociChart(), Version: "1.0.0", Digest: "sha256:" + digest(srv.archives["1.0.0"])},
+			expectVersion: "1.0.0",
+		},
+		{
+			name:      "cached version with another digest",
+			source:    Source{Chart: srv.ociChart(), Version: "1.0.0", Digest: "sha256:" + digest(srv.archives["0.1.0"])},
+			expectErr: true,
+		},
+		{
+			name:      "version that is not cached",
+			source:    Source{Chart: "test-chart", Repository: srv.URL + "/charts", Version: "1.0.0"},
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			archive, err := p.Pull(context.TODO(), tc.source)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			c, err := loader.Load(archive)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectVersion, c.Metadata.Version)
+		})
+	}
+}
+
+func TestPullUntrustedCertificate(t *testing.T) {
+	srv := newTestServer(t, "0.1.0")
+	defer srv.Close()
+	p := Puller{CacheDir: t.TempDir()}
+	_, err := p.Pull(context.TODO(), Source{Chart: "test-chart", Repository: srv.URL + "/charts"})
+	assert.Error(t, err)
+	_, err = p.Pull(context.TODO(), Source{Chart: srv.ociChart()})
+	assert.Error(t, err)
+
+	archive, err := p.Pull(context.TODO(), Source{Chart: srv.ociChart(), InsecureSkipTLSVerify: true})
+	require.NoError(t, err)
+	assert.Equal(t, "test-chart-0.1.0.tgz", filepath.Base(archive))
+}
+
+func TestRefresher(t *testing.T) {
+	srv := newTestServer(t, "0.1.0")
+	defer srv.Close()
+	src := Source{Chart: "test-chart", Repository: srv.URL + "/charts", CAFile: srv.caFile}
+	p := Puller{CacheDir: t.TempDir()}
+	archive, err := p.Pull(context.TODO(), src)
+	require.NoError(t, err)
+	r := NewRefresher(p, src, time.Millisecond, archive)
+
+	// A failed pull keeps the previous chart.
+	r.Source.Chart = "other-chart"
+	r.refresh(context.TODO())
+	assert.Equal(t, archive, r.Path())
+
+	// A newer version that satisfies the source replaces the chart.
+	newer := newTestServer(t, "0.1.0", "0.2.0")
+	defer newer.Close()
+	r.Source = Source{Chart: "test-chart", Repository: newer.URL + "/charts", CAFile: newer.caFile}
+	r.refresh(context.TODO())
+	assert.Equal(t, "test-chart-0.2.0.tgz", filepath.Base(r.Path()))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	assert.NoError(t, r.Start(ctx))
+}
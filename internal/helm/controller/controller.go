@@ -15,6 +15,7 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -22,20 +23,25 @@ import (
 
 	rpb "helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/releaseutil"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	crthandler "sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crpredicate "sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 	"sigs.k8s.io/yaml"
 
 	libhandler "github.com/operator-framework/operator-lib/handler"
 	"github.com/operator-framework/operator-lib/predicate"
 	"github.com/operator-framework/operator-sdk/internal/helm/release"
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
 	"github.com/operator-framework/operator-sdk/internal/util/k8sutil"
 )
 
@@ -51,12 +57,37 @@ type WatchOptions struct {
 	WatchDependentResources bool
 	OverrideValues          map[string]string
 	MaxConcurrentReconciles int
+	// ValuesFrom, when set, reconciles CRs when the Secrets and ConfigMaps their values are read from change.
+	ValuesFrom *release.ValuesFrom
+	// InstallOptions, UpgradeOptions and RollbackOptions are applied to every install, upgrade
+	// and rollback of a release, e.g. to wait for its resources.
+	InstallOptions  []release.InstallOption
+	UpgradeOptions  []release.UpgradeOption
+	RollbackOptions []release.RollbackOption
+	// Remediation, when set, retries and remediates failed installs and upgrades.
+	Remediation *watches.Remediation
+	// Readiness, when set, records the health of the resources of releases in the Ready condition of CRs.
+	Readiness *watches.Readiness
+	// Drift, when set, reports the resources of releases that drifted from their manifest.
+	Drift *watches.Drift
+	// Selector and Namespaces, when set, restrict the CRs that are reconciled to those whose
+	// labels match Selector, in Namespaces.
+	Selector   metav1.LabelSelector
+	Namespaces []string
+	// UninstallUnselected uninstalls the release of a CR that is relabeled away from Selector,
+	// rather than leaving it to the watch that selects the CR.
+	UninstallUnselected bool
 }
 
 // Add creates a new helm operator controller and adds it to the manager
 func Add(mgr manager.Manager, options WatchOptions) error {
 	controllerName := fmt.Sprintf("%v-controller", strings.ToLower(options.GVK.Kind))
 
+	selector, err := metav1.LabelSelectorAsSelector(&options.Selector)
+	if err != nil {
+		return err
+	}
+
 	r := &HelmOperatorReconciler{
 		Client:          mgr.GetClient(),
 		EventRecorder:   mgr.GetEventRecorderFor(controllerName),
@@ -64,6 +95,16 @@ func Add(mgr manager.Manager, options WatchOptions) error {
 		ManagerFactory:  options.ManagerFactory,
 		ReconcilePeriod: options.ReconcilePeriod,
 		OverrideValues:  options.OverrideValues,
+		InstallOptions:  options.InstallOptions,
+		UpgradeOptions:  options.UpgradeOptions,
+		RollbackOptions: options.RollbackOptions,
+		Remediation:     options.Remediation,
+		Readiness:       options.Readiness,
+		Drift:           options.Drift,
+		Selector:        selector,
+		Namespaces:      options.Namespaces,
+
+		UninstallUnselected: options.UninstallUnselected,
 	}
 
 	// Register the GVK with the schema
@@ -80,7 +121,8 @@ func Add(mgr manager.Manager, options WatchOptions) error {
 
 	o := &unstructured.Unstructured{}
 	o.SetGroupVersionKind(options.GVK)
-	if err := c.Watch(&source.Kind{Type: o}, &libhandler.InstrumentedEnqueueRequestForObject{}); err != nil {
+	if err := c.Watch(&source.Kind{Type: o}, &libhandler.InstrumentedEnqueueRequestForObject{},
+		crpredicate.NewPredicateFuncs(r.reconciles)); err != nil {
 		return err
 	}
 
@@ -88,11 +130,67 @@ func Add(mgr manager.Manager, options WatchOptions) error {
 		watchDependentResources(mgr, r, c)
 	}
 
+	if options.ValuesFrom != nil && (len(options.ValuesFrom.Refs) > 0 || options.ValuesFrom.AllowCRRefs != nil) {
+		if err := watchValuesFrom(mgr, c, options.GVK, *options.ValuesFrom); err != nil {
+			return err
+		}
+	}
+
 	log.Info("Watching resource", "apiVersion", options.GVK.GroupVersion(), "kind",
-		options.GVK.Kind, "namespace", options.Namespace, "reconcilePeriod", options.ReconcilePeriod.String())
+		options.GVK.Kind, "namespace", options.Namespace, "namespaces", options.Namespaces,
+		"selector", selector.String(), "reconcilePeriod", options.ReconcilePeriod.String())
+	return nil
+}
+
+// watchValuesFrom watches the Secrets and ConfigMaps that values are read from, and
+// enqueues the CRs in their namespace that reference them. Only their metadata is watched,
+// so that their data is not cached.
+func watchValuesFrom(mgr manager.Manager, c controller.Controller, gvk schema.GroupVersionKind,
+	valuesFrom release.ValuesFrom) error {
+
+	for _, kind := range []string{watches.ValuesKindSecret, watches.ValuesKindConfigMap} {
+		obj := &metav1.PartialObjectMetadata{}
+		obj.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind(kind))
+		mapFn := valuesFromMapFunc(mgr.GetClient(), gvk, valuesFrom, kind)
+		if err := c.Watch(&source.Kind{Type: obj}, crthandler.EnqueueRequestsFromMapFunc(mapFn)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// valuesFromMapFunc returns the requests of the CRs of gvk whose values are read from an
+// object of kind.
+func valuesFromMapFunc(reader client.Reader, gvk schema.GroupVersionKind, valuesFrom release.ValuesFrom,
+	kind string) crthandler.MapFunc {
+
+	return func(obj client.Object) []reconcile.Request {
+		crs := &unstructured.UnstructuredList{}
+		crs.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := reader.List(context.TODO(), crs, client.InNamespace(obj.GetNamespace())); err != nil {
+			log.Error(err, "Failed to list resources referencing values", "kind", kind,
+				"namespace", obj.GetNamespace(), "name", obj.GetName())
+			return nil
+		}
+		requests := []reconcile.Request{}
+		for i := range crs.Items {
+			refs, err := valuesFrom.References(&crs.Items[i])
+			if err != nil {
+				continue
+			}
+			for _, ref := range refs {
+				if ref.Kind == kind && ref.Name == obj.GetName() {
+					requests = append(requests, reconcile.Request{
+						NamespacedName: client.ObjectKeyFromObject(&crs.Items[i]),
+					})
+					break
+				}
+			}
+		}
+		return requests
+	}
+}
+
 // watchDependentResources adds a release hook function to the HelmOperatorReconciler
 // that adds watches for resources in released Helm charts.
 func watchDependentResources(mgr manager.Manager, r *HelmOperatorReconciler, c controller.Controller) {
@@ -0,0 +1,106 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+func TestValuesFromMapFunc(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Nginx"}
+	newCR := func(namespace, name string, spec map[string]interface{}) *unstructured.Unstructured {
+		cr := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+		cr.SetGroupVersionKind(gvk)
+		cr.SetNamespace(namespace)
+		cr.SetName(name)
+		return cr
+	}
+	crRefs := map[string]interface{}{
+		"valuesFrom": []interface{}{map[string]interface{}{"kind": "Secret", "name": "credentials"}},
+	}
+	s := runtime.NewScheme()
+	s.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+	s.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(
+		newCR("ns", "with-refs", crRefs),
+		newCR("ns", "without-refs", map[string]interface{}{}),
+		newCR("other", "with-refs", crRefs),
+	).Build()
+
+	allowCredentials := &watches.AllowCRValuesFrom{Names: []string{"credentials"}}
+
+	testCases := []struct {
+		name           string
+		valuesFrom     release.ValuesFrom
+		kind           string
+		objName        string
+		expectRequests []reconcile.Request
+	}{
+		{
+			name: "watch reference",
+			valuesFrom: release.ValuesFrom{Refs: []watches.ValuesReference{
+				{Kind: watches.ValuesKindConfigMap, Name: "shared-values"},
+			}},
+			kind:    watches.ValuesKindConfigMap,
+			objName: "shared-values",
+			expectRequests: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "with-refs"}},
+				{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "without-refs"}},
+			},
+		},
+		{
+			name:       "CR reference",
+			valuesFrom: release.ValuesFrom{AllowCRRefs: allowCredentials},
+			kind:       watches.ValuesKindSecret,
+			objName:    "credentials",
+			expectRequests: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "with-refs"}},
+			},
+		},
+		{
+			name:           "CR reference not allowed",
+			kind:           watches.ValuesKindSecret,
+			objName:        "credentials",
+			expectRequests: []reconcile.Request{},
+		},
+		{
+			name:           "other kind",
+			valuesFrom:     release.ValuesFrom{AllowCRRefs: allowCredentials},
+			kind:           watches.ValuesKindConfigMap,
+			objName:        "credentials",
+			expectRequests: []reconcile.Request{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: tc.objName}}
+			requests := valuesFromMapFunc(c, gvk, tc.valuesFrom, tc.kind)(obj)
+			assert.Equal(t, tc.expectRequests, requests)
+		})
+	}
+}
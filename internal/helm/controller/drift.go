@@ -0,0 +1,124 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+	"github.com/operator-framework/operator-sdk/internal/helm/metrics"
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
+)
+
+const (
+	// maxDriftedFields caps the number of drifted fields of a resource that are listed in the
+	// message of the Drifted condition.
+	maxDriftedFields = 5
+	// maxDriftedResources caps the number of drifted resources that are listed in the message
+	// of the Drifted condition and in drift events.
+	maxDriftedResources = 10
+)
+
+// reportDrift records the resources of the release of o that drifted from its manifest in
+// the Drifted condition of status, in events and in the release drift metric. With the
+// correct mode, only the resources that a patch actually changed are reported as corrected,
+// the others are reported as drifted. The condition is removed when drift is not reported.
+func (r HelmOperatorReconciler) reportDrift(o *unstructured.Unstructured, status *types.HelmAppStatus,
+	drifts []release.ResourceDrift) {
+
+	if r.Drift == nil {
+		status.RemoveCondition(types.ConditionDrifted)
+		return
+	}
+
+	fields := 0
+	var corrected, detected []string
+	for _, d := range drifts {
+		description := d.String() + ": missing"
+		if d.Missing {
+			fields++
+		} else {
+			fields += len(d.Fields)
+			description = d.String() + ": " + strings.Join(d.Fields, ", ")
+			if len(d.Fields) > maxDriftedFields {
+				description = fmt.Sprintf("%s: %s and %d more", d.String(),
+					strings.Join(d.Fields[:maxDriftedFields], ", "), len(d.Fields)-maxDriftedFields)
+			}
+			log.Info("Release resource drifted", "resource", d.String(), "fields", d.Fields)
+			log.V(1).Info("Release resource drift", "resource", d.String(), "diff", d.Diff)
+		}
+		if d.Corrected {
+			corrected = append(corrected, description)
+		} else {
+			detected = append(detected, description)
+		}
+	}
+	metrics.ReleaseDrift(r.GVK.String(), o.GetNamespace(), o.GetName(), fields)
+
+	if len(corrected) > 0 {
+		r.EventRecorder.Event(o, "Normal", string(types.ReasonDriftCorrected), driftMessage(corrected))
+	}
+	if len(detected) > 0 {
+		condition := types.HelmAppCondition{
+			Type:    types.ConditionDrifted,
+			Status:  types.StatusTrue,
+			Reason:  types.ReasonDriftDetected,
+			Message: driftMessage(detected),
+		}
+		// Drift that is not corrected persists, so it is only recorded as an event when it changes.
+		if !hasCondition(status, condition) {
+			r.EventRecorder.Event(o, "Warning", string(condition.Reason), condition.Message)
+		}
+		status.SetCondition(condition)
+		return
+	}
+	if len(corrected) > 0 {
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionDrifted,
+			Status:  types.StatusFalse,
+			Reason:  types.ReasonDriftCorrected,
+			Message: driftMessage(corrected),
+		})
+		return
+	}
+	status.SetCondition(types.HelmAppCondition{
+		Type:   types.ConditionDrifted,
+		Status: types.StatusFalse,
+		Reason: types.ReasonInSync,
+	})
+}
+
+// hasCondition returns true if status has a condition with the type, status, reason and
+// message of condition.
+func hasCondition(status *types.HelmAppStatus, condition types.HelmAppCondition) bool {
+	for _, c := range status.Conditions {
+		if c.Type == condition.Type {
+			return c.Status == condition.Status && c.Reason == condition.Reason && c.Message == condition.Message
+		}
+	}
+	return false
+}
+
+// driftMessage lists the descriptions of drifted resources, up to maxDriftedResources.
+func driftMessage(descriptions []string) string {
+	if len(descriptions) <= maxDriftedResources {
+		return strings.Join(descriptions, "; ")
+	}
+	return fmt.Sprintf("%s and %d more resources", strings.Join(descriptions[:maxDriftedResources], "; "),
+		len(descriptions)-maxDriftedResources)
+}
@@ -0,0 +1,164 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+func TestReportDrift(t *testing.T) {
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	configMap := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	drifts := []release.ResourceDrift{
+		{GroupVersionKind: deployment, Namespace: "ns", Name: "web",
+			Fields: []string{"/a", "/b", "/c", "/d", "/e", "/f", "/g"}},
+		{GroupVersionKind: configMap, Namespace: "ns", Name: "config", Missing: true},
+	}
+	message := "Deployment ns/web: /a, /b, /c, /d, /e and 2 more; ConfigMap ns/config: missing"
+	var many []release.ResourceDrift
+	var manyMessages []string
+	for i := 0; i < maxDriftedResources+3; i++ {
+		name := fmt.Sprintf("config-%d", i)
+		many = append(many, release.ResourceDrift{GroupVersionKind: configMap, Namespace: "ns", Name: name,
+			Missing: true})
+		manyMessages = append(manyMessages, "ConfigMap ns/"+name+": missing")
+	}
+	manyMessage := strings.Join(manyMessages[:maxDriftedResources], "; ") + " and 3 more resources"
+	corrected := make([]release.ResourceDrift, len(drifts))
+	for i, d := range drifts {
+		d.Corrected = true
+		corrected[i] = d
+	}
+
+	testCases := []struct {
+		name            string
+		drift           *watches.Drift
+		drifts          []release.ResourceDrift
+		previous        *types.HelmAppCondition
+		expectCondition *types.HelmAppCondition
+		expectEvents    []string
+	}{
+		{
+			name:            "in sync",
+			drift:           &watches.Drift{Mode: watches.DriftModeReport},
+			expectCondition: &types.HelmAppCondition{Status: types.StatusFalse, Reason: types.ReasonInSync},
+		},
+		{
+			name:   "reported",
+			drift:  &watches.Drift{Mode: watches.DriftModeReport},
+			drifts: drifts,
+			expectCondition: &types.HelmAppCondition{Status: types.StatusTrue, Reason: types.ReasonDriftDetected,
+				Message: message},
+			expectEvents: []string{"Warning DriftDetected " + message},
+		},
+		{
+			name:   "many reported",
+			drift:  &watches.Drift{Mode: watches.DriftModeReport},
+			drifts: many,
+			expectCondition: &types.HelmAppCondition{Status: types.StatusTrue, Reason: types.ReasonDriftDetected,
+				Message: manyMessage},
+			expectEvents: []string{"Warning DriftDetected " + manyMessage},
+		},
+		{
+			name:   "reported again",
+			drift:  &watches.Drift{Mode: watches.DriftModeReport},
+			drifts: drifts,
+			previous: &types.HelmAppCondition{Type: types.ConditionDrifted, Status: types.StatusTrue,
+				Reason: types.ReasonDriftDetected, Message: message},
+			expectCondition: &types.HelmAppCondition{Status: types.StatusTrue, Reason: types.ReasonDriftDetected,
+				Message: message},
+		},
+		{
+			name:   "corrected",
+			drift:  &watches.Drift{Mode: watches.DriftModeCorrect},
+			drifts: corrected,
+			expectCondition: &types.HelmAppCondition{Status: types.StatusFalse, Reason: types.ReasonDriftCorrected,
+				Message: message},
+			expectEvents: []string{"Normal DriftCorrected " + message},
+		},
+		{
+			name:   "not changed by the patch",
+			drift:  &watches.Drift{Mode: watches.DriftModeCorrect},
+			drifts: drifts,
+			expectCondition: &types.HelmAppCondition{Status: types.StatusTrue, Reason: types.ReasonDriftDetected,
+				Message: message},
+			expectEvents: []string{"Warning DriftDetected " + message},
+		},
+		{
+			name:   "partly corrected",
+			drift:  &watches.Drift{Mode: watches.DriftModeCorrect},
+			drifts: []release.ResourceDrift{drifts[0], corrected[1]},
+			expectCondition: &types.HelmAppCondition{Status: types.StatusTrue, Reason: types.ReasonDriftDetected,
+				Message: "Deployment ns/web: /a, /b, /c, /d, /e and 2 more"},
+			expectEvents: []string{"Normal DriftCorrected ConfigMap ns/config: missing",
+				"Warning DriftDetected Deployment ns/web: /a, /b, /c, /d, /e and 2 more"},
+		},
+		{
+			name:   "not reported",
+			drifts: drifts,
+			previous: &types.HelmAppCondition{Type: types.ConditionDrifted, Status: types.StatusTrue,
+				Reason: types.ReasonDriftDetected, Message: message},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(2)
+			r := HelmOperatorReconciler{
+				EventRecorder: recorder,
+				GVK:           schema.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Nginx"},
+				Drift:         tc.drift,
+			}
+			cr := &unstructured.Unstructured{}
+			cr.SetNamespace("ns")
+			cr.SetName("test")
+			status := &types.HelmAppStatus{}
+			if tc.previous != nil {
+				status.Conditions = []types.HelmAppCondition{*tc.previous}
+			}
+
+			r.reportDrift(cr, status, tc.drifts)
+
+			close(recorder.Events)
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			assert.Equal(t, tc.expectEvents, events)
+			if tc.expectCondition == nil {
+				assert.Empty(t, status.Conditions)
+				return
+			}
+			require.Len(t, status.Conditions, 1)
+			condition := status.Conditions[0]
+			assert.Equal(t, types.ConditionDrifted, condition.Type)
+			assert.Equal(t, tc.expectCondition.Status, condition.Status)
+			assert.Equal(t, tc.expectCondition.Reason, condition.Reason)
+			assert.Equal(t, tc.expectCondition.Message, condition.Message)
+		})
+	}
+}
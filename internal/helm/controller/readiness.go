@@ -0,0 +1,269 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+// healthCheck returns why a resource is not healthy, or an empty string if it is.
+type healthCheck func(*unstructured.Unstructured) (string, error)
+
+// notReadyRequeueInterval is how long to wait before checking the readiness of a release
+// again while it is not ready, when the reconcile period is longer.
+const notReadyRequeueInterval = 10 * time.Second
+
+// builtinHealthChecks check the health of the resources of built-in kinds.
+var builtinHealthChecks = map[schema.GroupKind]healthCheck{
+	{Group: "apps", Kind: "Deployment"}:        deploymentHealth,
+	{Group: "apps", Kind: "StatefulSet"}:       statefulSetHealth,
+	{Group: "batch", Kind: "Job"}:              jobHealth,
+	{Group: "", Kind: "PersistentVolumeClaim"}: pvcHealth,
+}
+
+// setReadyCondition checks the health of the resources of the deployed release of o, and
+// records it in the Ready condition of status. The condition is removed when readiness is
+// not checked.
+func (r HelmOperatorReconciler) setReadyCondition(ctx context.Context, o *unstructured.Unstructured,
+	status *types.HelmAppStatus) {
+
+	if r.Readiness == nil || status.DeployedRelease == nil {
+		status.RemoveCondition(types.ConditionReady)
+		return
+	}
+	unhealthy, err := r.unhealthyResources(ctx, o.GetNamespace(), status.DeployedRelease.Manifest)
+	switch {
+	case err != nil:
+		log.Error(err, "Failed to check readiness of release resources")
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionReady,
+			Status:  types.StatusUnknown,
+			Reason:  types.ReasonReadinessCheckError,
+			Message: err.Error(),
+		})
+	case len(unhealthy) > 0:
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionReady,
+			Status:  types.StatusFalse,
+			Reason:  types.ReasonResourcesNotReady,
+			Message: strings.Join(unhealthy, "; "),
+		})
+	default:
+		status.SetCondition(types.HelmAppCondition{
+			Type:   types.ConditionReady,
+			Status: types.StatusTrue,
+			Reason: types.ReasonResourcesReady,
+		})
+	}
+}
+
+// requeueAfter returns how long to wait before reconciling a CR with status again, which is
+// shorter than the reconcile period while the resources of its release are not ready, so that
+// the Ready condition follows their status, which does not trigger a reconcile.
+func (r HelmOperatorReconciler) requeueAfter(status *types.HelmAppStatus) time.Duration {
+	if r.Readiness == nil || r.ReconcilePeriod <= notReadyRequeueInterval {
+		return r.ReconcilePeriod
+	}
+	for _, c := range status.Conditions {
+		if c.Type == types.ConditionReady && c.Status != types.StatusTrue {
+			return notReadyRequeueInterval
+		}
+	}
+	return r.ReconcilePeriod
+}
+
+// unhealthyResources returns the resources in manifest that are not healthy, in manifest order,
+// each with the reason why. Resources without a health check are healthy. Resources without a
+// namespace are looked up in namespace. Resources are read from the cache of r.Client, which
+// shares the informers of the dependent watches of the release.
+func (r HelmOperatorReconciler) unhealthyResources(ctx context.Context, namespace, manifest string) ([]string, error) {
+	manifests := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(manifests))
+	for k := range manifests {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	var unhealthy []string
+	for _, k := range keys {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(manifests[k]), &obj.Object); err != nil {
+			return nil, fmt.Errorf("failed to parse release manifest: %w", err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		gvk := obj.GroupVersionKind()
+		check := r.healthCheck(gvk.GroupKind())
+		if check == nil {
+			continue
+		}
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+		name := fmt.Sprintf("%s %s", gvk.Kind, obj.GetName())
+
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				unhealthy = append(unhealthy, name+": not found")
+				continue
+			}
+			return nil, fmt.Errorf("failed to get %s: %w", name, err)
+		}
+		reason, err := check(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check health of %s: %w", name, err)
+		}
+		if reason != "" {
+			unhealthy = append(unhealthy, name+": "+reason)
+		}
+	}
+	return unhealthy, nil
+}
+
+// healthCheck returns the health check of the resources of gk, or nil if they have none.
+// Health rules take precedence over built-in checks.
+func (r HelmOperatorReconciler) healthCheck(gk schema.GroupKind) healthCheck {
+	for _, rule := range r.Readiness.HealthRules {
+		if rule.Group == gk.Group && rule.Kind == gk.Kind {
+			return conditionHealth(rule)
+		}
+	}
+	return builtinHealthChecks[gk]
+}
+
+// conditionHealth returns a health check that requires the condition of rule to be "True".
+func conditionHealth(rule watches.HealthRule) healthCheck {
+	return func(obj *unstructured.Unstructured) (string, error) {
+		conditions, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		if err != nil {
+			return "", err
+		}
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok || condition["type"] != rule.ConditionType {
+				continue
+			}
+			if condition["status"] == string(types.StatusTrue) {
+				return "", nil
+			}
+			reason := fmt.Sprintf("condition %s is %v", rule.ConditionType, condition["status"])
+			if message, ok := condition["message"].(string); ok && message != "" {
+				reason += ": " + message
+			}
+			return reason, nil
+		}
+		return fmt.Sprintf("condition %s not reported", rule.ConditionType), nil
+	}
+}
+
+func deploymentHealth(obj *unstructured.Unstructured) (string, error) {
+	d := &appsv1.Deployment{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, d); err != nil {
+		return "", err
+	}
+	if d.Status.ObservedGeneration < d.Generation {
+		return "rollout not observed yet", nil
+	}
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	if d.Status.UpdatedReplicas < replicas {
+		return fmt.Sprintf("%d of %d replicas updated", d.Status.UpdatedReplicas, replicas), nil
+	}
+	if d.Status.AvailableReplicas < replicas {
+		return fmt.Sprintf("%d of %d replicas available", d.Status.AvailableReplicas, replicas), nil
+	}
+	return "", nil
+}
+
+func statefulSetHealth(obj *unstructured.Unstructured) (string, error) {
+	s := &appsv1.StatefulSet{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, s); err != nil {
+		return "", err
+	}
+	if s.Status.ObservedGeneration < s.Generation {
+		return "rollout not observed yet", nil
+	}
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
+	}
+	// Only the replicas above the partition of a rolling update are updated.
+	updated := replicas
+	if ru := s.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil && *ru.Partition < replicas {
+		updated = replicas - *ru.Partition
+	}
+	if s.Spec.UpdateStrategy.Type != appsv1.OnDeleteStatefulSetStrategyType && s.Status.UpdatedReplicas < updated {
+		return fmt.Sprintf("%d of %d replicas updated", s.Status.UpdatedReplicas, updated), nil
+	}
+	if s.Status.ReadyReplicas < replicas {
+		return fmt.Sprintf("%d of %d replicas ready", s.Status.ReadyReplicas, replicas), nil
+	}
+	return "", nil
+}
+
+func jobHealth(obj *unstructured.Unstructured) (string, error) {
+	j := &batchv1.Job{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, j); err != nil {
+		return "", err
+	}
+	for _, c := range j.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return "", nil
+		case batchv1.JobFailed:
+			return fmt.Sprintf("failed: %s", c.Message), nil
+		}
+	}
+	completions := int32(1)
+	if j.Spec.Completions != nil {
+		completions = *j.Spec.Completions
+	}
+	return fmt.Sprintf("%d of %d completions succeeded", j.Status.Succeeded, completions), nil
+}
+
+func pvcHealth(obj *unstructured.Unstructured) (string, error) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pvc); err != nil {
+		return "", err
+	}
+	if pvc.Status.Phase != corev1.ClaimBound {
+		return fmt.Sprintf("not bound, phase is %q", pvc.Status.Phase), nil
+	}
+	return "", nil
+}
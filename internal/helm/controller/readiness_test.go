@@ -0,0 +1,273 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+func newObject(t *testing.T, manifest string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	require.NoError(t, yaml.Unmarshal([]byte(manifest), &obj.Object))
+	return obj
+}
+
+func TestHealthChecks(t *testing.T) {
+	rules := []watches.HealthRule{{Group: "example.com", Kind: "Database", ConditionType: "Available"}}
+
+	testCases := []struct {
+		name         string
+		obj          string
+		expectReason string
+	}{
+		{
+			name: "available deployment",
+			obj: `{apiVersion: apps/v1, kind: Deployment, metadata: {generation: 2}, spec: {replicas: 3},
+status: {observedGeneration: 2, updatedReplicas: 3, availableReplicas: 3}}`,
+		},
+		{
+			name: "deployment with unavailable replicas",
+			obj: `{apiVersion: apps/v1, kind: Deployment, metadata: {generation: 2}, spec: {replicas: 3},
+status: {observedGeneration: 2, updatedReplicas: 3, availableReplicas: 1}}`,
+			expectReason: "1 of 3 replicas available",
+		},
+		{
+			name:         "deployment rollout not observed",
+			obj:          `{apiVersion: apps/v1, kind: Deployment, metadata: {generation: 2}, status: {observedGeneration: 1}}`,
+			expectReason: "rollout not observed yet",
+		},
+		{
+			name: "deployment rollout in progress",
+			obj: `{apiVersion: apps/v1, kind: Deployment, metadata: {generation: 1},
+status: {observedGeneration: 1, availableReplicas: 1}}`,
+			expectReason: "0 of 1 replicas updated",
+		},
+		{
+			name: "ready statefulset",
+			obj: `{apiVersion: apps/v1, kind: StatefulSet, metadata: {generation: 1}, spec: {replicas: 2},
+status: {observedGeneration: 1, updatedReplicas: 2, readyReplicas: 2}}`,
+		},
+		{
+			name: "statefulset with unready replicas",
+			obj: `{apiVersion: apps/v1, kind: StatefulSet, metadata: {generation: 1}, spec: {replicas: 2},
+status: {observedGeneration: 1, updatedReplicas: 2, readyReplicas: 1}}`,
+			expectReason: "1 of 2 replicas ready",
+		},
+		{
+			name: "statefulset partitioned rollout",
+			obj: `{apiVersion: apps/v1, kind: StatefulSet, metadata: {generation: 1},
+spec: {replicas: 3, updateStrategy: {type: RollingUpdate, rollingUpdate: {partition: 2}}},
+status: {observedGeneration: 1, updatedReplicas: 1, readyReplicas: 3}}`,
+		},
+		{
+			name:         "running job",
+			obj:          `{apiVersion: batch/v1, kind: Job, spec: {completions: 2}, status: {succeeded: 1}}`,
+			expectReason: "1 of 2 completions succeeded",
+		},
+		{
+			name: "complete job",
+			obj:  `{apiVersion: batch/v1, kind: Job, status: {succeeded: 1, conditions: [{type: Complete, status: "True"}]}}`,
+		},
+		{
+			name: "failed job",
+			obj: `{apiVersion: batch/v1, kind: Job,
+status: {conditions: [{type: Failed, status: "True", message: backoff limit exceeded}]}}`,
+			expectReason: "failed: backoff limit exceeded",
+		},
+		{
+			name: "bound pvc",
+			obj:  `{apiVersion: v1, kind: PersistentVolumeClaim, status: {phase: Bound}}`,
+		},
+		{
+			name:         "pending pvc",
+			obj:          `{apiVersion: v1, kind: PersistentVolumeClaim, status: {phase: Pending}}`,
+			expectReason: `not bound, phase is "Pending"`,
+		},
+		{
+			name: "custom kind with condition",
+			obj:  `{apiVersion: example.com/v1, kind: Database, status: {conditions: [{type: Available, status: "True"}]}}`,
+		},
+		{
+			name: "custom kind with false condition",
+			obj: `{apiVersion: example.com/v1, kind: Database,
+status: {conditions: [{type: Available, status: "False", message: no primary}]}}`,
+			expectReason: "condition Available is False: no primary",
+		},
+		{
+			name:         "custom kind without condition",
+			obj:          `{apiVersion: example.com/v1, kind: Database}`,
+			expectReason: "condition Available not reported",
+		},
+	}
+
+	r := HelmOperatorReconciler{Readiness: &watches.Readiness{HealthRules: rules}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := newObject(t, tc.obj)
+			check := r.healthCheck(obj.GroupVersionKind().GroupKind())
+			require.NotNil(t, check)
+			reason, err := check(obj)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectReason, reason)
+		})
+	}
+
+	assert.Nil(t, r.healthCheck(schema.GroupKind{Kind: "ConfigMap"}))
+}
+
+func TestSetReadyCondition(t *testing.T) {
+	manifest := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+`
+	available := `{apiVersion: apps/v1, kind: Deployment, metadata: {name: web, namespace: ns},
+status: {updatedReplicas: 1, availableReplicas: 1}}`
+	unavailable := `{apiVersion: apps/v1, kind: Deployment, metadata: {name: web, namespace: ns},
+status: {updatedReplicas: 1}}`
+	bound := `{apiVersion: v1, kind: PersistentVolumeClaim, metadata: {name: data, namespace: ns}, status: {phase: Bound}}`
+
+	testCases := []struct {
+		name            string
+		readiness       *watches.Readiness
+		objs            []string
+		expectCondition *types.HelmAppCondition
+	}{
+		{
+			name:            "ready",
+			readiness:       &watches.Readiness{},
+			objs:            []string{available, bound},
+			expectCondition: &types.HelmAppCondition{Status: types.StatusTrue, Reason: types.ReasonResourcesReady},
+		},
+		{
+			name:      "not ready",
+			readiness: &watches.Readiness{},
+			objs:      []string{unavailable},
+			expectCondition: &types.HelmAppCondition{Status: types.StatusFalse, Reason: types.ReasonResourcesNotReady,
+				Message: "Deployment web: 0 of 1 replicas available; PersistentVolumeClaim data: not found"},
+		},
+		{
+			name: "readiness disabled",
+			objs: []string{unavailable},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objs := []client.Object{}
+			for _, obj := range tc.objs {
+				objs = append(objs, newObject(t, obj))
+			}
+			r := HelmOperatorReconciler{
+				Readiness: tc.readiness,
+				Client:    fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(objs...).Build(),
+			}
+			cr := &unstructured.Unstructured{}
+			cr.SetNamespace("ns")
+			status := &types.HelmAppStatus{
+				Conditions:      []types.HelmAppCondition{{Type: types.ConditionReady, Status: types.StatusTrue}},
+				DeployedRelease: &types.HelmAppRelease{Name: "test", Manifest: manifest},
+			}
+			r.setReadyCondition(context.TODO(), cr, status)
+
+			if tc.expectCondition == nil {
+				assert.Empty(t, status.Conditions)
+				return
+			}
+			require.Len(t, status.Conditions, 1)
+			condition := status.Conditions[0]
+			assert.Equal(t, types.ConditionReady, condition.Type)
+			assert.Equal(t, tc.expectCondition.Status, condition.Status)
+			assert.Equal(t, tc.expectCondition.Reason, condition.Reason)
+			assert.Equal(t, tc.expectCondition.Message, condition.Message)
+		})
+	}
+}
+
+func TestRequeueAfter(t *testing.T) {
+	notReady := &types.HelmAppStatus{Conditions: []types.HelmAppCondition{
+		{Type: types.ConditionReady, Status: types.StatusFalse},
+	}}
+	ready := &types.HelmAppStatus{Conditions: []types.HelmAppCondition{
+		{Type: types.ConditionReady, Status: types.StatusTrue},
+	}}
+
+	testCases := []struct {
+		name            string
+		readiness       *watches.Readiness
+		reconcilePeriod time.Duration
+		status          *types.HelmAppStatus
+		expect          time.Duration
+	}{
+		{
+			name:            "not ready",
+			readiness:       &watches.Readiness{},
+			reconcilePeriod: time.Minute,
+			status:          notReady,
+			expect:          notReadyRequeueInterval,
+		},
+		{
+			name:            "ready",
+			readiness:       &watches.Readiness{},
+			reconcilePeriod: time.Minute,
+			status:          ready,
+			expect:          time.Minute,
+		},
+		{
+			name:            "not ready with a shorter reconcile period",
+			readiness:       &watches.Readiness{},
+			reconcilePeriod: time.Second,
+			status:          notReady,
+			expect:          time.Second,
+		},
+		{
+			name:            "readiness disabled",
+			reconcilePeriod: time.Minute,
+			status:          notReady,
+			expect:          time.Minute,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := HelmOperatorReconciler{Readiness: tc.readiness, ReconcilePeriod: tc.reconcilePeriod}
+			assert.Equal(t, tc.expect, r.requeueAfter(tc.status))
+		})
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"helm.sh/helm/v3/pkg/storage/driver"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/record"
@@ -35,7 +36,9 @@ import (
 
 	"github.com/operator-framework/operator-sdk/internal/helm/internal/diff"
 	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+	"github.com/operator-framework/operator-sdk/internal/helm/metrics"
 	"github.com/operator-framework/operator-sdk/internal/helm/release"
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
 )
 
 // blank assignment to verify that HelmOperatorReconciler implements reconcile.Reconciler
@@ -52,7 +55,25 @@ type HelmOperatorReconciler struct {
 	ManagerFactory  release.ManagerFactory
 	ReconcilePeriod time.Duration
 	OverrideValues  map[string]string
-	releaseHook     ReleaseHookFunc
+	InstallOptions  []release.InstallOption
+	UpgradeOptions  []release.UpgradeOption
+	RollbackOptions []release.RollbackOption
+	// Remediation, when set, retries and remediates failed installs and upgrades.
+	Remediation *watches.Remediation
+	// Readiness, when set, checks the health of the resources of releases, which are
+	// read from the cache of Client, and records it in the Ready condition of CRs.
+	Readiness *watches.Readiness
+	// Drift, when set, reports the resources of releases that drifted from their manifest.
+	Drift *watches.Drift
+	// Selector and Namespaces, when set, restrict the CRs that are reconciled to those whose
+	// labels match Selector, in Namespaces. Other CRs are left to other operators, even when
+	// they are enqueued for their dependent resources or values.
+	Selector   labels.Selector
+	Namespaces []string
+	// UninstallUnselected uninstalls the release of a CR that is relabeled away from Selector.
+	// By default, the CR is released so that the watch that selects it takes over its release.
+	UninstallUnselected bool
+	releaseHook         ReleaseHookFunc
 }
 
 const (
@@ -69,8 +90,20 @@ const (
 // uninstalling a Helm release based on the resource's current state. If no
 // release changes are necessary, Reconcile will create or patch the underlying
 // resources to match the expected release manifest.
+func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	timer := metrics.ReconcileTimer(r.GVK.String())
+	defer timer.ObserveDuration()
 
-func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) { //nolint:gocyclo
+	result, err := r.reconcile(ctx, request)
+	if err != nil {
+		metrics.ReconcileFailed(r.GVK.String())
+	} else {
+		metrics.ReconcileSucceeded(r.GVK.String())
+	}
+	return result, err
+}
+
+func (r HelmOperatorReconciler) reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) { //nolint:gocyclo
 	o := &unstructured.Unstructured{}
 	o.SetGroupVersionKind(r.GVK)
 	o.SetNamespace(request.Namespace)
@@ -85,12 +118,25 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 
 	err := r.Client.Get(ctx, request.NamespacedName, o)
 	if apierrors.IsNotFound(err) {
+		// The CR may be deleted without this reconciler removing its finalizer, e.g. after
+		// the finalizer was removed by hand, so the series of its release are deleted here.
+		metrics.ReleaseDeleted(r.GVK.String(), request.Namespace, request.Name)
 		return reconcile.Result{}, nil
 	}
 	if err != nil {
 		log.Error(err, "Failed to lookup resource")
 		return reconcile.Result{}, err
 	}
+	selected := r.selects(o)
+	if !selected && !r.isClaimed(o) {
+		log.V(1).Info("Resource is not selected by the watch, skipping reconciliation")
+		return reconcile.Result{}, nil
+	}
+	if selected && r.isClaimedByOther(o) {
+		log.V(1).Info("Release is managed by another watch that selects the resource, skipping reconciliation",
+			"selector", o.GetAnnotations()[watchSelectorAnnotation])
+		return reconcile.Result{}, nil
+	}
 
 	manager, err := r.ManagerFactory.NewManager(o, r.OverrideValues)
 	if err != nil {
@@ -106,11 +152,13 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 			controllerutil.ContainsFinalizer(o, uninstallFinalizerLegacy)) {
 
 			log.Info("Resource is terminated, skipping reconciliation")
+			metrics.ReleaseDeleted(r.GVK.String(), o.GetNamespace(), o.GetName())
 			return reconcile.Result{}, nil
 		}
 
 		uninstalledRelease, err := manager.UninstallRelease(ctx)
 		if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+			metrics.ReleaseActionFailed(r.GVK.String(), metrics.ActionUninstall)
 			log.Error(err, "Failed to uninstall release")
 			status.SetCondition(types.HelmAppCondition{
 				Type:    types.ConditionReleaseFailed,
@@ -129,6 +177,7 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 		if errors.Is(err, driver.ErrReleaseNotFound) {
 			log.Info("Release not found")
 		} else {
+			metrics.ReleaseActionSucceeded(r.GVK.String(), metrics.ActionUninstall)
 			log.Info("Uninstalled release")
 			if log.V(0).Enabled() && uninstalledRelease != nil {
 				fmt.Println(diff.Generate(uninstalledRelease.Manifest, ""))
@@ -140,6 +189,7 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 					Reason: types.ReasonUninstallSuccessful,
 				})
 				status.DeployedRelease = nil
+				status.RemoveCondition(types.ConditionReady)
 			}
 		}
 		if wait {
@@ -184,6 +234,8 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 			return reconcile.Result{}, err
 		}
 
+		metrics.ReleaseDeleted(r.GVK.String(), o.GetNamespace(), o.GetName())
+
 		// Since the client is hitting a cache, waiting for the
 		// deletion here will guarantee that the next reconciliation
 		// will see that the CR has been deleted and that there's
@@ -196,6 +248,12 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 		return reconcile.Result{}, nil
 	}
 
+	// A CR that was relabeled away from the watch is released, so that the watch that
+	// selects it now takes over its release.
+	if !selected {
+		return r.releaseUnselected(ctx, o, manager)
+	}
+
 	status.SetCondition(types.HelmAppCondition{
 		Type:   types.ConditionInitialized,
 		Status: types.StatusTrue,
@@ -216,29 +274,34 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 	}
 	status.RemoveCondition(types.ConditionIrreconcilable)
 
+	// The release of a CR whose install or upgrade failed is only retried once the retry
+	// interval of its remediation elapsed, and not anymore once its remediation stopped.
+	retryAfter, remediationStopped := r.remediationState(o, manager, status)
+
 	if !manager.IsInstalled() {
+		if remediationStopped {
+			log.V(1).Info("Not installing release, remediation stopped until the resource changes")
+			err = r.updateResourceStatus(ctx, o, status)
+			return reconcile.Result{RequeueAfter: r.ReconcilePeriod}, err
+		}
+		if retryAfter > 0 {
+			return reconcile.Result{RequeueAfter: retryAfter}, nil
+		}
 		for k, v := range r.OverrideValues {
 			r.EventRecorder.Eventf(o, "Warning", "OverrideValuesInUse",
 				"Chart value %q overridden to %q by operator's watches.yaml", k, v)
 		}
-		installedRelease, err := manager.InstallRelease(ctx)
+		installedRelease, err := manager.InstallRelease(ctx, r.InstallOptions...)
 		if err != nil {
+			metrics.ReleaseActionFailed(r.GVK.String(), metrics.ActionInstall)
 			log.Error(err, "Release failed")
-			status.SetCondition(types.HelmAppCondition{
-				Type:    types.ConditionReleaseFailed,
-				Status:  types.StatusTrue,
-				Reason:  types.ReasonInstallError,
-				Message: err.Error(),
-			})
-			if err := r.updateResourceStatus(ctx, o, status); err != nil {
-				log.Error(err, "Failed to update status after install release failure")
-			}
-			return reconcile.Result{}, err
+			return r.releaseFailed(ctx, o, manager, status, types.ReasonInstallError, err)
 		}
+		metrics.ReleaseActionSucceeded(r.GVK.String(), metrics.ActionInstall)
 		status.RemoveCondition(types.ConditionReleaseFailed)
+		clearRemediation(status)
 
-		log.V(1).Info("Adding finalizer", "finalizer", uninstallFinalizer)
-		controllerutil.AddFinalizer(o, uninstallFinalizer)
+		r.claim(o)
 		if err := r.updateResource(ctx, o); err != nil {
 			log.Info("Failed to add CR uninstall finalizer")
 			return reconcile.Result{}, err
@@ -266,46 +329,61 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 			Reason:  types.ReasonInstallSuccessful,
 			Message: message,
 		})
-		status.DeployedRelease = &types.HelmAppRelease{
-			Name:     installedRelease.Name,
-			Manifest: installedRelease.Manifest,
-		}
+		status.DeployedRelease = deployedRelease(installedRelease, manager.PostRenderHash())
+		r.setReadyCondition(ctx, o, status)
+		metrics.ReleaseSynced(r.GVK.String(), o.GetNamespace(), o.GetName(), installedRelease.Version)
 		err = r.updateResourceStatus(ctx, o, status)
-		return reconcile.Result{RequeueAfter: r.ReconcilePeriod}, err
+		return reconcile.Result{RequeueAfter: r.requeueAfter(status)}, err
 	}
 
-	if !(controllerutil.ContainsFinalizer(o, uninstallFinalizer) ||
-		controllerutil.ContainsFinalizer(o, uninstallFinalizerLegacy)) {
+	// An existing release is only uninstalled with the CR once the CR adopted it.
+	if manager.IsAdoptionRequired() {
+		adoptedRelease, err := manager.AdoptRelease(ctx)
+		if err != nil {
+			log.Error(err, "Failed to adopt release")
+			status.SetCondition(types.HelmAppCondition{
+				Type:    types.ConditionReleaseFailed,
+				Status:  types.StatusTrue,
+				Reason:  types.ReasonAdoptError,
+				Message: err.Error(),
+			})
+			if err := r.updateResourceStatus(ctx, o, status); err != nil {
+				log.Error(err, "Failed to update status after adopt release failure")
+			}
+			return reconcile.Result{}, err
+		}
+		status.RemoveCondition(types.ConditionReleaseFailed)
+		log.Info("Adopted release", "revision", adoptedRelease.Version)
+		r.EventRecorder.Eventf(o, "Normal", "ReleaseAdopted", "Adopted release %q at revision %d",
+			adoptedRelease.Name, adoptedRelease.Version)
+	}
 
-		log.V(1).Info("Adding finalizer", "finalizer", uninstallFinalizer)
-		controllerutil.AddFinalizer(o, uninstallFinalizer)
+	if r.claim(o) {
 		if err := r.updateResource(ctx, o); err != nil {
 			log.Info("Failed to add CR uninstall finalizer")
 			return reconcile.Result{}, err
 		}
 	}
 
-	if manager.IsUpgradeRequired() {
+	if manager.IsUpgradeRequired() && !remediationStopped {
+		if retryAfter > 0 {
+			return reconcile.Result{RequeueAfter: retryAfter}, nil
+		}
 		for k, v := range r.OverrideValues {
 			r.EventRecorder.Eventf(o, "Warning", "OverrideValuesInUse",
 				"Chart value %q overridden to %q by operator's watches.yaml", k, v)
 		}
 		force := hasAnnotation(helmUpgradeForceAnnotation, o)
-		previousRelease, upgradedRelease, err := manager.UpgradeRelease(ctx, release.ForceUpgrade(force))
+		opts := append([]release.UpgradeOption{release.ForceUpgrade(force)}, r.UpgradeOptions...)
+		previousRelease, upgradedRelease, err := manager.UpgradeRelease(ctx, opts...)
 		if err != nil {
+			metrics.ReleaseActionFailed(r.GVK.String(), metrics.ActionUpgrade)
 			log.Error(err, "Release failed")
-			status.SetCondition(types.HelmAppCondition{
-				Type:    types.ConditionReleaseFailed,
-				Status:  types.StatusTrue,
-				Reason:  types.ReasonUpgradeError,
-				Message: err.Error(),
-			})
-			if err := r.updateResourceStatus(ctx, o, status); err != nil {
-				log.Error(err, "Failed to update status after sync release failure")
-			}
-			return reconcile.Result{}, err
+			return r.releaseFailed(ctx, o, manager, status, types.ReasonUpgradeError, err)
 		}
+		metrics.ReleaseActionSucceeded(r.GVK.String(), metrics.ActionUpgrade)
 		status.RemoveCondition(types.ConditionReleaseFailed)
+		clearRemediation(status)
 
 		if r.releaseHook != nil {
 			if err := r.releaseHook(upgradedRelease); err != nil {
@@ -329,12 +407,11 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 			Reason:  types.ReasonUpgradeSuccessful,
 			Message: message,
 		})
-		status.DeployedRelease = &types.HelmAppRelease{
-			Name:     upgradedRelease.Name,
-			Manifest: upgradedRelease.Manifest,
-		}
+		status.DeployedRelease = deployedRelease(upgradedRelease, manager.PostRenderHash())
+		r.setReadyCondition(ctx, o, status)
+		metrics.ReleaseSynced(r.GVK.String(), o.GetNamespace(), o.GetName(), upgradedRelease.Version)
 		err = r.updateResourceStatus(ctx, o, status)
-		return reconcile.Result{RequeueAfter: r.ReconcilePeriod}, err
+		return reconcile.Result{RequeueAfter: r.requeueAfter(status)}, err
 	}
 
 	// If a change is made to the CR spec that causes a release failure, a
@@ -342,10 +419,13 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 	// is then reverted to its previous state, the operator will stop
 	// attempting the release and will resume reconciling. In this case, we
 	// need to remove the ConditionReleaseFailed because the failing release is
-	// no longer being attempted.
-	status.RemoveCondition(types.ConditionReleaseFailed)
+	// no longer being attempted. A release whose remediation stopped is still
+	// failing, and the deployed release is reconciled in the meantime.
+	if !remediationStopped {
+		status.RemoveCondition(types.ConditionReleaseFailed)
+	}
 
-	expectedRelease, err := manager.ReconcileRelease(ctx)
+	expectedRelease, drifts, err := manager.ReconcileRelease(ctx)
 	if err != nil {
 		log.Error(err, "Failed to reconcile release")
 		status.SetCondition(types.HelmAppCondition{
@@ -360,6 +440,7 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 		return reconcile.Result{}, err
 	}
 	status.RemoveCondition(types.ConditionIrreconcilable)
+	r.reportDrift(o, status, drifts)
 
 	if r.releaseHook != nil {
 		if err := r.releaseHook(expectedRelease); err != nil {
@@ -383,12 +464,62 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 		Reason:  reason,
 		Message: message,
 	})
-	status.DeployedRelease = &types.HelmAppRelease{
-		Name:     expectedRelease.Name,
-		Manifest: expectedRelease.Manifest,
+	// The deployed release is only upgraded with the current post-render configuration when an
+	// upgrade is required, e.g. not while its remediation stopped.
+	postRenderHash := ""
+	if status.DeployedRelease != nil {
+		postRenderHash = status.DeployedRelease.PostRenderHash
+	}
+	if !manager.IsUpgradeRequired() {
+		postRenderHash = manager.PostRenderHash()
 	}
+	status.DeployedRelease = deployedRelease(expectedRelease, postRenderHash)
+	r.setReadyCondition(ctx, o, status)
+	metrics.ReleaseSynced(r.GVK.String(), o.GetNamespace(), o.GetName(), expectedRelease.Version)
 	err = r.updateResourceStatus(ctx, o, status)
-	return reconcile.Result{RequeueAfter: r.ReconcilePeriod}, err
+	return reconcile.Result{RequeueAfter: r.requeueAfter(status)}, err
+}
+
+// deployedRelease returns the status of a deployed release, that was post-rendered with the
+// configuration of postRenderHash.
+func deployedRelease(rel *rpb.Release, postRenderHash string) *types.HelmAppRelease {
+	deployed := &types.HelmAppRelease{
+		Name:           rel.Name,
+		Manifest:       rel.Manifest,
+		PostRenderHash: postRenderHash,
+	}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		deployed.ChartVersion = rel.Chart.Metadata.Version
+	}
+	return deployed
+}
+
+// isReleaseFailed returns true if status has a true ReleaseFailed condition.
+func isReleaseFailed(status *types.HelmAppStatus) bool {
+	for _, c := range status.Conditions {
+		if c.Type == types.ConditionReleaseFailed {
+			return c.Status == types.StatusTrue
+		}
+	}
+	return false
+}
+
+// selects returns true if obj is in the Namespaces of the reconciler, and its labels match
+// the Selector of the reconciler.
+func (r HelmOperatorReconciler) selects(obj client.Object) bool {
+	if len(r.Namespaces) > 0 {
+		found := false
+		for _, ns := range r.Namespaces {
+			if ns == obj.GetNamespace() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return r.Selector == nil || r.Selector.Matches(labels.Set(obj.GetLabels()))
 }
 
 // returns the boolean representation of the annotation string
@@ -415,8 +546,13 @@ func (r HelmOperatorReconciler) updateResource(ctx context.Context, o client.Obj
 }
 
 func (r HelmOperatorReconciler) updateResourceStatus(ctx context.Context, o *unstructured.Unstructured, status *types.HelmAppStatus) error {
+	metrics.ReleaseFailed(r.GVK.String(), o.GetNamespace(), o.GetName(), isReleaseFailed(status))
+	statusMap, err := status.ToMap()
+	if err != nil {
+		return err
+	}
 	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
-		o.Object["status"] = status
+		o.Object["status"] = statusMap
 		return r.Client.Status().Update(ctx, o)
 	})
 }
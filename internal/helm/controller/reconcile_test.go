@@ -15,10 +15,27 @@
 package controller
 
 import (
+	"context"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	rpb "helm.sh/helm/v3/pkg/release"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+	"github.com/operator-framework/operator-sdk/internal/helm/metrics"
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
 )
 
 func TestHasAnnotation(t *testing.T) {
@@ -140,3 +157,296 @@ func annotations(m map[string]interface{}) *unstructured.Unstructured {
 		},
 	}
 }
+
+func TestDeployedRelease(t *testing.T) {
+	rel := &rpb.Release{
+		Name:     "test",
+		Manifest: "manifest",
+		Chart:    &chart.Chart{Metadata: &chart.Metadata{Name: "test-chart", Version: "1.2.3"}},
+	}
+	assert.Equal(t, &types.HelmAppRelease{Name: "test", Manifest: "manifest", ChartVersion: "1.2.3"},
+		deployedRelease(rel, ""))
+
+	rel.Chart = nil
+	assert.Equal(t, &types.HelmAppRelease{Name: "test", Manifest: "manifest", PostRenderHash: "abc"},
+		deployedRelease(rel, "abc"))
+}
+
+func TestSelects(t *testing.T) {
+	selector, err := labels.Parse("track=canary")
+	require.NoError(t, err)
+	newCR := func(namespace string, lbls map[string]string) *unstructured.Unstructured {
+		cr := &unstructured.Unstructured{}
+		cr.SetNamespace(namespace)
+		cr.SetLabels(lbls)
+		return cr
+	}
+
+	testCases := []struct {
+		name       string
+		selector   labels.Selector
+		namespaces []string
+		cr         *unstructured.Unstructured
+		expected   bool
+	}{
+		{
+			name:     "no selector or namespaces",
+			cr:       newCR("default", nil),
+			expected: true,
+		},
+		{
+			name:     "matching labels",
+			selector: selector,
+			cr:       newCR("default", map[string]string{"track": "canary"}),
+			expected: true,
+		},
+		{
+			name:     "other labels",
+			selector: selector,
+			cr:       newCR("default", map[string]string{"track": "stable"}),
+		},
+		{
+			name:       "allowed namespace",
+			namespaces: []string{"canary-a", "canary-b"},
+			cr:         newCR("canary-b", nil),
+			expected:   true,
+		},
+		{
+			name:       "other namespace",
+			selector:   selector,
+			namespaces: []string{"canary-a"},
+			cr:         newCR("default", map[string]string{"track": "canary"}),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := HelmOperatorReconciler{Selector: tc.selector, Namespaces: tc.namespaces}
+			assert.Equal(t, tc.expected, r.selects(tc.cr))
+		})
+	}
+}
+
+func TestReconciles(t *testing.T) {
+	selector, err := labels.Parse("track=canary")
+	require.NoError(t, err)
+	newCR := func(namespace, claimedBy string, lbls map[string]string) *unstructured.Unstructured {
+		cr := &unstructured.Unstructured{}
+		cr.SetNamespace(namespace)
+		cr.SetLabels(lbls)
+		if claimedBy != "" {
+			cr.SetAnnotations(map[string]string{watchSelectorAnnotation: claimedBy})
+		}
+		return cr
+	}
+
+	testCases := []struct {
+		name          string
+		selector      labels.Selector
+		namespaces    []string
+		cr            *unstructured.Unstructured
+		expected      bool
+		expectClaimed bool
+		expectOther   bool
+	}{
+		{
+			name:     "selected",
+			selector: selector,
+			cr:       newCR("default", "", map[string]string{"track": "canary"}),
+			expected: true,
+		},
+		{
+			name:     "relabeled after install",
+			selector: selector,
+			cr:       newCR("default", "track=canary", map[string]string{"track": "stable"}),
+			expected: true, expectClaimed: true,
+		},
+		{
+			name:     "relabeled from another watch",
+			selector: selector,
+			cr:       newCR("default", "track=stable", map[string]string{"track": "canary"}),
+			expected: true,
+		},
+		{
+			name:     "selected by another watch",
+			selector: selector,
+			cr:       newCR("default", "beta=true", map[string]string{"track": "canary", "beta": "true"}),
+			expected: true, expectOther: true,
+		},
+		{
+			name:        "installed by a watch with a selector",
+			cr:          newCR("default", "track=canary", map[string]string{"track": "canary"}),
+			expected:    true,
+			expectOther: true,
+		},
+		{
+			name:     "invalid claim",
+			selector: selector,
+			cr:       newCR("default", "track=", map[string]string{"track": "canary"}),
+			expected: true,
+		},
+		{
+			name:       "claimed in another namespace",
+			selector:   selector,
+			namespaces: []string{"canary-a"},
+			cr:         newCR("default", "track=canary", nil),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := HelmOperatorReconciler{Selector: tc.selector, Namespaces: tc.namespaces}
+			assert.Equal(t, tc.expected, r.reconciles(tc.cr))
+			assert.Equal(t, tc.expectClaimed, r.isClaimed(tc.cr))
+			assert.Equal(t, tc.expectOther, r.isClaimedByOther(tc.cr))
+		})
+	}
+}
+
+// fakeManagerFactory returns its manager for every CR.
+type fakeManagerFactory struct {
+	manager *fakeManager
+}
+
+func (f fakeManagerFactory) NewManager(*unstructured.Unstructured, map[string]string) (release.Manager, error) {
+	return f.manager, nil
+}
+
+func TestReconcileRelabeledCR(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Relabeled"}
+	canary, err := labels.Parse("track=canary")
+	require.NoError(t, err)
+	stable, err := labels.Parse("track=stable")
+	require.NoError(t, err)
+	request := reconcile.Request{NamespacedName: apitypes.NamespacedName{Namespace: "ns", Name: "test"}}
+
+	// newReconcilers returns the reconcilers of the canary and stable watches of a CR that
+	// the canary watch installed before it was relabeled to the stable track.
+	newReconcilers := func(uninstallUnselected bool) (HelmOperatorReconciler, HelmOperatorReconciler, *fakeManager) {
+		cr := &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{}}}
+		cr.SetGroupVersionKind(gvk)
+		cr.SetNamespace("ns")
+		cr.SetName("test")
+		cr.SetLabels(map[string]string{"track": "stable"})
+		cr.SetAnnotations(map[string]string{watchSelectorAnnotation: "track=canary"})
+		cr.SetFinalizers([]string{uninstallFinalizer})
+		cr.Object["status"] = map[string]interface{}{
+			"deployedRelease": map[string]interface{}{"name": "test", "manifest": "---"},
+		}
+		s := runtime.NewScheme()
+		s.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+		c := fake.NewClientBuilder().WithScheme(s).WithObjects(cr).Build()
+		manager := &fakeManager{installed: true}
+		newReconciler := func(selector labels.Selector) HelmOperatorReconciler {
+			return HelmOperatorReconciler{
+				Client:         c,
+				EventRecorder:  record.NewFakeRecorder(10),
+				GVK:            gvk,
+				ManagerFactory: fakeManagerFactory{manager: manager},
+				Selector:       selector,
+
+				UninstallUnselected: uninstallUnselected,
+			}
+		}
+		return newReconciler(canary), newReconciler(stable), manager
+	}
+	get := func(t *testing.T, r HelmOperatorReconciler) *unstructured.Unstructured {
+		cr := &unstructured.Unstructured{}
+		cr.SetGroupVersionKind(gvk)
+		require.NoError(t, r.Client.Get(context.TODO(), request.NamespacedName, cr))
+		return cr
+	}
+
+	t.Run("hands over to the watch that selects the CR", func(t *testing.T) {
+		canaryReconciler, stableReconciler, manager := newReconcilers(false)
+		metrics.ReleaseSynced(gvk.String(), "ns", "test", 1)
+		require.Equal(t, 2, releaseSeries(t, gvk.String()))
+
+		_, err := canaryReconciler.Reconcile(context.TODO(), request)
+		require.NoError(t, err)
+		assert.Empty(t, manager.actions)
+		assert.Equal(t, 0, releaseSeries(t, gvk.String()))
+		released := get(t, canaryReconciler)
+		assert.Empty(t, released.GetFinalizers())
+		assert.NotContains(t, released.GetAnnotations(), watchSelectorAnnotation)
+		assert.NotNil(t, types.StatusFor(released).DeployedRelease)
+
+		// The stable watch takes over the release, and the canary watch leaves it alone.
+		_, err = stableReconciler.Reconcile(context.TODO(), request)
+		require.NoError(t, err)
+		_, err = canaryReconciler.Reconcile(context.TODO(), request)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"reconcile"}, manager.actions)
+		adopted := get(t, stableReconciler)
+		assert.Equal(t, []string{uninstallFinalizer}, adopted.GetFinalizers())
+		assert.Equal(t, "track=stable", adopted.GetAnnotations()[watchSelectorAnnotation])
+		deployed := false
+		for _, c := range types.StatusFor(adopted).Conditions {
+			deployed = deployed || c.Type == types.ConditionDeployed && c.Status == types.StatusTrue
+		}
+		assert.True(t, deployed)
+	})
+
+	t.Run("takes over from a watch that was removed", func(t *testing.T) {
+		_, stableReconciler, manager := newReconcilers(false)
+		_, err := stableReconciler.Reconcile(context.TODO(), request)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"reconcile"}, manager.actions)
+		adopted := get(t, stableReconciler)
+		assert.Equal(t, []string{uninstallFinalizer}, adopted.GetFinalizers())
+		assert.Equal(t, "track=stable", adopted.GetAnnotations()[watchSelectorAnnotation])
+	})
+
+	t.Run("uninstalls with uninstallUnselected", func(t *testing.T) {
+		canaryReconciler, _, manager := newReconcilers(true)
+		_, err := canaryReconciler.Reconcile(context.TODO(), request)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"uninstall"}, manager.actions)
+		released := get(t, canaryReconciler)
+		assert.Empty(t, released.GetFinalizers())
+		assert.NotContains(t, released.GetAnnotations(), watchSelectorAnnotation)
+		status := types.StatusFor(released)
+		assert.Nil(t, status.DeployedRelease)
+		require.Len(t, status.Conditions, 1)
+		assert.Equal(t, types.ConditionDeployed, status.Conditions[0].Type)
+		assert.Equal(t, types.ReasonUninstallSuccessful, status.Conditions[0].Reason)
+	})
+}
+
+func TestReconcileDeletedCR(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Deleted"}
+	metrics.ReleaseSynced(gvk.String(), "ns", "test", 1)
+	metrics.ReleaseFailed(gvk.String(), "ns", "test", false)
+	require.Equal(t, 3, releaseSeries(t, gvk.String()))
+
+	r := HelmOperatorReconciler{
+		Client: fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build(),
+		GVK:    gvk,
+	}
+	result, err := r.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: apitypes.NamespacedName{Namespace: "ns", Name: "test"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, result)
+	assert.Equal(t, 0, releaseSeries(t, gvk.String()))
+}
+
+// releaseSeries returns the number of series of the releases of CRs of gvk.
+func releaseSeries(t *testing.T, gvk string) int {
+	families, err := crmetrics.Registry.Gather()
+	require.NoError(t, err)
+	n := 0
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), "helm_operator_release_") {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "GVK" && label.GetValue() == gvk {
+					n++
+				}
+			}
+		}
+	}
+	return n
+}
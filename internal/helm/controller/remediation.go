@@ -0,0 +1,230 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	rpb "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+	"github.com/operator-framework/operator-sdk/internal/helm/metrics"
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+// maxRetryIntervalDoublings caps the growth of the retry interval of a remediation.
+const maxRetryIntervalDoublings = 10
+
+// remediationState returns how long to wait before retrying the failed install or upgrade
+// of the release of o, and whether its remediation stopped. The remediation is reset when
+// the generation of o changes, or when the chart or values of the release do, e.g. because
+// a Secret that its values are read from changed.
+func (r HelmOperatorReconciler) remediationState(o *unstructured.Unstructured, manager release.Manager,
+	status *types.HelmAppStatus) (time.Duration, bool) {
+
+	rem := status.Remediation
+	if r.Remediation == nil || rem == nil {
+		return 0, false
+	}
+	if !isRemediationOf(rem, o, manager) {
+		status.Remediation = nil
+		return 0, false
+	}
+	if rem.Stopped {
+		return 0, true
+	}
+	retryAfter := time.Until(rem.LastFailureTime.Add(retryInterval(*r.Remediation, rem.Failures)))
+	if retryAfter < 0 {
+		return 0, false
+	}
+	return retryAfter, false
+}
+
+// isRemediationOf returns true if rem remediates the release of the current generation of o,
+// with the current chart and values of manager.
+func isRemediationOf(rem *types.HelmAppRemediation, o *unstructured.Unstructured, manager release.Manager) bool {
+	return rem.Generation == o.GetGeneration() && rem.Hash == manager.ReleaseHash()
+}
+
+// retryInterval returns how long to wait before retrying after failures failed attempts.
+func retryInterval(rem watches.Remediation, failures int) time.Duration {
+	interval := watches.DefaultRetryInterval
+	if rem.RetryInterval != nil {
+		interval = rem.RetryInterval.Duration
+	}
+	for i := 1; i < failures && i <= maxRetryIntervalDoublings; i++ {
+		interval *= 2
+	}
+	return interval
+}
+
+// clearRemediation removes the remediation of a release that was installed or upgraded.
+func clearRemediation(status *types.HelmAppStatus) {
+	status.Remediation = nil
+	status.RemoveCondition(types.ConditionRemediated)
+}
+
+// releaseFailed records the failed install or upgrade of the release of o. Without a
+// remediation, the error is returned so that it is retried. Otherwise, it is retried after
+// the retry interval, and remediated once all retries failed.
+func (r HelmOperatorReconciler) releaseFailed(ctx context.Context, o *unstructured.Unstructured,
+	manager release.Manager, status *types.HelmAppStatus, reason types.HelmAppConditionReason,
+	releaseErr error) (reconcile.Result, error) {
+
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionReleaseFailed,
+		Status:  types.StatusTrue,
+		Reason:  reason,
+		Message: releaseErr.Error(),
+	})
+	if r.Remediation == nil {
+		if err := r.updateResourceStatus(ctx, o, status); err != nil {
+			log.Error(err, "Failed to update status after release failure")
+		}
+		return reconcile.Result{}, releaseErr
+	}
+
+	rem := status.Remediation
+	if rem == nil || !isRemediationOf(rem, o, manager) {
+		rem = &types.HelmAppRemediation{Generation: o.GetGeneration(), Hash: manager.ReleaseHash()}
+		status.Remediation = rem
+	}
+	rem.Failures++
+	rem.LastFailureTime = metav1.Now()
+
+	attempts := r.Remediation.Retries + 1
+	if rem.Failures < attempts {
+		retryAfter := retryInterval(*r.Remediation, rem.Failures)
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionRemediated,
+			Status:  types.StatusFalse,
+			Reason:  types.ReasonRetryScheduled,
+			Message: fmt.Sprintf("Attempt %d of %d failed, retrying in %s", rem.Failures, attempts, retryAfter),
+		})
+		err := r.updateResourceStatus(ctx, o, status)
+		return reconcile.Result{RequeueAfter: retryAfter}, err
+	}
+
+	rem.Stopped = true
+	condition := r.remediate(ctx, o, manager, status)
+	log.Info("Remediated release", "strategy", r.Remediation.Strategy, "reason", condition.Reason,
+		"message", condition.Message)
+	r.EventRecorder.Event(o, "Warning", string(condition.Reason), condition.Message)
+	status.SetCondition(condition)
+	err := r.updateResourceStatus(ctx, o, status)
+	return reconcile.Result{RequeueAfter: r.requeueAfter(status)}, err
+}
+
+// remediate applies the remediation strategy to the release of o once all attempts to install
+// or upgrade it failed, and returns the condition that records it.
+func (r HelmOperatorReconciler) remediate(ctx context.Context, o *unstructured.Unstructured,
+	manager release.Manager, status *types.HelmAppStatus) types.HelmAppCondition {
+
+	failed := fmt.Sprintf("%d attempts failed", status.Remediation.Failures)
+	condition := types.HelmAppCondition{Type: types.ConditionRemediated, Status: types.StatusFalse}
+
+	switch r.Remediation.Strategy {
+	case watches.RemediationRollback:
+		if !manager.IsInstalled() {
+			condition.Reason = types.ReasonManualInterventionRequired
+			condition.Message = failed + " and there is no deployed revision to roll back to, " +
+				"waiting for the resource to change"
+			return condition
+		}
+		rolledBackRelease, err := manager.RollbackRelease(ctx, r.RollbackOptions...)
+		if err != nil {
+			metrics.ReleaseActionFailed(r.GVK.String(), metrics.ActionRollback)
+			condition.Reason = types.ReasonRollbackError
+			condition.Message = fmt.Sprintf("%s, and rolling back failed: %s", failed, err)
+			return condition
+		}
+		metrics.ReleaseActionSucceeded(r.GVK.String(), metrics.ActionRollback)
+		metrics.ReleaseRevision(r.GVK.String(), o.GetNamespace(), o.GetName(), rolledBackRelease.Version)
+		r.runReleaseHook(rolledBackRelease)
+		// The rolled back revision may have been post-rendered with another configuration.
+		status.DeployedRelease = deployedRelease(rolledBackRelease, "")
+		r.setReadyCondition(ctx, o, status)
+		condition.Status = types.StatusTrue
+		condition.Reason = types.ReasonRollbackSuccessful
+		condition.Message = fmt.Sprintf("%s, rolled back to the last deployed revision as revision %d",
+			failed, rolledBackRelease.Version)
+		return condition
+
+	case watches.RemediationReinstall:
+		if manager.IsInstalled() {
+			_, err := manager.UninstallRelease(ctx)
+			if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+				metrics.ReleaseActionFailed(r.GVK.String(), metrics.ActionUninstall)
+				condition.Reason = types.ReasonReinstallError
+				condition.Message = fmt.Sprintf("%s, and uninstalling failed: %s", failed, err)
+				return condition
+			}
+			if err == nil {
+				metrics.ReleaseActionSucceeded(r.GVK.String(), metrics.ActionUninstall)
+			}
+		}
+		installedRelease, err := manager.InstallRelease(ctx, r.InstallOptions...)
+		if err != nil {
+			metrics.ReleaseActionFailed(r.GVK.String(), metrics.ActionInstall)
+			condition.Reason = types.ReasonReinstallError
+			condition.Message = fmt.Sprintf("%s, and reinstalling failed: %s", failed, err)
+			return condition
+		}
+		if r.claim(o) {
+			if err := r.updateResource(ctx, o); err != nil {
+				log.Error(err, "Failed to add CR uninstall finalizer")
+			}
+		}
+		metrics.ReleaseActionSucceeded(r.GVK.String(), metrics.ActionInstall)
+		metrics.ReleaseRevision(r.GVK.String(), o.GetNamespace(), o.GetName(), installedRelease.Version)
+		r.runReleaseHook(installedRelease)
+		status.RemoveCondition(types.ConditionReleaseFailed)
+		status.SetCondition(types.HelmAppCondition{
+			Type:   types.ConditionDeployed,
+			Status: types.StatusTrue,
+			Reason: types.ReasonInstallSuccessful,
+		})
+		status.DeployedRelease = deployedRelease(installedRelease, manager.PostRenderHash())
+		r.setReadyCondition(ctx, o, status)
+		// The reinstalled release is the expected one, so it is not remediated anymore.
+		status.Remediation = nil
+		condition.Status = types.StatusTrue
+		condition.Reason = types.ReasonReinstallSuccessful
+		condition.Message = failed + ", reinstalled the release"
+		return condition
+	}
+
+	condition.Reason = types.ReasonManualInterventionRequired
+	condition.Message = failed + ", waiting for the resource to change"
+	return condition
+}
+
+// runReleaseHook runs the release hook, if any, on a remediated release.
+func (r HelmOperatorReconciler) runReleaseHook(rel *rpb.Release) {
+	if r.releaseHook == nil {
+		return
+	}
+	if err := r.releaseHook(rel); err != nil {
+		log.Error(err, "Failed to run release hook")
+	}
+}
@@ -0,0 +1,318 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rpb "helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+// fakeManager records the actions of a remediation.
+type fakeManager struct {
+	release.Manager
+	hash       string
+	installed  bool
+	installErr error
+	actions    []string
+}
+
+func (m *fakeManager) ReleaseName() string {
+	return "test"
+}
+
+func (m *fakeManager) ReleaseHash() string {
+	return m.hash
+}
+
+func (m *fakeManager) PostRenderHash() string {
+	return ""
+}
+
+func (m *fakeManager) IsInstalled() bool {
+	return m.installed
+}
+
+func (m *fakeManager) IsAdoptionRequired() bool {
+	return false
+}
+
+func (m *fakeManager) IsUpgradeRequired() bool {
+	return false
+}
+
+func (m *fakeManager) Sync(context.Context) error {
+	return nil
+}
+
+func (m *fakeManager) ReconcileRelease(context.Context) (*rpb.Release, []release.ResourceDrift, error) {
+	m.actions = append(m.actions, "reconcile")
+	return &rpb.Release{Name: "test", Version: 2}, nil, nil
+}
+
+func (m *fakeManager) InstallRelease(context.Context, ...release.InstallOption) (*rpb.Release, error) {
+	m.actions = append(m.actions, "install")
+	if m.installErr != nil {
+		return nil, m.installErr
+	}
+	return &rpb.Release{Name: "test", Version: 1}, nil
+}
+
+func (m *fakeManager) RollbackRelease(context.Context, ...release.RollbackOption) (*rpb.Release, error) {
+	m.actions = append(m.actions, "rollback")
+	return &rpb.Release{Name: "test", Version: 4}, nil
+}
+
+func (m *fakeManager) UninstallRelease(context.Context, ...release.UninstallOption) (*rpb.Release, error) {
+	m.actions = append(m.actions, "uninstall")
+	return &rpb.Release{Name: "test"}, nil
+}
+
+func TestReleaseFailed(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Nginx"}
+	releaseErr := errors.New("upgrade failed")
+
+	testCases := []struct {
+		name             string
+		remediation      *watches.Remediation
+		manager          *fakeManager
+		expectRequeues   []time.Duration
+		expectErr        bool
+		expectActions    []string
+		readiness        *watches.Readiness
+		expectCondition  *types.HelmAppCondition
+		expectRemediated *types.HelmAppRemediation
+	}{
+		{
+			name:           "no remediation",
+			manager:        &fakeManager{installed: true},
+			expectRequeues: []time.Duration{0},
+			expectErr:      true,
+		},
+		{
+			name: "retries with backoff and rollback",
+			remediation: &watches.Remediation{Retries: 2, RetryInterval: &metav1.Duration{Duration: time.Minute},
+				Strategy: watches.RemediationRollback},
+			manager:          &fakeManager{hash: "h4sh", installed: true},
+			expectRequeues:   []time.Duration{time.Minute, 2 * time.Minute, time.Hour},
+			expectActions:    []string{"rollback"},
+			expectCondition:  &types.HelmAppCondition{Status: types.StatusTrue, Reason: types.ReasonRollbackSuccessful},
+			expectRemediated: &types.HelmAppRemediation{Generation: 3, Hash: "h4sh", Failures: 3, Stopped: true},
+		},
+		{
+			name:             "rollback checks readiness",
+			remediation:      &watches.Remediation{Strategy: watches.RemediationRollback},
+			manager:          &fakeManager{installed: true},
+			readiness:        &watches.Readiness{},
+			expectRequeues:   []time.Duration{time.Hour},
+			expectActions:    []string{"rollback"},
+			expectCondition:  &types.HelmAppCondition{Status: types.StatusTrue, Reason: types.ReasonRollbackSuccessful},
+			expectRemediated: &types.HelmAppRemediation{Generation: 3, Failures: 1, Stopped: true},
+		},
+		{
+			name:            "reinstall checks readiness",
+			remediation:     &watches.Remediation{Strategy: watches.RemediationReinstall},
+			manager:         &fakeManager{installed: true},
+			readiness:       &watches.Readiness{},
+			expectRequeues:  []time.Duration{time.Hour},
+			expectActions:   []string{"uninstall", "install"},
+			expectCondition: &types.HelmAppCondition{Status: types.StatusTrue, Reason: types.ReasonReinstallSuccessful},
+		},
+		{
+			name:             "rollback without a deployed revision",
+			remediation:      &watches.Remediation{Strategy: watches.RemediationRollback},
+			manager:          &fakeManager{},
+			expectRequeues:   []time.Duration{time.Hour},
+			expectCondition:  &types.HelmAppCondition{Status: types.StatusFalse, Reason: types.ReasonManualInterventionRequired},
+			expectRemediated: &types.HelmAppRemediation{Generation: 3, Failures: 1, Stopped: true},
+		},
+		{
+			name:            "reinstall",
+			remediation:     &watches.Remediation{Strategy: watches.RemediationReinstall},
+			manager:         &fakeManager{installed: true},
+			expectRequeues:  []time.Duration{time.Hour},
+			expectActions:   []string{"uninstall", "install"},
+			expectCondition: &types.HelmAppCondition{Status: types.StatusTrue, Reason: types.ReasonReinstallSuccessful},
+		},
+		{
+			name:             "failed reinstall",
+			remediation:      &watches.Remediation{Strategy: watches.RemediationReinstall},
+			manager:          &fakeManager{installErr: errors.New("install failed")},
+			expectRequeues:   []time.Duration{time.Hour},
+			expectActions:    []string{"install"},
+			expectCondition:  &types.HelmAppCondition{Status: types.StatusFalse, Reason: types.ReasonReinstallError},
+			expectRemediated: &types.HelmAppRemediation{Generation: 3, Failures: 1, Stopped: true},
+		},
+		{
+			name:             "manual",
+			remediation:      &watches.Remediation{Retries: 1, Strategy: watches.RemediationManual},
+			manager:          &fakeManager{installed: true},
+			expectRequeues:   []time.Duration{watches.DefaultRetryInterval, time.Hour},
+			expectCondition:  &types.HelmAppCondition{Status: types.StatusFalse, Reason: types.ReasonManualInterventionRequired},
+			expectRemediated: &types.HelmAppRemediation{Generation: 3, Failures: 2, Stopped: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cr := &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{}}}
+			cr.SetGroupVersionKind(gvk)
+			cr.SetNamespace("ns")
+			cr.SetName("test")
+			cr.SetGeneration(3)
+			s := runtime.NewScheme()
+			s.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+			r := HelmOperatorReconciler{
+				Client:          fake.NewClientBuilder().WithScheme(s).WithObjects(cr).Build(),
+				EventRecorder:   record.NewFakeRecorder(10),
+				GVK:             gvk,
+				ReconcilePeriod: time.Hour,
+				Remediation:     tc.remediation,
+				Readiness:       tc.readiness,
+			}
+
+			status := &types.HelmAppStatus{}
+			for i, expectRequeue := range tc.expectRequeues {
+				result, err := r.releaseFailed(context.TODO(), cr, tc.manager, status, types.ReasonUpgradeError, releaseErr)
+				if tc.expectErr {
+					assert.Equal(t, releaseErr, err)
+				} else {
+					require.NoError(t, err)
+				}
+				assert.Equal(t, expectRequeue, result.RequeueAfter, "attempt %d", i+1)
+			}
+			assert.Equal(t, tc.expectActions, tc.manager.actions)
+			if tc.readiness != nil {
+				var ready *types.HelmAppCondition
+				for i := range status.Conditions {
+					if status.Conditions[i].Type == types.ConditionReady {
+						ready = &status.Conditions[i]
+					}
+				}
+				require.NotNil(t, ready)
+				assert.Equal(t, types.StatusTrue, ready.Status)
+			}
+
+			if tc.expectCondition == nil {
+				assert.Nil(t, status.Remediation)
+				return
+			}
+			var condition *types.HelmAppCondition
+			for i := range status.Conditions {
+				if status.Conditions[i].Type == types.ConditionRemediated {
+					condition = &status.Conditions[i]
+				}
+			}
+			require.NotNil(t, condition)
+			assert.Equal(t, tc.expectCondition.Status, condition.Status)
+			assert.Equal(t, tc.expectCondition.Reason, condition.Reason)
+			if tc.expectRemediated == nil {
+				assert.Nil(t, status.Remediation)
+				return
+			}
+			require.NotNil(t, status.Remediation)
+			status.Remediation.LastFailureTime = metav1.Time{}
+			assert.Equal(t, tc.expectRemediated, status.Remediation)
+		})
+	}
+}
+
+func TestRemediationState(t *testing.T) {
+	rem := &watches.Remediation{Retries: 3, RetryInterval: &metav1.Duration{Duration: time.Minute}}
+	cr := &unstructured.Unstructured{}
+	cr.SetGeneration(2)
+
+	testCases := []struct {
+		name          string
+		remediation   *watches.Remediation
+		status        *types.HelmAppRemediation
+		expectRetry   bool
+		expectStopped bool
+		expectReset   bool
+	}{
+		{
+			name:        "no remediation",
+			remediation: rem,
+		},
+		{
+			name:        "retry interval not elapsed",
+			remediation: rem,
+			status:      &types.HelmAppRemediation{Generation: 2, Failures: 2, LastFailureTime: metav1.Now()},
+			expectRetry: true,
+		},
+		{
+			name:        "retry interval elapsed",
+			remediation: rem,
+			status: &types.HelmAppRemediation{Generation: 2, Failures: 1,
+				LastFailureTime: metav1.NewTime(time.Now().Add(-2 * time.Minute))},
+		},
+		{
+			name:          "stopped",
+			remediation:   rem,
+			status:        &types.HelmAppRemediation{Generation: 2, Failures: 4, Stopped: true},
+			expectStopped: true,
+		},
+		{
+			name:        "previous generation",
+			remediation: rem,
+			status:      &types.HelmAppRemediation{Generation: 1, Failures: 4, Stopped: true},
+			expectReset: true,
+		},
+		{
+			name:        "changed chart or values",
+			remediation: rem,
+			status:      &types.HelmAppRemediation{Generation: 2, Hash: "0ld", Failures: 4, Stopped: true},
+			expectReset: true,
+		},
+		{
+			name:   "remediation disabled",
+			status: &types.HelmAppRemediation{Generation: 2, Failures: 4, Stopped: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := HelmOperatorReconciler{Remediation: tc.remediation}
+			status := &types.HelmAppStatus{Remediation: tc.status}
+			retryAfter, stopped := r.remediationState(cr, &fakeManager{}, status)
+			assert.Equal(t, tc.expectRetry, retryAfter > 0)
+			assert.Equal(t, tc.expectStopped, stopped)
+			assert.Equal(t, tc.expectReset, tc.status != nil && status.Remediation == nil)
+		})
+	}
+}
+
+func TestRetryInterval(t *testing.T) {
+	rem := watches.Remediation{RetryInterval: &metav1.Duration{Duration: time.Second}}
+	assert.Equal(t, time.Second, retryInterval(rem, 1))
+	assert.Equal(t, 4*time.Second, retryInterval(rem, 3))
+	assert.Equal(t, 1024*time.Second, retryInterval(rem, 100))
+	assert.Equal(t, watches.DefaultRetryInterval, retryInterval(watches.Remediation{}, 1))
+}
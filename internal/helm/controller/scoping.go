@@ -0,0 +1,190 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+	"github.com/operator-framework/operator-sdk/internal/helm/metrics"
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
+)
+
+// watchSelectorAnnotation records the selector of the watch that manages the release of a
+// CR, so that the watch releases the CR once it is relabeled away from the selector.
+const watchSelectorAnnotation = "helm.sdk.operatorframework.io/watch-selector"
+
+// selectorKey returns the key of the Selector of the reconciler that is recorded in the
+// watchSelectorAnnotation of CRs, empty if every CR is selected.
+func (r HelmOperatorReconciler) selectorKey() string {
+	if r.Selector == nil || r.Selector.Empty() {
+		return ""
+	}
+	return r.Selector.String()
+}
+
+// isClaimed returns true if the release of obj is managed by the watch of the reconciler,
+// i.e. obj is in its Namespaces and has its selector key.
+func (r HelmOperatorReconciler) isClaimed(obj client.Object) bool {
+	key := r.selectorKey()
+	if key == "" || obj.GetAnnotations()[watchSelectorAnnotation] != key {
+		return false
+	}
+	withoutLabels := r
+	withoutLabels.Selector = nil
+	return withoutLabels.selects(obj)
+}
+
+// isClaimedByOther returns true if the release of obj is managed by a watch with another
+// selector that still matches the labels of obj. A claim whose selector does not match
+// anymore, e.g. since the watch that recorded it was removed before it released obj, is
+// taken over by the watch that selects obj.
+func (r HelmOperatorReconciler) isClaimedByOther(obj client.Object) bool {
+	key, ok := obj.GetAnnotations()[watchSelectorAnnotation]
+	if !ok || key == r.selectorKey() {
+		return false
+	}
+	selector, err := labels.Parse(key)
+	if err != nil {
+		log.Info("Ignoring invalid watch selector annotation", "namespace", obj.GetNamespace(),
+			"name", obj.GetName(), "selector", key)
+		return false
+	}
+	return selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// reconciles returns true if the reconciler reconciles obj, i.e. it selects obj or it
+// manages the release of obj, which it releases if it does not select obj anymore.
+func (r HelmOperatorReconciler) reconciles(obj client.Object) bool {
+	return r.selects(obj) || r.isClaimed(obj)
+}
+
+// claim adds the uninstall finalizer to o, and records the selector key of the watch of the
+// reconciler in the watchSelectorAnnotation. It returns true if o changed.
+func (r HelmOperatorReconciler) claim(o *unstructured.Unstructured) bool {
+	changed := false
+	if !(controllerutil.ContainsFinalizer(o, uninstallFinalizer) ||
+		controllerutil.ContainsFinalizer(o, uninstallFinalizerLegacy)) {
+
+		log.V(1).Info("Adding finalizer", "finalizer", uninstallFinalizer)
+		controllerutil.AddFinalizer(o, uninstallFinalizer)
+		changed = true
+	}
+	annotations := o.GetAnnotations()
+	key, ok := annotations[watchSelectorAnnotation]
+	switch selectorKey := r.selectorKey(); {
+	case selectorKey == "" && ok:
+		// A watch without a selector took over the release from a watch with a selector.
+		delete(annotations, watchSelectorAnnotation)
+	case selectorKey != "" && key != selectorKey:
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[watchSelectorAnnotation] = selectorKey
+	default:
+		return changed
+	}
+	o.SetAnnotations(annotations)
+	return true
+}
+
+// releaseUnselected releases o, which the watch of the reconciler manages but does not
+// select anymore, by removing its uninstall finalizer and watchSelectorAnnotation, so that
+// the watch that selects it, if any, takes over its release. With UninstallUnselected,
+// the release is uninstalled first.
+func (r HelmOperatorReconciler) releaseUnselected(ctx context.Context, o *unstructured.Unstructured,
+	manager release.Manager) (reconcile.Result, error) {
+
+	log := log.WithValues("namespace", o.GetNamespace(), "name", o.GetName(), "release", manager.ReleaseName())
+
+	if r.UninstallUnselected {
+		if err := r.uninstallUnselected(ctx, o, manager); err != nil {
+			return reconcile.Result{}, err
+		}
+	} else {
+		log.Info("Releasing resource that is not selected anymore")
+		r.EventRecorder.Eventf(o, "Normal", "ReleaseHandedOver",
+			"Stopped managing release %q, since the resource is not selected by the watch anymore",
+			manager.ReleaseName())
+	}
+
+	controllerutil.RemoveFinalizer(o, uninstallFinalizer)
+	controllerutil.RemoveFinalizer(o, uninstallFinalizerLegacy)
+	annotations := o.GetAnnotations()
+	delete(annotations, watchSelectorAnnotation)
+	o.SetAnnotations(annotations)
+	if err := r.updateResource(ctx, o); err != nil {
+		log.Info("Failed to release CR")
+		return reconcile.Result{}, err
+	}
+	metrics.ReleaseDeleted(r.GVK.String(), o.GetNamespace(), o.GetName())
+	return reconcile.Result{}, nil
+}
+
+// uninstallUnselected uninstalls the release of o, which is not selected anymore, and
+// records it in the status of o.
+func (r HelmOperatorReconciler) uninstallUnselected(ctx context.Context, o *unstructured.Unstructured,
+	manager release.Manager) error {
+
+	log := log.WithValues("namespace", o.GetNamespace(), "name", o.GetName(), "release", manager.ReleaseName())
+	status := types.StatusFor(o)
+
+	uninstalledRelease, err := manager.UninstallRelease(ctx)
+	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		metrics.ReleaseActionFailed(r.GVK.String(), metrics.ActionUninstall)
+		log.Error(err, "Failed to uninstall release of resource that is not selected anymore")
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionReleaseFailed,
+			Status:  types.StatusTrue,
+			Reason:  types.ReasonUninstallError,
+			Message: err.Error(),
+		})
+		if err := r.updateResourceStatus(ctx, o, status); err != nil {
+			log.Error(err, "Failed to update status after uninstall release failure")
+		}
+		return err
+	}
+	if uninstalledRelease != nil {
+		metrics.ReleaseActionSucceeded(r.GVK.String(), metrics.ActionUninstall)
+		log.Info("Uninstalled release of resource that is not selected anymore")
+		r.EventRecorder.Eventf(o, "Normal", string(types.ReasonUninstallSuccessful),
+			"Uninstalled release %q, since the resource is not selected by the watch anymore", uninstalledRelease.Name)
+	}
+
+	status.RemoveCondition(types.ConditionReleaseFailed)
+	status.RemoveCondition(types.ConditionReady)
+	status.RemoveCondition(types.ConditionDrifted)
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionDeployed,
+		Status:  types.StatusFalse,
+		Reason:  types.ReasonUninstallSuccessful,
+		Message: "The resource is not selected by the watch anymore.",
+	})
+	status.DeployedRelease = nil
+	clearRemediation(status)
+	if err := r.updateResourceStatus(ctx, o, status); err != nil {
+		log.Info("Failed to update CR status")
+		return err
+	}
+	return nil
+}
@@ -15,6 +15,8 @@
 package flags
 
 import (
+	"os"
+	"path/filepath"
 	"runtime"
 	"time"
 
@@ -33,6 +35,7 @@ type Flags struct {
 	LeaderElectionNamespace string
 	MaxConcurrentReconciles int
 	ProbeAddr               string
+	ChartCacheDir           string
 
 	// Path to a controller-runtime componentconfig file.
 	// If this is empty, use default values.
@@ -53,6 +56,11 @@ func (f *Flags) AddTo(flagSet *pflag.FlagSet) {
 		"./watches.yaml",
 		"Path to the watches file to use",
 	)
+	flagSet.StringVar(&f.ChartCacheDir,
+		"chart-cache-dir",
+		filepath.Join(os.TempDir(), "helm-operator", "charts"),
+		"Directory that the charts pulled from chart repositories and OCI registries are cached in",
+	)
 
 	// Controller flags.
 	flagSet.DurationVar(&f.ReconcilePeriod,
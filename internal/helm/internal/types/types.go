@@ -51,8 +51,12 @@ type HelmAppCondition struct {
 }
 
 type HelmAppRelease struct {
-	Name     string `json:"name,omitempty"`
-	Manifest string `json:"manifest,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Manifest     string `json:"manifest,omitempty"`
+	ChartVersion string `json:"chartVersion,omitempty"`
+	// PostRenderHash is the hash of the post-render configuration that the release was
+	// installed or upgraded with.
+	PostRenderHash string `json:"postRenderHash,omitempty"`
 }
 
 const (
@@ -60,6 +64,9 @@ const (
 	ConditionDeployed       HelmAppConditionType = "Deployed"
 	ConditionReleaseFailed  HelmAppConditionType = "ReleaseFailed"
 	ConditionIrreconcilable HelmAppConditionType = "Irreconcilable"
+	ConditionRemediated     HelmAppConditionType = "Remediated"
+	ConditionReady          HelmAppConditionType = "Ready"
+	ConditionDrifted        HelmAppConditionType = "Drifted"
 
 	StatusTrue    ConditionStatus = "True"
 	StatusFalse   ConditionStatus = "False"
@@ -72,11 +79,40 @@ const (
 	ReasonUpgradeError        HelmAppConditionReason = "UpgradeError"
 	ReasonReconcileError      HelmAppConditionReason = "ReconcileError"
 	ReasonUninstallError      HelmAppConditionReason = "UninstallError"
+	ReasonAdoptError          HelmAppConditionReason = "AdoptError"
+
+	ReasonRetryScheduled             HelmAppConditionReason = "RetryScheduled"
+	ReasonRollbackSuccessful         HelmAppConditionReason = "RollbackSuccessful"
+	ReasonRollbackError              HelmAppConditionReason = "RollbackError"
+	ReasonReinstallSuccessful        HelmAppConditionReason = "ReinstallSuccessful"
+	ReasonReinstallError             HelmAppConditionReason = "ReinstallError"
+	ReasonManualInterventionRequired HelmAppConditionReason = "ManualInterventionRequired"
+
+	ReasonResourcesReady      HelmAppConditionReason = "ResourcesReady"
+	ReasonResourcesNotReady   HelmAppConditionReason = "ResourcesNotReady"
+	ReasonReadinessCheckError HelmAppConditionReason = "ReadinessCheckError"
+
+	ReasonInSync         HelmAppConditionReason = "InSync"
+	ReasonDriftDetected  HelmAppConditionReason = "DriftDetected"
+	ReasonDriftCorrected HelmAppConditionReason = "DriftCorrected"
 )
 
+// HelmAppRemediation records the failed attempts to install or upgrade the release of a
+// generation of a CR.
+type HelmAppRemediation struct {
+	Generation int64 `json:"generation"`
+	// Hash is the hash of the chart and the values of the failed attempts.
+	Hash            string      `json:"hash,omitempty"`
+	Failures        int         `json:"failures"`
+	LastFailureTime metav1.Time `json:"lastFailureTime,omitempty"`
+	// Stopped is true once no more attempts are made for the generation.
+	Stopped bool `json:"stopped,omitempty"`
+}
+
 type HelmAppStatus struct {
-	Conditions      []HelmAppCondition `json:"conditions"`
-	DeployedRelease *HelmAppRelease    `json:"deployedRelease,omitempty"`
+	Conditions      []HelmAppCondition  `json:"conditions"`
+	DeployedRelease *HelmAppRelease     `json:"deployedRelease,omitempty"`
+	Remediation     *HelmAppRemediation `json:"remediation,omitempty"`
 }
 
 func (s *HelmAppStatus) ToMap() (map[string]interface{}, error) {
@@ -15,7 +15,12 @@
 package metrics
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	sdkVersion "github.com/operator-framework/operator-sdk/internal/version"
 )
@@ -24,6 +29,14 @@ const (
 	subsystem = "helm_operator"
 )
 
+// Release actions, as the values of the action label of release_actions_total.
+const (
+	ActionInstall   = "install"
+	ActionUpgrade   = "upgrade"
+	ActionUninstall = "uninstall"
+	ActionRollback  = "rollback"
+)
+
 var (
 	buildInfo = prometheus.NewGauge(
 		prometheus.GaugeOpts{
@@ -36,9 +49,179 @@ var (
 			},
 		},
 	)
+
+	reconciles = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "reconciles_total",
+			Help:      "Counter of reconciles by their result, succeeded or failed.",
+		},
+		[]string{
+			"GVK",
+			"result",
+		})
+
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "reconcile_duration_seconds",
+			Help:      "How long in seconds a reconcile takes.",
+			// Installs and upgrades that wait for their resources can take minutes.
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 14),
+		},
+		[]string{
+			"GVK",
+		})
+
+	releaseActions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "release_actions_total",
+			Help:      "Counter of installs, upgrades, uninstalls and rollbacks of releases by their result, succeeded or failed.",
+		},
+		[]string{
+			"GVK",
+			"action",
+			"result",
+		})
+
+	releaseRevision = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "release_revision",
+			Help:      "Gauge of the deployed revision of the release of a CR.",
+		},
+		[]string{
+			"GVK",
+			"namespace",
+			"name",
+		})
+
+	releaseFailed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "release_failed",
+			Help:      "Gauge of whether the release of a CR is in a failed state, 1 if it is and 0 otherwise.",
+		},
+		[]string{
+			"GVK",
+			"namespace",
+			"name",
+		})
+
+	releaseLastSuccessfulSync = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "release_last_successful_sync_timestamp_seconds",
+			Help:      "Gauge of the Unix time of the last reconcile that installed, upgraded or reconciled the release of a CR.",
+		},
+		[]string{
+			"GVK",
+			"namespace",
+			"name",
+		})
+
+	releaseDriftedFields = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "release_drifted_fields",
+			Help: "Gauge of the fields of the resources of the release of a CR that drifted from the release " +
+				"manifest, 1 for each missing resource.",
+		},
+		[]string{
+			"GVK",
+			"namespace",
+			"name",
+		})
 )
 
+func init() {
+	metrics.Registry.MustRegister(reconciles)
+	metrics.Registry.MustRegister(reconcileDuration)
+	metrics.Registry.MustRegister(releaseActions)
+	metrics.Registry.MustRegister(releaseRevision)
+	metrics.Registry.MustRegister(releaseFailed)
+	metrics.Registry.MustRegister(releaseLastSuccessfulSync)
+	metrics.Registry.MustRegister(releaseDriftedFields)
+}
+
+// We will never want to panic our app because of metric saving.
+// Therefore, we will recover our panics here and error log them
+// for later diagnosis but will never fail the app.
+func recoverMetricPanic() {
+	if r := recover(); r != nil {
+		logf.Log.WithName("metrics").Error(fmt.Errorf("%v", r),
+			"Recovering from metric function")
+	}
+}
+
 func RegisterBuildInfo(r prometheus.Registerer) {
 	buildInfo.Set(1)
 	r.MustRegister(buildInfo)
 }
+
+func ReconcileSucceeded(gvk string) {
+	defer recoverMetricPanic()
+	reconciles.WithLabelValues(gvk, "succeeded").Inc()
+}
+
+func ReconcileFailed(gvk string) {
+	defer recoverMetricPanic()
+	reconciles.WithLabelValues(gvk, "failed").Inc()
+}
+
+func ReconcileTimer(gvk string) *prometheus.Timer {
+	defer recoverMetricPanic()
+	return prometheus.NewTimer(prometheus.ObserverFunc(func(duration float64) {
+		reconcileDuration.WithLabelValues(gvk).Observe(duration)
+	}))
+}
+
+func ReleaseActionSucceeded(gvk, action string) {
+	defer recoverMetricPanic()
+	releaseActions.WithLabelValues(gvk, action, "succeeded").Inc()
+}
+
+func ReleaseActionFailed(gvk, action string) {
+	defer recoverMetricPanic()
+	releaseActions.WithLabelValues(gvk, action, "failed").Inc()
+}
+
+// ReleaseRevision sets the deployed revision of the release of a CR.
+func ReleaseRevision(gvk, namespace, name string, revision int) {
+	defer recoverMetricPanic()
+	releaseRevision.WithLabelValues(gvk, namespace, name).Set(float64(revision))
+}
+
+// ReleaseSynced sets the deployed revision of the release of a CR, and the time of its last
+// successful sync to now.
+func ReleaseSynced(gvk, namespace, name string, revision int) {
+	defer recoverMetricPanic()
+	releaseRevision.WithLabelValues(gvk, namespace, name).Set(float64(revision))
+	releaseLastSuccessfulSync.WithLabelValues(gvk, namespace, name).Set(float64(time.Now().Unix()))
+}
+
+// ReleaseFailed sets whether the release of a CR is in a failed state.
+func ReleaseFailed(gvk, namespace, name string, failed bool) {
+	defer recoverMetricPanic()
+	value := 0.0
+	if failed {
+		value = 1
+	}
+	releaseFailed.WithLabelValues(gvk, namespace, name).Set(value)
+}
+
+// ReleaseDeleted deletes all the series of the release of a CR, once it is uninstalled.
+func ReleaseDeleted(gvk, namespace, name string) {
+	defer recoverMetricPanic()
+	releaseRevision.DeleteLabelValues(gvk, namespace, name)
+	releaseFailed.DeleteLabelValues(gvk, namespace, name)
+	releaseLastSuccessfulSync.DeleteLabelValues(gvk, namespace, name)
+	releaseDriftedFields.DeleteLabelValues(gvk, namespace, name)
+}
+
+// ReleaseDrift sets the number of drifted fields of the resources of the release of a CR.
+func ReleaseDrift(gvk, namespace, name string, fields int) {
+	defer recoverMetricPanic()
+	releaseDriftedFields.WithLabelValues(gvk, namespace, name).Set(float64(fields))
+}
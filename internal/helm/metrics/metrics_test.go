@@ -0,0 +1,57 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleaseSeries(t *testing.T) {
+	gvk := "example.com/v1alpha1, Kind=Nginx"
+
+	ReleaseSynced(gvk, "ns", "a", 3)
+	ReleaseSynced(gvk, "ns", "b", 1)
+	ReleaseFailed(gvk, "ns", "a", true)
+	ReleaseFailed(gvk, "ns", "b", false)
+	ReleaseDrift(gvk, "ns", "a", 2)
+	ReleaseDrift(gvk, "ns", "b", 0)
+
+	assert.Equal(t, float64(3), testutil.ToFloat64(releaseRevision.WithLabelValues(gvk, "ns", "a")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(releaseFailed.WithLabelValues(gvk, "ns", "a")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(releaseFailed.WithLabelValues(gvk, "ns", "b")))
+	assert.NotZero(t, testutil.ToFloat64(releaseLastSuccessfulSync.WithLabelValues(gvk, "ns", "a")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(releaseDriftedFields.WithLabelValues(gvk, "ns", "a")))
+
+	ReleaseDeleted(gvk, "ns", "a")
+	assert.Equal(t, 1, testutil.CollectAndCount(releaseRevision))
+	assert.Equal(t, 1, testutil.CollectAndCount(releaseFailed))
+	assert.Equal(t, 1, testutil.CollectAndCount(releaseLastSuccessfulSync))
+	assert.Equal(t, 1, testutil.CollectAndCount(releaseDriftedFields))
+}
+
+func TestReleaseActions(t *testing.T) {
+	gvk := "example.com/v1alpha1, Kind=Memcached"
+
+	ReleaseActionSucceeded(gvk, ActionInstall)
+	ReleaseActionFailed(gvk, ActionUpgrade)
+	ReleaseActionSucceeded(gvk, ActionUpgrade)
+	ReleaseActionFailed(gvk, ActionUpgrade)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(releaseActions.WithLabelValues(gvk, ActionInstall, "succeeded")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(releaseActions.WithLabelValues(gvk, ActionUpgrade, "failed")))
+}
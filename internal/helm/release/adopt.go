@@ -0,0 +1,158 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/operator-framework/operator-lib/handler"
+	rpb "helm.sh/helm/v3/pkg/release"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// AdoptReleaseAnnotation, set to "true" on a CR, adopts an existing release of the chart
+// of its watch, e.g. one installed with helm install, whose name is the release name of the CR.
+const AdoptReleaseAnnotation = "helm.sdk.operatorframework.io/adopt-release"
+
+// IsAdoptionRequired returns true if the deployed release was not deployed for the CR,
+// and the CR adopts it.
+func (m manager) IsAdoptionRequired() bool {
+	return m.adopt && m.isInstalled
+}
+
+// AdoptRelease makes the CR the owner of the existing resources of the deployed release,
+// as it is of the resources of the releases that it installs. Missing resources are
+// created when the release is reconciled.
+func (m manager) AdoptRelease(ctx context.Context) (*rpb.Release, error) {
+	// The client injects the owner references and annotations of the CR into the resources.
+	expectedInfos, err := m.kubeClient.Build(bytes.NewBufferString(m.deployedRelease.Manifest), false)
+	if err != nil {
+		return nil, err
+	}
+	err = expectedInfos.Visit(func(expected *resource.Info, err error) error {
+		if err != nil {
+			return fmt.Errorf("visit error: %w", err)
+		}
+		helper := resource.NewHelper(expected.Client, expected.Mapping)
+		existing, err := helper.Get(expected.Namespace, expected.Name)
+		if apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("could not get object: %w", err)
+		}
+
+		patch, err := ownershipPatch(existing, expected.Object)
+		if err != nil {
+			return fmt.Errorf("could not adopt %s %s: %w", expected.Mapping.GroupVersionKind.Kind, expected.Name, err)
+		}
+		if patch == nil {
+			return nil
+		}
+		_, err = helper.Patch(expected.Namespace, expected.Name, apitypes.MergePatchType, patch, &metav1.PatchOptions{})
+		if err != nil {
+			return fmt.Errorf("patch error: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to adopt release: %w", err)
+	}
+	return m.deployedRelease, nil
+}
+
+// ownershipPatch returns a JSON merge patch that adds the owner references and owner
+// annotations of expected to existing, or nil if it already has them. The patch fails if
+// existing changed since it was read. It returns an error if existing has another controller.
+func ownershipPatch(existing, expected runtime.Object) ([]byte, error) {
+	existingMeta, err := meta.Accessor(existing)
+	if err != nil {
+		return nil, err
+	}
+	expectedMeta, err := meta.Accessor(expected)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := false
+	ownerRefs := existingMeta.GetOwnerReferences()
+	for _, ref := range expectedMeta.GetOwnerReferences() {
+		if hasOwnerReference(ownerRefs, ref.UID) {
+			continue
+		}
+		if ref.Controller != nil && *ref.Controller {
+			if controller := metav1.GetControllerOf(existingMeta); controller != nil {
+				return nil, fmt.Errorf("it is controlled by %s %s", controller.Kind, controller.Name)
+			}
+		}
+		ownerRefs = append(ownerRefs, ref)
+		changed = true
+	}
+
+	annotations := map[string]interface{}{}
+	for _, key := range []string{handler.NamespacedNameAnnotation, handler.TypeAnnotation} {
+		if value, ok := expectedMeta.GetAnnotations()[key]; ok && existingMeta.GetAnnotations()[key] != value {
+			annotations[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, nil
+	}
+
+	patchMeta := map[string]interface{}{"resourceVersion": existingMeta.GetResourceVersion()}
+	if len(ownerRefs) > len(existingMeta.GetOwnerReferences()) {
+		patchMeta["ownerReferences"] = ownerRefs
+	}
+	if len(annotations) > 0 {
+		patchMeta["annotations"] = annotations
+	}
+	return json.Marshal(map[string]interface{}{"metadata": patchMeta})
+}
+
+// hasOwnerReference returns true if refs has a reference to the owner with uid.
+func hasOwnerReference(refs []metav1.OwnerReference, uid apitypes.UID) bool {
+	for _, ref := range refs {
+		if ref.UID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// isAdoptionRequested returns true if cr has the AdoptReleaseAnnotation set to "true".
+func isAdoptionRequested(cr *unstructured.Unstructured) bool {
+	adopt, _ := strconv.ParseBool(cr.GetAnnotations()[AdoptReleaseAnnotation])
+	return adopt
+}
+
+// hasDeployedRevision returns true if a revision of history is deployed.
+func hasDeployedRevision(history []*rpb.Release) bool {
+	for _, rel := range history {
+		if rel.Info != nil && rel.Info.Status == rpb.StatusDeployed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"testing"
+
+	"github.com/operator-framework/operator-lib/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cpb "helm.sh/helm/v3/pkg/chart"
+	rpb "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestOwnershipPatch(t *testing.T) {
+	trueVal := true
+	crRef := metav1.OwnerReference{APIVersion: "example.com/v1alpha1", Kind: "Nginx", Name: "web", UID: "cr-uid",
+		Controller: &trueVal, BlockOwnerDeletion: &trueVal}
+	otherRef := metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "owner", UID: "other-uid"}
+
+	newObject := func(refs []metav1.OwnerReference, annotations map[string]string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind("Service")
+		u.SetName("web")
+		u.SetResourceVersion("42")
+		u.SetOwnerReferences(refs)
+		u.SetAnnotations(annotations)
+		return u
+	}
+	ownerAnnotations := map[string]string{
+		handler.NamespacedNameAnnotation: "ns/web",
+		handler.TypeAnnotation:           "Nginx.example.com",
+	}
+
+	testCases := []struct {
+		name        string
+		existing    *unstructured.Unstructured
+		expected    *unstructured.Unstructured
+		expectPatch string
+		expectErr   bool
+	}{
+		{
+			name:     "owner reference",
+			existing: newObject([]metav1.OwnerReference{otherRef}, nil),
+			expected: newObject([]metav1.OwnerReference{crRef}, nil),
+			expectPatch: `{"metadata":{"ownerReferences":[` +
+				`{"apiVersion":"v1","kind":"ConfigMap","name":"owner","uid":"other-uid"},` +
+				`{"apiVersion":"example.com/v1alpha1","kind":"Nginx","name":"web","uid":"cr-uid",` +
+				`"controller":true,"blockOwnerDeletion":true}],"resourceVersion":"42"}}`,
+		},
+		{
+			name:     "owner annotations",
+			existing: newObject(nil, map[string]string{"a": "b"}),
+			expected: newObject(nil, ownerAnnotations),
+			expectPatch: `{"metadata":{"annotations":{"operator-sdk/primary-resource":"ns/web",` +
+				`"operator-sdk/primary-resource-type":"Nginx.example.com"},"resourceVersion":"42"}}`,
+		},
+		{
+			name:     "already owned",
+			existing: newObject([]metav1.OwnerReference{crRef}, ownerAnnotations),
+			expected: newObject([]metav1.OwnerReference{crRef}, ownerAnnotations),
+		},
+		{
+			name: "other controller",
+			existing: newObject([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs",
+				UID: "rs-uid", Controller: &trueVal}}, nil),
+			expected:  newObject([]metav1.OwnerReference{crRef}, nil),
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			patch, err := ownershipPatch(tc.existing, tc.expected)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tc.expectPatch == "" {
+				assert.Nil(t, patch)
+				return
+			}
+			assert.JSONEq(t, tc.expectPatch, string(patch))
+		})
+	}
+}
+
+func TestGetReleaseName(t *testing.T) {
+	newRelease := func(name, chart string, status rpb.Status) *rpb.Release {
+		return &rpb.Release{
+			Name:    name,
+			Version: 1,
+			Chart:   &cpb.Chart{Metadata: &cpb.Metadata{Name: chart}},
+			Info:    &rpb.Info{Status: status},
+		}
+	}
+	newCR := func(annotations map[string]string, deployedRelease string) *unstructured.Unstructured {
+		cr := &unstructured.Unstructured{}
+		cr.SetGroupVersionKind(schema.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Nginx"})
+		cr.SetNamespace("ns")
+		cr.SetName("web")
+		cr.SetAnnotations(annotations)
+		if deployedRelease != "" {
+			cr.Object["status"] = map[string]interface{}{
+				"deployedRelease": map[string]interface{}{"name": deployedRelease},
+			}
+		}
+		return cr
+	}
+	adopt := map[string]string{AdoptReleaseAnnotation: "true"}
+
+	testCases := []struct {
+		name          string
+		releases      []*rpb.Release
+		cr            *unstructured.Unstructured
+		tmpl          string
+		expectName    string
+		expectAdopt   bool
+		expectedError bool
+	}{
+		{
+			name:       "no release",
+			cr:         newCR(nil, ""),
+			expectName: "web",
+		},
+		{
+			name:       "template",
+			cr:         newCR(nil, ""),
+			tmpl:       "{{.Name}}-{{.Kind}}",
+			expectName: "web-nginx",
+		},
+		{
+			name:       "deployed release of the CR",
+			releases:   []*rpb.Release{newRelease("web", "nginx", rpb.StatusDeployed)},
+			cr:         newCR(nil, "web"),
+			tmpl:       "{{.Name}}-{{.Kind}}",
+			expectName: "web",
+		},
+		{
+			name:       "existing release named after the CR",
+			releases:   []*rpb.Release{newRelease("web", "nginx", rpb.StatusDeployed)},
+			cr:         newCR(nil, ""),
+			expectName: "web",
+		},
+		{
+			name:        "adopted release named after the CR",
+			releases:    []*rpb.Release{newRelease("web", "nginx", rpb.StatusDeployed)},
+			cr:          newCR(adopt, ""),
+			expectName:  "web",
+			expectAdopt: true,
+		},
+		{
+			name:          "existing release with a template name",
+			releases:      []*rpb.Release{newRelease("web-nginx", "nginx", rpb.StatusDeployed)},
+			cr:            newCR(nil, ""),
+			tmpl:          "{{.Name}}-{{.Kind}}",
+			expectedError: true,
+		},
+		{
+			name:        "adopted release with a template name",
+			releases:    []*rpb.Release{newRelease("web-nginx", "nginx", rpb.StatusDeployed)},
+			cr:          newCR(adopt, ""),
+			tmpl:        "{{.Name}}-{{.Kind}}",
+			expectName:  "web-nginx",
+			expectAdopt: true,
+		},
+		{
+			name:          "release named after the CR of another chart",
+			releases:      []*rpb.Release{newRelease("web", "redis", rpb.StatusDeployed)},
+			cr:            newCR(nil, ""),
+			expectedError: true,
+		},
+		{
+			name:       "release without deployed revision",
+			releases:   []*rpb.Release{newRelease("web", "nginx", rpb.StatusPendingInstall)},
+			cr:         newCR(nil, ""),
+			expectName: "web",
+		},
+		{
+			name:          "release of another chart",
+			releases:      []*rpb.Release{newRelease("web", "redis", rpb.StatusDeployed)},
+			cr:            newCR(adopt, ""),
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			storageBackend := storage.Init(driver.NewMemory())
+			for _, rel := range tc.releases {
+				require.NoError(t, storageBackend.Create(rel))
+			}
+
+			name, adopt, err := getReleaseName(storageBackend, "nginx", tc.cr, tc.tmpl)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectName, name)
+			assert.Equal(t, tc.expectAdopt, adopt)
+		})
+	}
+}
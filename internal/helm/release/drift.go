@@ -0,0 +1,156 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	jsonpatch "gomodules.xyz/jsonpatch/v3"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/diff"
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+// ResourceDrift describes how a resource of a release drifted from the release manifest.
+type ResourceDrift struct {
+	schema.GroupVersionKind
+	Namespace string
+	Name      string
+	// Missing is true if the resource does not exist.
+	Missing bool
+	// Fields are the JSON pointers of the fields whose live values differ from the manifest.
+	Fields []string
+	// Diff is the diff from the live resource, restricted to the fields in the manifest,
+	// to the manifest. The values of the data of Secrets are redacted.
+	Diff string
+	// Corrected is true if the resource was created or patched back to the manifest.
+	Corrected bool
+}
+
+// String returns the kind, namespace and name of the drifted resource.
+func (d ResourceDrift) String() string {
+	if d.Namespace == "" {
+		return fmt.Sprintf("%s %s", d.Kind, d.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", d.Kind, d.Namespace, d.Name)
+}
+
+// WithDrift configures how the resources of releases that drifted from their manifest are handled.
+func WithDrift(drift watches.Drift) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.drift = &drift
+	}
+}
+
+// driftedFields returns the JSON pointers of the fields of expected whose values differ in
+// existing. Fields that are only in existing, e.g. those added by Kubernetes, do not drift.
+func driftedFields(existingJSON, expectedJSON []byte) ([]string, error) {
+	ops, err := jsonpatch.CreatePatch(existingJSON, expectedJSON)
+	if err != nil {
+		return nil, err
+	}
+	fields := []string{}
+	for _, op := range ops {
+		if op.Operation != "remove" && !(op.Operation == "add" && op.Value == nil) {
+			fields = append(fields, op.Path)
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// driftDiff returns the diff from existing, restricted to the fields in expected, to expected.
+func driftDiff(existing, expected map[string]interface{}) (string, error) {
+	existingYAML, err := yaml.Marshal(restrictTo(existing, expected))
+	if err != nil {
+		return "", err
+	}
+	expectedYAML, err := yaml.Marshal(expected)
+	if err != nil {
+		return "", err
+	}
+	return diff.Generate(string(existingYAML), string(expectedYAML)), nil
+}
+
+// redactedValue replaces the values of the data of Secrets in drift diffs.
+const redactedValue = "<redacted>"
+
+// redactSecretData replaces the values of the data and stringData of a Secret in existing
+// and expected, so that drift diffs do not leak them. The expected values that differ from
+// the existing ones are still marked as changed.
+func redactSecretData(existing, expected map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	existing = runtime.DeepCopyJSON(existing)
+	expected = runtime.DeepCopyJSON(expected)
+	for _, field := range []string{"data", "stringData"} {
+		existingData, _ := existing[field].(map[string]interface{})
+		expectedData, _ := expected[field].(map[string]interface{})
+		for k, v := range expectedData {
+			expectedData[k] = redactedValue
+			if ev, ok := existingData[k]; ok && !reflect.DeepEqual(ev, v) {
+				expectedData[k] = redactedValue + " (changed)"
+			}
+		}
+		for k := range existingData {
+			existingData[k] = redactedValue
+		}
+	}
+	return existing, expected
+}
+
+// restrictTo returns the fields of live that are also in expected. Items of lists that
+// are only in live are kept.
+func restrictTo(live, expected interface{}) interface{} {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return live
+		}
+		out := make(map[string]interface{}, len(e))
+		for k, ev := range e {
+			if lv, ok := l[k]; ok {
+				out[k] = restrictTo(lv, ev)
+			}
+		}
+		return out
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok {
+			return live
+		}
+		out := make([]interface{}, len(l))
+		for i, lv := range l {
+			out[i] = lv
+			if i < len(e) {
+				out[i] = restrictTo(lv, e[i])
+			}
+		}
+		return out
+	}
+	return live
+}
+
+// toMap returns the JSON representation of an object as a map.
+func toMap(objJSON []byte) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	err := json.Unmarshal(objJSON, &m)
+	return m, err
+}
@@ -0,0 +1,151 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+func TestDetectDrift(t *testing.T) {
+	expected := `{apiVersion: apps/v1, kind: Deployment, metadata: {name: web, namespace: ns, labels: {app: web}},
+spec: {replicas: 2, template: {metadata: {annotations: {a/b: c}},
+spec: {containers: [{name: web, image: "web:1"}]}}}}`
+	ignoreReplicas := []watches.IgnoreDifference{
+		{Group: "apps", Kind: "Deployment", JSONPointers: []string{"/spec/replicas", "/spec/template/metadata/annotations/a~1b"}},
+	}
+
+	testCases := []struct {
+		name           string
+		existing       string
+		ignores        []watches.IgnoreDifference
+		expectFields   []string
+		expectReplicas interface{}
+	}{
+		{
+			name: "in sync with fields added by Kubernetes",
+			existing: `{apiVersion: apps/v1, kind: Deployment,
+metadata: {name: web, namespace: ns, labels: {app: web}, resourceVersion: "42"},
+spec: {replicas: 2, strategy: {type: RollingUpdate}, template: {metadata: {annotations: {a/b: c}},
+spec: {containers: [{name: web, image: "web:1", imagePullPolicy: IfNotPresent}]}}},
+status: {replicas: 2}}`,
+			expectFields:   []string{},
+			expectReplicas: float64(2),
+		},
+		{
+			name: "drifted",
+			existing: `{apiVersion: apps/v1, kind: Deployment, metadata: {name: web, namespace: ns},
+spec: {replicas: 5, template: {metadata: {annotations: {a/b: d}}, spec: {containers: [{name: web, image: "web:2"}]}}}}`,
+			expectFields: []string{"/metadata/labels", "/spec/replicas", "/spec/template/metadata/annotations/a~1b",
+				"/spec/template/spec/containers/0/image"},
+			expectReplicas: float64(2),
+		},
+		{
+			name: "ignored fields",
+			existing: `{apiVersion: apps/v1, kind: Deployment, metadata: {name: web, namespace: ns, labels: {app: web}},
+spec: {replicas: 5, template: {metadata: {annotations: {a/b: d}}, spec: {containers: [{name: web, image: "web:2"}]}}}}`,
+			ignores:        ignoreReplicas,
+			expectFields:   []string{"/spec/template/spec/containers/0/image"},
+			expectReplicas: float64(5),
+		},
+		{
+			name: "ignored field that does not exist",
+			existing: `{apiVersion: apps/v1, kind: Deployment, metadata: {name: web, namespace: ns, labels: {app: web}},
+spec: {template: {metadata: {annotations: {a/b: c}}, spec: {containers: [{name: web, image: "web:1"}]}}}}`,
+			ignores:      ignoreReplicas,
+			expectFields: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			existing := &unstructured.Unstructured{}
+			require.NoError(t, yaml.Unmarshal([]byte(tc.existing), &existing.Object))
+			info := &resource.Info{Object: &unstructured.Unstructured{}}
+			require.NoError(t, yaml.Unmarshal([]byte(expected), &info.Object.(*unstructured.Unstructured).Object))
+			rd := &ResourceDrift{GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}}
+
+			require.NoError(t, detectDrift(tc.ignores, existing, info, rd))
+			assert.Equal(t, tc.expectFields, rd.Fields)
+			assert.Equal(t, len(tc.expectFields) > 0, rd.Diff != "")
+			replicas, _, _ := unstructured.NestedFieldNoCopy(info.Object.(*unstructured.Unstructured).Object,
+				"spec", "replicas")
+			assert.Equal(t, tc.expectReplicas, replicas)
+		})
+	}
+}
+
+func TestRestrictTo(t *testing.T) {
+	live := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": 3,
+			"paused":   false,
+			"containers": []interface{}{
+				map[string]interface{}{"name": "a", "image": "a:1", "imagePullPolicy": "Always"},
+				map[string]interface{}{"name": "sidecar"},
+			},
+		},
+		"status": map[string]interface{}{},
+	}
+	expected := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas":   2,
+			"containers": []interface{}{map[string]interface{}{"name": "a", "image": "a:2"}},
+		},
+	}
+	assert.Equal(t, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": 3,
+			"containers": []interface{}{
+				map[string]interface{}{"name": "a", "image": "a:1"},
+				map[string]interface{}{"name": "sidecar"},
+			},
+		},
+	}, restrictTo(live, expected))
+}
+
+func TestResourceDriftString(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	assert.Equal(t, "ConfigMap ns/config", ResourceDrift{GroupVersionKind: gvk, Namespace: "ns", Name: "config"}.String())
+	gvk.Kind = "Namespace"
+	assert.Equal(t, "Namespace ns", ResourceDrift{GroupVersionKind: gvk, Name: "ns"}.String())
+}
+
+func TestDetectSecretDrift(t *testing.T) {
+	existing := &unstructured.Unstructured{}
+	require.NoError(t, yaml.Unmarshal([]byte(`{apiVersion: v1, kind: Secret, metadata: {name: s, namespace: ns},
+data: {user: YWRtaW4=, password: b2xk}}`), &existing.Object))
+	info := &resource.Info{Object: &unstructured.Unstructured{}}
+	require.NoError(t, yaml.Unmarshal([]byte(`{apiVersion: v1, kind: Secret, metadata: {name: s, namespace: ns},
+data: {user: YWRtaW4=, password: bmV3}}`), &info.Object.(*unstructured.Unstructured).Object))
+	rd := &ResourceDrift{GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Secret"}}
+
+	require.NoError(t, detectDrift(nil, existing, info, rd))
+	assert.Equal(t, []string{"/data/password"}, rd.Fields)
+	assert.Contains(t, rd.Diff, "password: <redacted> (changed)")
+	assert.NotContains(t, rd.Diff, "b2xk")
+	assert.NotContains(t, rd.Diff, "bmV3")
+	assert.NotContains(t, rd.Diff, "YWRtaW4=")
+	password, _, _ := unstructured.NestedString(info.Object.(*unstructured.Unstructured).Object, "data", "password")
+	assert.Equal(t, "bmV3", password)
+}
@@ -0,0 +1,305 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+// WithIgnoreDifferences configures fields of the resources of releases that are neither
+// reported as drifted, nor corrected, nor changed by upgrades and rollbacks.
+func WithIgnoreDifferences(ignores []watches.IgnoreDifference) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.ignoreDifferences = ignores
+	}
+}
+
+// WithServerSideApply configures whether drifted resources are corrected with server-side apply.
+func WithServerSideApply(serverSideApply bool) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.serverSideApply = serverSideApply
+	}
+}
+
+// ignoreDifferences removes the fields of the resources of gk that ignores select from
+// expected, if omit is true. Otherwise, they are set to their values in existing, so that
+// they neither drift nor change, and only removed from expected if they are not in existing.
+func ignoreDifferences(ignores []watches.IgnoreDifference, gk schema.GroupKind, existing,
+	expected map[string]interface{}, omit bool) {
+
+	for _, ignore := range ignores {
+		if ignore.Group != gk.Group || ignore.Kind != gk.Kind {
+			continue
+		}
+		for _, p := range ignore.JSONPointers {
+			tokens := pointerTokens(p)
+			if v, ok := pointerGet(existing, tokens); ok && !omit {
+				pointerSet(expected, tokens, v)
+			} else {
+				pointerRemove(expected, tokens)
+			}
+		}
+		for _, fields := range managedFieldSets(existing, ignore.ManagedFieldsManagers) {
+			ignoreManagedFields(fields, existing, expected, omit)
+		}
+	}
+}
+
+// hasIgnoreDifferences returns true if ignores select fields of the resources of gk.
+func hasIgnoreDifferences(ignores []watches.IgnoreDifference, gk schema.GroupKind) bool {
+	for _, ignore := range ignores {
+		if ignore.Group == gk.Group && ignore.Kind == gk.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+// managedFieldSets returns the FieldsV1 sets of the managed fields entries of obj whose
+// manager is one of managers.
+func managedFieldSets(obj map[string]interface{}, managers []string) []map[string]interface{} {
+	if len(managers) == 0 {
+		return nil
+	}
+	entries, _, _ := unstructured.NestedSlice(obj, "metadata", "managedFields")
+	var sets []map[string]interface{}
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fields, ok := entry["fieldsV1"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, manager := range managers {
+			if entry["manager"] == manager {
+				sets = append(sets, fields)
+				break
+			}
+		}
+	}
+	return sets
+}
+
+// appliedBy returns true if obj has fields applied with server-side apply by manager.
+func appliedBy(obj map[string]interface{}, manager string) bool {
+	entries, _, _ := unstructured.NestedSlice(obj, "metadata", "managedFields")
+	for _, e := range entries {
+		if entry, ok := e.(map[string]interface{}); ok && entry["manager"] == manager && entry["operation"] == "Apply" {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreManagedFields ignores the fields of expected that are in the FieldsV1 set fields
+// of live, as ignoreDifferences does. Items of lists that are only selected as a whole are
+// not removed from expected.
+func ignoreManagedFields(fields map[string]interface{}, live, expected interface{}, omit bool) {
+	for key, child := range fields {
+		childFields, _ := child.(map[string]interface{})
+		// A set without fields other than "." selects the whole value.
+		whole := len(childFields) == 0 || (len(childFields) == 1 && childFields["."] != nil)
+
+		switch {
+		case strings.HasPrefix(key, "f:"):
+			name := strings.TrimPrefix(key, "f:")
+			e, ok := expected.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			l, _ := live.(map[string]interface{})
+			lv, inLive := l[name]
+			if whole {
+				if omit || !inLive {
+					delete(e, name)
+				} else {
+					e[name] = lv
+				}
+			} else if ev, ok := e[name]; ok {
+				ignoreManagedFields(childFields, lv, ev, omit)
+			}
+		case strings.HasPrefix(key, "k:"):
+			itemKey := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(key, "k:")), &itemKey); err != nil {
+				continue
+			}
+			e, _ := expected.([]interface{})
+			ei := indexOfItem(e, itemKey)
+			if ei < 0 {
+				continue
+			}
+			l, _ := live.([]interface{})
+			var lv interface{}
+			if li := indexOfItem(l, itemKey); li >= 0 {
+				lv = l[li]
+			}
+			if whole {
+				if !omit && lv != nil {
+					e[ei] = lv
+				}
+			} else {
+				ignoreManagedFields(childFields, lv, e[ei], omit)
+			}
+		}
+	}
+}
+
+// indexOfItem returns the index of the item of list whose fields have the values of key,
+// or -1 if there is none.
+func indexOfItem(list []interface{}, key map[string]interface{}) int {
+	for i, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		matches := true
+		for k, v := range key {
+			if !reflect.DeepEqual(m[k], v) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return i
+		}
+	}
+	return -1
+}
+
+// ignoreDifferencesClient ignores fields of the resources that it updates, so that upgrades
+// and rollbacks do not change them.
+type ignoreDifferencesClient struct {
+	kube.Interface
+	ignores []watches.IgnoreDifference
+}
+
+// Update sets the ignored fields of the resources of target to their live values, and
+// updates them.
+func (c ignoreDifferencesClient) Update(original, target kube.ResourceList, force bool) (*kube.Result, error) {
+	err := target.Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		gk := info.Mapping.GroupVersionKind.GroupKind()
+		if !hasIgnoreDifferences(c.ignores, gk) {
+			return nil
+		}
+		existing, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+		if apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("could not get object: %w", err)
+		}
+		existingMap, err := objectMap(existing)
+		if err != nil {
+			return err
+		}
+		targetMap, err := objectMap(info.Object)
+		if err != nil {
+			return err
+		}
+		ignoreDifferences(c.ignores, gk, existingMap, targetMap, false)
+		info.Object = &unstructured.Unstructured{Object: targetMap}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to ignore differences: %w", err)
+	}
+	return c.Interface.Update(original, target, force)
+}
+
+// objectMap returns a copy of the JSON representation of obj as a map.
+func objectMap(obj runtime.Object) (map[string]interface{}, error) {
+	objJSON, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return toMap(objJSON)
+}
+
+// pointerTokens returns the unescaped reference tokens of the JSON pointer p.
+func pointerTokens(p string) []string {
+	tokens := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens
+}
+
+// pointerGet returns the value that tokens reference in obj.
+func pointerGet(obj interface{}, tokens []string) (interface{}, bool) {
+	for _, t := range tokens {
+		switch o := obj.(type) {
+		case map[string]interface{}:
+			v, ok := o[t]
+			if !ok {
+				return nil, false
+			}
+			obj = v
+		case []interface{}:
+			i, err := strconv.Atoi(t)
+			if err != nil || i < 0 || i >= len(o) {
+				return nil, false
+			}
+			obj = o[i]
+		default:
+			return nil, false
+		}
+	}
+	return obj, true
+}
+
+// pointerSet sets the value that tokens reference in obj to v, if its parent exists.
+func pointerSet(obj interface{}, tokens []string, v interface{}) {
+	parent, ok := pointerGet(obj, tokens[:len(tokens)-1])
+	if !ok {
+		return
+	}
+	last := tokens[len(tokens)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[last] = v
+	case []interface{}:
+		if i, err := strconv.Atoi(last); err == nil && i >= 0 && i < len(p) {
+			p[i] = v
+		}
+	}
+}
+
+// pointerRemove removes the field that tokens reference in obj, if it is in a map.
+func pointerRemove(obj interface{}, tokens []string) {
+	parent, ok := pointerGet(obj, tokens[:len(tokens)-1])
+	if !ok {
+		return
+	}
+	if p, ok := parent.(map[string]interface{}); ok {
+		delete(p, tokens[len(tokens)-1])
+	}
+}
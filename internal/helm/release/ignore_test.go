@@ -0,0 +1,173 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+func TestIgnoreDifferences(t *testing.T) {
+	gk := schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	existing := `
+metadata:
+  name: web
+  annotations:
+    injector/status: injected
+  managedFields:
+  - manager: helm-operator
+    operation: Update
+    fieldsV1: {"f:spec": {"f:replicas": {}}}
+  - manager: kube-controller-manager
+    operation: Update
+    fieldsV1: {"f:spec": {"f:replicas": {}}}
+  - manager: injector
+    operation: Update
+    fieldsV1:
+      f:metadata: {f:annotations: {f:injector/status: {}}}
+      f:spec:
+        f:template:
+          f:spec:
+            f:containers:
+              k:{"name":"web"}: {.: {}, f:env: {}}
+              k:{"name":"proxy"}: {.: {}, f:image: {}}
+spec:
+  replicas: 5
+  template:
+    spec:
+      containers:
+      - name: web
+        image: web:1
+        env: [{name: PROXY, value: "on"}]
+      - name: proxy
+        image: proxy:2
+`
+	expected := `
+metadata:
+  name: web
+  annotations:
+    injector/status: pending
+spec:
+  replicas: 2
+  paused: false
+  template:
+    spec:
+      containers:
+      - name: web
+        image: web:1
+`
+
+	testCases := []struct {
+		name     string
+		ignores  []watches.IgnoreDifference
+		omit     bool
+		expected string
+	}{
+		{
+			name: "managed fields",
+			ignores: []watches.IgnoreDifference{
+				{Group: "apps", Kind: "Deployment", ManagedFieldsManagers: []string{"kube-controller-manager", "injector"}},
+			},
+			expected: `
+metadata:
+  name: web
+  annotations:
+    injector/status: injected
+spec:
+  replicas: 5
+  paused: false
+  template:
+    spec:
+      containers:
+      - name: web
+        image: web:1
+        env: [{name: PROXY, value: "on"}]
+`,
+		},
+		{
+			name: "omitted managed fields",
+			ignores: []watches.IgnoreDifference{
+				{Group: "apps", Kind: "Deployment", ManagedFieldsManagers: []string{"kube-controller-manager", "injector"}},
+			},
+			omit: true,
+			expected: `
+metadata:
+  name: web
+  annotations: {}
+spec:
+  paused: false
+  template:
+    spec:
+      containers:
+      - name: web
+        image: web:1
+`,
+		},
+		{
+			name: "JSON pointers",
+			ignores: []watches.IgnoreDifference{
+				{Group: "apps", Kind: "Deployment", JSONPointers: []string{"/spec/replicas", "/spec/paused"}},
+			},
+			expected: `
+metadata:
+  name: web
+  annotations:
+    injector/status: pending
+spec:
+  replicas: 5
+  template:
+    spec:
+      containers:
+      - name: web
+        image: web:1
+`,
+		},
+		{
+			name:     "other kind",
+			ignores:  []watches.IgnoreDifference{{Kind: "Deployment", JSONPointers: []string{"/spec/replicas"}}},
+			expected: expected,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			existingMap, expectedMap, resultMap := map[string]interface{}{}, map[string]interface{}{}, map[string]interface{}{}
+			require.NoError(t, yaml.Unmarshal([]byte(existing), &existingMap))
+			require.NoError(t, yaml.Unmarshal([]byte(expected), &expectedMap))
+			require.NoError(t, yaml.Unmarshal([]byte(tc.expected), &resultMap))
+
+			ignoreDifferences(tc.ignores, gk, existingMap, expectedMap, tc.omit)
+			assert.Equal(t, resultMap, expectedMap)
+		})
+	}
+}
+
+func TestAppliedBy(t *testing.T) {
+	obj := map[string]interface{}{}
+	require.NoError(t, yaml.Unmarshal([]byte(`
+metadata:
+  managedFields:
+  - {manager: helm-operator, operation: Update}
+  - {manager: kubectl, operation: Apply}
+`), &obj))
+	assert.False(t, appliedBy(obj, watches.ServerSideApplyFieldManager))
+	assert.True(t, appliedBy(obj, "kubectl"))
+}
@@ -17,15 +17,20 @@ package release
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
+	"time"
 
 	jsonpatch "gomodules.xyz/jsonpatch/v3"
 	"helm.sh/helm/v3/pkg/action"
 	cpb "helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/postrender"
 	rpb "helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/storage"
@@ -34,8 +39,11 @@ import (
 	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	apitypes "k8s.io/apimachinery/pkg/types"
 	apiutilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
@@ -44,18 +52,24 @@ import (
 
 	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
 	"github.com/operator-framework/operator-sdk/internal/helm/manifestutil"
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
 )
 
 // Manager manages a Helm release. It can install, upgrade, reconcile,
 // and uninstall a release.
 type Manager interface {
 	ReleaseName() string
+	ReleaseHash() string
+	PostRenderHash() string
 	IsInstalled() bool
 	IsUpgradeRequired() bool
+	IsAdoptionRequired() bool
 	Sync(context.Context) error
 	InstallRelease(context.Context, ...InstallOption) (*rpb.Release, error)
 	UpgradeRelease(context.Context, ...UpgradeOption) (*rpb.Release, *rpb.Release, error)
-	ReconcileRelease(context.Context) (*rpb.Release, error)
+	AdoptRelease(context.Context) (*rpb.Release, error)
+	ReconcileRelease(context.Context) (*rpb.Release, []ResourceDrift, error)
+	RollbackRelease(context.Context, ...RollbackOption) (*rpb.Release, error)
 	UninstallRelease(context.Context, ...UninstallOption) (*rpb.Release, error)
 	CleanupRelease(context.Context, string) (bool, error)
 }
@@ -67,10 +81,16 @@ type manager struct {
 
 	releaseName string
 	namespace   string
+	adopt       bool
+
+	reconcileOpts reconcileOptions
 
 	values map[string]interface{}
 	status *types.HelmAppStatus
 
+	postRenderer   postrender.PostRenderer
+	postRenderHash string
+
 	isInstalled       bool
 	isUpgradeRequired bool
 	deployedRelease   *rpb.Release
@@ -80,12 +100,44 @@ type manager struct {
 type InstallOption func(*action.Install) error
 type UpgradeOption func(*action.Upgrade) error
 type UninstallOption func(*action.Uninstall) error
+type RollbackOption func(*action.Rollback) error
 
 // ReleaseName returns the name of the release.
 func (m manager) ReleaseName() string {
 	return m.releaseName
 }
 
+// ReleaseHash returns a hash of the chart, the values and the post-render configuration that
+// the release is installed or upgraded with, which changes whenever a different release
+// would be installed.
+func (m manager) ReleaseHash() string {
+	h := sha256.New()
+	writeChartHash(h, m.chart)
+	// Maps are marshaled with sorted keys, so equal values have the same hash.
+	values, _ := json.Marshal(m.values)
+	_, _ = h.Write(values)
+	_, _ = h.Write([]byte(m.postRenderHash))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// PostRenderHash returns a hash of the post-render configuration that the release is
+// installed or upgraded with, empty if its manifests are not post-rendered.
+func (m manager) PostRenderHash() string {
+	return m.postRenderHash
+}
+
+// writeChartHash writes the content of c and of its dependencies to h.
+func writeChartHash(h io.Writer, c *cpb.Chart) {
+	if c == nil {
+		return
+	}
+	b, _ := json.Marshal(c)
+	_, _ = h.Write(b)
+	for _, dep := range c.Dependencies() {
+		writeChartHash(h, dep)
+	}
+}
+
 func (m manager) IsInstalled() bool {
 	return m.isInstalled
 }
@@ -145,10 +197,22 @@ func (m manager) isUpgrade(deployedRelease *rpb.Release) bool {
 	skip = skip && m.releaseName == deployedRelease.Name
 	skip = skip && apiequality.Semantic.DeepEqual(m.chart, deployedRelease.Chart)
 	skip = skip && apiequality.Semantic.DeepEqual(m.values, deployedRelease.Config)
+	// The manifest of the deployed release also depends on its post-renderer, whose hash is
+	// only recorded in the status of the CR.
+	skip = skip && m.postRenderHash == m.deployedPostRenderHash()
 
 	return !skip
 }
 
+// deployedPostRenderHash returns the hash of the post-render configuration of the deployed
+// release, as recorded in the status of the CR.
+func (m manager) deployedPostRenderHash() string {
+	if m.status == nil || m.status.DeployedRelease == nil {
+		return ""
+	}
+	return m.status.DeployedRelease.PostRenderHash
+}
+
 func (m manager) getDeployedRelease() (*rpb.Release, error) {
 	deployedRelease, err := m.storageBackend.Deployed(m.releaseName)
 	if err != nil {
@@ -165,6 +229,7 @@ func (m manager) InstallRelease(ctx context.Context, opts ...InstallOption) (*rp
 	install := action.NewInstall(m.actionConfig)
 	install.ReleaseName = m.releaseName
 	install.Namespace = m.namespace
+	install.PostRenderer = m.postRenderer
 	for _, o := range opts {
 		if err := o(install); err != nil {
 			return nil, fmt.Errorf("failed to apply install option: %w", err)
@@ -173,8 +238,8 @@ func (m manager) InstallRelease(ctx context.Context, opts ...InstallOption) (*rp
 
 	installedRelease, err := install.Run(m.chart, m.values)
 	if err != nil {
-		// Workaround for helm/helm#3338
-		if installedRelease != nil {
+		// Workaround for helm/helm#3338. An atomic install is already uninstalled.
+		if installedRelease != nil && !install.Atomic {
 			uninstall := action.NewUninstall(m.actionConfig)
 			_, uninstallErr := uninstall.Run(m.releaseName)
 
@@ -195,6 +260,30 @@ func (m manager) InstallRelease(ctx context.Context, opts ...InstallOption) (*rp
 	return installedRelease, nil
 }
 
+// AtomicInstall uninstalls a failed install, as helm install --atomic does.
+func AtomicInstall(atomic bool) InstallOption {
+	return func(i *action.Install) error {
+		i.Atomic = atomic
+		return nil
+	}
+}
+
+// WaitInstall waits until the resources of an install are ready.
+func WaitInstall(wait bool) InstallOption {
+	return func(i *action.Install) error {
+		i.Wait = wait
+		return nil
+	}
+}
+
+// TimeoutInstall sets how long to wait for the resources and hooks of an install.
+func TimeoutInstall(timeout time.Duration) InstallOption {
+	return func(i *action.Install) error {
+		i.Timeout = timeout
+		return nil
+	}
+}
+
 func ForceUpgrade(force bool) UpgradeOption {
 	return func(u *action.Upgrade) error {
 		u.Force = force
@@ -202,10 +291,51 @@ func ForceUpgrade(force bool) UpgradeOption {
 	}
 }
 
+// AtomicUpgrade rolls back a failed upgrade, as helm upgrade --atomic does.
+func AtomicUpgrade(atomic bool) UpgradeOption {
+	return func(u *action.Upgrade) error {
+		u.Atomic = atomic
+		return nil
+	}
+}
+
+// WaitUpgrade waits until the resources of an upgrade are ready.
+func WaitUpgrade(wait bool) UpgradeOption {
+	return func(u *action.Upgrade) error {
+		u.Wait = wait
+		return nil
+	}
+}
+
+// TimeoutUpgrade sets how long to wait for the resources and hooks of an upgrade.
+func TimeoutUpgrade(timeout time.Duration) UpgradeOption {
+	return func(u *action.Upgrade) error {
+		u.Timeout = timeout
+		return nil
+	}
+}
+
+// WaitRollback waits until the resources of a rollback are ready.
+func WaitRollback(wait bool) RollbackOption {
+	return func(r *action.Rollback) error {
+		r.Wait = wait
+		return nil
+	}
+}
+
+// TimeoutRollback sets how long to wait for the resources and hooks of a rollback.
+func TimeoutRollback(timeout time.Duration) RollbackOption {
+	return func(r *action.Rollback) error {
+		r.Timeout = timeout
+		return nil
+	}
+}
+
 // UpgradeRelease performs a Helm release upgrade.
 func (m manager) UpgradeRelease(ctx context.Context, opts ...UpgradeOption) (*rpb.Release, *rpb.Release, error) {
 	upgrade := action.NewUpgrade(m.actionConfig)
 	upgrade.Namespace = m.namespace
+	upgrade.PostRenderer = m.postRenderer
 	for _, o := range opts {
 		if err := o(upgrade); err != nil {
 			return nil, nil, fmt.Errorf("failed to apply upgrade option: %w", err)
@@ -214,8 +344,8 @@ func (m manager) UpgradeRelease(ctx context.Context, opts ...UpgradeOption) (*rp
 
 	upgradedRelease, err := upgrade.Run(m.releaseName, m.chart, m.values)
 	if err != nil {
-		// Workaround for helm/helm#3338
-		if upgradedRelease != nil {
+		// Workaround for helm/helm#3338. An atomic upgrade is already rolled back.
+		if upgradedRelease != nil && !upgrade.Atomic {
 			rollback := action.NewRollback(m.actionConfig)
 			rollback.Force = true
 
@@ -234,56 +364,223 @@ func (m manager) UpgradeRelease(ctx context.Context, opts ...UpgradeOption) (*rp
 	return m.deployedRelease, upgradedRelease, err
 }
 
+// RollbackRelease rolls the release back to the revision that was deployed
+// when the manager was synced.
+func (m manager) RollbackRelease(ctx context.Context, opts ...RollbackOption) (*rpb.Release, error) {
+	if m.deployedRelease == nil {
+		return nil, driver.ErrReleaseNotFound
+	}
+	rollback := action.NewRollback(m.actionConfig)
+	rollback.Version = m.deployedRelease.Version
+	for _, o := range opts {
+		if err := o(rollback); err != nil {
+			return nil, fmt.Errorf("failed to apply rollback option: %w", err)
+		}
+	}
+	if err := rollback.Run(m.releaseName); err != nil {
+		return nil, fmt.Errorf("failed to roll back release to revision %d: %w", m.deployedRelease.Version, err)
+	}
+	return m.getDeployedRelease()
+}
+
 // ReconcileRelease creates or patches resources as necessary to match the
-// deployed release's manifest.
-func (m manager) ReconcileRelease(ctx context.Context) (*rpb.Release, error) {
-	err := reconcileRelease(ctx, m.kubeClient, m.deployedRelease.Manifest)
-	return m.deployedRelease, err
+// deployed release's manifest, and returns the resources that drifted from it.
+// Drifted resources are only reported if the drift mode is watches.DriftModeReport.
+func (m manager) ReconcileRelease(ctx context.Context) (*rpb.Release, []ResourceDrift, error) {
+	drifts, err := reconcileRelease(ctx, m.kubeClient, m.deployedRelease.Manifest, m.reconcileOpts)
+	return m.deployedRelease, drifts, err
 }
 
-func reconcileRelease(_ context.Context, kubeClient kube.Interface, expectedManifest string) error {
+// reconcileOptions configure how the resources of a release are reconciled.
+type reconcileOptions struct {
+	drift             *watches.Drift
+	ignoreDifferences []watches.IgnoreDifference
+	serverSideApply   bool
+}
+
+func reconcileRelease(_ context.Context, kubeClient kube.Interface, expectedManifest string,
+	opts reconcileOptions) ([]ResourceDrift, error) {
+
 	expectedInfos, err := kubeClient.Build(bytes.NewBufferString(expectedManifest), false)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return expectedInfos.Visit(func(expected *resource.Info, err error) error {
+	reportOnly := opts.drift != nil && opts.drift.Mode == watches.DriftModeReport
+	var drifts []ResourceDrift
+	err = expectedInfos.Visit(func(expected *resource.Info, err error) error {
 		if err != nil {
 			return fmt.Errorf("visit error: %w", err)
 		}
+		resourceDrift := ResourceDrift{
+			GroupVersionKind: expected.Mapping.GroupVersionKind,
+			Namespace:        expected.Namespace,
+			Name:             expected.Name,
+		}
 
 		helper := resource.NewHelper(expected.Client, expected.Mapping)
 		existing, err := helper.Get(expected.Namespace, expected.Name)
 		if apierrors.IsNotFound(err) {
-			if _, err := helper.Create(expected.Namespace, true, expected.Object); err != nil {
-				return fmt.Errorf("create error: %s", err)
+			resourceDrift.Missing = true
+			if reportOnly {
+				drifts = append(drifts, resourceDrift)
+				return nil
 			}
-			return nil
+			if opts.serverSideApply {
+				_, err = serverSideApply(helper, expected, expected.Object)
+			} else if _, err = helper.Create(expected.Namespace, true, expected.Object); err != nil {
+				err = fmt.Errorf("create error: %s", err)
+			}
+			resourceDrift.Corrected = err == nil
+			drifts = append(drifts, resourceDrift)
+			return err
 		} else if err != nil {
 			return fmt.Errorf("could not get object: %w", err)
 		}
 
-		// Replicate helm's patch creation, which will create a Three-Way-Merge patch for
-		// native kubernetes Objects and fall back to a JSON merge patch for unstructured Objects such as CRDs
-		// We also extend the JSON merge patch by ignoring "remove" operations for fields added by kubernetes
-		// Reference in the helm source code:
-		// https://github.com/helm/helm/blob/1c9b54ad7f62a5ce12f87c3ae55136ca20f09c98/pkg/kube/client.go#L392
-		patch, patchType, err := createPatch(existing, expected)
-		if err != nil {
-			return fmt.Errorf("error creating patch: %w", err)
+		manifestObject := expected.Object
+		if err := detectDrift(opts.ignoreDifferences, existing, expected, &resourceDrift); err != nil {
+			return fmt.Errorf("error detecting drift: %w", err)
 		}
-
-		if patch == nil {
-			// nothing to do
+		if reportOnly {
+			if len(resourceDrift.Fields) > 0 {
+				drifts = append(drifts, resourceDrift)
+			}
 			return nil
 		}
+		patched, err := correctDrift(helper, existing, expected, manifestObject, &resourceDrift, opts)
+		if len(resourceDrift.Fields) > 0 {
+			resourceDrift.Corrected = err == nil && changed(existing, patched)
+			drifts = append(drifts, resourceDrift)
+		}
+		return err
+	})
+	return drifts, err
+}
+
+// correctDrift patches the existing resource of expected to the manifest, and returns the
+// patched resource, or nil when it was not patched.
+func correctDrift(helper *resource.Helper, existing runtime.Object, expected *resource.Info,
+	manifestObject runtime.Object, resourceDrift *ResourceDrift, opts reconcileOptions) (runtime.Object, error) {
 
-		_, err = helper.Patch(expected.Namespace, expected.Name, patchType, patch,
-			&metav1.PatchOptions{})
+	if opts.serverSideApply {
+		// The ignored fields are left out of the applied object, so that the operator
+		// does not manage them.
+		existingMap, err := objectMap(existing)
 		if err != nil {
-			return fmt.Errorf("patch error: %w", err)
+			return nil, err
 		}
-		return nil
+		if len(resourceDrift.Fields) == 0 && appliedBy(existingMap, watches.ServerSideApplyFieldManager) {
+			return nil, nil
+		}
+		applyMap, err := objectMap(manifestObject)
+		if err != nil {
+			return nil, err
+		}
+		ignoreDifferences(opts.ignoreDifferences, resourceDrift.GroupKind(), existingMap, applyMap, true)
+		return serverSideApply(helper, expected, &unstructured.Unstructured{Object: applyMap})
+	}
+
+	// Replicate helm's patch creation, which will create a Three-Way-Merge patch for
+	// native kubernetes Objects and fall back to a JSON merge patch for unstructured Objects such as CRDs
+	// We also extend the JSON merge patch by ignoring "remove" operations for fields added by kubernetes
+	// Reference in the helm source code:
+	// https://github.com/helm/helm/blob/1c9b54ad7f62a5ce12f87c3ae55136ca20f09c98/pkg/kube/client.go#L392
+	patch, patchType, err := createPatch(existing, expected)
+	if err != nil {
+		return nil, fmt.Errorf("error creating patch: %w", err)
+	}
+
+	if patch == nil {
+		// nothing to do
+		return nil, nil
+	}
+
+	patched, err := helper.Patch(expected.Namespace, expected.Name, patchType, patch,
+		&metav1.PatchOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("patch error: %w", err)
+	}
+	return patched, nil
+}
+
+// changed returns true if patched is a newer version of existing, i.e. a patch actually
+// changed the resource.
+func changed(existing, patched runtime.Object) bool {
+	if patched == nil {
+		return false
+	}
+	existingMeta, err := meta.Accessor(existing)
+	if err != nil {
+		return false
+	}
+	patchedMeta, err := meta.Accessor(patched)
+	if err != nil {
+		return false
+	}
+	return existingMeta.GetResourceVersion() != patchedMeta.GetResourceVersion()
+}
+
+// serverSideApply creates or updates the resource of info to obj with server-side apply, and
+// takes over the fields of obj from other field managers. The apply is forced, since the
+// manifest is the desired state of the fields in it, and an apply that conflicts would leave
+// the resource drifted. Fields that other controllers own are left out of obj by
+// ignoreDifferences, so they are not taken over.
+func serverSideApply(helper *resource.Helper, info *resource.Info, obj runtime.Object) (runtime.Object, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	force := true
+	applied, err := helper.Patch(info.Namespace, info.Name, apitypes.ApplyPatchType, data, &metav1.PatchOptions{
+		FieldManager: watches.ServerSideApplyFieldManager,
+		Force:        &force,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("apply error: %w", err)
+	}
+	return applied, nil
+}
+
+// detectDrift records the fields of existing that drifted from expected in resourceDrift.
+// The ignored fields are first set to their existing values in expected, so that they are
+// not patched either.
+func detectDrift(ignores []watches.IgnoreDifference, existing runtime.Object, expected *resource.Info,
+	resourceDrift *ResourceDrift) error {
+
+	existingJSON, err := json.Marshal(existing)
+	if err != nil {
+		return err
+	}
+	expectedJSON, err := json.Marshal(expected.Object)
+	if err != nil {
+		return err
+	}
+	existingMap, err := toMap(existingJSON)
+	if err != nil {
+		return err
+	}
+	expectedMap, err := toMap(expectedJSON)
+	if err != nil {
+		return err
+	}
+	if hasIgnoreDifferences(ignores, resourceDrift.GroupKind()) {
+		ignoreDifferences(ignores, resourceDrift.GroupKind(), existingMap, expectedMap, false)
+		expected.Object = &unstructured.Unstructured{Object: expectedMap}
+		if expectedJSON, err = json.Marshal(expectedMap); err != nil {
+			return err
+		}
+	}
+
+	if resourceDrift.Fields, err = driftedFields(existingJSON, expectedJSON); err != nil {
+		return err
+	}
+	if len(resourceDrift.Fields) > 0 {
+		if resourceDrift.GroupKind() == (schema.GroupKind{Kind: "Secret"}) {
+			existingMap, expectedMap = redactSecretData(existingMap, expectedMap)
+		}
+		resourceDrift.Diff, err = driftDiff(existingMap, expectedMap)
+	}
+	return err
 }
 
 func createPatch(existing runtime.Object, expected *resource.Info) ([]byte, apitypes.PatchType, error) {
@@ -15,11 +15,13 @@
 package release
 
 import (
+	"context"
 	"fmt"
 
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/postrender"
 	helmrelease "helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage"
 	"helm.sh/helm/v3/pkg/storage/driver"
@@ -30,6 +32,7 @@ import (
 
 	"github.com/operator-framework/operator-sdk/internal/helm/client"
 	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
 )
 
 // ManagerFactory creates Managers that are specific to custom resources. It is
@@ -41,13 +44,52 @@ type ManagerFactory interface {
 }
 
 type managerFactory struct {
-	mgr      crmanager.Manager
-	chartDir string
+	mgr        crmanager.Manager
+	chartDir   string
+	valuesFrom *ValuesFrom
+	drift      *watches.Drift
+
+	ignoreDifferences []watches.IgnoreDifference
+	serverSideApply   bool
+	postRenderer      postrender.PostRenderer
+	postRenderHash    string
+
+	releaseNameTemplate string
+	chartPath           func() string
+}
+
+// ManagerFactoryOption configures the Managers created by a ManagerFactory.
+type ManagerFactoryOption func(*managerFactory)
+
+// WithValuesFrom merges the values read from Secrets and ConfigMaps into those of releases.
+func WithValuesFrom(valuesFrom ValuesFrom) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.valuesFrom = &valuesFrom
+	}
+}
+
+// WithReleaseNameTemplate renders the names of releases with the releaseName template of a watch.
+func WithReleaseNameTemplate(releaseNameTemplate string) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.releaseNameTemplate = releaseNameTemplate
+	}
+}
+
+// WithChartPath loads the chart of each Manager from the path returned by chartPath, e.g. that
+// of a chart that is pulled again periodically, instead of the chart directory of the factory.
+func WithChartPath(chartPath func() string) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.chartPath = chartPath
+	}
 }
 
 // NewManagerFactory returns a new Helm manager factory capable of installing and uninstalling releases.
-func NewManagerFactory(mgr crmanager.Manager, chartDir string) ManagerFactory {
-	return &managerFactory{mgr, chartDir}
+func NewManagerFactory(mgr crmanager.Manager, chartDir string, opts ...ManagerFactoryOption) ManagerFactory {
+	f := &managerFactory{mgr: mgr, chartDir: chartDir}
+	for _, o := range opts {
+		o(f)
+	}
+	return f
 }
 
 func (f managerFactory) NewManager(cr *unstructured.Unstructured, overrideValues map[string]string) (Manager, error) {
@@ -72,12 +114,17 @@ func (f managerFactory) NewManager(cr *unstructured.Unstructured, overrideValues
 		return nil, fmt.Errorf("failed to inject owner references: %w", err)
 	}
 
-	crChart, err := loader.LoadDir(f.chartDir)
+	// The chart is either a directory or the archive of a pulled chart.
+	chartDir := f.chartDir
+	if f.chartPath != nil {
+		chartDir = f.chartPath()
+	}
+	crChart, err := loader.Load(chartDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load chart dir: %w", err)
+		return nil, fmt.Errorf("failed to load chart: %w", err)
 	}
 
-	releaseName, err := getReleaseName(storageBackend, crChart.Name(), cr)
+	releaseName, adopt, err := getReleaseName(storageBackend, crChart.Name(), cr, f.releaseNameTemplate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get helm release name: %w", err)
 	}
@@ -86,6 +133,12 @@ func (f managerFactory) NewManager(cr *unstructured.Unstructured, overrideValues
 	if !ok {
 		return nil, fmt.Errorf("failed to get spec: expected map[string]interface{}")
 	}
+	// Uninstalling a release doesn't need its values, whose Secrets and ConfigMaps may already be deleted.
+	if f.valuesFrom != nil && cr.GetDeletionTimestamp() == nil {
+		if crValues, err = f.valuesFrom.Values(context.TODO(), cr); err != nil {
+			return nil, err
+		}
+	}
 
 	expOverrides, err := parseOverrides(overrideValues)
 	if err != nil {
@@ -99,6 +152,9 @@ func (f managerFactory) NewManager(cr *unstructured.Unstructured, overrideValues
 		KubeClient:       ownerRefClient,
 		Log:              func(_ string, _ ...interface{}) {},
 	}
+	if len(f.ignoreDifferences) > 0 {
+		actionConfig.KubeClient = ignoreDifferencesClient{Interface: ownerRefClient, ignores: f.ignoreDifferences}
+	}
 
 	return &manager{
 		actionConfig:   actionConfig,
@@ -107,23 +163,38 @@ func (f managerFactory) NewManager(cr *unstructured.Unstructured, overrideValues
 
 		releaseName: releaseName,
 		namespace:   cr.GetNamespace(),
+		adopt:       adopt,
+		reconcileOpts: reconcileOptions{
+			drift:             f.drift,
+			ignoreDifferences: f.ignoreDifferences,
+			serverSideApply:   f.serverSideApply,
+		},
 
-		chart:  crChart,
-		values: values,
-		status: types.StatusFor(cr),
+		chart:          crChart,
+		values:         values,
+		status:         types.StatusFor(cr),
+		postRenderer:   f.postRenderer,
+		postRenderHash: f.postRenderHash,
 	}, nil
 }
 
-// getReleaseName returns a release name for the CR.
+// getReleaseName returns a release name for the CR, and whether the CR adopts
+// an existing release with that name.
 //
-// getReleaseName searches for a release using the CR name. If a release
-// cannot be found, or if it is found and was created by the chart managed
-// by this manager, the CR name is returned.
+// The release name of a CR that has a deployed release is the name of that
+// release, so that changes to the releaseName template of a watch do not affect
+// existing releases. Otherwise, it is rendered with releaseNameTemplate, the CR
+// name by default. If a release with that name cannot be found, or if it is the
+// deployed release of the CR, or if it has no deployed revision, the name is
+// returned.
 //
 // If a release is found but it was created by another chart, that means we
 // have a release name collision, so return an error. This case is possible
 // because Kubernetes allows instances of different types to have the same name
-// in the same namespace.
+// in the same namespace. A deployed release of the same chart that was not
+// deployed for the CR, e.g. with helm install, is adopted if the CR has the
+// AdoptReleaseAnnotation. Without it, a release named after the CR is used
+// as it is, as it always was, but a release with another name is an error.
 //
 // TODO(jlanford): As noted above, using the CR name as the release name raises
 //   the possibility of collision. We should move this logic to a validating
@@ -131,30 +202,58 @@ func (f managerFactory) NewManager(cr *unstructured.Unstructured, overrideValues
 //   collision. As is, the only indication of collision will be in the CR status
 //   and operator logs.
 func getReleaseName(storageBackend *storage.Storage, crChartName string,
-	cr *unstructured.Unstructured) (string, error) {
-	// If a release with the CR name does not exist, return the CR name.
-	releaseName := cr.GetName()
+	cr *unstructured.Unstructured, releaseNameTemplate string) (string, bool, error) {
+
+	deployed := types.StatusFor(cr).DeployedRelease
+	isDeployedForCR := deployed != nil && deployed.Name != ""
+	releaseName := ""
+	if isDeployedForCR {
+		releaseName = deployed.Name
+	} else {
+		var err error
+		releaseName, err = watches.RenderReleaseName(releaseNameTemplate, cr.GroupVersionKind(),
+			cr.GetNamespace(), cr.GetName())
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	// If a release with the name does not exist, return the name.
 	history, exists, err := releaseHistory(storageBackend, releaseName)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 	if !exists {
-		return releaseName, nil
+		return releaseName, false, nil
 	}
 
 	// If a release name with the CR name exists, but the release's chart is
 	// different than the chart managed by this operator, return an error
 	// because something else created the existing release.
 	if history[0].Chart == nil {
-		return "", fmt.Errorf("could not find chart metadata in release with name %q", releaseName)
+		return "", false, fmt.Errorf("could not find chart metadata in release with name %q", releaseName)
 	}
 	existingChartName := history[0].Chart.Name()
 	if existingChartName != crChartName {
-		return "", fmt.Errorf("duplicate release name: found existing release with name %q for chart %q",
+		return "", false, fmt.Errorf("duplicate release name: found existing release with name %q for chart %q",
 			releaseName, existingChartName)
 	}
 
-	return releaseName, nil
+	// Revisions that were never deployed, e.g. of an interrupted install, are
+	// cleaned up when the manager is synced.
+	if isDeployedForCR || !hasDeployedRevision(history) {
+		return releaseName, false, nil
+	}
+	// A release named after the CR was always used by the CR, as it was its only possible
+	// release name, so it is only adopted if requested, and used as it is otherwise.
+	if releaseName == cr.GetName() {
+		return releaseName, isAdoptionRequested(cr), nil
+	}
+	if !isAdoptionRequested(cr) {
+		return "", false, fmt.Errorf("release %q already exists and was not deployed for this resource: "+
+			"set the %s annotation to \"true\" to adopt it", releaseName, AdoptReleaseAnnotation)
+	}
+	return releaseName, true, nil
 }
 
 func releaseHistory(storageBackend *storage.Storage, releaseName string) ([]*helmrelease.Release, bool, error) {
@@ -28,6 +28,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/resource"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
 )
 
 func newTestUnstructured(containers []interface{}) *unstructured.Unstructured {
@@ -223,6 +225,8 @@ func TestManagerisUpgrade(t *testing.T) {
 		values          map[string]interface{}
 		chart           *cpb.Chart
 		deployedRelease *rpb.Release
+		postRenderHash  string
+		status          *types.HelmAppStatus
 		want            bool
 	}{
 		{
@@ -252,14 +256,48 @@ func TestManagerisUpgrade(t *testing.T) {
 			deployedRelease: newTestRelease(newTestChart(t, "./testdata/simple"), map[string]interface{}{"key": ""}, "deployed", "deployed-ns"),
 			want:            true,
 		},
+		{
+			name:            "same post-render configuration",
+			releaseName:     "deployed",
+			releaseNs:       "deployed-ns",
+			values:          map[string]interface{}{"key": "value"},
+			chart:           newTestChart(t, "./testdata/simple"),
+			deployedRelease: newTestRelease(newTestChart(t, "./testdata/simple"), map[string]interface{}{"key": "value"}, "deployed", "deployed-ns"),
+			postRenderHash:  "abc",
+			status:          &types.HelmAppStatus{DeployedRelease: &types.HelmAppRelease{PostRenderHash: "abc"}},
+			want:            false,
+		},
+		{
+			name:            "different post-render configuration",
+			releaseName:     "deployed",
+			releaseNs:       "deployed-ns",
+			values:          map[string]interface{}{"key": "value"},
+			chart:           newTestChart(t, "./testdata/simple"),
+			deployedRelease: newTestRelease(newTestChart(t, "./testdata/simple"), map[string]interface{}{"key": "value"}, "deployed", "deployed-ns"),
+			postRenderHash:  "def",
+			status:          &types.HelmAppStatus{DeployedRelease: &types.HelmAppRelease{PostRenderHash: "abc"}},
+			want:            true,
+		},
+		{
+			name:            "post-render configuration added",
+			releaseName:     "deployed",
+			releaseNs:       "deployed-ns",
+			values:          map[string]interface{}{"key": "value"},
+			chart:           newTestChart(t, "./testdata/simple"),
+			deployedRelease: newTestRelease(newTestChart(t, "./testdata/simple"), map[string]interface{}{"key": "value"}, "deployed", "deployed-ns"),
+			postRenderHash:  "abc",
+			want:            true,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			m := manager{
-				releaseName: test.releaseName,
-				namespace:   test.releaseNs,
-				values:      test.values,
-				chart:       test.chart,
+				releaseName:    test.releaseName,
+				namespace:      test.releaseNs,
+				values:         test.values,
+				chart:          test.chart,
+				postRenderHash: test.postRenderHash,
+				status:         test.status,
 			}
 			isUpgrade := m.isUpgrade(test.deployedRelease)
 			assert.Equal(t, test.want, isUpgrade)
@@ -267,6 +305,22 @@ func TestManagerisUpgrade(t *testing.T) {
 	}
 }
 
+func TestManagerReleaseHash(t *testing.T) {
+	values := map[string]interface{}{"key": "value", "nested": map[string]interface{}{"a": "1", "b": "2"}}
+	hash := manager{chart: newTestChart(t, "./testdata/simple"), values: values}.ReleaseHash()
+
+	same := manager{
+		chart:  newTestChart(t, "./testdata/simple"),
+		values: map[string]interface{}{"nested": map[string]interface{}{"b": "2", "a": "1"}, "key": "value"},
+	}
+	assert.Equal(t, hash, same.ReleaseHash())
+	assert.NotEqual(t, hash, manager{chart: newTestChart(t, "./testdata/simpledf"), values: values}.ReleaseHash())
+	assert.NotEqual(t, hash, manager{chart: newTestChart(t, "./testdata/simple"),
+		values: map[string]interface{}{"key": "other"}}.ReleaseHash())
+	assert.NotEqual(t, hash, manager{chart: newTestChart(t, "./testdata/simple"), values: values,
+		postRenderHash: "abc"}.ReleaseHash())
+}
+
 func newTestChart(t *testing.T, path string) *cpb.Chart {
 	chart, err := lpb.Load(path)
 	assert.Nil(t, err)
@@ -0,0 +1,185 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+
+	"helm.sh/helm/v3/pkg/postrender"
+	"sigs.k8s.io/kustomize/api/filesys"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/resid"
+	ktypes "sigs.k8s.io/kustomize/api/types"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+// kustomizeDir is the directory of the in-memory kustomization of a kustomize post-renderer.
+const kustomizeDir = "/postrender"
+
+// WithPostRenderer modifies the rendered manifests of the releases that are installed
+// and upgraded with postRenderer. The post-renderers returned by NewPostRenderer also
+// have a hash of their configuration, so that releases are upgraded when it changes.
+func WithPostRenderer(postRenderer postrender.PostRenderer) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.postRenderer = postRenderer
+		if h, ok := postRenderer.(hasher); ok {
+			f.postRenderHash = h.Hash()
+		}
+	}
+}
+
+// hasher is implemented by post-renderers whose output only changes with their hash.
+type hasher interface {
+	Hash() string
+}
+
+// NewPostRenderer returns the post-renderer that pr configures. It returns an error if
+// the executable of pr cannot be found.
+func NewPostRenderer(pr watches.PostRender) (postrender.PostRenderer, error) {
+	config, err := json.Marshal(pr)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	_, _ = h.Write(config)
+
+	switch {
+	case pr.Exec != "":
+		postRenderer, err := postrender.NewExec(pr.Exec)
+		if err != nil {
+			return nil, err
+		}
+		// The output of the executable also changes with its content, e.g. in a new
+		// operator image.
+		path, err := exec.LookPath(pr.Exec)
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read post-renderer executable: %w", err)
+		}
+		_, _ = h.Write(content)
+		return execPostRenderer{PostRenderer: postRenderer, hash: hex.EncodeToString(h.Sum(nil))}, nil
+	case pr.Kustomize != nil:
+		return kustomizePostRenderer{kustomize: *pr.Kustomize, hash: hex.EncodeToString(h.Sum(nil))}, nil
+	}
+	return nil, errors.New("post-render has neither kustomize nor exec")
+}
+
+// execPostRenderer pipes rendered manifests through an executable.
+type execPostRenderer struct {
+	postrender.PostRenderer
+	hash string
+}
+
+// Hash returns a hash of the configuration and of the executable of r.
+func (r execPostRenderer) Hash() string {
+	return r.hash
+}
+
+// kustomizePostRenderer modifies rendered manifests with kustomize.
+type kustomizePostRenderer struct {
+	kustomize watches.KustomizePostRender
+	hash      string
+}
+
+// Hash returns a hash of the configuration of r.
+func (r kustomizePostRenderer) Hash() string {
+	return r.hash
+}
+
+// Run builds a kustomization of renderedManifests and the patches, labels and images of r
+// in memory.
+func (r kustomizePostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	// kustomize fails to build a kustomization without resources, e.g. of a chart that
+	// only has hooks.
+	if len(bytes.TrimSpace(renderedManifests.Bytes())) == 0 {
+		return renderedManifests, nil
+	}
+
+	kustomization := ktypes.Kustomization{
+		TypeMeta: ktypes.TypeMeta{
+			APIVersion: ktypes.KustomizationVersion,
+			Kind:       ktypes.KustomizationKind,
+		},
+		Resources:    []string{"manifests.yaml"},
+		CommonLabels: r.kustomize.CommonLabels,
+	}
+	// Strategic merge patches name the resource that they patch, so they have no target.
+	for _, p := range r.kustomize.PatchesStrategicMerge {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal strategic merge patch: %w", err)
+		}
+		kustomization.Patches = append(kustomization.Patches, ktypes.Patch{Patch: string(b)})
+	}
+	for _, p := range r.kustomize.PatchesJSON6902 {
+		b, err := json.Marshal(p.Patch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal JSON 6902 patch: %w", err)
+		}
+		kustomization.Patches = append(kustomization.Patches, ktypes.Patch{
+			Patch: string(b),
+			Target: &ktypes.Selector{
+				Gvk:                resid.Gvk{Group: p.Target.Group, Version: p.Target.Version, Kind: p.Target.Kind},
+				Namespace:          p.Target.Namespace,
+				Name:               p.Target.Name,
+				LabelSelector:      p.Target.LabelSelector,
+				AnnotationSelector: p.Target.AnnotationSelector,
+			},
+		})
+	}
+	for _, image := range r.kustomize.Images {
+		kustomization.Images = append(kustomization.Images, ktypes.Image{
+			Name:    image.Name,
+			NewName: image.NewName,
+			NewTag:  image.NewTag,
+			Digest:  image.Digest,
+		})
+	}
+	// JSON is YAML, so kustomize reads the kustomization as it is marshaled.
+	b, err := json.Marshal(kustomization)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal kustomization: %w", err)
+	}
+
+	fSys := filesys.MakeFsInMemory()
+	if err := fSys.WriteFile(filepath.Join(kustomizeDir, "manifests.yaml"), renderedManifests.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := fSys.WriteFile(filepath.Join(kustomizeDir, "kustomization.yaml"), b); err != nil {
+		return nil, err
+	}
+
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fSys, kustomizeDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run kustomize: %w", err)
+	}
+	postRendered, err := resources.AsYaml()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal kustomized manifests: %w", err)
+	}
+	return bytes.NewBuffer(postRendered), nil
+}
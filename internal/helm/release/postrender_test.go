@@ -0,0 +1,275 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+func TestKustomizePostRenderer(t *testing.T) {
+	manifests := `---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: web:1
+`
+	service := `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+`
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - image: web:1
+        name: web
+`
+	tolerations := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web"},
+		"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+			"tolerations": []interface{}{map[string]interface{}{"key": "dedicated", "operator": "Exists"}},
+			"containers":  []interface{}{map[string]interface{}{"name": "web", "image": "mirror/web:1"}},
+		}}},
+	}
+	labels := watches.JSON6902Patch{
+		Target: watches.PatchTarget{Version: "v1", Kind: "Service", Name: "web"},
+		Patch: []map[string]interface{}{
+			{"op": "add", "path": "/metadata/labels", "value": map[string]interface{}{"team": "platform"}},
+		},
+	}
+
+	testCases := []struct {
+		name      string
+		kustomize watches.KustomizePostRender
+		manifests string
+		expected  map[string]string
+		expectErr bool
+	}{
+		{
+			name: "patched",
+			kustomize: watches.KustomizePostRender{
+				PatchesStrategicMerge: []map[string]interface{}{tolerations},
+				PatchesJSON6902:       []watches.JSON6902Patch{labels},
+			},
+			manifests: manifests,
+			expected: map[string]string{
+				"Service": `apiVersion: v1
+kind: Service
+metadata:
+  labels:
+    team: platform
+  name: web
+spec:
+  ports:
+  - port: 80
+`,
+				"Deployment": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - image: mirror/web:1
+        name: web
+      tolerations:
+      - key: dedicated
+        operator: Exists
+`,
+			},
+		},
+		{
+			name:      "no manifests",
+			kustomize: watches.KustomizePostRender{PatchesJSON6902: []watches.JSON6902Patch{labels}},
+			manifests: "\n",
+			expected:  map[string]string{},
+		},
+		{
+			name: "target that selects nothing",
+			kustomize: watches.KustomizePostRender{PatchesJSON6902: []watches.JSON6902Patch{{
+				Target: watches.PatchTarget{Version: "v1", Kind: "ConfigMap", Name: "web"},
+				Patch:  labels.Patch,
+			}}},
+			manifests: manifests,
+			expected: map[string]string{
+				"Service":    service,
+				"Deployment": deployment,
+			},
+		},
+		{
+			name: "target selectors",
+			kustomize: watches.KustomizePostRender{PatchesJSON6902: []watches.JSON6902Patch{{
+				Target: watches.PatchTarget{Kind: "Service|Deployment", AnnotationSelector: "team"},
+				Patch: []map[string]interface{}{
+					{"op": "add", "path": "/metadata/labels", "value": map[string]interface{}{"patched": "true"}},
+				},
+			}}},
+			manifests: strings.Replace(manifests, "  name: web\nspec:\n  ports",
+				"  name: web\n  annotations:\n    team: platform\nspec:\n  ports", 1),
+			expected: map[string]string{
+				"Service": `apiVersion: v1
+kind: Service
+metadata:
+  annotations:
+    team: platform
+  labels:
+    patched: "true"
+  name: web
+spec:
+  ports:
+  - port: 80
+`,
+				"Deployment": deployment,
+			},
+		},
+		{
+			name: "common labels and images",
+			kustomize: watches.KustomizePostRender{
+				CommonLabels: map[string]string{"team": "platform"},
+				Images:       []watches.KustomizeImage{{Name: "web", NewName: "mirror/web", NewTag: "2"}},
+			},
+			manifests: manifests,
+			expected: map[string]string{
+				"Service": `apiVersion: v1
+kind: Service
+metadata:
+  labels:
+    team: platform
+  name: web
+spec:
+  ports:
+  - port: 80
+  selector:
+    team: platform
+`,
+				"Deployment": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels:
+    team: platform
+  name: web
+spec:
+  selector:
+    matchLabels:
+      team: platform
+  template:
+    metadata:
+      labels:
+        team: platform
+    spec:
+      containers:
+      - image: mirror/web:2
+        name: web
+`,
+			},
+		},
+		{
+			name: "strategic merge patch of a missing resource",
+			kustomize: watches.KustomizePostRender{PatchesStrategicMerge: []map[string]interface{}{{
+				"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]interface{}{"name": "web"},
+			}}},
+			manifests: manifests,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			postRenderer, err := NewPostRenderer(watches.PostRender{Kustomize: &tc.kustomize})
+			require.NoError(t, err)
+
+			out, err := postRenderer.Run(bytes.NewBufferString(tc.manifests))
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			rendered := map[string]string{}
+			for _, m := range releaseutil.SplitManifests(out.String()) {
+				obj := map[string]interface{}{}
+				require.NoError(t, yaml.Unmarshal([]byte(m), &obj))
+				if kind, ok := obj["kind"].(string); ok {
+					rendered[kind] = strings.TrimSpace(m) + "\n"
+				}
+			}
+			assert.Equal(t, tc.expected, rendered)
+		})
+	}
+}
+
+func TestNewPostRenderer(t *testing.T) {
+	_, err := NewPostRenderer(watches.PostRender{Exec: "/does/not/exist"})
+	assert.Error(t, err)
+	_, err = NewPostRenderer(watches.PostRender{})
+	assert.Error(t, err)
+
+	hash := func(pr watches.PostRender) string {
+		postRenderer, err := NewPostRenderer(pr)
+		require.NoError(t, err)
+		f := &managerFactory{}
+		WithPostRenderer(postRenderer)(f)
+		return f.postRenderHash
+	}
+	patch := watches.KustomizePostRender{CommonLabels: map[string]string{"team": "platform"}}
+	other := watches.KustomizePostRender{CommonLabels: map[string]string{"team": "data"}}
+	assert.NotEmpty(t, hash(watches.PostRender{Kustomize: &patch}))
+	assert.Equal(t, hash(watches.PostRender{Kustomize: &patch}), hash(watches.PostRender{Kustomize: &patch}))
+	assert.NotEqual(t, hash(watches.PostRender{Kustomize: &patch}), hash(watches.PostRender{Kustomize: &other}))
+
+	exec := filepath.Join(t.TempDir(), "post-render")
+	require.NoError(t, ioutil.WriteFile(exec, []byte("#!/bin/sh\ncat\n"), 0755))
+	execHash := hash(watches.PostRender{Exec: exec})
+	require.NoError(t, ioutil.WriteFile(exec, []byte("#!/bin/sh\nsed s/a/b/\n"), 0755))
+	assert.NotEqual(t, execHash, hash(watches.PostRender{Exec: exec}))
+}
@@ -0,0 +1,176 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+// CRValuesFromField is the field of a CR spec that references the Secrets and ConfigMaps
+// its values are read from, when the watch allows it.
+const CRValuesFromField = "valuesFrom"
+
+// ValuesFrom reads release values from the Secrets and ConfigMaps referenced by a watch
+// and by CRs.
+type ValuesFrom struct {
+	// Reader reads the referenced Secrets and ConfigMaps. It should not be the cached client
+	// of a manager, which would cache every Secret and ConfigMap of the watched namespaces.
+	Reader client.Reader
+	// Refs are the references of the watch, which apply to every CR.
+	Refs []watches.ValuesReference
+	// AllowCRRefs, when set, allows CRs to add references with spec.valuesFrom to the
+	// Secrets and ConfigMaps it allows.
+	AllowCRRefs *watches.AllowCRValuesFrom
+}
+
+// CRValuesReferences returns the references of the spec.valuesFrom field of cr.
+func CRValuesReferences(cr *unstructured.Unstructured) ([]watches.ValuesReference, error) {
+	spec, _ := cr.Object["spec"].(map[string]interface{})
+	field, ok := spec[CRValuesFromField]
+	if !ok {
+		return nil, nil
+	}
+	b, err := json.Marshal(field)
+	if err != nil {
+		return nil, err
+	}
+	refs := []watches.ValuesReference{}
+	if err := json.Unmarshal(b, &refs); err != nil {
+		return nil, fmt.Errorf("invalid spec.%s: %w", CRValuesFromField, err)
+	}
+	for _, ref := range refs {
+		if err := watches.VerifyValuesReference(ref); err != nil {
+			return nil, fmt.Errorf("invalid spec.%s: %w", CRValuesFromField, err)
+		}
+	}
+	return refs, nil
+}
+
+// References returns the references that apply to cr, in the order they are merged.
+func (v ValuesFrom) References(cr *unstructured.Unstructured) ([]watches.ValuesReference, error) {
+	refs := append([]watches.ValuesReference{}, v.Refs...)
+	if v.AllowCRRefs == nil {
+		return refs, nil
+	}
+	crRefs, err := CRValuesReferences(cr)
+	if err != nil {
+		return nil, err
+	}
+	return append(refs, crRefs...), nil
+}
+
+// Values returns the values of the release of cr: those of the references of the watch,
+// then those of the references of cr, then its spec, each overriding the previous ones.
+func (v ValuesFrom) Values(ctx context.Context, cr *unstructured.Unstructured) (map[string]interface{}, error) {
+	spec, ok := cr.Object["spec"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to get spec: expected map[string]interface{}")
+	}
+	refs, err := v.References(cr)
+	if err != nil {
+		return nil, err
+	}
+	if v.AllowCRRefs != nil {
+		spec = mergeMaps(spec, nil)
+		delete(spec, CRValuesFromField)
+	}
+	if len(refs) == 0 {
+		return spec, nil
+	}
+
+	values := map[string]interface{}{}
+	for i, ref := range refs {
+		// The references of the watch come first, those of the CR must be allowed.
+		var allow *watches.AllowCRValuesFrom
+		if i >= len(v.Refs) {
+			allow = v.AllowCRRefs
+		}
+		refValues, err := v.referenceValues(ctx, cr.GetNamespace(), ref, allow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get values from %s %s/%s: %w", ref.Kind, cr.GetNamespace(), ref.Name, err)
+		}
+		values = mergeMaps(values, refValues)
+	}
+	return mergeMaps(values, spec), nil
+}
+
+// referenceValues returns the values of ref, or nil if ref is optional and missing. When allow
+// is set, the referenced object must be allowed by it.
+func (v ValuesFrom) referenceValues(ctx context.Context, namespace string,
+	ref watches.ValuesReference, allow *watches.AllowCRValuesFrom) (map[string]interface{}, error) {
+
+	key := ref.ValuesKey
+	if key == "" {
+		key = watches.DefaultValuesKey
+	}
+	var obj client.Object
+	switch ref.Kind {
+	case watches.ValuesKindSecret:
+		obj = &corev1.Secret{}
+	case watches.ValuesKindConfigMap:
+		obj = &corev1.ConfigMap{}
+	default:
+		return nil, watches.VerifyValuesReference(ref)
+	}
+	if err := v.Reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, obj); err != nil {
+		if apierrors.IsNotFound(err) && ref.Optional {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if allow != nil && !allow.Allows(obj) {
+		return nil, errors.New("not allowed by the allowCRValuesFrom of the watch")
+	}
+	var data string
+	var found bool
+	switch o := obj.(type) {
+	case *corev1.Secret:
+		var b []byte
+		b, found = o.Data[key]
+		data = string(b)
+	case *corev1.ConfigMap:
+		data, found = o.Data[key]
+	}
+	if !found {
+		if ref.Optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("key %q not found", key)
+	}
+
+	values := map[string]interface{}{}
+	if ref.TargetPath != "" {
+		if err := unstructured.SetNestedField(values, data, strings.Split(ref.TargetPath, ".")...); err != nil {
+			return nil, err
+		}
+		return values, nil
+	}
+	if err := yaml.Unmarshal([]byte(data), &values); err != nil {
+		return nil, fmt.Errorf("failed to parse key %q: %w", key, err)
+	}
+	return values, nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+func TestValuesFromValues(t *testing.T) {
+	objs := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared-values", Namespace: "ns"},
+			Data: map[string]string{
+				"values.yaml": "replicas: 1\nimage:\n  repository: nginx\n  tag: \"1.19\"\n",
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "ns"},
+			Data: map[string][]byte{
+				"password":    []byte("s3cr3t"),
+				"values.yaml": []byte("image:\n  tag: \"1.20\"\n"),
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "tls", Namespace: "ns", Labels: map[string]string{"values": "true"}},
+			Data:       map[string][]byte{"values.yaml": []byte("tls:\n  enabled: true\n")},
+		},
+	).Build()
+	allowCredentials := &watches.AllowCRValuesFrom{Names: []string{"credentials"}}
+
+	testCases := []struct {
+		name         string
+		valuesFrom   ValuesFrom
+		spec         map[string]interface{}
+		expectValues map[string]interface{}
+		expectErr    bool
+	}{
+		{
+			name:         "no references",
+			spec:         map[string]interface{}{"replicas": int64(2)},
+			expectValues: map[string]interface{}{"replicas": int64(2)},
+		},
+		{
+			name: "references are merged in order before the spec",
+			valuesFrom: ValuesFrom{Refs: []watches.ValuesReference{
+				{Kind: watches.ValuesKindConfigMap, Name: "shared-values"},
+				{Kind: watches.ValuesKindSecret, Name: "credentials"},
+			}},
+			spec: map[string]interface{}{"replicas": int64(2)},
+			expectValues: map[string]interface{}{
+				"replicas": int64(2),
+				"image":    map[string]interface{}{"repository": "nginx", "tag": "1.20"},
+			},
+		},
+		{
+			name: "target path",
+			valuesFrom: ValuesFrom{Refs: []watches.ValuesReference{
+				{Kind: watches.ValuesKindSecret, Name: "credentials", ValuesKey: "password", TargetPath: "db.password"},
+			}},
+			spec: map[string]interface{}{},
+			expectValues: map[string]interface{}{
+				"db": map[string]interface{}{"password": "s3cr3t"},
+			},
+		},
+		{
+			name: "optional references are skipped",
+			valuesFrom: ValuesFrom{Refs: []watches.ValuesReference{
+				{Kind: watches.ValuesKindSecret, Name: "missing", Optional: true},
+				{Kind: watches.ValuesKindSecret, Name: "credentials", ValuesKey: "missing", Optional: true},
+			}},
+			spec:         map[string]interface{}{"replicas": int64(2)},
+			expectValues: map[string]interface{}{"replicas": int64(2)},
+		},
+		{
+			name: "missing object",
+			valuesFrom: ValuesFrom{Refs: []watches.ValuesReference{
+				{Kind: watches.ValuesKindSecret, Name: "missing"},
+			}},
+			spec:      map[string]interface{}{},
+			expectErr: true,
+		},
+		{
+			name: "missing key",
+			valuesFrom: ValuesFrom{Refs: []watches.ValuesReference{
+				{Kind: watches.ValuesKindConfigMap, Name: "shared-values", ValuesKey: "missing"},
+			}},
+			spec:      map[string]interface{}{},
+			expectErr: true,
+		},
+		{
+			name: "CR references",
+			valuesFrom: ValuesFrom{
+				Refs:        []watches.ValuesReference{{Kind: watches.ValuesKindConfigMap, Name: "shared-values"}},
+				AllowCRRefs: allowCredentials,
+			},
+			spec: map[string]interface{}{
+				"valuesFrom": []interface{}{
+					map[string]interface{}{"kind": "Secret", "name": "credentials"},
+				},
+			},
+			expectValues: map[string]interface{}{
+				"replicas": float64(1),
+				"image":    map[string]interface{}{"repository": "nginx", "tag": "1.20"},
+			},
+		},
+		{
+			name: "CR references allowed by labels",
+			valuesFrom: ValuesFrom{AllowCRRefs: &watches.AllowCRValuesFrom{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"values": "true"}},
+			}},
+			spec: map[string]interface{}{
+				"valuesFrom": []interface{}{
+					map[string]interface{}{"kind": "Secret", "name": "tls"},
+				},
+			},
+			expectValues: map[string]interface{}{
+				"tls": map[string]interface{}{"enabled": true},
+			},
+		},
+		{
+			name:       "CR reference to a Secret that is not allowed",
+			valuesFrom: ValuesFrom{AllowCRRefs: allowCredentials},
+			spec: map[string]interface{}{
+				"valuesFrom": []interface{}{
+					map[string]interface{}{"kind": "Secret", "name": "tls"},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "watch references are not restricted",
+			valuesFrom: ValuesFrom{
+				Refs:        []watches.ValuesReference{{Kind: watches.ValuesKindSecret, Name: "tls"}},
+				AllowCRRefs: allowCredentials,
+			},
+			spec: map[string]interface{}{},
+			expectValues: map[string]interface{}{
+				"tls": map[string]interface{}{"enabled": true},
+			},
+		},
+		{
+			name: "CR references are values when not allowed",
+			spec: map[string]interface{}{
+				"valuesFrom": []interface{}{
+					map[string]interface{}{"kind": "Secret", "name": "credentials"},
+				},
+			},
+			expectValues: map[string]interface{}{
+				"valuesFrom": []interface{}{
+					map[string]interface{}{"kind": "Secret", "name": "credentials"},
+				},
+			},
+		},
+		{
+			name:       "invalid CR reference",
+			valuesFrom: ValuesFrom{AllowCRRefs: allowCredentials},
+			spec: map[string]interface{}{
+				"valuesFrom": []interface{}{
+					map[string]interface{}{"kind": "Deployment", "name": "credentials"},
+				},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cr := &unstructured.Unstructured{Object: map[string]interface{}{"spec": tc.spec}}
+			cr.SetNamespace("ns")
+			cr.SetName("test")
+			tc.valuesFrom.Reader = objs
+			values, err := tc.valuesFrom.Values(context.TODO(), cr)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectValues, values)
+		})
+	}
+}
@@ -19,10 +19,20 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/url"
 	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"helm.sh/helm/v3/pkg/chartutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/yaml"
 )
 
@@ -32,9 +42,312 @@ const WatchesFile = "watches.yaml"
 // custom resource.
 type Watch struct {
 	schema.GroupVersionKind `json:",inline"`
-	ChartDir                string            `json:"chart"`
+	// ChartDir is the directory of a chart, the name of a chart in ChartRepository,
+	// or the oci:// reference of a chart, without a tag.
+	ChartDir string `json:"chart"`
+	// ChartRepository is the URL of the chart repository the chart is pulled from.
+	ChartRepository string `json:"chartRepository,omitempty"`
+	// ChartVersion is the version of a chart that is pulled, or a semver range of
+	// versions, the newest of which is pulled. The newest stable version by default.
+	ChartVersion string `json:"chartVersion,omitempty"`
+	// ChartDigest is the "sha256:" digest that the archive of a pulled chart must have.
+	ChartDigest string `json:"chartDigest,omitempty"`
+	// ChartPull configures the credentials and TLS options that a chart is pulled with, and
+	// how often it is pulled again.
+	ChartPull               *ChartPull        `json:"chartPull,omitempty"`
 	WatchDependentResources *bool             `json:"watchDependentResources,omitempty"`
 	OverrideValues          map[string]string `json:"overrideValues,omitempty"`
+	// ValuesFrom are merged into the values of every release, in order, before the CR's.
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+	// AllowCRValuesFrom, when set, allows CRs to reference the Secrets and ConfigMaps it
+	// allows with the reserved spec.valuesFrom field, which is then not passed to the chart
+	// as a value.
+	AllowCRValuesFrom *AllowCRValuesFrom `json:"allowCRValuesFrom,omitempty"`
+	// Atomic reverts failed installs and upgrades, as helm's --atomic flag does. It implies Wait.
+	Atomic bool `json:"atomic,omitempty"`
+	// Wait waits until the resources of a release are ready before it is deployed.
+	Wait bool `json:"wait,omitempty"`
+	// Timeout is how long to wait for the resources and hooks of a release, DefaultTimeout
+	// when Wait or Atomic is set.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// Remediation, when set, retries failed installs and upgrades, and then remediates them.
+	Remediation *Remediation `json:"remediation,omitempty"`
+	// Readiness, when set, checks the health of the resources of a release on every
+	// reconcile, and records it in the Ready condition of the CR.
+	Readiness *Readiness `json:"readiness,omitempty"`
+	// Drift, when set, reports the resources of a release that drifted from its manifest,
+	// and configures whether they are corrected.
+	Drift *Drift `json:"drift,omitempty"`
+	// IgnoreDifferences are fields of the resources of a release that are neither reported
+	// as drifted, nor corrected, nor changed by upgrades and rollbacks.
+	IgnoreDifferences []IgnoreDifference `json:"ignoreDifferences,omitempty"`
+	// ServerSideApply corrects drifted resources with server-side apply, as the
+	// ServerSideApplyFieldManager field manager, rather than with patches.
+	ServerSideApply bool `json:"serverSideApply,omitempty"`
+	// ReleaseName is the text/template of the names of releases, e.g. "{{.Name}}-{{.Kind}}",
+	// rendered with the Name, Namespace, Group, Version and Kind of each CR and lowercased.
+	// The name of the CR by default.
+	ReleaseName string `json:"releaseName,omitempty"`
+	// PostRender, when set, modifies the rendered manifests of a release before it is
+	// installed or upgraded.
+	PostRender *PostRender `json:"postRender,omitempty"`
+	// Selector restricts the CRs that are reconciled to those whose labels it matches.
+	// All CRs by default.
+	Selector metav1.LabelSelector `json:"selector,omitempty"`
+	// Namespaces restricts the CRs that are reconciled to those in the namespaces, which
+	// are watched even if they are not in the namespaces of WATCH_NAMESPACE. The namespaces
+	// of WATCH_NAMESPACE by default.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// UninstallUnselected, when true, uninstalls the release of a CR that is relabeled away
+	// from Selector. By default, the CR is only released, so that the watch that selects it
+	// takes over its release.
+	UninstallUnselected bool `json:"uninstallUnselected,omitempty"`
+}
+
+// ChartPull configures how the chart of a watch is pulled from a chart repository or an OCI registry.
+type ChartPull struct {
+	// UsernameFile and PasswordFile are the paths of files, e.g. mounted from a Secret, that
+	// hold the basic auth credentials of the repository or registry.
+	UsernameFile string `json:"usernameFile,omitempty"`
+	PasswordFile string `json:"passwordFile,omitempty"`
+	// CertFile and KeyFile are the paths of the client certificate and key presented to the
+	// repository or registry, and CAFile the path of the certificate authorities that its
+	// certificate is verified with.
+	CertFile              string `json:"certFile,omitempty"`
+	KeyFile               string `json:"keyFile,omitempty"`
+	CAFile                string `json:"caFile,omitempty"`
+	InsecureSkipTLSVerify bool   `json:"insecureSkipTLSVerify,omitempty"`
+	// Interval, when set, is how often the chart is pulled again while the operator runs, so
+	// that releases are upgraded to the newest version that satisfies chartVersion.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// PostRender modifies the rendered manifests of a release with either kustomize patches
+// or an executable.
+type PostRender struct {
+	// Kustomize patches the manifests with kustomize.
+	Kustomize *KustomizePostRender `json:"kustomize,omitempty"`
+	// Exec is the path of an executable that the manifests are piped through, as with
+	// helm's --post-renderer flag.
+	Exec string `json:"exec,omitempty"`
+}
+
+// KustomizePostRender configures how kustomize modifies the manifests of a release.
+type KustomizePostRender struct {
+	// PatchesStrategicMerge are strategic merge patches, each with the apiVersion, kind
+	// and metadata.name of the resource it patches.
+	PatchesStrategicMerge []map[string]interface{} `json:"patchesStrategicMerge,omitempty"`
+	// PatchesJSON6902 are JSON patches of the resources that they target.
+	PatchesJSON6902 []JSON6902Patch `json:"patchesJson6902,omitempty"`
+	// CommonLabels are added to all the resources, and to their selectors.
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+	// Images replace the names, tags or digests of the images of containers.
+	Images []KustomizeImage `json:"images,omitempty"`
+}
+
+// JSON6902Patch applies JSON patch operations to the resources that it targets.
+type JSON6902Patch struct {
+	Target PatchTarget `json:"target"`
+	// Patch is a list of JSON patch operations, each with an op and a path.
+	Patch []map[string]interface{} `json:"patch"`
+}
+
+// PatchTarget selects the resources of a JSON6902Patch. The group, version, kind, name and
+// namespace are regular expressions, and a resource is selected if it matches all of the
+// set fields and selectors.
+type PatchTarget struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	// LabelSelector and AnnotationSelector select resources by their labels and
+	// annotations, with the syntax of label selectors, e.g. "app=web,tier!=cache".
+	LabelSelector      string `json:"labelSelector,omitempty"`
+	AnnotationSelector string `json:"annotationSelector,omitempty"`
+}
+
+// KustomizeImage replaces the name, tag or digest of the images with a name.
+type KustomizeImage struct {
+	// Name is the name of the images, without a tag or digest.
+	Name    string `json:"name"`
+	NewName string `json:"newName,omitempty"`
+	NewTag  string `json:"newTag,omitempty"`
+	// Digest replaces the tag of the images, NewTag is ignored when it is set.
+	Digest string `json:"digest,omitempty"`
+}
+
+// Drift configures how resources of a release that drifted from its manifest are handled.
+type Drift struct {
+	// Mode is either DriftModeCorrect, the default, or DriftModeReport.
+	Mode string `json:"mode,omitempty"`
+	// IgnoreDifferences are merged into the IgnoreDifferences of the watch when it is loaded.
+	//
+	// Deprecated: use Watch.IgnoreDifferences, which also applies to upgrades and rollbacks.
+	IgnoreDifferences []IgnoreDifference `json:"ignoreDifferences,omitempty"`
+}
+
+// IgnoreDifference selects fields of the resources of a kind, with JSON pointers, and
+// by the field managers that manage them.
+type IgnoreDifference struct {
+	Group        string   `json:"group,omitempty"`
+	Kind         string   `json:"kind"`
+	JSONPointers []string `json:"jsonPointers,omitempty"`
+	// ManagedFieldsManagers are the names of field managers, e.g. kube-controller-manager,
+	// whose fields are selected, as recorded in the managed fields of resources.
+	ManagedFieldsManagers []string `json:"managedFieldsManagers,omitempty"`
+}
+
+// Supported values of Drift.Mode.
+const (
+	// DriftModeCorrect patches drifted resources back to the manifest, and recreates missing ones.
+	DriftModeCorrect = "correct"
+	// DriftModeReport only reports drifted and missing resources.
+	DriftModeReport = "report"
+
+	// ServerSideApplyFieldManager is the field manager of resources corrected with server-side apply.
+	ServerSideApplyFieldManager = "helm-operator"
+)
+
+// Readiness configures how the health of the resources of a release is checked.
+type Readiness struct {
+	// HealthRules check the health of resources of kinds that have no built-in check.
+	HealthRules []HealthRule `json:"healthRules,omitempty"`
+}
+
+// HealthRule checks the health of the resources of a kind with one of their conditions.
+type HealthRule struct {
+	Group string `json:"group,omitempty"`
+	Kind  string `json:"kind"`
+	// ConditionType is the type of the condition whose status is "True" on healthy
+	// resources, DefaultHealthConditionType by default.
+	ConditionType string `json:"conditionType,omitempty"`
+}
+
+// Remediation configures how failed installs and upgrades of a release are remediated.
+type Remediation struct {
+	// Retries is the number of times a failed install or upgrade is retried before Strategy is applied.
+	Retries int `json:"retries,omitempty"`
+	// RetryInterval is how long to wait before the first retry, doubled before each of the
+	// next ones. DefaultRetryInterval by default.
+	RetryInterval *metav1.Duration `json:"retryInterval,omitempty"`
+	// Strategy is applied once the retries have failed, RemediationManual by default.
+	Strategy string `json:"strategy,omitempty"`
+}
+
+// Supported values of Remediation.Strategy.
+const (
+	// RemediationRollback rolls the release back to its last deployed revision.
+	RemediationRollback = "rollback"
+	// RemediationReinstall uninstalls the release and installs it again.
+	RemediationReinstall = "reinstall"
+	// RemediationManual stops until the CR changes.
+	RemediationManual = "manual"
+)
+
+// Defaults of the Helm options and remediation of watches.
+const (
+	DefaultTimeout       = 5 * time.Minute
+	DefaultRetryInterval = 30 * time.Second
+
+	// DefaultHealthConditionType is the condition type of a HealthRule without one.
+	DefaultHealthConditionType = "Ready"
+)
+
+// ValuesReference references a Secret or ConfigMap in the namespace of a CR whose
+// data is merged into the values of its release.
+type ValuesReference struct {
+	// Kind is either "Secret" or "ConfigMap".
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// ValuesKey is the key of the data that holds the values, "values.yaml" by default.
+	ValuesKey string `json:"valuesKey,omitempty"`
+	// TargetPath, when set, is the dot-separated path of the value that is set to the
+	// data of ValuesKey, as a string, instead of merging it as YAML.
+	TargetPath string `json:"targetPath,omitempty"`
+	// Optional references are skipped when the object or its key does not exist.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// AllowCRValuesFrom allows the references of CRs to the Secrets and ConfigMaps that it
+// names, or whose labels it selects. Other references of CRs are refused, so that CRs
+// cannot expose the data of any Secret in their namespace through their release.
+type AllowCRValuesFrom struct {
+	// Names are the names of the Secrets and ConfigMaps that CRs may reference.
+	Names []string `json:"names,omitempty"`
+	// Selector selects the Secrets and ConfigMaps that CRs may reference by their labels.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// Allows returns true if CRs may reference the Secret or ConfigMap obj.
+func (a AllowCRValuesFrom) Allows(obj metav1.Object) bool {
+	for _, name := range a.Names {
+		if name == obj.GetName() {
+			return true
+		}
+	}
+	if a.Selector == nil {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(a.Selector)
+	return err == nil && !selector.Empty() && selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// Supported values of ValuesReference.Kind.
+const (
+	ValuesKindSecret    = "Secret"
+	ValuesKindConfigMap = "ConfigMap"
+
+	// DefaultValuesKey is the key of the values of a ValuesReference without a ValuesKey.
+	DefaultValuesKey = "values.yaml"
+
+	// OCIScheme prefixes the reference of a chart in an OCI registry.
+	OCIScheme = "oci://"
+)
+
+// releaseNameValues are the values that the releaseName template of a watch is rendered with.
+type releaseNameValues struct {
+	Name      string
+	Namespace string
+	Group     string
+	Version   string
+	Kind      string
+}
+
+// RenderReleaseName returns the release name of the CR namespace/name of kind gvk, rendered
+// with the releaseName template tmpl, or the name of the CR if tmpl is empty.
+func RenderReleaseName(tmpl string, gvk schema.GroupVersionKind, namespace, name string) (string, error) {
+	if tmpl == "" {
+		return name, nil
+	}
+	t, err := template.New("releaseName").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	values := releaseNameValues{
+		Name:      name,
+		Namespace: namespace,
+		Group:     gvk.Group,
+		Version:   gvk.Version,
+		Kind:      gvk.Kind,
+	}
+	if err := t.Execute(&b, values); err != nil {
+		return "", err
+	}
+	releaseName := strings.ToLower(b.String())
+	if err := chartutil.ValidateReleaseName(releaseName); err != nil {
+		return "", fmt.Errorf("invalid release name %q: %w", releaseName, err)
+	}
+	return releaseName, nil
+}
+
+var chartDigestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// IsRemoteChart returns true if the chart of w is pulled from a chart repository or
+// an OCI registry, rather than read from a directory.
+func (w Watch) IsRemoteChart() bool {
+	return w.ChartRepository != "" || strings.HasPrefix(w.ChartDir, OCIScheme)
 }
 
 // UnmarshalYAML unmarshals an individual watch from the Helm watches.yaml file
@@ -98,8 +411,83 @@ func LoadReader(reader io.Reader) ([]Watch, error) {
 			return nil, fmt.Errorf("invalid GVK: %s: %w", gvk, err)
 		}
 
-		if _, err := chartutil.IsChartDir(w.ChartDir); err != nil {
-			return nil, fmt.Errorf("invalid chart directory %s: %w", w.ChartDir, err)
+		if w.IsRemoteChart() {
+			if err := verifyRemoteChart(w); err != nil {
+				return nil, fmt.Errorf("invalid chart %s: %w", w.ChartDir, err)
+			}
+		} else {
+			if _, err := chartutil.IsChartDir(w.ChartDir); err != nil {
+				return nil, fmt.Errorf("invalid chart directory %s: %w", w.ChartDir, err)
+			}
+			if w.ChartVersion != "" || w.ChartDigest != "" || w.ChartPull != nil {
+				return nil, fmt.Errorf("chartVersion, chartDigest and chartPull of GVK %s require a "+
+					"chartRepository or an %s chart", gvk, OCIScheme)
+			}
+		}
+
+		for _, ref := range w.ValuesFrom {
+			if err := VerifyValuesReference(ref); err != nil {
+				return nil, fmt.Errorf("invalid valuesFrom for GVK %s: %w", gvk, err)
+			}
+		}
+		if w.AllowCRValuesFrom != nil {
+			if err := verifyAllowCRValuesFrom(*w.AllowCRValuesFrom); err != nil {
+				return nil, fmt.Errorf("invalid allowCRValuesFrom for GVK %s: %w", gvk, err)
+			}
+		}
+
+		if w.Remediation != nil {
+			if err := verifyRemediation(*w.Remediation); err != nil {
+				return nil, fmt.Errorf("invalid remediation for GVK %s: %w", gvk, err)
+			}
+			if w.Remediation.RetryInterval == nil {
+				w.Remediation.RetryInterval = &metav1.Duration{Duration: DefaultRetryInterval}
+			}
+			if w.Remediation.Strategy == "" {
+				w.Remediation.Strategy = RemediationManual
+			}
+		}
+		if w.Readiness != nil {
+			for j, rule := range w.Readiness.HealthRules {
+				if rule.Kind == "" {
+					return nil, fmt.Errorf("invalid readiness for GVK %s: health rule kind must not be empty", gvk)
+				}
+				if rule.ConditionType == "" {
+					w.Readiness.HealthRules[j].ConditionType = DefaultHealthConditionType
+				}
+			}
+		}
+		if w.Drift != nil {
+			if err := verifyDrift(*w.Drift); err != nil {
+				return nil, fmt.Errorf("invalid drift for GVK %s: %w", gvk, err)
+			}
+			if w.Drift.Mode == "" {
+				w.Drift.Mode = DriftModeCorrect
+			}
+			w.IgnoreDifferences = append(w.IgnoreDifferences, w.Drift.IgnoreDifferences...)
+			w.Drift.IgnoreDifferences = nil
+		}
+		if err := verifyIgnoreDifferences(w.IgnoreDifferences); err != nil {
+			return nil, fmt.Errorf("invalid ignoreDifferences for GVK %s: %w", gvk, err)
+		}
+		if w.ReleaseName != "" {
+			if _, err := RenderReleaseName(w.ReleaseName, gvk, "namespace", "name"); err != nil {
+				return nil, fmt.Errorf("invalid releaseName for GVK %s: %w", gvk, err)
+			}
+		}
+		if w.PostRender != nil {
+			if err := verifyPostRender(*w.PostRender); err != nil {
+				return nil, fmt.Errorf("invalid postRender for GVK %s: %w", gvk, err)
+			}
+		}
+		if _, err := metav1.LabelSelectorAsSelector(&w.Selector); err != nil {
+			return nil, fmt.Errorf("invalid selector for GVK %s: %w", gvk, err)
+		}
+		if err := verifyNamespaces(w.Namespaces); err != nil {
+			return nil, fmt.Errorf("invalid namespaces for GVK %s: %w", gvk, err)
+		}
+		if w.Timeout == nil && (w.Wait || w.Atomic) {
+			w.Timeout = &metav1.Duration{Duration: DefaultTimeout}
 		}
 
 		if _, ok := watchesMap[gvk]; ok {
@@ -139,3 +527,190 @@ func verifyGVK(gvk schema.GroupVersionKind) error {
 	}
 	return nil
 }
+
+func verifyRemoteChart(w Watch) error {
+	if w.ChartRepository != "" {
+		if strings.HasPrefix(w.ChartDir, OCIScheme) {
+			return fmt.Errorf("an %s chart must not have a chartRepository", OCIScheme)
+		}
+		if w.ChartDir == "" || strings.Contains(w.ChartDir, "/") {
+			return errors.New("chart must be the name of a chart in the chartRepository")
+		}
+		u, err := url.Parse(w.ChartRepository)
+		if err != nil {
+			return fmt.Errorf("invalid chartRepository: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("chartRepository must be an http or https URL, got %q", w.ChartRepository)
+		}
+	} else {
+		ref := strings.TrimPrefix(w.ChartDir, OCIScheme)
+		slash := strings.Index(ref, "/")
+		if slash <= 0 || slash == len(ref)-1 {
+			return errors.New("an OCI reference must have a registry and a repository")
+		}
+		if strings.Contains(ref, "@") || strings.Contains(ref[slash:], ":") {
+			return errors.New("an OCI reference must not have a tag or digest, use chartVersion and chartDigest")
+		}
+	}
+	if w.ChartVersion != "" {
+		if _, err := semver.NewConstraint(w.ChartVersion); err != nil {
+			return fmt.Errorf("invalid chartVersion %q: %w", w.ChartVersion, err)
+		}
+	}
+	if w.ChartDigest != "" && !chartDigestRegexp.MatchString(w.ChartDigest) {
+		return fmt.Errorf("chartDigest must be \"sha256:\" followed by 64 hexadecimal digits, got %q", w.ChartDigest)
+	}
+	if w.ChartPull != nil {
+		return verifyChartPull(*w.ChartPull)
+	}
+	return nil
+}
+
+func verifyChartPull(p ChartPull) error {
+	if (p.UsernameFile == "") != (p.PasswordFile == "") {
+		return errors.New("chartPull usernameFile and passwordFile must be set together")
+	}
+	if (p.CertFile == "") != (p.KeyFile == "") {
+		return errors.New("chartPull certFile and keyFile must be set together")
+	}
+	if p.Interval != nil && p.Interval.Duration <= 0 {
+		return fmt.Errorf("chartPull interval must be positive, got %s", p.Interval.Duration)
+	}
+	return nil
+}
+
+func verifyRemediation(r Remediation) error {
+	if r.Retries < 0 {
+		return fmt.Errorf("retries must not be negative, got %d", r.Retries)
+	}
+	if r.RetryInterval != nil && r.RetryInterval.Duration <= 0 {
+		return fmt.Errorf("retryInterval must be positive, got %s", r.RetryInterval.Duration)
+	}
+	switch r.Strategy {
+	case "", RemediationRollback, RemediationReinstall, RemediationManual:
+		return nil
+	}
+	return fmt.Errorf("strategy must be %q, %q or %q, got %q", RemediationRollback, RemediationReinstall,
+		RemediationManual, r.Strategy)
+}
+
+func verifyDrift(d Drift) error {
+	if d.Mode != "" && d.Mode != DriftModeCorrect && d.Mode != DriftModeReport {
+		return fmt.Errorf("mode must be %q or %q, got %q", DriftModeCorrect, DriftModeReport, d.Mode)
+	}
+	return nil
+}
+
+func verifyIgnoreDifferences(ignores []IgnoreDifference) error {
+	for _, ignore := range ignores {
+		if ignore.Kind == "" {
+			return errors.New("kind must not be empty")
+		}
+		if len(ignore.JSONPointers) == 0 && len(ignore.ManagedFieldsManagers) == 0 {
+			return fmt.Errorf("kind %s must have jsonPointers or managedFieldsManagers", ignore.Kind)
+		}
+		for _, p := range ignore.JSONPointers {
+			if !strings.HasPrefix(p, "/") {
+				return fmt.Errorf("JSON pointer %q must start with \"/\"", p)
+			}
+		}
+	}
+	return nil
+}
+
+func verifyPostRender(pr PostRender) error {
+	if (pr.Kustomize == nil) == (pr.Exec == "") {
+		return errors.New("exactly one of kustomize and exec must be set")
+	}
+	if pr.Kustomize == nil {
+		return nil
+	}
+	k := pr.Kustomize
+	if len(k.PatchesStrategicMerge) == 0 && len(k.PatchesJSON6902) == 0 && len(k.CommonLabels) == 0 &&
+		len(k.Images) == 0 {
+		return errors.New("kustomize must have patchesStrategicMerge, patchesJson6902, commonLabels or images")
+	}
+	for _, p := range k.PatchesStrategicMerge {
+		u := unstructured.Unstructured{Object: p}
+		if u.GetAPIVersion() == "" || u.GetKind() == "" || u.GetName() == "" {
+			return errors.New("strategic merge patch must have an apiVersion, kind and metadata.name")
+		}
+	}
+	for _, p := range k.PatchesJSON6902 {
+		if err := verifyPatchTarget(p.Target); err != nil {
+			return err
+		}
+		if len(p.Patch) == 0 {
+			return fmt.Errorf("JSON 6902 patch of target %+v must have operations", p.Target)
+		}
+		for _, op := range p.Patch {
+			if op["op"] == nil || op["path"] == nil {
+				return fmt.Errorf("JSON 6902 patch operation of target %+v must have an op and a path", p.Target)
+			}
+		}
+	}
+	for _, image := range k.Images {
+		if image.Name == "" {
+			return errors.New("image name must not be empty")
+		}
+		if image.NewName == "" && image.NewTag == "" && image.Digest == "" {
+			return fmt.Errorf("image %s must have a newName, newTag or digest", image.Name)
+		}
+	}
+	return nil
+}
+
+func verifyPatchTarget(t PatchTarget) error {
+	if t.Kind == "" && t.Name == "" && t.LabelSelector == "" && t.AnnotationSelector == "" {
+		return errors.New("JSON 6902 patch target must have a kind, name, labelSelector or annotationSelector")
+	}
+	for _, expr := range []string{t.Group, t.Version, t.Kind, t.Name, t.Namespace} {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("invalid JSON 6902 patch target: %w", err)
+		}
+	}
+	for _, selector := range []string{t.LabelSelector, t.AnnotationSelector} {
+		if _, err := labels.Parse(selector); err != nil {
+			return fmt.Errorf("invalid JSON 6902 patch target selector %q: %w", selector, err)
+		}
+	}
+	return nil
+}
+
+func verifyNamespaces(namespaces []string) error {
+	for _, ns := range namespaces {
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			return fmt.Errorf("namespace %q is invalid: %s", ns, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// VerifyValuesReference returns an error if ref does not name a Secret or ConfigMap.
+func VerifyValuesReference(ref ValuesReference) error {
+	if ref.Kind != ValuesKindSecret && ref.Kind != ValuesKindConfigMap {
+		return fmt.Errorf("kind must be %q or %q, got %q", ValuesKindSecret, ValuesKindConfigMap, ref.Kind)
+	}
+	if ref.Name == "" {
+		return errors.New("name must not be empty")
+	}
+	return nil
+}
+
+func verifyAllowCRValuesFrom(a AllowCRValuesFrom) error {
+	if len(a.Names) == 0 && a.Selector == nil {
+		return errors.New("names or selector must be set")
+	}
+	if a.Selector == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(a.Selector)
+	if err != nil {
+		return err
+	}
+	if selector.Empty() {
+		return errors.New("selector must not be empty")
+	}
+	return nil
+}
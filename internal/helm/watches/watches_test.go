@@ -18,9 +18,12 @@ import (
 	"bytes"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -102,6 +105,704 @@ func TestLoadReader(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			name: "valid with values from",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  allowCRValuesFrom:
+    names: [tls]
+    selector:
+      matchLabels:
+        example.com/values: "true"
+  valuesFrom:
+  - kind: ConfigMap
+    name: shared-values
+    optional: true
+  - kind: Secret
+    name: credentials
+    valuesKey: password
+    targetPath: db.password
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					ValuesFrom: []ValuesReference{
+						{Kind: ValuesKindConfigMap, Name: "shared-values", Optional: true},
+						{Kind: ValuesKindSecret, Name: "credentials", ValuesKey: "password", TargetPath: "db.password"},
+					},
+					AllowCRValuesFrom: &AllowCRValuesFrom{
+						Names: []string{"tls"},
+						Selector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"example.com/values": "true"},
+						},
+					},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid values from kind",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  valuesFrom:
+  - kind: Deployment
+    name: values
+`,
+			expectErr: true,
+		},
+		{
+			name: "values from without a name",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  valuesFrom:
+  - kind: Secret
+`,
+			expectErr: true,
+		},
+		{
+			name: "allow CR values from without names or a selector",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  allowCRValuesFrom: {}
+`,
+			expectErr: true,
+		},
+		{
+			name: "valid remote charts",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: nginx
+  chartRepository: https://charts.example.com/stable
+  chartVersion: ">=1.2.0 <2.0.0"
+  chartPull:
+    usernameFile: /etc/chart-credentials/username
+    passwordFile: /etc/chart-credentials/password
+    caFile: /etc/chart-credentials/ca.crt
+    interval: 1h
+- group: mygroup
+  version: v1alpha1
+  kind: MyOtherKind
+  chart: oci://registry.example.com:5000/charts/nginx
+  chartVersion: 1.2.3
+  chartDigest: sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind: schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+					ChartDir:         "nginx",
+					ChartRepository:  "https://charts.example.com/stable",
+					ChartVersion:     ">=1.2.0 <2.0.0",
+					ChartPull: &ChartPull{
+						UsernameFile: "/etc/chart-credentials/username",
+						PasswordFile: "/etc/chart-credentials/password",
+						CAFile:       "/etc/chart-credentials/ca.crt",
+						Interval:     &metav1.Duration{Duration: time.Hour},
+					},
+					WatchDependentResources: &trueVal,
+				},
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyOtherKind"},
+					ChartDir:                "oci://registry.example.com:5000/charts/nginx",
+					ChartVersion:            "1.2.3",
+					ChartDigest:             "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+					WatchDependentResources: &trueVal,
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "chart pull with a username file only",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: nginx
+  chartRepository: https://charts.example.com/stable
+  chartPull:
+    usernameFile: /etc/chart-credentials/username
+`,
+			expectErr: true,
+		},
+		{
+			name: "chart pull of a local chart",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  chartPull:
+    interval: 1h
+`,
+			expectErr: true,
+		},
+		{
+			name: "valid with remediation",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  wait: true
+  remediation:
+    retries: 3
+- group: mygroup
+  version: v1alpha1
+  kind: MyOtherKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  atomic: true
+  timeout: 10m
+  remediation:
+    retryInterval: 1m
+    strategy: rollback
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					Wait:                    true,
+					Timeout:                 &metav1.Duration{Duration: DefaultTimeout},
+					Remediation: &Remediation{
+						Retries:       3,
+						RetryInterval: &metav1.Duration{Duration: DefaultRetryInterval},
+						Strategy:      RemediationManual,
+					},
+				},
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyOtherKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					Atomic:                  true,
+					Timeout:                 &metav1.Duration{Duration: 10 * time.Minute},
+					Remediation: &Remediation{
+						RetryInterval: &metav1.Duration{Duration: time.Minute},
+						Strategy:      RemediationRollback,
+					},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid remediation strategy",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  remediation:
+    strategy: retry
+`,
+			expectErr: true,
+		},
+		{
+			name: "negative remediation retries",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  remediation:
+    retries: -1
+`,
+			expectErr: true,
+		},
+		{
+			name: "valid with readiness",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  readiness:
+    healthRules:
+    - group: cert-manager.io
+      kind: Certificate
+    - group: example.com
+      kind: Database
+      conditionType: Available
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					Readiness: &Readiness{
+						HealthRules: []HealthRule{
+							{Group: "cert-manager.io", Kind: "Certificate", ConditionType: DefaultHealthConditionType},
+							{Group: "example.com", Kind: "Database", ConditionType: "Available"},
+						},
+					},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "health rule without kind",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  readiness:
+    healthRules:
+    - group: example.com
+`,
+			expectErr: true,
+		},
+		{
+			name: "valid with drift",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  drift: {}
+  serverSideApply: true
+  ignoreDifferences:
+  - group: apps
+    kind: Deployment
+    jsonPointers:
+    - /spec/replicas
+  - kind: ConfigMap
+    managedFieldsManagers:
+    - kubectl
+- group: mygroup
+  version: v1alpha1
+  kind: MyOtherKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  drift:
+    mode: report
+    ignoreDifferences:
+    - group: apps
+      kind: Deployment
+      jsonPointers:
+      - /spec/replicas
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					Drift:                   &Drift{Mode: DriftModeCorrect},
+					ServerSideApply:         true,
+					IgnoreDifferences: []IgnoreDifference{
+						{Group: "apps", Kind: "Deployment", JSONPointers: []string{"/spec/replicas"}},
+						{Kind: "ConfigMap", ManagedFieldsManagers: []string{"kubectl"}},
+					},
+				},
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyOtherKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					Drift:                   &Drift{Mode: DriftModeReport},
+					IgnoreDifferences: []IgnoreDifference{
+						{Group: "apps", Kind: "Deployment", JSONPointers: []string{"/spec/replicas"}},
+					},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid deprecated drift ignoreDifferences",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  drift:
+    ignoreDifferences:
+    - kind: Deployment
+`,
+			expectErr: true,
+		},
+		{
+			name: "invalid drift mode",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  drift:
+    mode: ignore
+`,
+			expectErr: true,
+		},
+		{
+			name: "invalid ignoreDifferences JSON pointer",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  ignoreDifferences:
+  - group: apps
+    kind: Deployment
+    jsonPointers:
+    - spec.replicas
+`,
+			expectErr: true,
+		},
+		{
+			name: "ignoreDifferences without fields",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  ignoreDifferences:
+  - group: apps
+    kind: Deployment
+`,
+			expectErr: true,
+		},
+		{
+			name: "valid with postRender",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  postRender:
+    kustomize:
+      patchesStrategicMerge:
+      - apiVersion: apps/v1
+        kind: Deployment
+        metadata:
+          name: web
+        spec:
+          replicas: 2
+      patchesJson6902:
+      - target:
+          version: v1
+          kind: Service
+          name: web
+        patch:
+        - op: add
+          path: /metadata/labels/team
+          value: platform
+      - target:
+          kind: Deployment|StatefulSet
+          labelSelector: tier=web
+        patch:
+        - op: add
+          path: /spec/template/spec/priorityClassName
+          value: high
+      commonLabels:
+        team: platform
+      images:
+      - name: nginx
+        newName: mirror.example.com/nginx
+        newTag: "1.21"
+- group: mygroup
+  version: v1alpha1
+  kind: MyOtherKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  postRender:
+    exec: /usr/local/bin/post-render
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					PostRender: &PostRender{Kustomize: &KustomizePostRender{
+						PatchesStrategicMerge: []map[string]interface{}{{
+							"apiVersion": "apps/v1",
+							"kind":       "Deployment",
+							"metadata":   map[string]interface{}{"name": "web"},
+							"spec":       map[string]interface{}{"replicas": float64(2)},
+						}},
+						PatchesJSON6902: []JSON6902Patch{{
+							Target: PatchTarget{Version: "v1", Kind: "Service", Name: "web"},
+							Patch: []map[string]interface{}{
+								{"op": "add", "path": "/metadata/labels/team", "value": "platform"},
+							},
+						}, {
+							Target: PatchTarget{Kind: "Deployment|StatefulSet", LabelSelector: "tier=web"},
+							Patch: []map[string]interface{}{
+								{"op": "add", "path": "/spec/template/spec/priorityClassName", "value": "high"},
+							},
+						}},
+						CommonLabels: map[string]string{"team": "platform"},
+						Images:       []KustomizeImage{{Name: "nginx", NewName: "mirror.example.com/nginx", NewTag: "1.21"}},
+					}},
+				},
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyOtherKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					PostRender:              &PostRender{Exec: "/usr/local/bin/post-render"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "postRender with kustomize and exec",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  postRender:
+    exec: /usr/local/bin/post-render
+    kustomize:
+      patchesStrategicMerge:
+      - {apiVersion: v1, kind: Service, metadata: {name: web}}
+`,
+			expectErr: true,
+		},
+		{
+			name: "invalid postRender strategic merge patch",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  postRender:
+    kustomize:
+      patchesStrategicMerge:
+      - {kind: Service, metadata: {name: web}}
+`,
+			expectErr: true,
+		},
+		{
+			name: "invalid postRender JSON 6902 patch",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  postRender:
+    kustomize:
+      patchesJson6902:
+      - target: {version: v1, kind: Service, name: web}
+        patch:
+        - {path: /spec/type, value: NodePort}
+`,
+			expectErr: true,
+		},
+		{
+			name: "postRender JSON 6902 patch target without kind, name or selector",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  postRender:
+    kustomize:
+      patchesJson6902:
+      - target: {version: v1}
+        patch:
+        - {op: replace, path: /spec/type, value: NodePort}
+`,
+			expectErr: true,
+		},
+		{
+			name: "invalid postRender JSON 6902 patch target selector",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  postRender:
+    kustomize:
+      patchesJson6902:
+      - target: {kind: Service, labelSelector: "tier in web"}
+        patch:
+        - {op: replace, path: /spec/type, value: NodePort}
+`,
+			expectErr: true,
+		},
+		{
+			name: "postRender image without replacement",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  postRender:
+    kustomize:
+      images:
+      - name: nginx
+`,
+			expectErr: true,
+		},
+		{
+			name: "valid with releaseName",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  releaseName: "{{.Name}}-{{.Kind}}"
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					ReleaseName:             "{{.Name}}-{{.Kind}}",
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid releaseName field",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  releaseName: "{{.Name}}-{{.UID}}"
+`,
+			expectErr: true,
+		},
+		{
+			name: "invalid releaseName characters",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  releaseName: "{{.Namespace}}/{{.Name}}"
+`,
+			expectErr: true,
+		},
+		{
+			name: "valid selector and namespaces",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  selector:
+    matchLabels:
+      track: canary
+    matchExpressions:
+    - key: tier
+      operator: In
+      values: [web]
+  namespaces: [canary-a, canary-b]
+  uninstallUnselected: true
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind: schema.GroupVersionKind{
+						Group:   "mygroup",
+						Version: "v1alpha1",
+						Kind:    "MyKind",
+					},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					Selector: metav1.LabelSelector{
+						MatchLabels: map[string]string{"track": "canary"},
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"web"}},
+						},
+					},
+					Namespaces:          []string{"canary-a", "canary-b"},
+					UninstallUnselected: true,
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid selector operator",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  selector:
+    matchExpressions:
+    - key: tier
+      operator: Like
+      values: [web]
+`,
+			expectErr: true,
+		},
+		{
+			name: "invalid namespace",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  namespaces: [Canary_A]
+`,
+			expectErr: true,
+		},
+		{
+			name: "invalid chart version",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: nginx
+  chartRepository: https://charts.example.com/stable
+  chartVersion: latest
+`,
+			expectErr: true,
+		},
+		{
+			name: "invalid chart digest",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: oci://registry.example.com/charts/nginx
+  chartDigest: 2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae
+`,
+			expectErr: true,
+		},
+		{
+			name: "OCI chart with a tag",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: oci://registry.example.com/charts/nginx:1.2.3
+`,
+			expectErr: true,
+		},
+		{
+			name: "chart repository that is not an http URL",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: nginx
+  chartRepository: ftp://charts.example.com
+`,
+			expectErr: true,
+		},
+		{
+			name: "chart version of a chart directory",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  chartVersion: 1.2.3
+`,
+			expectErr: true,
+		},
 		{
 			name: "duplicate gvk",
 			data: `---
@@ -266,3 +967,33 @@ func removeFile(t *testing.T, f *os.File) {
 		t.Fatal(err)
 	}
 }
+
+func TestRenderReleaseName(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Nginx"}
+
+	testCases := []struct {
+		name        string
+		tmpl        string
+		crName      string
+		expected    string
+		expectedErr bool
+	}{
+		{name: "default", crName: "Web", expected: "Web"},
+		{name: "template", tmpl: "{{.Name}}-{{.Kind}}", crName: "web", expected: "web-nginx"},
+		{name: "namespace and group", tmpl: "{{.Namespace}}-{{.Name}}.{{.Group}}", crName: "web",
+			expected: "ns-web.example.com"},
+		{name: "too long", tmpl: "{{.Name}}-{{.Kind}}", crName: strings.Repeat("a", 50), expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			releaseName, err := RenderReleaseName(tc.tmpl, gvk, "ns", tc.crName)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, releaseName)
+		})
+	}
+}
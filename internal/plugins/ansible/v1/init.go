@@ -215,11 +215,6 @@ func addInitCustomizations(projectName string) error {
 	if err != nil {
 		return err
 	}
-	// Remove the webhook option for the componentConfig since webhooks are not supported by ansible
-	err = sdkutil.ReplaceInFile(managerConfigFile, "webhook:\n  port: 9443", "")
-	if err != nil {
-		return err
-	}
 
 	// Remove the call to the command as manager. Helm/Ansible has not been exposing this entrypoint
 	// todo: provide the manager entrypoint for helm/ansible and then remove it
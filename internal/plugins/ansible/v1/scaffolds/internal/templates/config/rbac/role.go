@@ -132,6 +132,25 @@ rules:
       - patch
       - update
       - watch
+  ##
+  ## Events recorded on CRs, e.g. when a finalizer is abandoned
+  ##
+  - apiGroups:
+      - ""
+    resources:
+      - events
+    verbs:
+      - create
+      - patch
+  ##
+  ## CRDs read to apply their schema to CRs, for watches with applySchema
+  ##
+  - apiGroups:
+      - apiextensions.k8s.io
+    resources:
+      - customresourcedefinitions
+    verbs:
+      - get
 %s
 `
 
@@ -0,0 +1,177 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+)
+
+var _ machinery.Template = &Manifests{}
+
+const (
+	mutatingWebhookMarker   = "mutating-webhooks"
+	validatingWebhookMarker = "validating-webhooks"
+)
+
+// manifestsPath returns the path of the webhook configurations, which matches the
+// path config/webhook/kustomization.yaml expects for the webhook version.
+func manifestsPath(webhookVersion string) string {
+	if webhookVersion != "v1" {
+		return filepath.Join("config", "webhook", fmt.Sprintf("manifests.%s.yaml", webhookVersion))
+	}
+	return filepath.Join("config", "webhook", "manifests.yaml")
+}
+
+// Manifests scaffolds the webhook configurations that the admission webhooks of each
+// resource are added to
+type Manifests struct {
+	machinery.TemplateMixin
+	machinery.ResourceMixin
+}
+
+// SetTemplateDefaults implements machinery.Template
+func (f *Manifests) SetTemplateDefaults() error {
+	if f.Path == "" {
+		f.Path = manifestsPath(f.Resource.Webhooks.WebhookVersion)
+	}
+
+	f.TemplateBody = fmt.Sprintf(manifestsTemplate,
+		machinery.NewMarkerFor(f.Path, mutatingWebhookMarker),
+		machinery.NewMarkerFor(f.Path, validatingWebhookMarker),
+	)
+
+	// The webhook configurations are shared by all resources.
+	f.IfExistsAction = machinery.SkipFile
+
+	return nil
+}
+
+var _ machinery.Inserter = &ManifestsUpdater{}
+
+// ManifestsUpdater adds the admission webhooks of a resource to the webhook configurations
+type ManifestsUpdater struct {
+	machinery.ResourceMixin
+}
+
+// GetPath implements machinery.Builder
+func (f *ManifestsUpdater) GetPath() string {
+	return manifestsPath(f.Resource.Webhooks.WebhookVersion)
+}
+
+// GetIfExistsAction implements machinery.Builder
+func (*ManifestsUpdater) GetIfExistsAction() machinery.IfExistsAction {
+	return machinery.OverwriteFile
+}
+
+// GetMarkers implements machinery.Inserter
+func (f *ManifestsUpdater) GetMarkers() []machinery.Marker {
+	return []machinery.Marker{
+		machinery.NewMarkerFor(f.GetPath(), mutatingWebhookMarker),
+		machinery.NewMarkerFor(f.GetPath(), validatingWebhookMarker),
+	}
+}
+
+// GetCodeFragments implements machinery.Inserter
+func (f *ManifestsUpdater) GetCodeFragments() machinery.CodeFragmentsMap {
+	fragments := make(machinery.CodeFragmentsMap, 2)
+
+	// If resource is not being provided we are creating the file, not updating it
+	if f.Resource == nil {
+		return fragments
+	}
+
+	if f.Resource.HasDefaultingWebhook() {
+		fragments[machinery.NewMarkerFor(f.GetPath(), mutatingWebhookMarker)] = []string{
+			f.executeFragment("mutate"),
+		}
+	}
+	if f.Resource.HasValidationWebhook() {
+		fragments[machinery.NewMarkerFor(f.GetPath(), validatingWebhookMarker)] = []string{
+			f.executeFragment("validate"),
+		}
+	}
+	return fragments
+}
+
+// executeFragment renders the webhook of the resource served on the path with the given prefix
+func (f *ManifestsUpdater) executeFragment(prefix string) string {
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("webhook").Funcs(machinery.DefaultFuncMap()).Parse(webhookFragment))
+	err := tmpl.Execute(buf, struct {
+		*ManifestsUpdater
+		Prefix string
+		// Path must match the path the ansible-operator serves the webhook on
+		Path string
+	}{
+		ManifestsUpdater: f,
+		Prefix:           prefix[:1],
+		Path: fmt.Sprintf("/%s-%s-%s-%s", prefix, strings.ReplaceAll(f.Resource.QualifiedGroup(), ".", "-"),
+			f.Resource.Version, strings.ToLower(f.Resource.Kind)),
+	})
+	if err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+const manifestsTemplate = `---
+apiVersion: admissionregistration.k8s.io/{{ .Resource.Webhooks.WebhookVersion }}
+kind: MutatingWebhookConfiguration
+metadata:
+  name: mutating-webhook-configuration
+webhooks:
+%s
+---
+apiVersion: admissionregistration.k8s.io/{{ .Resource.Webhooks.WebhookVersion }}
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: validating-webhook-configuration
+webhooks:
+%s
+`
+
+const webhookFragment = `- admissionReviewVersions:
+  - v1
+  - v1beta1
+  clientConfig:
+    service:
+      name: webhook-service
+      namespace: system
+      path: {{ .Path }}
+  # Each request runs the playbook or role of the webhook, which is stopped once 90% of
+  # timeoutSeconds (at most 30) passed, failing the request. Keep it well within that,
+  # since the API server waits for it. With failurePolicy Fail, requests are rejected while
+  # the operator is unavailable or too slow; set it to Ignore to admit them unchecked instead.
+  failurePolicy: Fail
+  name: {{ .Prefix }}{{ lower .Resource.Kind }}.{{ .Resource.QualifiedGroup }}
+  rules:
+  - apiGroups:
+    - {{ .Resource.QualifiedGroup }}
+    apiVersions:
+    - {{ .Resource.Version }}
+    operations:
+    - CREATE
+    - UPDATE
+    resources:
+    - {{ .Resource.Plural }}
+  sideEffects: None
+  timeoutSeconds: 10
+`
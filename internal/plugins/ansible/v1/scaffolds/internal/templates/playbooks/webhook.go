@@ -0,0 +1,115 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package playbooks
+
+import (
+	"path/filepath"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+)
+
+var _ machinery.Template = &ValidatingWebhook{}
+
+// ValidatingWebhook scaffolds the playbook run for admission requests of the validating webhook
+type ValidatingWebhook struct {
+	machinery.TemplateMixin
+	machinery.ResourceMixin
+
+	Force bool
+}
+
+// SetTemplateDefaults implements machinery.Template
+func (f *ValidatingWebhook) SetTemplateDefaults() error {
+	if f.Path == "" {
+		f.Path = filepath.Join("playbooks", "%[kind]_validate.yml")
+	}
+	f.Path = f.Resource.Replacer().Replace(f.Path)
+
+	f.TemplateBody = validatingWebhookTmpl
+
+	if f.Force {
+		f.IfExistsAction = machinery.OverwriteFile
+	} else {
+		// Keep the playbook if it was edited before the webhook was scaffolded again.
+		f.IfExistsAction = machinery.SkipFile
+	}
+
+	return nil
+}
+
+var _ machinery.Template = &MutatingWebhook{}
+
+// MutatingWebhook scaffolds the playbook run for admission requests of the mutating webhook
+type MutatingWebhook struct {
+	machinery.TemplateMixin
+	machinery.ResourceMixin
+
+	Force bool
+}
+
+// SetTemplateDefaults implements machinery.Template
+func (f *MutatingWebhook) SetTemplateDefaults() error {
+	if f.Path == "" {
+		f.Path = filepath.Join("playbooks", "%[kind]_mutate.yml")
+	}
+	f.Path = f.Resource.Replacer().Replace(f.Path)
+
+	f.TemplateBody = mutatingWebhookTmpl
+
+	if f.Force {
+		f.IfExistsAction = machinery.OverwriteFile
+	} else {
+		// Keep the playbook if it was edited before the webhook was scaffolded again.
+		f.IfExistsAction = machinery.SkipFile
+	}
+
+	return nil
+}
+
+const validatingWebhookTmpl = `---
+# Run for each admission request to create or update a {{ .Resource.Kind }}.
+# The request is available in the ansible_operator_admission variable.
+# A failed task denies the request, with the task's message as the reason.
+- hosts: localhost
+  gather_facts: no
+  collections:
+    - community.kubernetes
+    - operator_sdk.util
+  tasks:
+    - name: Validate the {{ .Resource.Kind }}
+      assert:
+        that:
+          # FIXME: Add the conditions a valid {{ .Resource.Kind }} must meet.
+          - true
+        fail_msg: "Invalid {{ .Resource.Kind }}"
+`
+
+const mutatingWebhookTmpl = `---
+# Run for each admission request to create or update a {{ .Resource.Kind }}.
+# The request is available in the ansible_operator_admission variable.
+# A failed task denies the request, with the task's message as the reason.
+# Set the "patch" stat to a list of JSONPatch operations to modify the object.
+- hosts: localhost
+  gather_facts: no
+  collections:
+    - community.kubernetes
+    - operator_sdk.util
+  tasks:
+    - name: Default the {{ .Resource.Kind }}
+      set_stats:
+        data:
+          # FIXME: Add the JSONPatch operations that default the {{ .Resource.Kind }}.
+          patch: []
+`
@@ -0,0 +1,135 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scaffolds
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/afero"
+	"sigs.k8s.io/kubebuilder/v3/pkg/config"
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+	"sigs.k8s.io/kubebuilder/v3/pkg/model/resource"
+	"sigs.k8s.io/kubebuilder/v3/pkg/plugins"
+
+	"github.com/operator-framework/operator-sdk/internal/plugins/ansible/v1/constants"
+	"github.com/operator-framework/operator-sdk/internal/plugins/ansible/v1/scaffolds/internal/templates/config/webhook"
+	"github.com/operator-framework/operator-sdk/internal/plugins/ansible/v1/scaffolds/internal/templates/playbooks"
+)
+
+const watchesFile = "watches.yaml"
+
+var _ plugins.Scaffolder = &webhookScaffolder{}
+
+type webhookScaffolder struct {
+	fs machinery.Filesystem
+
+	config   config.Config
+	resource resource.Resource
+
+	force bool
+}
+
+// NewCreateWebhookScaffolder returns a new plugins.Scaffolder for webhook creation operations
+func NewCreateWebhookScaffolder(cfg config.Config, res resource.Resource, force bool) plugins.Scaffolder {
+	return &webhookScaffolder{
+		config:   cfg,
+		resource: res,
+		force:    force,
+	}
+}
+
+// InjectFS implements plugins.Scaffolder
+func (s *webhookScaffolder) InjectFS(fs machinery.Filesystem) {
+	s.fs = fs
+}
+
+// Scaffold implements plugins.Scaffolder
+func (s *webhookScaffolder) Scaffold() error {
+	if err := s.config.UpdateResource(s.resource); err != nil {
+		return err
+	}
+
+	// Initialize the machinery.Scaffold that will write the files to disk
+	scaffold := machinery.NewScaffold(s.fs,
+		// NOTE: kubebuilder's default permissions are only for root users
+		machinery.WithDirectoryPermissions(0755),
+		machinery.WithFilePermissions(0644),
+		machinery.WithConfig(s.config),
+		machinery.WithResource(&s.resource),
+	)
+
+	createWebhookTemplates := []machinery.Builder{
+		&webhook.Manifests{},
+		&webhook.ManifestsUpdater{},
+	}
+	webhooks := []watchWebhook{}
+	if s.resource.HasDefaultingWebhook() {
+		createWebhookTemplates = append(createWebhookTemplates, &playbooks.MutatingWebhook{Force: s.force})
+		webhooks = append(webhooks, watchWebhook{key: "mutatingWebhook", playbook: "%[kind]_mutate.yml"})
+	}
+	if s.resource.HasValidationWebhook() {
+		createWebhookTemplates = append(createWebhookTemplates, &playbooks.ValidatingWebhook{Force: s.force})
+		webhooks = append(webhooks, watchWebhook{key: "validatingWebhook", playbook: "%[kind]_validate.yml"})
+	}
+
+	if err := scaffold.Execute(createWebhookTemplates...); err != nil {
+		return err
+	}
+
+	return s.updateWatches(webhooks)
+}
+
+// watchWebhook is a webhook to add to the watch of a resource
+type watchWebhook struct {
+	// key of the webhook in the watch
+	key string
+	// playbook the webhook runs, relative to the playbooks dir
+	playbook string
+}
+
+// updateWatches adds webhooks to the watch of the resource in watches.yaml, unless
+// the watch already declares them.
+func (s *webhookScaffolder) updateWatches(webhooks []watchWebhook) error {
+	b, err := afero.ReadFile(s.fs.FS, watchesFile)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", watchesFile, err)
+	}
+
+	// Match the watch as scaffolded by 'create api', up to the next watch or marker.
+	watchRegexp := regexp.MustCompile(fmt.Sprintf(`(?m)^- version: %s\n  group: %s\n  kind: %s\n(?:  .*\n)*`,
+		regexp.QuoteMeta(s.resource.Version), regexp.QuoteMeta(s.resource.QualifiedGroup()),
+		regexp.QuoteMeta(s.resource.Kind)))
+	loc := watchRegexp.FindIndex(b)
+	if loc == nil {
+		return fmt.Errorf("unable to find the watch for %s in %s, please add the webhooks to it manually",
+			s.resource.GVK, watchesFile)
+	}
+	watch := string(b[loc[0]:loc[1]])
+
+	sb := &strings.Builder{}
+	for _, wh := range webhooks {
+		if strings.Contains(watch, fmt.Sprintf("  %s:", wh.key)) {
+			continue
+		}
+		fmt.Fprintf(sb, "  %s:\n    playbook: %s\n", wh.key,
+			filepath.Join(constants.PlaybooksDir, s.resource.Replacer().Replace(wh.playbook)))
+	}
+
+	updated := string(b[:loc[1]]) + sb.String() + string(b[loc[1]:])
+	return afero.WriteFile(s.fs.FS, watchesFile, []byte(updated), 0644)
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scaffolds
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+	"sigs.k8s.io/kubebuilder/v3/pkg/model/resource"
+)
+
+func TestUpdateWatches(t *testing.T) {
+	const watches = `---
+# Use the 'create api' subcommand to add watches to this file.
+- version: v1alpha1
+  group: cache.example.com
+  kind: Memcached
+  role: memcached
+- version: v1alpha1
+  group: cache.example.com
+  kind: Other
+  role: other
+#+kubebuilder:scaffold:watch
+`
+	mutating := watchWebhook{key: "mutatingWebhook", playbook: "%[kind]_mutate.yml"}
+	validating := watchWebhook{key: "validatingWebhook", playbook: "%[kind]_validate.yml"}
+
+	testCases := []struct {
+		name      string
+		watches   string
+		kind      string
+		webhooks  []watchWebhook
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "webhooks are added to the watch of the resource",
+			watches:  watches,
+			kind:     "Memcached",
+			webhooks: []watchWebhook{mutating, validating},
+			expected: `---
+# Use the 'create api' subcommand to add watches to this file.
+- version: v1alpha1
+  group: cache.example.com
+  kind: Memcached
+  role: memcached
+  mutatingWebhook:
+    playbook: playbooks/memcached_mutate.yml
+  validatingWebhook:
+    playbook: playbooks/memcached_validate.yml
+- version: v1alpha1
+  group: cache.example.com
+  kind: Other
+  role: other
+#+kubebuilder:scaffold:watch
+`,
+		},
+		{
+			name:     "last watch",
+			watches:  watches,
+			kind:     "Other",
+			webhooks: []watchWebhook{validating},
+			expected: `---
+# Use the 'create api' subcommand to add watches to this file.
+- version: v1alpha1
+  group: cache.example.com
+  kind: Memcached
+  role: memcached
+- version: v1alpha1
+  group: cache.example.com
+  kind: Other
+  role: other
+  validatingWebhook:
+    playbook: playbooks/other_validate.yml
+#+kubebuilder:scaffold:watch
+`,
+		},
+		{
+			name: "declared webhooks are kept",
+			watches: `- version: v1alpha1
+  group: cache.example.com
+  kind: Memcached
+  role: memcached
+  validatingWebhook:
+    role: validate
+`,
+			kind:     "Memcached",
+			webhooks: []watchWebhook{mutating, validating},
+			expected: `- version: v1alpha1
+  group: cache.example.com
+  kind: Memcached
+  role: memcached
+  validatingWebhook:
+    role: validate
+  mutatingWebhook:
+    playbook: playbooks/memcached_mutate.yml
+`,
+		},
+		{
+			name:      "no watch for the resource",
+			watches:   watches,
+			kind:      "Missing",
+			webhooks:  []watchWebhook{validating},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := machinery.Filesystem{FS: afero.NewMemMapFs()}
+			require.NoError(t, afero.WriteFile(fs.FS, watchesFile, []byte(tc.watches), 0644))
+			s := &webhookScaffolder{
+				fs: fs,
+				resource: resource.Resource{GVK: resource.GVK{
+					Group: "cache", Domain: "example.com", Version: "v1alpha1", Kind: tc.kind,
+				}},
+			}
+
+			err := s.updateWatches(tc.webhooks)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			b, err := afero.ReadFile(fs.FS, watchesFile)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(b))
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ansible
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/kubebuilder/v3/pkg/config"
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+	"sigs.k8s.io/kubebuilder/v3/pkg/model/resource"
+	"sigs.k8s.io/kubebuilder/v3/pkg/plugin"
+	pluginutil "sigs.k8s.io/kubebuilder/v3/pkg/plugin/util"
+
+	"github.com/operator-framework/operator-sdk/internal/plugins/ansible/v1/scaffolds"
+	"github.com/operator-framework/operator-sdk/internal/plugins/util"
+)
+
+const (
+	webhookVersionFlag = "webhook-version"
+	defaultingFlag     = "defaulting"
+	validationFlag     = "programmatic-validation"
+	forceFlag          = "force"
+
+	defaultWebhookVersion = "v1"
+)
+
+type createWebhookOptions struct {
+	WebhookVersion           string
+	DoDefaulting, DoValidate bool
+}
+
+func (opts createWebhookOptions) UpdateResource(res *resource.Resource) {
+	res.Webhooks.WebhookVersion = opts.WebhookVersion
+	res.Webhooks.Defaulting = opts.DoDefaulting
+	res.Webhooks.Validation = opts.DoValidate
+
+	// Ensure that Path is empty as this is not a Go project
+	res.Path = ""
+}
+
+var _ plugin.CreateWebhookSubcommand = &createWebhookSubcommand{}
+
+type createWebhookSubcommand struct {
+	config   config.Config
+	resource *resource.Resource
+	options  createWebhookOptions
+
+	// force indicates that the webhook should be scaffolded even if it already exists
+	force bool
+}
+
+func (p *createWebhookSubcommand) UpdateMetadata(cliMeta plugin.CLIMetadata, subcmdMeta *plugin.SubcommandMetadata) {
+	subcmdMeta.Description = `Scaffold admission webhooks for an API resource, served by the ansible-operator.
+
+    - generates the webhook configurations and kustomize manifests
+    - generates an Ansible playbook per webhook, run for each admission request
+    - updates watches.yaml to map the webhooks to the playbooks
+
+    A failed task denies the admission request. The mutating webhook playbook modifies
+    the resource by returning a list of JSONPatch operations as the "patch" stat.
+
+`
+	subcmdMeta.Examples = fmt.Sprintf(`# Create defaulting and validating webhooks for an existing API
+  $ %[1]s create webhook \
+      --group=apps --version=v1alpha1 \
+      --kind=AppService \
+      --defaulting \
+      --programmatic-validation
+`, cliMeta.CommandName)
+}
+
+func (p *createWebhookSubcommand) BindFlags(fs *pflag.FlagSet) {
+	fs.SortFlags = false
+	fs.StringVar(&p.options.WebhookVersion, webhookVersionFlag, defaultWebhookVersion,
+		"version of {Mutating,Validating}WebhookConfigurations to scaffold. Options: [v1, v1beta1]")
+	fs.BoolVar(&p.options.DoDefaulting, defaultingFlag, false, "if set, scaffold the mutating webhook")
+	fs.BoolVar(&p.options.DoValidate, validationFlag, false, "if set, scaffold the validating webhook")
+	fs.BoolVar(&p.force, forceFlag, false, "attempt to create the webhooks even if they already exist")
+}
+
+func (p *createWebhookSubcommand) InjectConfig(c config.Config) error {
+	p.config = c
+
+	return nil
+}
+
+func (p *createWebhookSubcommand) InjectResource(res *resource.Resource) error {
+	p.resource = res
+
+	p.options.UpdateResource(p.resource)
+
+	if err := p.resource.Validate(); err != nil {
+		return err
+	}
+
+	if !p.resource.HasDefaultingWebhook() && !p.resource.HasValidationWebhook() {
+		return fmt.Errorf("at least one of --%s and --%s must be set", defaultingFlag, validationFlag)
+	}
+
+	// Check that the API exists to create webhooks for
+	r, err := p.config.GetResource(p.resource.GVK)
+	if err != nil || !r.HasAPI() {
+		return errors.New("webhooks can only be created for an existing API")
+	}
+	if r.Webhooks != nil && !r.Webhooks.IsEmpty() && !p.force {
+		return errors.New("the webhook resource already exists")
+	}
+
+	if pluginutil.HasDifferentWebhookVersion(p.config, p.resource.Webhooks.WebhookVersion) {
+		return fmt.Errorf("only one webhook version can be used for all resources, cannot add %q",
+			p.resource.Webhooks.WebhookVersion)
+	}
+
+	return nil
+}
+
+func (p *createWebhookSubcommand) Scaffold(fs machinery.Filesystem) error {
+	if err := util.UpdateKustomizationsCreateWebhook(); err != nil {
+		return fmt.Errorf("error updating kustomization.yaml files: %v", err)
+	}
+
+	scaffolder := scaffolds.NewCreateWebhookScaffolder(p.config, *p.resource, p.force)
+	scaffolder.InjectFS(fs)
+	if err := scaffolder.Scaffold(); err != nil {
+		return err
+	}
+
+	return nil
+}
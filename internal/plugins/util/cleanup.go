@@ -21,6 +21,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
@@ -137,3 +139,96 @@ func UpdateKustomizationsCreateAPI() error {
 
 	return nil
 }
+
+// UpdateKustomizationsCreateWebhook restores the webhook parts of config/default/kustomization.yaml
+// that UpdateKustomizationsInit removed, for CreateWebhook plugins of non-Go projects. The webhook
+// is enabled and cert-manager is left for the user to enable, as for Go projects. The bases and
+// patches it references are scaffolded by the kustomize plugin.
+func UpdateKustomizationsCreateWebhook() error {
+
+	defaultKFile := filepath.Join("config", "default", "kustomization.yaml")
+	defaultKBytes, err := ioutil.ReadFile(defaultKFile)
+	if err != nil {
+		return fmt.Errorf("read %s: %v", defaultKFile, err)
+	}
+	updated, err := addWebhookKustomizations(string(defaultKBytes))
+	if err != nil {
+		return fmt.Errorf("update %s: %v", defaultKFile, err)
+	}
+	return ioutil.WriteFile(defaultKFile, []byte(updated), 0644)
+}
+
+// varsRegexp matches the vars key of a kustomization.
+var varsRegexp = regexp.MustCompile(`(?m)^vars:`)
+
+// addWebhookKustomizations adds the webhook base and patch, and the commented out cert-manager
+// parts, to the default kustomization k. k is returned as is if it already has the webhook base,
+// e.g. when a webhook was created before.
+func addWebhookKustomizations(k string) (string, error) {
+	if strings.Contains(k, "- ../webhook") {
+		return k, nil
+	}
+
+	const managerBase = "\n- ../manager\n"
+	if !strings.Contains(k, managerBase) {
+		return "", errors.New("unable to find the manager base")
+	}
+	k = strings.Replace(k, managerBase, `
+- ../manager
+# [WEBHOOK] Serves the admission webhooks declared in watches.yaml.
+- ../webhook
+# [CERTMANAGER] To enable cert-manager, uncomment all sections with 'CERTMANAGER'. 'WEBHOOK' components are required.
+#- ../certmanager
+`, 1)
+
+	const patches = `
+# [WEBHOOK] Serves the admission webhooks declared in watches.yaml.
+- manager_webhook_patch.yaml
+
+# [CERTMANAGER] To enable cert-manager, uncomment all sections with 'CERTMANAGER'.
+# 'CERTMANAGER' needs to be enabled to use ca injection
+#- webhookcainjection_patch.yaml
+`
+	// The patches are appended to patchesStrategicMerge, which is expected to be the last key,
+	// or to precede the vars of the user, to which the cert-manager vars are left to be added.
+	if loc := varsRegexp.FindStringIndex(k); loc != nil {
+		log.Infof("The default kustomization already declares vars, add the cert-manager vars to them " +
+			"to enable cert-manager")
+		return k[:loc[0]] + strings.TrimPrefix(patches, "\n") + "\n" + k[loc[0]:], nil
+	}
+	return k + patches + certManagerVars, nil
+}
+
+// certManagerVars are the commented out vars of config/default/kustomization.yaml that
+// enable cert-manager for webhooks.
+const certManagerVars = `
+# the following config is for teaching kustomize how to do var substitution
+vars:
+# [CERTMANAGER] To enable cert-manager, uncomment all sections with 'CERTMANAGER' prefix.
+#- name: CERTIFICATE_NAMESPACE # namespace of the certificate CR
+#  objref:
+#    kind: Certificate
+#    group: cert-manager.io
+#    version: v1
+#    name: serving-cert # this name should match the one in certificate.yaml
+#  fieldref:
+#    fieldpath: metadata.namespace
+#- name: CERTIFICATE_NAME
+#  objref:
+#    kind: Certificate
+#    group: cert-manager.io
+#    version: v1
+#    name: serving-cert # this name should match the one in certificate.yaml
+#- name: SERVICE_NAMESPACE # namespace of the service
+#  objref:
+#    kind: Service
+#    version: v1
+#    name: webhook-service
+#  fieldref:
+#    fieldpath: metadata.namespace
+#- name: SERVICE_NAME
+#  objref:
+#    kind: Service
+#    version: v1
+#    name: webhook-service
+`
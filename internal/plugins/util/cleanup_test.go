@@ -0,0 +1,65 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddWebhookKustomizations(t *testing.T) {
+	const kustomization = `namePrefix: memcached-
+
+bases:
+- ../crd
+- ../rbac
+- ../manager
+
+patchesStrategicMerge:
+- manager_auth_proxy_patch.yaml
+`
+
+	t.Run("webhook is added", func(t *testing.T) {
+		k, err := addWebhookKustomizations(kustomization)
+		require.NoError(t, err)
+		assert.Contains(t, k, "- ../manager\n# [WEBHOOK] Serves the admission webhooks declared in watches.yaml.\n- ../webhook\n")
+		assert.Contains(t, k, "- manager_auth_proxy_patch.yaml\n\n# [WEBHOOK] Serves the admission webhooks "+
+			"declared in watches.yaml.\n- manager_webhook_patch.yaml\n")
+		assert.Equal(t, 1, strings.Count(k, "\nvars:\n"))
+
+		// A second webhook leaves the kustomization as is.
+		again, err := addWebhookKustomizations(k)
+		require.NoError(t, err)
+		assert.Equal(t, k, again)
+	})
+
+	t.Run("vars of the user are kept", func(t *testing.T) {
+		withVars := kustomization + "\nvars:\n- name: MY_VAR\n  objref:\n    kind: Service\n    version: v1\n    name: my-service\n"
+		k, err := addWebhookKustomizations(withVars)
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(k, "vars:"))
+		assert.Contains(t, k, "- manager_webhook_patch.yaml\n")
+		assert.Less(t, strings.Index(k, "- manager_webhook_patch.yaml"), strings.Index(k, "vars:"))
+		assert.True(t, strings.HasSuffix(k, "    name: my-service\n"))
+	})
+
+	t.Run("no manager base", func(t *testing.T) {
+		_, err := addWebhookKustomizations("bases:\n- ../crd\n")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,298 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ansibletest reconciles the CRs of an Ansible-based operator in Go tests,
+// without building the operator image or deploying it to a cluster. The watch of the
+// CRs is read from the operator's watches.yaml with LoadWatch.
+package ansibletest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"runtime"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/controller"
+	ansiblestatus "github.com/operator-framework/operator-sdk/internal/ansible/controller/status"
+	"github.com/operator-framework/operator-sdk/internal/ansible/events"
+	"github.com/operator-framework/operator-sdk/internal/ansible/proxy"
+	"github.com/operator-framework/operator-sdk/internal/ansible/proxy/controllermap"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner"
+	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
+)
+
+const (
+	// eventBufferSize is the number of Events recorded during a reconcile that are kept.
+	eventBufferSize = 100
+	// defaultAnsibleVerbosity is the default of the ansible-operator --ansible-verbosity flag.
+	defaultAnsibleVerbosity = 2
+)
+
+// Options - configures a Harness.
+type Options struct {
+	// Watch is the watches.yaml entry of the CRs that are reconciled.
+	Watch watches.Watch
+	// Runner runs the playbook or role of Watch. By default, a Runner is created from
+	// Watch, which requires ansible-runner to be installed and Config to be set.
+	Runner runner.Runner
+	// Config is the API server the CRs are reconciled against, e.g. one started with
+	// envtest. The playbook or role reaches it through the proxy, which records its
+	// requests. When nil, a fake client is used and Runner must be set.
+	Config *rest.Config
+	// Objects are created before the first reconcile, e.g. the Secrets a playbook reads.
+	Objects []client.Object
+}
+
+// LoadWatch - returns the entry for gvk of the watches.yaml at path, with the defaults
+// the ansible-operator applies when run without flags.
+func LoadWatch(path string, gvk schema.GroupVersionKind) (watches.Watch, error) {
+	ws, err := watches.Load(path, runtime.NumCPU(), defaultAnsibleVerbosity)
+	if err != nil {
+		return watches.Watch{}, err
+	}
+	for _, w := range ws {
+		if w.GroupVersionKind == gvk {
+			return w, nil
+		}
+	}
+	return watches.Watch{}, fmt.Errorf("no watch for %s in %s", gvk, path)
+}
+
+// Harness - reconciles CRs the way the ansible-operator does.
+type Harness struct {
+	// Client reads and writes the objects of the API server, or of the fake client.
+	Client client.Client
+
+	reconciler *controller.AnsibleOperatorReconciler
+	recorder   *record.FakeRecorder
+	requests   *requestLog
+	closers    []func()
+}
+
+// New - returns a Harness configured by opts. Close must be called once it is no longer used.
+func New(opts Options) (*Harness, error) {
+	if opts.Watch.GroupVersionKind.Empty() {
+		return nil, errors.New("the watch must have a group, version and kind")
+	}
+	h := &Harness{
+		recorder: record.NewFakeRecorder(eventBufferSize),
+		requests: &requestLog{},
+	}
+
+	proxyURL := ""
+	if opts.Config == nil {
+		if opts.Runner == nil {
+			return nil, errors.New("a runner must be set when no API server config is")
+		}
+		h.Client = fake.NewClientBuilder().WithObjects(opts.Objects...).Build()
+	} else {
+		c, err := client.New(opts.Config, client.Options{})
+		if err != nil {
+			return nil, err
+		}
+		h.Client = c
+		for _, obj := range opts.Objects {
+			if err := h.Client.Create(context.TODO(), obj); err != nil {
+				return nil, fmt.Errorf("unable to create %s: %w", client.ObjectKeyFromObject(obj), err)
+			}
+		}
+		if proxyURL, err = h.startProxy(opts); err != nil {
+			h.Close()
+			return nil, err
+		}
+	}
+
+	if opts.Runner == nil {
+		r, err := runner.New(opts.Watch, "", nil, h.Client)
+		if err != nil {
+			h.Close()
+			return nil, err
+		}
+		opts.Runner = r
+	}
+	var finalizerTimeout time.Duration
+	var finalizerMaxAttempts int
+	if opts.Watch.Finalizer != nil {
+		finalizerTimeout = opts.Watch.Finalizer.Timeout
+		finalizerMaxAttempts = opts.Watch.Finalizer.MaxAttempts
+	}
+	h.reconciler = &controller.AnsibleOperatorReconciler{
+		GVK:                  opts.Watch.GroupVersionKind,
+		Runner:               opts.Runner,
+		Client:               h.Client,
+		APIReader:            h.Client,
+		EventHandlers:        []events.EventHandler{},
+		ReconcilePeriod:      opts.Watch.ReconcilePeriod,
+		ManageStatus:         opts.Watch.ManageStatus,
+		DryRun:               opts.Watch.DryRun,
+		DriftDetection:       opts.Watch.DriftDetection,
+		ProxyURL:             proxyURL,
+		EventRecorder:        h.recorder,
+		FinalizerTimeout:     finalizerTimeout,
+		FinalizerMaxAttempts: finalizerMaxAttempts,
+
+		RecordAppliedGeneration: len(opts.Watch.Routes) > 0,
+	}
+	return h, nil
+}
+
+// startProxy serves the proxy in front of the API server of opts.Config, and returns the
+// URL of a server in front of it that records the requests made to the proxy.
+func (h *Harness) startProxy(opts Options) (string, error) {
+	restMapper, err := apiutil.NewDynamicRESTMapper(opts.Config)
+	if err != nil {
+		return "", err
+	}
+	cMap := controllermap.NewControllerMap()
+	cMap.Store(opts.Watch.GroupVersionKind, &controllermap.Contents{APIPolicy: opts.Watch.APIPolicy},
+		opts.Watch.Blacklist)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	h.closers = append(h.closers, func() { _ = l.Close() })
+	// No controller watches the dependent resources, so there's no cache to serve from.
+	err = proxy.Run(make(chan error, 1), proxy.Options{
+		KubeConfig:        opts.Config,
+		RESTMapper:        restMapper,
+		ControllerMap:     cMap,
+		WatchedNamespaces: []string{},
+		DisableCache:      true,
+		OwnerInjection:    true,
+		Listener:          l,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	front := httptest.NewServer(h.requests.handler(
+		httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: l.Addr().String()})))
+	h.closers = append(h.closers, front.Close)
+	return front.URL, nil
+}
+
+// Close - stops the proxy, if it was started.
+func (h *Harness) Close() {
+	for i := len(h.closers) - 1; i >= 0; i-- {
+		h.closers[i]()
+	}
+	h.closers = nil
+}
+
+// Reconcile - creates cr, unless it already exists, and reconciles it once.
+func (h *Harness) Reconcile(ctx context.Context, cr *unstructured.Unstructured) (*Result, error) {
+	key := client.ObjectKeyFromObject(cr)
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(cr.GroupVersionKind())
+	if err := h.Client.Get(ctx, key, existing); apierrors.IsNotFound(err) {
+		if err := h.Client.Create(ctx, cr.DeepCopy()); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	h.requests.take()
+	res := &Result{}
+	res.Result, res.Err = h.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+	res.Requests = h.requests.take()
+	for len(h.recorder.Events) > 0 {
+		res.Events = append(res.Events, <-h.recorder.Events)
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(cr.GroupVersionKind())
+	if err := h.Client.Get(ctx, key, obj); err == nil {
+		res.Object = obj
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ExpectObject - fails t unless the object with the name and namespace of obj exists,
+// in which case it is read into obj.
+func (h *Harness) ExpectObject(t testing.TB, obj client.Object) {
+	t.Helper()
+	if err := h.Client.Get(context.TODO(), client.ObjectKeyFromObject(obj), obj); err != nil {
+		t.Fatalf("Unable to get %s: %v", client.ObjectKeyFromObject(obj), err)
+	}
+}
+
+// Result - the outcome of reconciling a CR once.
+type Result struct {
+	reconcile.Result
+	// Err is the error the reconcile returned, e.g. because a task failed.
+	Err error
+	// Object is the CR after the reconcile, or nil if it no longer exists.
+	Object *unstructured.Unstructured
+	// Requests are the requests the playbook or role made through the proxy.
+	Requests []Request
+	// Events are the Events recorded on the CR, formatted as "<type> <reason> <message>".
+	Events []string
+}
+
+// Condition - returns the condition of type ct of the CR, or nil if it has none.
+func (r *Result) Condition(ct ansiblestatus.ConditionType) *ansiblestatus.Condition {
+	if r.Object == nil {
+		return nil
+	}
+	statusMap, _ := r.Object.Object["status"].(map[string]interface{})
+	return ansiblestatus.GetCondition(ansiblestatus.CreateFromMap(statusMap), ct)
+}
+
+// ExpectCondition - fails t unless the CR has a condition of type ct with status and reason.
+func (r *Result) ExpectCondition(t testing.TB, ct ansiblestatus.ConditionType, status v1.ConditionStatus,
+	reason string) {
+	t.Helper()
+	c := r.Condition(ct)
+	if c == nil {
+		t.Fatalf("The CR has no %s condition", ct)
+	}
+	if c.Status != status || c.Reason != reason {
+		t.Fatalf("Unexpected %s condition status %s and reason %s, expected status %s and reason %s: %s",
+			ct, c.Status, c.Reason, status, reason, c.Message)
+	}
+}
+
+// ExpectRequest - fails t unless the playbook or role made a request with verb for the
+// resource, e.g. "deployments", with name in namespace. The first such request is returned.
+func (r *Result) ExpectRequest(t testing.TB, verb, resource, namespace, name string) Request {
+	t.Helper()
+	for _, req := range r.Requests {
+		if req.Verb == verb && req.Resource == resource && req.Namespace == namespace && req.Name == name {
+			return req
+		}
+	}
+	t.Fatalf("No %s request for %s %s/%s in %v", verb, resource, namespace, name, r.Requests)
+	return Request{}
+}
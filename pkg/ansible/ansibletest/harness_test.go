@@ -0,0 +1,314 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ansibletest
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ansiblestatus "github.com/operator-framework/operator-sdk/internal/ansible/controller/status"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/eventapi"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/fake"
+	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
+)
+
+func TestHarnessReconcile(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "cache.example.com", Version: "v1alpha1", Kind: "Memcached"}
+	eventTime := time.Now()
+	testCases := []struct {
+		name                  string
+		events                []eventapi.JobEvent
+		expectedErr           bool
+		expectedRunningStatus v1.ConditionStatus
+		expectedRunningReason string
+		expectedFailure       bool
+	}{
+		{
+			name: "successful run",
+			events: []eventapi.JobEvent{
+				{Event: eventapi.EventPlaybookOnStats, Created: eventapi.EventTime{Time: eventTime}},
+			},
+			expectedRunningStatus: v1.ConditionTrue,
+			expectedRunningReason: ansiblestatus.SuccessfulReason,
+		},
+		{
+			name: "failed task",
+			events: []eventapi.JobEvent{
+				{
+					Event:     eventapi.EventRunnerOnFailed,
+					Created:   eventapi.EventTime{Time: eventTime},
+					EventData: map[string]interface{}{"res": map[string]interface{}{"msg": "task failed"}},
+				},
+				{Event: eventapi.EventPlaybookOnStats, Created: eventapi.EventTime{Time: eventTime}},
+			},
+			expectedErr:           true,
+			expectedRunningStatus: v1.ConditionFalse,
+			expectedRunningReason: ansiblestatus.RunningReason,
+			expectedFailure:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(Options{
+				Watch:  watches.Watch{GroupVersionKind: gvk, ManageStatus: true},
+				Runner: &fake.Runner{JobEvents: tc.events},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer h.Close()
+
+			cr := &unstructured.Unstructured{}
+			cr.SetGroupVersionKind(gvk)
+			cr.SetName("example")
+			cr.SetNamespace("default")
+			res, err := h.Reconcile(context.TODO(), cr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tc.expectedErr != (res.Err != nil) {
+				t.Fatalf("Unexpected reconcile error: %v", res.Err)
+			}
+			if res.Object == nil {
+				t.Fatalf("The CR no longer exists")
+			}
+			res.ExpectCondition(t, ansiblestatus.RunningConditionType, tc.expectedRunningStatus,
+				tc.expectedRunningReason)
+			if tc.expectedFailure {
+				res.ExpectCondition(t, ansiblestatus.FailureConditionType, v1.ConditionTrue, ansiblestatus.FailedReason)
+			} else if c := res.Condition(ansiblestatus.FailureConditionType); c != nil {
+				t.Fatalf("Unexpected Failure condition: %v", c)
+			}
+
+			got := &unstructured.Unstructured{}
+			got.SetGroupVersionKind(gvk)
+			got.SetName("example")
+			got.SetNamespace("default")
+			h.ExpectObject(t, got)
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	if _, err := New(Options{Runner: &fake.Runner{}}); err == nil {
+		t.Fatalf("Expected an error for a watch without a GVK")
+	}
+	gvk := schema.GroupVersionKind{Group: "cache.example.com", Version: "v1alpha1", Kind: "Memcached"}
+	if _, err := New(Options{Watch: watches.Watch{GroupVersionKind: gvk}}); err == nil {
+		t.Fatalf("Expected an error without a runner or API server config")
+	}
+}
+
+func TestRequestLog(t *testing.T) {
+	l := &requestLog{}
+	s := httptest.NewServer(l.handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		if req.Method == http.MethodPost {
+			if !strings.Contains(string(body), `"name":"memcached"`) {
+				t.Errorf("The request body was not restored: %s", body)
+			}
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		if strings.HasSuffix(req.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})))
+	defer s.Close()
+
+	if _, err := http.Post(s.URL+"/apis/apps/v1/namespaces/default/deployments", "application/json",
+		strings.NewReader(`{"metadata":{"name":"memcached"}}`)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := http.Get(s.URL + "/api/v1/namespaces/default/secrets/missing"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := http.Get(s.URL + "/version"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Request{
+		{Verb: "create", APIGroup: "apps", APIVersion: "v1", Resource: "deployments", Namespace: "default",
+			Name: "memcached", Code: http.StatusCreated},
+		{Verb: "get", APIVersion: "v1", Resource: "secrets", Namespace: "default", Name: "missing",
+			Code: http.StatusNotFound},
+	}
+	requests := l.take()
+	if len(requests) != len(expected) {
+		t.Fatalf("Unexpected requests %v expected %v", requests, expected)
+	}
+	for i := range requests {
+		if requests[i] != expected[i] {
+			t.Fatalf("Unexpected request %v expected %v", requests[i], expected[i])
+		}
+	}
+	if requests := l.take(); len(requests) != 0 {
+		t.Fatalf("Unexpected requests after take: %v", requests)
+	}
+}
+
+// proxyRunner makes requests through the proxy with the kubeconfig of the run, as the
+// k8s modules would, before sending the events of the fake Runner.
+type proxyRunner struct {
+	*fake.Runner
+	t *testing.T
+}
+
+func (r *proxyRunner) Run(ident string, u *unstructured.Unstructured, kubeconfig string,
+	opts ...runner.RunOption) (runner.RunResult, error) {
+	cfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	// The k8s modules send JSON, which is all the injection of owner references handles.
+	cfg.ContentType = "application/json"
+	c, err := client.New(cfg, client.Options{})
+	if err != nil {
+		return nil, err
+	}
+	cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "memcached", Namespace: u.GetNamespace()}}
+	if err := c.Create(context.TODO(), cm); err != nil {
+		return nil, err
+	}
+	if err := c.Get(context.TODO(), client.ObjectKey{Namespace: u.GetNamespace(), Name: "missing"},
+		&v1.ConfigMap{}); !apierrors.IsNotFound(err) {
+		r.t.Errorf("Expected the missing ConfigMap not to be found, got %v", err)
+	}
+	return r.Runner.Run(ident, u, kubeconfig, opts...)
+}
+
+// apiServer is a minimal API server serving ConfigMaps and Memcacheds from memory.
+type apiServer struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *apiServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch req.URL.Path {
+	case "/api":
+		_, _ = w.Write([]byte(`{"kind":"APIVersions","versions":["v1"]}`))
+		return
+	case "/apis":
+		_, _ = w.Write([]byte(`{"kind":"APIGroupList","apiVersion":"v1","groups":[{"name":"cache.example.com",` +
+			`"versions":[{"groupVersion":"cache.example.com/v1alpha1","version":"v1alpha1"}],` +
+			`"preferredVersion":{"groupVersion":"cache.example.com/v1alpha1","version":"v1alpha1"}}]}`))
+		return
+	case "/api/v1":
+		_, _ = w.Write([]byte(`{"kind":"APIResourceList","groupVersion":"v1","resources":[` +
+			`{"name":"configmaps","namespaced":true,"kind":"ConfigMap","verbs":["create","get","list","watch"]}]}`))
+		return
+	case "/apis/cache.example.com/v1alpha1":
+		_, _ = w.Write([]byte(`{"kind":"APIResourceList","groupVersion":"cache.example.com/v1alpha1","resources":[` +
+			`{"name":"memcacheds","namespaced":true,"kind":"Memcached","verbs":["create","get","list","update","watch"]},` +
+			`{"name":"memcacheds/status","namespaced":true,"kind":"Memcached","verbs":["get","update"]}]}`))
+		return
+	}
+
+	key := strings.TrimSuffix(req.URL.Path, "/status")
+	switch req.Method {
+	case http.MethodPost:
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(readBody(req)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		obj.SetResourceVersion("1")
+		data, _ := obj.MarshalJSON()
+		s.objects[key+"/"+obj.GetName()] = data
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(data)
+	case http.MethodPut:
+		s.objects[key] = readBody(req)
+		_, _ = w.Write(s.objects[key])
+	case http.MethodGet:
+		data, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+			return
+		}
+		_, _ = w.Write(data)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func readBody(req *http.Request) []byte {
+	data, _ := ioutil.ReadAll(req.Body)
+	return data
+}
+
+func TestHarnessProxy(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "cache.example.com", Version: "v1alpha1", Kind: "Memcached"}
+	server := httptest.NewServer(&apiServer{objects: map[string][]byte{}})
+	defer server.Close()
+
+	h, err := New(Options{
+		Watch: watches.Watch{GroupVersionKind: gvk},
+		Runner: &proxyRunner{t: t, Runner: &fake.Runner{JobEvents: []eventapi.JobEvent{
+			{Event: eventapi.EventPlaybookOnStats, Created: eventapi.EventTime{Time: time.Now()}},
+		}}},
+		Config: &rest.Config{Host: server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer h.Close()
+
+	cr := &unstructured.Unstructured{}
+	cr.SetGroupVersionKind(gvk)
+	cr.SetName("example")
+	cr.SetNamespace("default")
+	res, err := h.Reconcile(context.TODO(), cr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if res.Err != nil {
+		t.Fatalf("Unexpected reconcile error: %v", res.Err)
+	}
+
+	if req := res.ExpectRequest(t, "create", "configmaps", "default", "memcached"); req.Code != http.StatusCreated {
+		t.Fatalf("Unexpected code %d for the create request", req.Code)
+	}
+	if req := res.ExpectRequest(t, "get", "configmaps", "default", "missing"); req.Code != http.StatusNotFound {
+		t.Fatalf("Unexpected code %d for the get request", req.Code)
+	}
+	// The proxy injects the owner reference of the CR into created objects.
+	cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "memcached", Namespace: "default"}}
+	h.ExpectObject(t, cm)
+	if len(cm.OwnerReferences) != 1 || cm.OwnerReferences[0].Name != "example" {
+		t.Fatalf("Unexpected owner references %v", cm.OwnerReferences)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ansibletest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	k8sRequest "github.com/operator-framework/operator-sdk/internal/ansible/proxy/requestfactory"
+)
+
+// Request - a request the playbook or role made through the proxy.
+type Request struct {
+	// Verb is the Kubernetes verb of the request, e.g. "get", "list" or "create".
+	Verb       string
+	APIGroup   string
+	APIVersion string
+	// Resource is the plural name of the resource, e.g. "deployments".
+	Resource    string
+	Subresource string
+	Namespace   string
+	// Name is the name of the object. For a create, it is read from the request body.
+	Name string
+	// Code is the status code of the response.
+	Code int
+}
+
+// requestLog records the resource requests served by a handler.
+type requestLog struct {
+	mu       sync.Mutex
+	requests []Request
+}
+
+func (l *requestLog) handler(next http.Handler) http.Handler {
+	rf := k8sRequest.RequestInfoFactory{APIPrefixes: sets.NewString("api", "apis"),
+		GrouplessAPIPrefixes: sets.NewString("api")}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r, err := rf.NewRequestInfo(req)
+		if err != nil || !r.IsResourceRequest {
+			next.ServeHTTP(w, req)
+			return
+		}
+		request := Request{
+			Verb:        r.Verb,
+			APIGroup:    r.APIGroup,
+			APIVersion:  r.APIVersion,
+			Resource:    r.Resource,
+			Subresource: r.Subresource,
+			Namespace:   r.Namespace,
+			Name:        requestObjectName(r, req),
+		}
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rec, req)
+		request.Code = rec.code
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.requests = append(l.requests, request)
+	})
+}
+
+// take returns the recorded requests and clears the log.
+func (l *requestLog) take() []Request {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	requests := l.requests
+	l.requests = nil
+	return requests
+}
+
+// requestObjectName returns the name of the object of a request. The name of an
+// object being created is read from the request body, which is then restored.
+func requestObjectName(r *k8sRequest.RequestInfo, req *http.Request) string {
+	if r.Name != "" || req.Method != http.MethodPost || req.Body == nil {
+		return r.Name
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	if err != nil {
+		return ""
+	}
+	obj := metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return ""
+	}
+	if obj.Name == "" {
+		return obj.GenerateName
+	}
+	return obj.Name
+}
+
+// statusRecorder records the status code written to a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.code = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap allows the responses of watch requests to be flushed as they are streamed.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}